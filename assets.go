@@ -0,0 +1,143 @@
+package hop
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Asset is a resource that a rendered page references and that the
+// browser should start fetching early.
+type Asset struct {
+	URL string
+	// As is the destination of the asset: style, script or image.
+	As string
+	// Module is set for module scripts, which are preloaded with
+	// rel=modulepreload.
+	Module bool
+	// SrcSet and Sizes are the responsive candidates of an image.
+	SrcSet string
+	Sizes  string
+}
+
+// Link returns the value of a Link header that preloads the asset,
+// e.g. `</app.css>; rel=preload; as=style`.
+func (a Asset) Link() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<%s>", linkURLEscaper.Replace(a.URL))
+	if a.Module {
+		b.WriteString("; rel=modulepreload")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "; rel=preload; as=%s", a.As)
+	if a.SrcSet != "" {
+		fmt.Fprintf(&b, "; imagesrcset=%q", a.SrcSet)
+	}
+	if a.Sizes != "" {
+		fmt.Fprintf(&b, "; imagesizes=%q", a.Sizes)
+	}
+	return b.String()
+}
+
+var linkURLEscaper = strings.NewReplacer("<", "%3C", ">", "%3E", " ", "%20")
+
+// Assets collects the assets referenced by renders:
+//
+//   - stylesheets, <link rel="stylesheet" href="...">
+//   - module scripts, <script type="module" src="...">
+//   - images marked as important, <img priority src="..."> or
+//     <img fetchpriority="high" src="...">, where priority is rendered
+//     as fetchpriority="high"
+//
+// Each URL is collected once, in the order it is rendered.
+type Assets struct {
+	List []Asset
+	seen map[string]bool
+}
+
+// ExecuteFunctionWithAssets is like ExecuteFunction but also collects
+// the assets that the output references in assets. A handler that
+// renders into a buffer can then announce them before writing the
+// body, see WriteEarlyHints.
+func (p *Program) ExecuteFunctionWithAssets(w io.Writer, moduleName string, functionName string, data any, assets *Assets) error {
+	withAssets := *p
+	withAssets.assets = assets
+	return withAssets.ExecuteFunction(w, moduleName, functionName, data)
+}
+
+// Header returns the value of a Link header that preloads the assets.
+func (a *Assets) Header() string {
+	links := make([]string, len(a.List))
+	for i, asset := range a.List {
+		links[i] = asset.Link()
+	}
+	return strings.Join(links, ", ")
+}
+
+// WriteEarlyHints sends a 103 Early Hints response that preloads the
+// assets, if there are any. The Link header stays set for the final
+// response.
+func WriteEarlyHints(w http.ResponseWriter, assets *Assets) {
+	if len(assets.List) == 0 {
+		return
+	}
+	for _, asset := range assets.List {
+		w.Header().Add("Link", asset.Link())
+	}
+	w.WriteHeader(http.StatusEarlyHints)
+}
+
+// appendPriority renders the `priority` attribute of an image, which
+// marks it as important, as fetchpriority="high" unless the image sets
+// fetchpriority itself.
+func appendPriority(attrs []html.Attribute, n *html.Node) []html.Attribute {
+	if _, ok := getAttribute(n, "fetchpriority"); ok {
+		return attrs
+	}
+	return append(attrs, html.Attribute{Key: "fetchpriority", Val: "high"})
+}
+
+// collect adds the assets in the tree rooted at n.
+func (a *Assets) collect(n *html.Node) {
+	if a == nil {
+		return
+	}
+	if n.Type == html.ElementNode {
+		if asset, ok := assetOf(n); ok && !a.seen[asset.URL] {
+			if a.seen == nil {
+				a.seen = map[string]bool{}
+			}
+			a.seen[asset.URL] = true
+			a.List = append(a.List, asset)
+		}
+	}
+	for c := range n.ChildNodes() {
+		a.collect(c)
+	}
+}
+
+// assetOf returns the asset that a rendered element references.
+func assetOf(n *html.Node) (Asset, bool) {
+	attr := func(key string) string {
+		v, _ := getAttribute(n, key)
+		return v
+	}
+	switch n.Data {
+	case "link":
+		if strings.EqualFold(attr("rel"), "stylesheet") && attr("href") != "" {
+			return Asset{URL: attr("href"), As: "style"}, true
+		}
+	case "script":
+		if attr("type") == "module" && attr("src") != "" {
+			return Asset{URL: attr("src"), As: "script", Module: true}, true
+		}
+	case "img":
+		if strings.EqualFold(attr("fetchpriority"), "high") && attr("src") != "" {
+			return Asset{URL: attr("src"), As: "image", SrcSet: attr("srcset"), Sizes: attr("sizes")}, true
+		}
+	}
+	return Asset{}, false
+}
@@ -0,0 +1,70 @@
+package hop
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/hoplang/hop-go/parser"
+)
+
+// Budget limits the complexity of the templates of functions, to keep
+// them small enough to review and change. A limit of zero is not
+// enforced. See TemplateStats for how the templates are measured.
+type Budget struct {
+	// MaxNodes is the largest number of nodes of a function.
+	MaxNodes int
+	// MaxDepth is the deepest nesting of elements in a function.
+	MaxDepth int
+	// MaxRenders is the largest number of `render` tags in a function.
+	MaxRenders int
+	// Strict turns functions over budget into compile errors. Without
+	// it they are reported as warnings.
+	Strict bool
+}
+
+// SetBudget sets the budget that the templates of the functions must
+// stay within:
+//
+//	c.SetBudget(hop.Budget{MaxNodes: 200, MaxDepth: 12, MaxRenders: 20})
+func (c *Compiler) SetBudget(budget Budget) {
+	c.budget = budget
+}
+
+// checkBudget reports the functions of a module that are over budget,
+// as warnings or, for a strict budget, as an error.
+func (p *Program) checkBudget(moduleName string, budget Budget) error {
+	mod := p.modules[moduleName]
+	var errs []error
+	for _, functionName := range slices.Sorted(maps.Keys(mod.functions)) {
+		function := mod.functions[functionName]
+		stats := functionStats(function)
+		for _, limit := range []struct {
+			what  string
+			value int
+			max   int
+		}{
+			{"nodes", stats.Nodes, budget.MaxNodes},
+			{"levels of nesting", stats.MaxDepth, budget.MaxDepth},
+			{"render tags", stats.Renders, budget.MaxRenders},
+		} {
+			if limit.max <= 0 || limit.value <= limit.max {
+				continue
+			}
+			message := fmt.Sprintf("function '%s' has %d %s, the budget is %d", functionName, limit.value, limit.what, limit.max)
+			pos := mod.nodePositions[function].Start
+			if budget.Strict {
+				errs = append(errs, withModule(&parser.ParseError{Pos: pos, Message: "budget error: " + message}, "checking budget", moduleName, mod.path))
+				continue
+			}
+			p.warnings = append(p.warnings, Warning{
+				Module:  moduleName,
+				File:    mod.path,
+				Pos:     pos,
+				Message: message,
+			})
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,75 @@
+package hop
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hoplang/hop-go/escape"
+	"github.com/hoplang/hop-go/parser"
+	"github.com/hoplang/hop-go/typechecker"
+	"golang.org/x/net/html"
+)
+
+// SetBuildVar defines a compile-time constant, such as the base URL of
+// assets or the revision of the source. Templates reference it with a
+// $ prefix in inner-text and attr- bindings:
+//
+//	<link rel="stylesheet" attr-href="$assetBase">
+//	<footer inner-text="$revision"></footer>
+//
+// Build variables are typechecked as strings and substituted during
+// compilation, so they cost nothing at render time. Their values are
+// trusted and are not checked against the URL policy.
+func (c *Compiler) SetBuildVar(name string, value string) {
+	if c.options.BuildVars == nil {
+		c.options.BuildVars = map[string]string{}
+	}
+	c.options.BuildVars[name] = value
+}
+
+// substituteBuildVars replaces the bindings of build variables below n
+// by static attributes and text. The bindings must have been
+// typechecked.
+func substituteBuildVars(n *html.Node, positions map[*html.Node]parser.NodePosition, vars map[string]string) error {
+	if len(vars) == 0 {
+		return nil
+	}
+	for c := range n.ChildNodes() {
+		if err := substituteBuildVars(c, positions, vars); err != nil {
+			return err
+		}
+	}
+	if n.Type != html.ElementNode {
+		return nil
+	}
+	var attrs []html.Attribute
+	for _, attr := range n.Attr {
+		name, ok := strings.CutPrefix(attr.Val, "$")
+		if !ok {
+			attrs = append(attrs, attr)
+			continue
+		}
+		value := vars[name]
+		switch {
+		case attr.Key == "inner-text":
+			for n.FirstChild != nil {
+				n.RemoveChild(n.FirstChild)
+			}
+			n.AppendChild(&html.Node{Type: html.TextNode, Data: value})
+		case strings.HasPrefix(attr.Key, "attr-"):
+			if err := escape.CheckAttribute(value); err != nil {
+				pos := positions[n].Attributes[attr.Key]
+				return &typechecker.TypeError{
+					Start:   pos.ValueStart,
+					End:     pos.ValueEnd,
+					Context: fmt.Sprintf("can not use build variable '%s' as %s: %s", name, attr.Key, err),
+				}
+			}
+			attrs = append(attrs, html.Attribute{Key: strings.TrimPrefix(attr.Key, "attr-"), Val: value})
+		default:
+			attrs = append(attrs, attr)
+		}
+	}
+	n.Attr = attrs
+	return nil
+}
@@ -0,0 +1,73 @@
+package hop
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/hoplang/hop-go/parser"
+	"golang.org/x/net/html"
+)
+
+// ClassNames returns the class names that the templates of the program
+// use literally, sorted and without duplicates. It is meant to be
+// written to a file that is scanned by a CSS tool such as Tailwind,
+// which only generates the classes that it finds in its content.
+//
+// Besides static class attributes, the names of class- bindings and
+// the string literals of attr-class bindings are included, i.e. the
+// fallbacks and the arguments of the default filter:
+//
+//	<div class="rounded p-2" attr-class="item.color ?? 'bg-gray-100'"></div>
+//	<li class-active="tab.selected"></li>
+//
+// Classes that are only known at render time can not be found and must
+// be listed in the configuration of the tool instead.
+func (p *Program) ClassNames() []string {
+	classes := map[string]bool{}
+	addClasses := func(s string) {
+		for _, class := range strings.Fields(s) {
+			classes[class] = true
+		}
+	}
+	var visit func(n *html.Node)
+	visit = func(n *html.Node) {
+		for _, attr := range n.Attr {
+			if name, ok := strings.CutPrefix(attr.Key, "class-"); ok {
+				classes[name] = true
+				continue
+			}
+			switch attr.Key {
+			case "class":
+				addClasses(attr.Val)
+			case "attr-class":
+				binding, err := parser.ParseBinding(attr.Val)
+				if err != nil {
+					continue
+				}
+				for _, alternative := range binding.Alternatives {
+					if alternative.IsLiteral {
+						addClasses(alternative.Literal)
+					}
+				}
+				for _, filter := range binding.Filters {
+					if filter.Name != "default" {
+						continue
+					}
+					for _, arg := range filter.Args {
+						if s, ok := arg.(string); ok {
+							addClasses(s)
+						}
+					}
+				}
+			}
+		}
+		for c := range n.ChildNodes() {
+			visit(c)
+		}
+	}
+	for _, module := range p.modules {
+		visit(module.root)
+	}
+	return slices.Sorted(maps.Keys(classes))
+}
@@ -0,0 +1,151 @@
+package hop
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/hoplang/hop-go/parser"
+	"golang.org/x/net/html"
+)
+
+// Clone is a subtree of markup that is repeated in the templates of a
+// program, and that could be extracted into a shared function, see
+// Compiler.ExtractFunction.
+type Clone struct {
+	// Nodes is the number of nodes of the subtree.
+	Nodes int
+	// Occurrences are the places the subtree appears, ordered by
+	// module and position.
+	Occurrences []Occurrence
+}
+
+// Occurrence is a place where a clone appears.
+type Occurrence struct {
+	Module   string
+	File     string
+	Function string
+	Pos      parser.Position
+}
+
+// variableAttributes are the attributes whose values name variables.
+var variableAttributes = map[string]bool{
+	"as":          true,
+	"index-as":    true,
+	"meta-as":     true,
+	"params-as":   true,
+	"children-as": true,
+}
+
+// cloneCandidate is an element whose subtree may be repeated.
+type cloneCandidate struct {
+	n          *html.Node
+	size       int
+	occurrence Occurrence
+}
+
+// Clones finds the subtrees of at least minNodes nodes that appear more
+// than once in the functions of the program, ordered by size. Subtrees
+// are compared by their shape: the tags, the attribute names and the
+// values of static attributes must be the same, but text and the paths
+// that bindings use may differ, since those usually become the
+// parameters of the shared function. The names of variables may differ
+// as well. A clone is only reported when its
+// occurrences are not all part of a larger clone.
+func (p *Program) Clones(minNodes int) []Clone {
+	groups := map[string][]cloneCandidate{}
+	hashes := map[*html.Node]string{}
+	for _, moduleName := range slices.Sorted(maps.Keys(p.modules)) {
+		mod := p.modules[moduleName]
+		var visit func(n *html.Node, functionName string) (string, int)
+		visit = func(n *html.Node, functionName string) (string, int) {
+			if n.Type == html.ElementNode && n.Data == "function" {
+				functionName, _ = getAttribute(n, "name")
+			}
+			h := sha256.New()
+			size := 1
+			switch n.Type {
+			case html.ElementNode:
+				h.Write([]byte("<" + n.Data))
+				for _, attr := range slices.SortedFunc(slices.Values(n.Attr), func(a, b html.Attribute) int {
+					return cmp.Compare(a.Key, b.Key)
+				}) {
+					h.Write([]byte(" " + attr.Key))
+					if !pathAttributes[attr.Key] && !variableAttributes[attr.Key] && !strings.HasPrefix(attr.Key, "attr-") && !strings.HasPrefix(attr.Key, "class-") && !strings.HasPrefix(attr.Key, "data-bind-") {
+						h.Write([]byte("=" + attr.Val))
+					}
+				}
+				h.Write([]byte(">"))
+			case html.TextNode:
+				h.Write([]byte("text"))
+			default:
+				// Comments and the root do not take part in the shape.
+			}
+			for c := range n.ChildNodes() {
+				if c.Type == html.CommentNode || (c.Type == html.TextNode && strings.TrimSpace(c.Data) == "") {
+					continue
+				}
+				childHash, childSize := visit(c, functionName)
+				h.Write([]byte(childHash))
+				size += childSize
+			}
+			hash := hex.EncodeToString(h.Sum(nil))
+			pos, ok := mod.nodePositions[n]
+			if n.Type == html.ElementNode && n.Data != "function" && functionName != "" && ok && size >= minNodes {
+				hashes[n] = hash
+				groups[hash] = append(groups[hash], cloneCandidate{n: n, size: size, occurrence: Occurrence{
+					Module:   moduleName,
+					File:     mod.path,
+					Function: functionName,
+					Pos:      pos.Start,
+				}})
+			}
+			return hash, size
+		}
+		visit(mod.root, "")
+	}
+
+	var clones []Clone
+	for _, candidates := range groups {
+		if len(candidates) < 2 {
+			continue
+		}
+		// Skip the subtrees that are only repeated as part of a larger
+		// clone.
+		nested := true
+		for _, candidate := range candidates {
+			parentHash, ok := hashes[candidate.n.Parent]
+			if !ok || len(groups[parentHash]) < 2 {
+				nested = false
+				break
+			}
+		}
+		if nested {
+			continue
+		}
+		clone := Clone{Nodes: candidates[0].size}
+		for _, candidate := range candidates {
+			clone.Occurrences = append(clone.Occurrences, candidate.occurrence)
+		}
+		slices.SortFunc(clone.Occurrences, compareOccurrences)
+		clones = append(clones, clone)
+	}
+	slices.SortFunc(clones, func(a, b Clone) int {
+		return cmp.Or(
+			cmp.Compare(b.Nodes, a.Nodes),
+			compareOccurrences(a.Occurrences[0], b.Occurrences[0]),
+		)
+	})
+	return clones
+}
+
+func compareOccurrences(a, b Occurrence) int {
+	return cmp.Or(
+		cmp.Compare(a.Module, b.Module),
+		cmp.Compare(a.Pos.Line, b.Pos.Line),
+		cmp.Compare(a.Pos.Column, b.Pos.Column),
+	)
+}
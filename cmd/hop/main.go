@@ -0,0 +1,207 @@
+// Command hop provides tools for working with hop templates.
+//
+// Usage:
+//
+//	hop repl [dir]
+//	hop catalog [-o out] [dir]
+//	hop classes [-o out] [dir]
+//	hop clones [-min n] [dir]
+//	hop convert [-o out] [-param name] file
+//
+// The repl subcommand compiles the modules in dir, or in the current
+// directory, and starts an interactive shell for exploring them.
+//
+// The catalog subcommand writes a static site to out, or to the
+// directory catalog, that shows every exported function of the modules
+// in dir rendered with sample data.
+//
+// The classes subcommand writes the class names that the modules in dir
+// use literally, one per line, to out or to standard output, for CSS
+// tools such as Tailwind that scan content for class names.
+//
+// The clones subcommand lists the subtrees of at least n nodes that are
+// repeated in the functions of the modules in dir, as candidates for
+// shared functions.
+//
+// The convert subcommand translates an html/template file, or a Vue
+// (.vue) or React (.jsx, .tsx) component, into a hop module, which is
+// written to out or to standard output. The parts of the template that
+// could not be converted are listed on standard error.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hoplang/hop-go"
+	"github.com/hoplang/hop-go/hopcatalog"
+	"github.com/hoplang/hop-go/hopconvert"
+	"github.com/hoplang/hop-go/hoprepl"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: hop <command> [arguments]\n\ncommands:\n  repl [dir]                  explore the modules in dir interactively\n  catalog [-o out] [dir]      generate a catalog of the modules in dir\n  classes [-o out] [dir]      list the class names used by the modules in dir\n  clones [-min n] [dir]       list the repeated markup of the modules in dir\n  convert [-o out] file       convert a template or component to hop\n")
+	os.Exit(2)
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		usage()
+	}
+	var err error
+	switch flag.Arg(0) {
+	case "repl":
+		err = repl(flag.Args()[1:])
+	case "catalog":
+		err = catalog(flag.Args()[1:])
+	case "classes":
+		err = classes(flag.Args()[1:])
+	case "clones":
+		err = clones(flag.Args()[1:])
+	case "convert":
+		err = convert(flag.Args()[1:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "hop: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func repl(args []string) error {
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	program, err := compileDir(dir)
+	if err != nil {
+		return err
+	}
+	return hoprepl.New(program).Run(os.Stdin, os.Stdout)
+}
+
+func catalog(args []string) error {
+	flags := flag.NewFlagSet("catalog", flag.ExitOnError)
+	out := flags.String("o", "catalog", "output directory")
+	flags.Parse(args)
+	dir := "."
+	if flags.NArg() > 0 {
+		dir = flags.Arg(0)
+	}
+	program, err := compileDir(dir)
+	if err != nil {
+		return err
+	}
+	pages, err := hopcatalog.Generate(program)
+	if err != nil {
+		return err
+	}
+	for path, content := range pages {
+		path = filepath.Join(*out, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func classes(args []string) error {
+	flags := flag.NewFlagSet("classes", flag.ExitOnError)
+	out := flags.String("o", "", "output file")
+	flags.Parse(args)
+	dir := "."
+	if flags.NArg() > 0 {
+		dir = flags.Arg(0)
+	}
+	program, err := compileDir(dir)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	for _, class := range program.ClassNames() {
+		b.WriteString(class)
+		b.WriteByte('\n')
+	}
+	if *out == "" {
+		_, err := os.Stdout.WriteString(b.String())
+		return err
+	}
+	return os.WriteFile(*out, []byte(b.String()), 0o644)
+}
+
+func clones(args []string) error {
+	flags := flag.NewFlagSet("clones", flag.ExitOnError)
+	minNodes := flags.Int("min", 8, "minimum number of nodes of a clone")
+	flags.Parse(args)
+	dir := "."
+	if flags.NArg() > 0 {
+		dir = flags.Arg(0)
+	}
+	program, err := compileDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, clone := range program.Clones(*minNodes) {
+		fmt.Printf("%d nodes repeated %d times:\n", clone.Nodes, len(clone.Occurrences))
+		for _, o := range clone.Occurrences {
+			location := o.Module
+			if o.File != "" {
+				location = filepath.Join(dir, o.File)
+			}
+			fmt.Printf("\t%s: %s: in function %s\n", location, o.Pos, o.Function)
+		}
+	}
+	return nil
+}
+
+func convert(args []string) error {
+	flags := flag.NewFlagSet("convert", flag.ExitOnError)
+	out := flags.String("o", "", "output file")
+	param := flags.String("param", "", "name of the parameter of the functions (default data, or props for components)")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		usage()
+	}
+	file := flags.Arg(0)
+	source, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	convertFile := hopconvert.Convert
+	switch filepath.Ext(file) {
+	case ".vue":
+		convertFile = hopconvert.ConvertVue
+	case ".jsx", ".tsx":
+		convertFile = hopconvert.ConvertJSX
+	}
+	result, err := convertFile(filepath.Base(file), string(source), hopconvert.Options{Param: *param})
+	if err != nil {
+		return err
+	}
+	for _, issue := range result.Issues {
+		fmt.Fprintf(os.Stderr, "%s:%d: %s: %s\n", file, issue.Line, issue.Source, issue.Message)
+	}
+	if *out == "" {
+		_, err := os.Stdout.WriteString(result.Source)
+		return err
+	}
+	return os.WriteFile(*out, []byte(result.Source), 0o644)
+}
+
+// compileDir compiles the modules in dir.
+func compileDir(dir string) (*hop.Program, error) {
+	c := hop.NewCompiler()
+	if err := c.AddFS(os.DirFS(dir)); err != nil {
+		return nil, err
+	}
+	return c.Compile()
+}
@@ -0,0 +1,97 @@
+package hop
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+
+	"github.com/hoplang/hop-go/typechecker"
+)
+
+// BreakingChange describes a change to a function signature that may
+// break callers of a previous version of a program.
+type BreakingChange struct {
+	Module   string
+	Function string
+	Message  string
+}
+
+func (c BreakingChange) String() string {
+	return fmt.Sprintf("%s/%s: %s", c.Module, c.Function, c.Message)
+}
+
+// CompareSignatures reports the breaking changes between the exported
+// functions of before and after.
+//
+// A function that has been removed, or whose parameter type no longer
+// accepts every value accepted by the old version, is a breaking change.
+// The result is sorted by module and function name.
+func CompareSignatures(before, after *Program) []BreakingChange {
+	var changes []BreakingChange
+	for moduleName, oldModule := range before.modules {
+		newModule, exists := after.modules[moduleName]
+		for functionName := range oldModule.functions {
+			if oldModule.private[functionName] {
+				continue
+			}
+			if !exists {
+				changes = append(changes, BreakingChange{
+					Module:   moduleName,
+					Function: functionName,
+					Message:  "module was removed",
+				})
+				continue
+			}
+			if _, ok := newModule.functions[functionName]; !ok || newModule.private[functionName] {
+				changes = append(changes, BreakingChange{
+					Module:   moduleName,
+					Function: functionName,
+					Message:  "function was removed",
+				})
+				continue
+			}
+			oldType := oldModule.functionTypes[functionName]
+			newType := newModule.functionTypes[functionName]
+			if err := typechecker.CheckCompatible(oldType.Params, newType.Params); err != nil {
+				changes = append(changes, BreakingChange{
+					Module:   moduleName,
+					Function: functionName,
+					Message:  fmt.Sprintf("incompatible parameter type: %s", err),
+				})
+			}
+			if oldType.Slot != nil && newType.Slot == nil {
+				changes = append(changes, BreakingChange{
+					Module:   moduleName,
+					Function: functionName,
+					Message:  "no longer passes a value to its children",
+				})
+			}
+			for _, slot := range slices.Sorted(maps.Keys(oldType.Slots)) {
+				if _, exists := newType.Slots[slot]; !exists {
+					changes = append(changes, BreakingChange{
+						Module:   moduleName,
+						Function: functionName,
+						Message:  fmt.Sprintf("slot %s was removed", slot),
+					})
+				}
+			}
+			for _, slot := range slices.Sorted(maps.Keys(newType.Slots)) {
+				if required := newType.Slots[slot]; required && !oldType.Slots[slot] {
+					changes = append(changes, BreakingChange{
+						Module:   moduleName,
+						Function: functionName,
+						Message:  fmt.Sprintf("slot %s is required", slot),
+					})
+				}
+			}
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Module != changes[j].Module {
+			return changes[i].Module < changes[j].Module
+		}
+		return changes[i].Function < changes[j].Function
+	})
+	return changes
+}
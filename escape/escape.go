@@ -0,0 +1,57 @@
+// Package escape defines how hop escapes values that are written into
+// HTML output.
+//
+// Text content and attribute values are escaped the same way: the
+// characters &, ', <, >, " and carriage return are replaced by character
+// references. Line feeds and tabs are kept as is, which is valid in
+// quoted attribute values. Attribute values must in addition not
+// contain control characters, see CheckAttribute.
+//
+// This matches the escaping done by golang.org/x/net/html when
+// rendering nodes, so values escaped with this package can be written
+// directly to the output by any render backend and produce the same
+// bytes.
+package escape
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+var replacer = strings.NewReplacer(
+	"&", "&amp;",
+	"'", "&#39;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&#34;",
+	"\r", "&#13;",
+)
+
+// Text escapes s for use as the text content of an element.
+func Text(s string) string {
+	return replacer.Replace(s)
+}
+
+// CheckAttribute reports an error if s contains a control character
+// other than tab, line feed, form feed and carriage return. Such
+// characters are not allowed in attribute values.
+func CheckAttribute(s string) error {
+	for _, r := range s {
+		if unicode.IsControl(r) && r != '\t' && r != '\n' && r != '\f' && r != '\r' {
+			return fmt.Errorf("control character %U is not allowed in attribute values", r)
+		}
+	}
+	return nil
+}
+
+// Attribute escapes s for use as a quoted attribute value.
+//
+// It is meant for custom element handlers that write attributes
+// themselves and returns an error if s contains control characters.
+func Attribute(s string) (string, error) {
+	if err := CheckAttribute(s); err != nil {
+		return "", err
+	}
+	return replacer.Replace(s), nil
+}
@@ -0,0 +1,152 @@
+package escape_test
+
+import (
+	"bytes"
+	"html"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/hoplang/hop-go"
+	"github.com/hoplang/hop-go/escape"
+	xhtml "golang.org/x/net/html"
+)
+
+var seeds = []string{
+	"",
+	"plain",
+	"<script>alert(1)</script>",
+	"a & b",
+	"&amp;",
+	`"double" and 'single'`,
+	"line\r\nbreak",
+	"<<>>&&",
+	"café ☃",
+}
+
+// renderText renders s as a text node using golang.org/x/net/html.
+func renderText(t *testing.T, s string) string {
+	var buf bytes.Buffer
+	err := xhtml.Render(&buf, &xhtml.Node{Type: xhtml.TextNode, Data: s})
+	if err != nil {
+		t.Fatalf("Failed to render: %s", err)
+	}
+	return buf.String()
+}
+
+// executeInnerText renders s through the inner-text binding of a program.
+func executeInnerText(t *testing.T, s string) string {
+	c := hop.NewCompiler()
+	c.AddModule("main", `<function name="main" params-as="p"><fragment inner-text="p"></fragment></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	var buf bytes.Buffer
+	if err := program.ExecuteFunction(&buf, "main", "main", s); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	return buf.String()
+}
+
+func TestText(t *testing.T) {
+	tests := map[string]string{
+		"<b>":     "&lt;b&gt;",
+		"a & b":   "a &amp; b",
+		`"x"`:     "&#34;x&#34;",
+		"'x'":     "&#39;x&#39;",
+		"a\rb":    "a&#13;b",
+		"&lt;":    "&amp;lt;",
+		"no-op":   "no-op",
+		"☃":       "☃",
+		"a\nb\tc": "a\nb\tc",
+	}
+	for input, expected := range tests {
+		if got := escape.Text(input); got != expected {
+			t.Errorf("Text(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
+
+func FuzzText(f *testing.F) {
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		if !utf8.ValidString(s) {
+			t.Skip()
+		}
+		escaped := escape.Text(s)
+		if strings.ContainsAny(escaped, "<>\"'\r") {
+			t.Errorf("Text(%q) = %q contains unescaped characters", s, escaped)
+		}
+		if unescaped := html.UnescapeString(escaped); unescaped != s {
+			t.Errorf("Text(%q) does not round trip, got %q", s, unescaped)
+		}
+		if rendered := renderText(t, s); rendered != escaped {
+			t.Errorf("Text(%q) = %q but html.Render produced %q", s, escaped, rendered)
+		}
+		if executed := executeInnerText(t, s); executed != escaped {
+			t.Errorf("Text(%q) = %q but inner-text produced %q", s, escaped, executed)
+		}
+	})
+}
+
+// renderAttribute renders s as the value of an attribute using
+// golang.org/x/net/html and returns the quoted value.
+func renderAttribute(t *testing.T, s string) string {
+	var buf bytes.Buffer
+	err := xhtml.Render(&buf, &xhtml.Node{
+		Type: xhtml.ElementNode,
+		Data: "div",
+		Attr: []xhtml.Attribute{{Key: "title", Val: s}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to render: %s", err)
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(buf.String(), `<div title="`), `"></div>`)
+}
+
+func TestAttribute(t *testing.T) {
+	tests := map[string]string{
+		`say "hi"`:   "say &#34;hi&#34;",
+		"it's":       "it&#39;s",
+		"two\nlines": "two\nlines",
+		"crlf\r\n":   "crlf&#13;\n",
+	}
+	for input, expected := range tests {
+		got, err := escape.Attribute(input)
+		if err != nil {
+			t.Errorf("Attribute(%q) returned error %s", input, err)
+		}
+		if got != expected {
+			t.Errorf("Attribute(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+	for _, input := range []string{"a\x00b", "bell\a", "esc\x1b[0m", "c1\u0085"} {
+		if _, err := escape.Attribute(input); err == nil {
+			t.Errorf("Expected Attribute(%q) to reject control character", input)
+		}
+	}
+}
+
+func FuzzAttribute(f *testing.F) {
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		if !utf8.ValidString(s) {
+			t.Skip()
+		}
+		escaped, err := escape.Attribute(s)
+		if err != nil {
+			return
+		}
+		if strings.ContainsAny(escaped, "<>\"'\r") {
+			t.Errorf("Attribute(%q) = %q contains unescaped characters", s, escaped)
+		}
+		if rendered := renderAttribute(t, s); rendered != escaped {
+			t.Errorf("Attribute(%q) = %q but html.Render produced %q", s, escaped, rendered)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package hop
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ExecuteFunctionWithETag is like ExecuteFunction but also computes a
+// strong entity tag of the output while it is written, such as
+// "Vj3kq9yXh5hN0nqk8qXq3A". Since the tag is only known once the
+// function has been rendered, a handler that streams the output must
+// either buffer it before setting the ETag header or store the tag in
+// an ETagCache to answer later requests.
+func (p *Program) ExecuteFunctionWithETag(w io.Writer, moduleName string, functionName string, data any) (string, error) {
+	h := sha256.New()
+	if err := p.ExecuteFunction(io.MultiWriter(w, h), moduleName, functionName, data); err != nil {
+		return "", err
+	}
+	return `"` + base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:16]) + `"`, nil
+}
+
+// NotModified reports whether a request with an If-None-Match header
+// can be answered with 304 Not Modified for a response with the given
+// entity tag. Weak tags match as well, as required for GET and HEAD.
+func NotModified(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" || etag == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ETagCache remembers the entity tags of rendered responses so that
+// conditional requests can be answered without rendering:
+//
+//	key := r.URL.Path + "@" + post.Version
+//	if etag, ok := cache.Get(key); ok && hop.NotModified(r, etag) {
+//		w.WriteHeader(http.StatusNotModified)
+//		return
+//	}
+//	var buf bytes.Buffer
+//	etag, err := program.ExecuteFunctionWithETag(&buf, "posts", "post", post)
+//	...
+//	cache.Set(key, etag)
+//	w.Header().Set("ETag", etag)
+//	buf.WriteTo(w)
+//
+// The key must identify everything that the output depends on, such
+// as the data and the version of the program. At most capacity tags
+// are kept, evicting the least recently used one. An ETagCache is safe
+// for concurrent use.
+type ETagCache struct {
+	capacity int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+// etagCacheEntry is a cached entity tag.
+type etagCacheEntry struct {
+	key  string
+	etag string
+}
+
+// NewETagCache returns an ETagCache that holds up to capacity tags.
+func NewETagCache(capacity int) *ETagCache {
+	return &ETagCache{
+		capacity: max(capacity, 1),
+		entries:  map[string]*list.Element{},
+		lru:      list.New(),
+	}
+}
+
+// Get returns the entity tag stored for key.
+func (c *ETagCache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	c.lru.MoveToFront(e)
+	return e.Value.(*etagCacheEntry).etag, true
+}
+
+// Set stores the entity tag for key.
+func (c *ETagCache) Set(key string, etag string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*etagCacheEntry).etag = etag
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&etagCacheEntry{key: key, etag: etag})
+	for c.lru.Len() > c.capacity {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*etagCacheEntry).key)
+	}
+}
+
+// Invalidate removes the entity tag stored for key.
+func (c *ETagCache) Invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.lru.Remove(e)
+		delete(c.entries, key)
+	}
+}
@@ -0,0 +1,118 @@
+package hop
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+
+	"github.com/hoplang/hop-go/parser"
+	"golang.org/x/net/html"
+)
+
+// Assignment decides which variant of an experiment to render. It is
+// called with the name of the experiment and the names of its variants
+// in the order they are declared, and returns one of them. Returning
+// an unknown name renders the first variant.
+type Assignment func(experiment string, variants []string) string
+
+// Experiment is a `variant` element of a program.
+type Experiment struct {
+	Name     string
+	Variants []string
+	Module   string
+	File     string
+	Pos      parser.Position
+}
+
+// WithAssignment returns a copy of the program that uses assign to
+// choose the variants of `variant` elements:
+//
+//	<variant experiment="checkout-cta">
+//		<case name="control"><button>Buy</button></case>
+//		<case name="urgent"><button>Buy now</button></case>
+//	</variant>
+//
+// Programs without an assignment render the first variant of every
+// experiment.
+func (p *Program) WithAssignment(assign Assignment) *Program {
+	withAssignment := *p
+	withAssignment.assignment = assign
+	return &withAssignment
+}
+
+// Experiments returns the `variant` elements of the program, ordered
+// by experiment name, module and position.
+func (p *Program) Experiments() []Experiment {
+	var experiments []Experiment
+	for _, moduleName := range slices.Sorted(maps.Keys(p.modules)) {
+		mod := p.modules[moduleName]
+		var visit func(n *html.Node)
+		visit = func(n *html.Node) {
+			if n.Type == html.ElementNode && n.Data == "variant" {
+				name, _ := getAttribute(n, "experiment")
+				experiments = append(experiments, Experiment{
+					Name:     name,
+					Variants: variantNames(n),
+					Module:   moduleName,
+					File:     mod.path,
+					Pos:      mod.nodePositions[n].Start,
+				})
+			}
+			for c := range n.ChildNodes() {
+				visit(c)
+			}
+		}
+		visit(mod.root)
+	}
+	slices.SortStableFunc(experiments, func(a, b Experiment) int {
+		return cmp.Or(
+			cmp.Compare(a.Name, b.Name),
+			cmp.Compare(a.Module, b.Module),
+			cmp.Compare(a.Pos.Line, b.Pos.Line),
+			cmp.Compare(a.Pos.Column, b.Pos.Column),
+		)
+	})
+	return experiments
+}
+
+// variantNames returns the names of the cases of a `variant` element.
+func variantNames(n *html.Node) []string {
+	var names []string
+	for c := range n.ChildNodes() {
+		if c.Type == html.ElementNode && c.Data == "case" {
+			name, _ := getAttribute(c, "name")
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// evaluateVariant evaluates a `variant` element by evaluating the
+// children of the case chosen by the assignment of the program.
+func (p *Program) evaluateVariant(currentModule string, n *html.Node, s map[string]any) ([]*html.Node, error) {
+	experiment, _ := getAttribute(n, "experiment")
+	names := variantNames(n)
+	chosen := names[0]
+	if p.assignment != nil {
+		if name := p.assignment(experiment, slices.Clone(names)); slices.Contains(names, name) {
+			chosen = name
+		}
+	}
+	var results []*html.Node
+	for c := range n.ChildNodes() {
+		if c.Type != html.ElementNode || c.Data != "case" {
+			continue
+		}
+		if name, _ := getAttribute(c, "name"); name != chosen {
+			continue
+		}
+		for cc := range c.ChildNodes() {
+			nodes, err := p.evaluateNode(currentModule, cc, s)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, nodes...)
+		}
+	}
+	return results, nil
+}
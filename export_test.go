@@ -0,0 +1,13 @@
+package hop
+
+// RemoveModule removes a module from p without updating the modules
+// that use it, for tests of Verify.
+func RemoveModule(p *Program, moduleName string) {
+	delete(p.modules, moduleName)
+}
+
+// RemoveFunctionType removes the type of a function from p, for tests
+// of Verify.
+func RemoveFunctionType(p *Program, moduleName, functionName string) {
+	delete(p.modules[moduleName].functionTypes, functionName)
+}
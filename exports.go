@@ -0,0 +1,96 @@
+package hop
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/hoplang/hop-go/parser"
+	"golang.org/x/net/html"
+)
+
+// addExport records the re-export of a function by the export element
+// exp and returns the module that the function is exported from:
+//
+// <export function="primary-button" from="internal/buttons"></export>
+//
+// Other modules can then import the function from this module. Like an
+// import, an export can rename the function with an `as` attribute.
+func (mod *module) addExport(exp *html.Node, positions map[*html.Node]parser.NodePosition) (string, error) {
+	errorf := func(format string, args ...any) error {
+		return &parser.ParseError{
+			Pos:     positions[exp].Start,
+			Message: "parse error: " + fmt.Sprintf(format, args...),
+		}
+	}
+	from, _ := getAttribute(exp, "from")
+	if from == "" {
+		return "", errorf("export is missing attribute 'from'")
+	}
+	function, _ := getAttribute(exp, "function")
+	if function == "" {
+		return "", errorf("export is missing attribute 'function'")
+	}
+	name := importedName(exp)
+	if name == "" {
+		return "", errorf("attribute 'as' of export can not be empty")
+	}
+	if _, exists := mod.exports[name]; exists {
+		return "", errorf("duplicate export of '%s'", name)
+	}
+	mod.exports[name] = renderTarget{module: from, function: function}
+	return from, nil
+}
+
+// resolveExports checks the exports of every module and resolves the
+// imports of re-exported functions to the modules that define them, so
+// that their types and render targets are those of the definitions.
+func (p *Program) resolveExports() error {
+	for _, moduleName := range slices.Sorted(maps.Keys(p.modules)) {
+		mod := p.modules[moduleName]
+		for _, name := range slices.Sorted(maps.Keys(mod.exports)) {
+			if _, ok := mod.functions[name]; ok {
+				return fmt.Errorf("resolving module %s: function %s is both defined and exported", moduleName, name)
+			}
+			target, err := p.exportedFunction(mod.exports[name])
+			if err != nil {
+				return fmt.Errorf("resolving module %s: %w", moduleName, err)
+			}
+			if defining, ok := p.modules[target.module]; ok && (defining.functions[target.function] == nil || defining.private[target.function]) {
+				return fmt.Errorf("resolving module %s: exported function %s not found in module %s",
+					moduleName, target.function, target.module)
+			}
+		}
+	}
+	for _, moduleName := range slices.Sorted(maps.Keys(p.modules)) {
+		mod := p.modules[moduleName]
+		clear(mod.imports)
+		for _, name := range slices.Sorted(maps.Keys(mod.importedAs)) {
+			target, err := p.exportedFunction(mod.importedAs[name])
+			if err != nil {
+				return fmt.Errorf("resolving module %s: %w", moduleName, err)
+			}
+			mod.importedAs[name] = target
+			mod.imports[target.module] = append(mod.imports[target.module], target.function)
+		}
+	}
+	return nil
+}
+
+// exportedFunction follows the re-exports of target to the function
+// that they refer to. Functions that are not found are returned as
+// they are and reported when imports are resolved.
+func (p *Program) exportedFunction(target renderTarget) (renderTarget, error) {
+	for range len(p.modules) + 1 {
+		mod, ok := p.modules[target.module]
+		if !ok || mod.functions[target.function] != nil {
+			return target, nil
+		}
+		next, ok := mod.exports[target.function]
+		if !ok {
+			return target, nil
+		}
+		target = next
+	}
+	return renderTarget{}, fmt.Errorf("cycle in the exports of function %s", target.function)
+}
@@ -0,0 +1,229 @@
+package hop
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/hoplang/hop-go/parser"
+	"golang.org/x/net/html"
+)
+
+// resolveExtends compiles layout inheritance down to render calls with
+// named slots. A page extends a layout module and overrides its blocks:
+//
+// <extends module="layouts/base">
+// <block name="content">...</block>
+// </extends>
+//
+// where the top-level markup of layouts/base declares the blocks with
+// their default content:
+//
+// <html><body><block name="content"></block></body></html>
+//
+// The `extends` tag becomes a render of the markup of the layout, which
+// is wrapped in a function as for `include`, and its blocks become the
+// fills of the render. Every other block becomes a slot, whose default
+// content is used if a page does not override it. Since blocks are
+// optional, an empty block is given an empty default. A layout can
+// itself extend another layout.
+func (p *Program) resolveExtends(dependencyGraph map[string]map[string]bool) error {
+	for _, moduleName := range slices.Sorted(maps.Keys(p.modules)) {
+		mod := p.modules[moduleName]
+		for _, n := range findElements(mod.root, "extends") {
+			if err := p.checkExtends(moduleName, n); err != nil {
+				return withModule(err, "parsing", moduleName, mod.path)
+			}
+		}
+	}
+	prepared := map[string]bool{}
+	for _, moduleName := range slices.Sorted(maps.Keys(p.modules)) {
+		p.inheritBlocks(moduleName, prepared)
+	}
+
+	for _, moduleName := range slices.Sorted(maps.Keys(p.modules)) {
+		mod := p.modules[moduleName]
+		for _, n := range findElements(mod.root, "block") {
+			if err := convertBlock(n, mod.nodePositions); err != nil {
+				return withModule(err, "parsing", moduleName, mod.path)
+			}
+		}
+		for _, n := range findElements(mod.root, "extends") {
+			layoutName, _ := getAttribute(n, "module")
+			layout := p.modules[layoutName]
+			if layout.markup == nil {
+				layout.markup = wrapIncludedMarkup(layout.root, layout.nodePositions)
+				p.modules[layoutName] = layout
+			}
+			name := "#" + layoutName
+			n.Data = "render"
+			n.Attr = []html.Attribute{{Key: "function", Val: name}}
+			if _, ok := mod.importedAs[name]; !ok {
+				// The import lets the typechecker find the type of
+				// the layout. It is not added to the imports of the
+				// module since the layout is not a public function.
+				imp := &html.Node{
+					Type: html.ElementNode,
+					Data: "import",
+					Attr: []html.Attribute{
+						{Key: "function", Val: includeFunction},
+						{Key: "from", Val: layoutName},
+						{Key: "as", Val: name},
+					},
+				}
+				mod.root.InsertBefore(imp, mod.root.FirstChild)
+				mod.nodePositions[imp] = mod.nodePositions[n]
+				mod.importedAs[name] = renderTarget{module: layoutName, function: includeFunction}
+			}
+			dependencyGraph[moduleName][layoutName] = true
+		}
+	}
+	return nil
+}
+
+// inheritBlocks passes the blocks of the layouts that a module extends
+// through the module, so that a page can override the blocks of every
+// layout above it. For each block of a layout that the `extends` tag
+// does not override, it adds an override that declares a block of the
+// same name with a copy of the default content, so that the layout
+// declares the block again:
+//
+// <block name="title"><block name="title">default</block></block>
+//
+// Cycles are left for sorting to report.
+func (p *Program) inheritBlocks(moduleName string, prepared map[string]bool) {
+	if prepared[moduleName] {
+		return
+	}
+	prepared[moduleName] = true
+	for _, n := range findElements(p.modules[moduleName].root, "extends") {
+		layoutName, _ := getAttribute(n, "module")
+		p.inheritBlocks(layoutName, prepared)
+		if inFunction(n) {
+			// Only the markup of a layout is extended further.
+			continue
+		}
+		overridden := map[string]bool{}
+		for c := range n.ChildNodes() {
+			if c.Type == html.ElementNode && c.Data == "block" {
+				name, _ := getAttribute(c, "name")
+				overridden[name] = true
+			}
+		}
+		for _, block := range findElements(p.modules[layoutName].root, "block") {
+			name, _ := getAttribute(block, "name")
+			if overridden[name] || isOverride(block) {
+				continue
+			}
+			overridden[name] = true
+			declaration := cloneNode(block)
+			override := &html.Node{Type: html.ElementNode, Data: "block", Attr: declaration.Attr}
+			override.AppendChild(declaration)
+			n.AppendChild(override)
+		}
+	}
+}
+
+// isOverride reports whether a block overrides a block of an extended
+// layout.
+func isOverride(block *html.Node) bool {
+	return block.Parent != nil && block.Parent.Type == html.ElementNode && block.Parent.Data == "extends"
+}
+
+// inFunction reports whether n is inside a `function` tag.
+func inFunction(n *html.Node) bool {
+	for a := n.Parent; a != nil; a = a.Parent {
+		if a.Type == html.ElementNode && a.Data == "function" {
+			return true
+		}
+	}
+	return false
+}
+
+// findElements returns the elements below n with the given tag in
+// document order.
+func findElements(n *html.Node, tag string) []*html.Node {
+	var result []*html.Node
+	for c := range n.ChildNodes() {
+		if c.Type == html.ElementNode && c.Data == tag {
+			result = append(result, c)
+		}
+		result = append(result, findElements(c, tag)...)
+	}
+	return result
+}
+
+// checkExtends checks an `extends` tag of a module.
+func (p *Program) checkExtends(moduleName string, n *html.Node) error {
+	positions := p.modules[moduleName].nodePositions
+	errorf := func(n *html.Node, format string, args ...any) error {
+		return &parser.ParseError{
+			Pos:     positions[n].Start,
+			Message: "parse error: " + fmt.Sprintf(format, args...),
+		}
+	}
+	for _, attr := range n.Attr {
+		if attr.Key != "module" {
+			return errorf(n, "unrecognized attribute '%s' in extends", attr.Key)
+		}
+	}
+	layoutName, ok := getAttribute(n, "module")
+	if !ok {
+		return errorf(n, "extends is missing attribute 'module'")
+	}
+	layout, exists := p.modules[layoutName]
+	if !exists {
+		return errorf(n, "unknown module '%s'", layoutName)
+	}
+	if layout.info.ParamsAs != "" {
+		return errorf(n, "module '%s' has parameters and can not be extended", layoutName)
+	}
+	for c := range n.ChildNodes() {
+		switch {
+		case c.Type == html.CommentNode:
+		case c.Type == html.TextNode && strings.TrimSpace(c.Data) == "":
+		case c.Type == html.ElementNode && c.Data == "block":
+		default:
+			return errorf(c, "extends can only contain block")
+		}
+	}
+	return nil
+}
+
+// convertBlock turns a `block` tag into a fill if it overrides a block
+// of an extended layout and into a slot otherwise.
+func convertBlock(n *html.Node, positions map[*html.Node]parser.NodePosition) error {
+	for _, attr := range n.Attr {
+		if attr.Key != "name" {
+			return &parser.ParseError{
+				Pos:     positions[n].Start,
+				Message: fmt.Sprintf("parse error: unrecognized attribute '%s' in block", attr.Key),
+			}
+		}
+	}
+	name, _ := getAttribute(n, "name")
+	if name == "" {
+		return &parser.ParseError{
+			Pos:     positions[n].Start,
+			Message: "parse error: block is missing attribute 'name'",
+		}
+	}
+	if isOverride(n) {
+		n.Data = "fill"
+		n.Attr = []html.Attribute{{Key: "slot", Val: name}}
+		return nil
+	}
+	n.Data = "slot"
+	hasDefault := false
+	for c := range n.ChildNodes() {
+		if c.Type != html.TextNode || strings.TrimSpace(c.Data) != "" {
+			hasDefault = true
+		}
+	}
+	if !hasDefault {
+		// A hop comment renders nothing but makes the slot optional.
+		n.AppendChild(&html.Node{Type: html.CommentNode, Data: "# default of block " + name})
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package hop
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/hoplang/hop-go/hoptype"
+	"github.com/hoplang/hop-go/parser"
+	"golang.org/x/net/html"
+)
+
+// Extraction is the result of extracting nodes into a new function.
+type Extraction struct {
+	// Source is the new source of the module.
+	Source string
+	// Params is the inferred parameter type of the new function.
+	Params *hoptype.Type
+}
+
+// pathAttributes are the attributes of hop elements whose values are
+// paths into the scope.
+var pathAttributes = map[string]bool{
+	"inner-text": true,
+	"inner-html": true,
+	"each":       true,
+	"true":       true,
+	"not":        true,
+	"on":         true,
+	"from":       true,
+	"to":         true,
+	"params":     true,
+	"sort-by":    true,
+	"filter":     true,
+	"limit":      true,
+	"offset":     true,
+}
+
+// loopAttributes are the path attributes of a `for` tag that are
+// evaluated in the scope of the loop.
+var loopAttributes = map[string]bool{
+	"sort-by": true,
+	"filter":  true,
+}
+
+// ExtractFunction moves the nodes between start and end of a function
+// of the given module into a new top-level function with the given
+// name, and replaces them with a render call to it.
+//
+// The selection must consist of whole sibling nodes. The variables that
+// the selected nodes use from the enclosing scope become the parameter
+// of the new function; since render calls pass a single value, at most
+// one variable may be used. The new function is placed after the
+// function the nodes were taken from.
+//
+// The module added to the compiler is not modified. The new source is
+// compiled together with the other modules of the compiler to infer the
+// parameter type of the new function.
+func (c *Compiler) ExtractFunction(moduleName string, start, end parser.Position, name string) (*Extraction, error) {
+	source, ok := c.modules[moduleName]
+	if !ok {
+		return nil, fmt.Errorf("no module with name %s", moduleName)
+	}
+	result, err := parser.Parse(source)
+	if err != nil {
+		return nil, withModule(err, "parsing", moduleName, c.paths[moduleName])
+	}
+	root := result.Root
+	if findElement(root, "function", "name", name) != nil || findImport(root, name) != nil {
+		return nil, fmt.Errorf("module %s already has a function with name %s", moduleName, name)
+	}
+
+	span := func(n *html.Node) (int, int) {
+		pos := result.NodePositions[n]
+		from, to := offset(source, pos.Start), offset(source, pos.End)
+		if n.Type == html.ElementNode && !parser.IsVoidElement(n.Data) {
+			// The end position of an element is the start of its end tag.
+			to += strings.IndexByte(source[to:], '>') + 1
+		}
+		return from, to
+	}
+	selStart, selEnd := offset(source, start), offset(source, end)
+
+	// Find the innermost node whose children contain the selection.
+	parent := root
+	var function *html.Node
+	for {
+		var next *html.Node
+		for c := range parent.ChildNodes() {
+			if from, to := span(c); c.Type == html.ElementNode && from < selStart && selEnd < to {
+				next = c
+			}
+		}
+		if next == nil {
+			break
+		}
+		if function == nil && next.Data == "function" {
+			function = next
+		}
+		parent = next
+	}
+	if function == nil {
+		return nil, fmt.Errorf("selection is not inside a function")
+	}
+
+	var selected []*html.Node
+	for c := range parent.ChildNodes() {
+		from, to := span(c)
+		switch {
+		case selStart <= from && to <= selEnd:
+			selected = append(selected, c)
+		case to <= selStart || selEnd <= from:
+		case c.Type == html.TextNode && strings.TrimSpace(c.Data) == "":
+		default:
+			return nil, fmt.Errorf("%s: selection does not cover whole nodes", result.NodePositions[c].Start)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("selection does not contain any nodes")
+	}
+	selStart, _ = span(selected[0])
+	_, selEnd = span(selected[len(selected)-1])
+
+	// Nested functions are only visible inside the functions that
+	// define them, so render calls to them can not be extracted.
+	helpers := map[string]bool{}
+	for n := parent; n != root; n = n.Parent {
+		if n.Type != html.ElementNode || n.Data != "function" {
+			continue
+		}
+		if n != function {
+			name, _ := getAttribute(n, "name")
+			helpers[name] = true
+		}
+		collectHelpers(n, helpers)
+	}
+
+	free := map[string]bool{}
+	var visit func(n *html.Node, bound map[string]bool) error
+	visit = func(n *html.Node, bound map[string]bool) error {
+		if n.Type != html.ElementNode {
+			return nil
+		}
+		pos := result.NodePositions[n].Start
+		switch n.Data {
+		case "function", "import":
+			return fmt.Errorf("%s: can not extract a %s tag", pos, n.Data)
+		case "children", "slot":
+			return fmt.Errorf("%s: can not extract a %s tag", pos, n.Data)
+		case "render":
+			if target, _ := getAttribute(n, "function"); helpers[target] {
+				return fmt.Errorf("%s: can not extract a render call to nested function %s", pos, target)
+			}
+		}
+		inner := bound
+		for _, key := range []string{"as", "index-as", "meta-as", "key-as", "value-as", "children-as"} {
+			if v, ok := getAttribute(n, key); ok {
+				inner = maps.Clone(inner)
+				inner[v] = true
+			}
+		}
+		for _, attr := range n.Attr {
+			if !pathAttributes[attr.Key] && !strings.HasPrefix(attr.Key, "attr-") && !strings.HasPrefix(attr.Key, "class-") && !strings.HasPrefix(attr.Key, "data-bind-") && !strings.HasPrefix(attr.Key, "with-") {
+				continue
+			}
+			if _, literal := parser.ParseLiteral(attr.Val); literal {
+				// A literal bound of a range or parameter.
+				continue
+			}
+			parsed, err := parser.ParseBinding(attr.Val)
+			if err != nil {
+				return err
+			}
+			for _, alternative := range parsed.Alternatives {
+				if alternative.IsLiteral {
+					continue
+				}
+				parts, err := parser.ParsePath(alternative.Path)
+				if err != nil {
+					return err
+				}
+				scope := bound
+				if loopAttributes[attr.Key] {
+					scope = inner
+				}
+				if len(parts) > 0 && !scope[parts[0].Value] {
+					free[parts[0].Value] = true
+				}
+			}
+		}
+		for c := range n.ChildNodes() {
+			if err := visit(c, inner); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, n := range selected {
+		if err := visit(n, map[string]bool{}); err != nil {
+			return nil, err
+		}
+	}
+	vars := slices.Sorted(maps.Keys(free))
+	if len(vars) > 1 {
+		return nil, fmt.Errorf("selection uses the variables %s but a function takes a single parameter", strings.Join(vars, ", "))
+	}
+
+	var definition, call string
+	if len(vars) == 1 {
+		definition = fmt.Sprintf(`<function name="%s" params-as="%s">`, name, vars[0])
+		call = fmt.Sprintf(`<render function="%s" params="%s"></render>`, name, vars[0])
+	} else {
+		definition = fmt.Sprintf(`<function name="%s">`, name)
+		call = fmt.Sprintf(`<render function="%s"></render>`, name)
+	}
+	definition += "\n\t" + source[selStart:selEnd] + "\n</function>"
+
+	_, functionEnd := span(function)
+	newSource := source[:selStart] + call + source[selEnd:functionEnd] + "\n\n" + definition + source[functionEnd:]
+
+	compiler := *c
+	compiler.modules = maps.Clone(c.modules)
+	compiler.modules[moduleName] = newSource
+	program, err := compiler.Compile()
+	if err != nil {
+		return nil, fmt.Errorf("extracted function does not compile: %w", err)
+	}
+	params, err := program.FunctionType(moduleName, name)
+	if err != nil {
+		return nil, err
+	}
+	return &Extraction{Source: newSource, Params: params}, nil
+}
+
+// collectHelpers adds the names of the functions nested in n to names,
+// without descending into the nested functions.
+func collectHelpers(n *html.Node, names map[string]bool) {
+	for c := range n.ChildNodes() {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		if c.Data == "function" {
+			name, _ := getAttribute(c, "name")
+			names[name] = true
+			continue
+		}
+		collectHelpers(c, names)
+	}
+}
@@ -0,0 +1,84 @@
+package hop
+
+import (
+	"github.com/hoplang/hop-go/parser"
+	"golang.org/x/net/html"
+)
+
+// Features decides whether the feature flag with the given name is
+// enabled. It is consulted by `<if feature>` elements:
+//
+//	<if feature="new-navbar">
+//		<render function="navbar"></render>
+//	</if>
+type Features func(name string) bool
+
+// FeatureSet returns Features that enable exactly the given flags.
+func FeatureSet(names ...string) Features {
+	enabled := make(map[string]bool, len(names))
+	for _, name := range names {
+		enabled[name] = true
+	}
+	return func(name string) bool {
+		return enabled[name]
+	}
+}
+
+// SetFeatures resolves `<if feature>` elements at compile time. Elements
+// for disabled flags are removed together with their children before
+// typechecking, so the data used only by them is not part of the
+// parameter types. Without compile-time features the flags are decided
+// at render time, see Program.WithFeatures.
+func (c *Compiler) SetFeatures(features Features) {
+	c.features = features
+}
+
+// WithFeatures returns a copy of the program that decides the
+// `<if feature>` elements that were not resolved at compile time using
+// features. Flags are disabled in programs without features.
+func (p *Program) WithFeatures(features Features) *Program {
+	withFeatures := *p
+	withFeatures.features = features
+	return &withFeatures
+}
+
+// resolveFeatures replaces each `<if feature>` element below n by its
+// children if the flag is enabled and removes it otherwise.
+func resolveFeatures(n *html.Node, features Features) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		resolveFeatures(c, features)
+		if c.Type == html.ElementNode && c.Data == "if" && len(c.Attr) == 1 && c.Attr[0].Key == "feature" && c.Attr[0].Val != "" {
+			if features(c.Attr[0].Val) {
+				unwrap(c)
+			} else {
+				n.RemoveChild(c)
+			}
+		}
+		c = next
+	}
+}
+
+// unwrap replaces n by its children.
+func unwrap(n *html.Node) {
+	parent := n.Parent
+	for n.FirstChild != nil {
+		child := n.FirstChild
+		n.RemoveChild(child)
+		parent.InsertBefore(child, n)
+	}
+	parent.RemoveChild(n)
+}
+
+// resolveCompileTimeConditions resolves the feature flags and targets
+// below root that are decided at compile time, and inlines the icons
+// that remain.
+func (c *Compiler) resolveCompileTimeConditions(root *html.Node, positions map[*html.Node]parser.NodePosition) error {
+	if c.features != nil {
+		resolveFeatures(root, c.features)
+	}
+	if err := resolveTargets(root, positions, c.target); err != nil {
+		return err
+	}
+	return resolveIcons(root, positions, c.icons)
+}
@@ -0,0 +1,304 @@
+package hop
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hoplang/hop-go/parser"
+	"github.com/hoplang/hop-go/typechecker"
+)
+
+// filter is a filter that bindings can pipe their value through.
+type filter struct {
+	typ   *typechecker.FilterType
+	apply func(v any, args []any) (any, error)
+}
+
+// standardFilters are the filters that are available in every program:
+//
+//	uppercase      converts a string to upper case
+//	lowercase      converts a string to lower case
+//	trim           removes leading and trailing white space
+//	truncate(n)    shortens a string to n characters, ending with …
+//	default(v)     replaces a missing, null or empty value by v
+var standardFilters = map[string]filter{
+	"uppercase": stringFilter(strings.ToUpper),
+	"lowercase": stringFilter(strings.ToLower),
+	"trim":      stringFilter(strings.TrimSpace),
+	"truncate": {
+		typ: &typechecker.FilterType{
+			Input:  typechecker.PrimitiveType("string"),
+			Params: []typechecker.TypeExpr{typechecker.PrimitiveType("number")},
+			Result: typechecker.PrimitiveType("string"),
+		},
+		apply: func(v any, args []any) (any, error) {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("can not truncate '%v' of type %T, expected a string", v, v)
+			}
+			n, ok := args[0].(float64)
+			if !ok || n < 1 || n != float64(int(n)) {
+				return nil, fmt.Errorf("can not truncate to '%v' characters, expected a positive integer", args[0])
+			}
+			if utf8.RuneCountInString(s) <= int(n) {
+				return s, nil
+			}
+			return string([]rune(s)[:int(n)-1]) + "…", nil
+		},
+	},
+	"default": func() filter {
+		t := &typechecker.TypeVar{Name: "t", Allowed: []typechecker.TypeExpr{typechecker.PrimitiveType("string"), typechecker.PrimitiveType("number")}}
+		return filter{
+			typ: &typechecker.FilterType{
+				Input:  t,
+				Params: []typechecker.TypeExpr{t},
+				Result: t,
+			},
+			apply: func(v any, args []any) (any, error) {
+				if v == nil || v == "" {
+					return args[0], nil
+				}
+				return v, nil
+			},
+		}
+	}(),
+}
+
+// stringFilter returns a filter that maps strings to strings.
+func stringFilter(fn func(string) string) filter {
+	return filter{
+		typ: &typechecker.FilterType{
+			Input:  typechecker.PrimitiveType("string"),
+			Result: typechecker.PrimitiveType("string"),
+		},
+		apply: func(v any, args []any) (any, error) {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("can not use '%v' of type %T as string", v, v)
+			}
+			return fn(s), nil
+		},
+	}
+}
+
+// filterTypes returns the signatures of the filters of the compiler.
+func (c *Compiler) filterTypes() map[string]*typechecker.FilterType {
+	types := make(map[string]*typechecker.FilterType, len(c.filters))
+	for name, filter := range c.filters {
+		types[name] = filter.typ
+	}
+	return types
+}
+
+// applyFilters pipes the value of a binding through its filters. A
+// binding whose first filter is default also accepts a value that is
+// missing, so that `user.nickname | default('Anonymous')` works like
+// `user.nickname ?? 'Anonymous'`.
+func (p *Program) applyFilters(v any, err error, filters []parser.Filter) (any, error) {
+	if err != nil {
+		if len(filters) == 0 || filters[0].Name != "default" {
+			return nil, err
+		}
+		v = nil
+	}
+	for _, f := range filters {
+		filter, exists := p.filters[f.Name]
+		if !exists {
+			return nil, fmt.Errorf("unknown filter '%s'", f.Name)
+		}
+		v, err = filter.apply(v, f.Args)
+		if err != nil {
+			return nil, fmt.Errorf("filter %s: %w", f.Name, err)
+		}
+	}
+	return v, nil
+}
+
+var errorType = reflect.TypeFor[error]()
+
+// RegisterFilter makes a Go function available to the bindings of
+// templates as a filter:
+//
+//	c.RegisterFilter("money", func(cents int, currency string) string { ... })
+//
+//	<td inner-text="order.total | money('EUR')"></td>
+//
+// The first parameter of fn receives the piped value and the remaining
+// parameters the arguments of the filter. fn may return an error as a
+// second result, which aborts the render. The signature of the filter
+// is derived from the types of fn: strings are strings, integers and
+// floats are numbers, HTML, URL and JS are the trusted types, slices
+// are arrays, maps with string keys are maps and interfaces accept any
+// value. A filter with the name of a standard filter replaces it.
+//
+// RegisterFilter panics if name is not a valid filter name or fn is
+// not a function with such a signature.
+func (c *Compiler) RegisterFilter(name string, fn any) {
+	if !validFilterName.MatchString(name) {
+		panic(fmt.Sprintf("hop: invalid filter name %q", name))
+	}
+	f, err := reflectFilter(fn)
+	if err != nil {
+		panic(fmt.Sprintf("hop: invalid filter %s: %s", name, err))
+	}
+	c.filters[name] = f
+}
+
+var validFilterName = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+
+// reflectFilter derives a filter from a Go function.
+func reflectFilter(fn any) (filter, error) {
+	v := reflect.ValueOf(fn)
+	t := v.Type()
+	if t.Kind() != reflect.Func {
+		return filter{}, fmt.Errorf("%s is not a function", t)
+	}
+	if t.NumIn() == 0 || t.IsVariadic() {
+		return filter{}, fmt.Errorf("%s must take the piped value and a fixed number of arguments", t)
+	}
+	if t.NumOut() == 0 || t.NumOut() > 2 || (t.NumOut() == 2 && t.Out(1) != errorType) {
+		return filter{}, fmt.Errorf("%s must return a value and optionally an error", t)
+	}
+	var types []typechecker.TypeExpr
+	for i := range t.NumIn() + 1 {
+		rt := t.Out(0)
+		if i < t.NumIn() {
+			rt = t.In(i)
+		}
+		typ, err := goType(rt)
+		if err != nil {
+			return filter{}, err
+		}
+		types = append(types, typ)
+	}
+	return filter{
+		typ: &typechecker.FilterType{
+			Input:  types[0],
+			Params: types[1 : len(types)-1],
+			Result: types[len(types)-1],
+		},
+		apply: func(input any, args []any) (any, error) {
+			in := make([]reflect.Value, t.NumIn())
+			for i, arg := range append([]any{input}, args...) {
+				var err error
+				in[i], err = convertValue(arg, t.In(i))
+				if err != nil {
+					return nil, err
+				}
+			}
+			out := v.Call(in)
+			if len(out) == 2 && !out[1].IsNil() {
+				return nil, out[1].Interface().(error)
+			}
+			return fromGoValue(out[0]), nil
+		},
+	}, nil
+}
+
+// goType returns the type of the values of a Go type.
+func goType(t reflect.Type) (typechecker.TypeExpr, error) {
+	switch t {
+	case reflect.TypeFor[HTML]():
+		return typechecker.HTMLType, nil
+	case reflect.TypeFor[URL]():
+		return typechecker.URLType, nil
+	case reflect.TypeFor[JS]():
+		return typechecker.JSType, nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return typechecker.PrimitiveType("string"), nil
+	case reflect.Bool:
+		return typechecker.PrimitiveType("boolean"), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return typechecker.PrimitiveType("number"), nil
+	case reflect.Slice:
+		elem, err := goType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &typechecker.ArrayType{ElementType: elem}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			break
+		}
+		elem, err := goType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &typechecker.MapType{ValueType: elem}, nil
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			return &typechecker.TypeVar{Name: "any"}, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// convertValue converts a value of the data of a template to the Go
+// type t.
+func convertValue(v any, t reflect.Type) (reflect.Value, error) {
+	if v == nil {
+		switch t.Kind() {
+		case reflect.Interface, reflect.Slice, reflect.Map:
+			return reflect.Zero(t), nil
+		}
+		return reflect.Value{}, fmt.Errorf("can not use null as %s", t)
+	}
+	rv := reflect.ValueOf(v)
+	switch {
+	case rv.Type().AssignableTo(t):
+		return rv, nil
+	case rv.Kind() == t.Kind() && rv.Type().ConvertibleTo(t) && t.Kind() != reflect.Slice && t.Kind() != reflect.Map:
+		return rv.Convert(t), nil
+	case rv.CanFloat() && t.Kind() == reflect.Float32:
+		return rv.Convert(t), nil
+	case rv.CanFloat() && (t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64):
+		f := rv.Float()
+		if f != math.Trunc(f) || (t.Kind() >= reflect.Uint && f < 0) {
+			return reflect.Value{}, fmt.Errorf("can not use '%v' as %s", v, t)
+		}
+		return rv.Convert(t), nil
+	case rv.Kind() == reflect.Slice && t.Kind() == reflect.Slice:
+		result := reflect.MakeSlice(t, rv.Len(), rv.Len())
+		for i := range rv.Len() {
+			elem, err := convertValue(rv.Index(i).Interface(), t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			result.Index(i).Set(elem)
+		}
+		return result, nil
+	case rv.Kind() == reflect.Map && t.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String:
+		result := reflect.MakeMapWithSize(t, rv.Len())
+		for iter := rv.MapRange(); iter.Next(); {
+			elem, err := convertValue(iter.Value().Interface(), t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			result.SetMapIndex(iter.Key().Convert(t.Key()), elem)
+		}
+		return result, nil
+	}
+	return reflect.Value{}, fmt.Errorf("can not use '%v' of type %T as %s", v, v, t)
+}
+
+// fromGoValue converts the result of a filter to a value of the data
+// of a template, where numbers are float64.
+func fromGoValue(v reflect.Value) any {
+	switch {
+	case v.CanInt():
+		return float64(v.Int())
+	case v.CanUint():
+		return float64(v.Uint())
+	case v.CanFloat():
+		return v.Float()
+	}
+	return v.Interface()
+}
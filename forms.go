@@ -0,0 +1,203 @@
+package hop
+
+import (
+	"fmt"
+	"reflect"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// FormField is the state of a form field that a `field` tag renders:
+// the submitted value and the message of a failed validation, if any.
+// Templates accept any data with the same fields, such as
+// {"value": "", "error": "Required"}.
+type FormField struct {
+	Value string `json:"value"`
+	Error string `json:"error"`
+}
+
+// evaluateField evaluates a `field` tag, which renders an input from a
+// FormField:
+//
+// <field name="email" type="email" from="form.email"></field>
+//
+// renders
+//
+// <input name="email" type="email" id="email" value="..." aria-invalid="true" aria-describedby="email-error">
+// <p id="email-error" class="field-error" role="alert">...</p>
+//
+// where the aria attributes and the error message are only rendered if
+// the field has an error. The id defaults to the name, and the other
+// attributes of the tag are copied to the input.
+func (p *Program) evaluateField(currentModule string, n *html.Node, s map[string]any) ([]*html.Node, error) {
+	from, _ := getAttribute(n, "from")
+	value, err := p.evaluatePath(from+".value", s)
+	if err != nil {
+		return nil, err
+	}
+	message, err := p.evaluatePath(from+".error", s)
+	if err != nil {
+		return nil, err
+	}
+	valueString, valueOK := value.(string)
+	messageString, messageOK := message.(string)
+	if !valueOK || !messageOK {
+		return nil, fmt.Errorf("form field %s must have a string value and a string error", from)
+	}
+
+	nodes, err := p.evaluateNative(currentModule, n, s)
+	if err != nil {
+		return nil, err
+	}
+	input := nodes[0]
+	input.Data = "input"
+	input.DataAtom = atom.Input
+	var attrs []html.Attribute
+	for _, attr := range input.Attr {
+		if attr.Key != "from" {
+			attrs = append(attrs, attr)
+		}
+	}
+	id, hasID := getAttribute(input, "id")
+	if !hasID {
+		id, _ = getAttribute(input, "name")
+		attrs = append(attrs, html.Attribute{Key: "id", Val: id})
+	}
+	attrs = append(attrs, html.Attribute{Key: "value", Val: valueString})
+	input.Attr = attrs
+	if messageString == "" {
+		return nodes, nil
+	}
+
+	errorID := id + "-error"
+	input.Attr = append(input.Attr,
+		html.Attribute{Key: "aria-invalid", Val: "true"},
+		html.Attribute{Key: "aria-describedby", Val: errorID})
+	errorNode := &html.Node{
+		Type:     html.ElementNode,
+		Data:     "p",
+		DataAtom: atom.P,
+		Attr: []html.Attribute{
+			{Key: "id", Val: errorID},
+			{Key: "class", Val: "field-error"},
+			{Key: "role", Val: "alert"},
+		},
+	}
+	errorNode.AppendChild(&html.Node{Type: html.TextNode, Data: messageString})
+	return []*html.Node{input, errorNode}, nil
+}
+
+// Option is an option of an `options` or `choices` tag.
+type Option struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+}
+
+// evaluateOptions evaluates an `options` tag, which renders the options
+// of a select and marks the one with the selected value:
+//
+// <select name="country">
+// <options from="countries" selected="user.country"></options>
+// </select>
+//
+// or a `choices` tag, which renders labelled radio buttons or
+// checkboxes and checks those with a selected value:
+//
+// <choices type="checkbox" name="tags" from="tags" selected="post.tags"></choices>
+func (p *Program) evaluateOptions(n *html.Node, s map[string]any) ([]*html.Node, error) {
+	from, _ := getAttribute(n, "from")
+	v, err := p.evaluatePath(from, s)
+	if err != nil {
+		return nil, err
+	}
+	options, err := toOptions(v)
+	if err != nil {
+		return nil, err
+	}
+	selected := map[string]bool{}
+	if path, ok := getAttribute(n, "selected"); ok {
+		v, err := p.evaluatePath(path, s)
+		if err != nil {
+			return nil, err
+		}
+		if value, ok := v.(string); ok {
+			selected[value] = true
+		} else if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice {
+			for i := 0; i < rv.Len(); i++ {
+				if value, ok := rv.Index(i).Interface().(string); ok {
+					selected[value] = true
+				}
+			}
+		} else if v != nil {
+			return nil, fmt.Errorf("can not use '%s' of type %s as selected value", stringify(v), typeof(v))
+		}
+	}
+
+	var result []*html.Node
+	for _, option := range options {
+		if n.Data == "options" {
+			node := &html.Node{
+				Type:     html.ElementNode,
+				Data:     "option",
+				DataAtom: atom.Option,
+				Attr:     []html.Attribute{{Key: "value", Val: option.Value}},
+			}
+			if selected[option.Value] {
+				node.Attr = append(node.Attr, html.Attribute{Key: "selected"})
+			}
+			node.AppendChild(&html.Node{Type: html.TextNode, Data: option.Label})
+			result = append(result, node)
+			continue
+		}
+		kind, _ := getAttribute(n, "type")
+		name, _ := getAttribute(n, "name")
+		input := &html.Node{
+			Type:     html.ElementNode,
+			Data:     "input",
+			DataAtom: atom.Input,
+			Attr: []html.Attribute{
+				{Key: "type", Val: kind},
+				{Key: "name", Val: name},
+				{Key: "value", Val: option.Value},
+			},
+		}
+		if selected[option.Value] {
+			input.Attr = append(input.Attr, html.Attribute{Key: "checked"})
+		}
+		label := &html.Node{Type: html.ElementNode, Data: "label", DataAtom: atom.Label}
+		label.AppendChild(input)
+		label.AppendChild(&html.Node{Type: html.TextNode, Data: option.Label})
+		result = append(result, label)
+	}
+	return result, nil
+}
+
+// toOptions converts the value of the from attribute of an `options`
+// or `choices` tag, which is a slice of objects with a value and a
+// label such as []Option.
+func toOptions(v any) ([]Option, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("can not use '%s' of type %s as options", stringify(v), typeof(v))
+	}
+	options := make([]Option, rv.Len())
+	for i := range options {
+		scope := map[string]any{"option": rv.Index(i).Interface()}
+		value, err := lookup("option.value", scope)
+		if err != nil {
+			return nil, fmt.Errorf("option %d: %w", i, err)
+		}
+		label, err := lookup("option.label", scope)
+		if err != nil {
+			return nil, fmt.Errorf("option %d: %w", i, err)
+		}
+		var valueOK, labelOK bool
+		options[i].Value, valueOK = value.(string)
+		options[i].Label, labelOK = label.(string)
+		if !valueOK || !labelOK {
+			return nil, fmt.Errorf("option %d must have a string value and a string label", i)
+		}
+	}
+	return options, nil
+}
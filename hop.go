@@ -1,15 +1,24 @@
 package hop
 
 import (
+	"cmp"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
+	"log/slog"
 	"maps"
+	"math"
+	"path"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/hoplang/hop-go/escape"
+	"github.com/hoplang/hop-go/hoptype"
 	"github.com/hoplang/hop-go/internal/toposort"
 	"github.com/hoplang/hop-go/parser"
 	"github.com/hoplang/hop-go/typechecker"
@@ -17,24 +26,125 @@ import (
 )
 
 type module struct {
-	root          *html.Node
-	functions     map[string]*html.Node
-	imports       map[string][]string
-	functionTypes map[string]typechecker.TypeExpr
+	root      *html.Node
+	functions map[string]*html.Node
+	imports   map[string][]string
+	// importedAs maps the name that each imported function is used by
+	// in the module, which differs from its name if the import has an
+	// `as` attribute, to the function.
+	importedAs map[string]renderTarget
+	// exports maps the names of the functions that the module
+	// re-exports from other modules to the functions.
+	exports       map[string]renderTarget
+	functionTypes map[string]*typechecker.FunctionType
 	nodePositions map[*html.Node]parser.NodePosition
+	renderTargets map[*html.Node]renderTarget
+	// path is the path of the file that the module was read from,
+	// or empty if the module was not added from a file system.
+	path string
+	info ModuleInfo
+	// private holds the names of functions that were nested in other
+	// functions. They can only be rendered by their enclosing function.
+	private map[string]bool
+	// overrides holds the functions that layers of the module replaced.
+	overrides []Override
+	// markup is the function that holds the top-level markup of the
+	// module if it is included by another module, see resolveIncludes.
+	markup *html.Node
+	// urlBindings holds the types of the bindings of URL attributes,
+	// which collectWarnings warns about.
+	urlBindings map[typechecker.Binding]typechecker.TypeExpr
 }
 
 type Program struct {
-	modules map[string]module
+	modules             map[string]module
+	urlPolicy           URLPolicy
+	warnings            []Warning
+	logger              *slog.Logger
+	slowRenderThreshold time.Duration
+	// stats records statistics about the current render. It is only set
+	// on the copy of the program made by ExecuteFunctionWithStats.
+	stats *ExecStats
+	// features decides the `<if feature>` conditions that were not
+	// resolved at compile time, see WithFeatures.
+	features Features
+	// assignment chooses the variants of `variant` elements, see
+	// WithAssignment.
+	assignment Assignment
+	// translator translates the text of `t` tags, see WithTranslator.
+	translator Translator
+	// imageResolver builds the URLs of `img-set` tags.
+	imageResolver ImageResolver
+	// hasIcons is set when icons were registered, see Compiler.AddIcons,
+	// and renderedIcons holds the icons whose symbol the current
+	// execution has rendered.
+	hasIcons      bool
+	renderedIcons map[string]bool
+	// filters are the filters that bindings can use.
+	filters map[string]filter
+	// timeFormats are the layouts of the formats of `time` tags.
+	timeFormats map[string]string
+	// numberFormat is how `money` and `measure` tags write numbers.
+	numberFormat NumberFormat
+	// assets collects the assets of renders, see
+	// ExecuteFunctionWithAssets.
+	assets *Assets
+	// boundary is called between chunks of streamed output, see
+	// StreamHTTP.
+	boundary func() error
+	// streams holds the loops over Rows of the current render. It is
+	// only set on the copy of the program made by ExecuteFunction.
+	streams *rowStreams
+	// rawHTMLStrings allows `raw-html` to insert plain strings, see
+	// Compiler.SetRawHTMLStrings.
+	rawHTMLStrings bool
+	// overrides holds the functions that layers replaced, see
+	// Compiler.AddLayer.
+	overrides []Override
 }
 
 type Compiler struct {
-	modules map[string]string
+	modules             map[string]string
+	paths               map[string]string
+	options             typechecker.Options
+	singleFunctionFiles bool
+	urlPolicy           URLPolicy
+	logger              *slog.Logger
+	features            Features
+	target              string
+	// hashes holds the content hashes of the modules added by Sync.
+	hashes map[string]string
+	// layers holds the layer that each module was added by, and
+	// overlays the modules of later layers that are merged into it.
+	layers   map[string]string
+	overlays map[string][]overlay
+	// imageResolver builds the URLs of `img-set` tags.
+	imageResolver ImageResolver
+	// icons are the icons registered with AddIcons.
+	icons map[string]icon
+	// filters are the filters that bindings can use.
+	filters map[string]filter
+	// timeFormats are the layouts of the formats of `time` tags.
+	timeFormats map[string]string
+	// numberFormat is how `money` and `measure` tags write numbers.
+	numberFormat NumberFormat
+	// budget limits the complexity of functions, see SetBudget.
+	budget Budget
 }
 
 func NewCompiler() *Compiler {
 	return &Compiler{
-		modules: map[string]string{},
+		modules:       map[string]string{},
+		paths:         map[string]string{},
+		hashes:        map[string]string{},
+		layers:        map[string]string{},
+		overlays:      map[string][]overlay{},
+		urlPolicy:     DefaultURLPolicy,
+		logger:        discardLogger,
+		imageResolver: DefaultImageResolver,
+		filters:       maps.Clone(standardFilters),
+		timeFormats:   maps.Clone(standardTimeFormats),
+		numberFormat:  NumberFormats["en"],
 	}
 }
 
@@ -55,34 +165,123 @@ func (c *Compiler) AddFS(fsys fs.FS) error {
 		}
 		moduleName := strings.TrimSuffix(path, ".hop")
 		c.AddModule(moduleName, string(content))
+		c.paths[moduleName] = path
 		return nil
 	})
 }
 
+// AddModule adds or replaces the source of a module. Replacing a module
+// keeps the layers that were added on top of it.
 func (c *Compiler) AddModule(moduleName string, template string) {
 	c.modules[moduleName] = template
+	delete(c.paths, moduleName)
+	delete(c.hashes, moduleName)
+}
+
+// SetSingleFunctionFiles controls whether modules without any `function`
+// tags are treated as the body of a single function. The function is
+// named after the last element of the module name, and its parameter
+// can be named using the params-as attribute of the module metadata:
+//
+//	<module params-as="post"></module>
+//	<h1 inner-text="post.title"></h1>
+func (c *Compiler) SetSingleFunctionFiles(enabled bool) {
+	c.singleFunctionFiles = enabled
+}
+
+// SetStrictParams controls whether render calls may pass objects with
+// fields that the called function does not use. In strict mode such
+// calls are reported as type errors listing the unused fields.
+func (c *Compiler) SetStrictParams(strict bool) {
+	c.options.StrictParams = strict
+}
+
+// SetStrictAttributes controls whether attributes that look like
+// misspelled hop attributes are reported as errors. Without strict mode
+// an attribute such as innertext or atr-class is rendered literally.
+func (c *Compiler) SetStrictAttributes(strict bool) {
+	c.options.StrictAttributes = strict
+}
+
+// SetRawHTMLStrings controls whether `raw-html` may insert plain
+// strings, such as the fields of JSON data. By default its value must
+// be trusted HTML, so that markup is only inserted from values that
+// the application has explicitly converted to HTML.
+func (c *Compiler) SetRawHTMLStrings(allowed bool) {
+	c.options.RawHTMLStrings = allowed
 }
 
 func (c *Compiler) Compile() (*Program, error) {
+	start := time.Now()
+	p, err := c.compile()
+	if err != nil {
+		c.logger.Debug("compilation failed", "duration", time.Since(start), "error", err)
+		return nil, err
+	}
+	c.logger.Debug("compiled program", "modules", len(p.modules), "duration", time.Since(start))
+	return p, nil
+}
+
+func (c *Compiler) compile() (*Program, error) {
 	p := &Program{
-		modules: map[string]module{},
+		modules:             map[string]module{},
+		urlPolicy:           c.urlPolicy,
+		logger:              c.logger,
+		imageResolver:       c.imageResolver,
+		hasIcons:            len(c.icons) > 0,
+		rawHTMLStrings:      c.options.RawHTMLStrings,
+		filters:             maps.Clone(c.filters),
+		timeFormats:         maps.Clone(c.timeFormats),
+		numberFormat:        c.numberFormat,
+		slowRenderThreshold: defaultSlowRenderThreshold,
 	}
 
 	dependencyGraph := make(map[string]map[string]bool)
 
-	// Step 1: Parse all modules and collect dependencies
-	for moduleName, templateSrc := range c.modules {
+	// Step 1: Parse all modules and collect dependencies. Modules are
+	// visited in sorted order so that compilation is reproducible.
+	for _, moduleName := range slices.Sorted(maps.Keys(c.modules)) {
+		parseStart := time.Now()
+		templateSrc := c.modules[moduleName]
 		parseResult, err := parser.Parse(templateSrc)
 		if err != nil {
-			return nil, fmt.Errorf("parsing module %s: %w", moduleName, err)
+			return nil, withModule(err, "parsing", moduleName, c.paths[moduleName])
 		}
 
 		mod := module{
 			root:          parseResult.Root,
 			functions:     map[string]*html.Node{},
 			imports:       map[string][]string{},
-			functionTypes: map[string]typechecker.TypeExpr{},
+			importedAs:    map[string]renderTarget{},
+			exports:       map[string]renderTarget{},
+			functionTypes: map[string]*typechecker.FunctionType{},
 			nodePositions: parseResult.NodePositions,
+			renderTargets: map[*html.Node]renderTarget{},
+			path:          c.paths[moduleName],
+		}
+
+		if err := c.resolveCompileTimeConditions(parseResult.Root, parseResult.NodePositions); err != nil {
+			return nil, withModule(err, "parsing", moduleName, c.paths[moduleName])
+		}
+
+		mod.info, err = parseModuleInfo(parseResult.Root, parseResult.NodePositions)
+		if err != nil {
+			return nil, withModule(err, "parsing", moduleName, mod.path)
+		}
+
+		isSingleFunction := c.singleFunctionFiles &&
+			wrapSingleFunction(parseResult.Root, parseResult.NodePositions, path.Base(moduleName), mod.info)
+		if mod.info.ParamsAs != "" && !isSingleFunction {
+			return nil, withModule(fmt.Errorf("params-as in module metadata is only allowed in single-function files"),
+				"parsing", moduleName, mod.path)
+		}
+		if err := c.applyLayers(moduleName, &mod); err != nil {
+			return nil, err
+		}
+		p.overrides = append(p.overrides, mod.overrides...)
+		mod.private, err = liftNestedFunctions(parseResult.Root, mod.nodePositions)
+		if err != nil {
+			return nil, withModule(err, "parsing", moduleName, mod.path)
 		}
 
 		dependencyGraph[moduleName] = make(map[string]bool)
@@ -109,13 +308,49 @@ func (c *Compiler) Compile() (*Program, error) {
 						function = attr.Val
 					}
 				}
-				mod.imports[module] = append(mod.imports[module], function)
+				// Wildcard imports are expanded once all modules
+				// have been parsed, see expandWildcardImports.
+				if function != "" {
+					if err := mod.addImport(c, module, function, parseResult.NodePositions); err != nil {
+						return nil, withModule(err, "parsing", moduleName, mod.path)
+					}
+				} else if _, ok := getAttribute(c, "as"); ok {
+					err := &parser.ParseError{
+						Pos:     parseResult.NodePositions[c].Start,
+						Message: "parse error: an import without function can not have attribute 'as'",
+					}
+					return nil, withModule(err, "parsing", moduleName, mod.path)
+				}
 				// Add import to dependency graph
 				dependencyGraph[moduleName][module] = true
+			case "export":
+				from, err := mod.addExport(c, parseResult.NodePositions)
+				if err != nil {
+					return nil, withModule(err, "parsing", moduleName, mod.path)
+				}
+				dependencyGraph[moduleName][from] = true
 			}
 		}
 
+		if isSingleFunction {
+			mod.markup = mod.functions[path.Base(moduleName)]
+		}
+
 		p.modules[moduleName] = mod
+		c.logger.Debug("parsed module", "module", moduleName, "duration", time.Since(parseStart))
+	}
+
+	if err := p.expandWildcardImports(); err != nil {
+		return nil, err
+	}
+	if err := p.resolveExports(); err != nil {
+		return nil, err
+	}
+	if err := p.resolveExtends(dependencyGraph); err != nil {
+		return nil, err
+	}
+	if err := p.resolveIncludes(dependencyGraph); err != nil {
+		return nil, err
 	}
 
 	sortedModules, err := toposort.TopologicalSort(dependencyGraph, "module")
@@ -123,62 +358,161 @@ func (c *Compiler) Compile() (*Program, error) {
 		return nil, fmt.Errorf("sorting modules: %w", err)
 	}
 
+	// Step 2: Resolve imports and render targets before typechecking
 	for _, moduleName := range sortedModules {
+		if err := p.resolveImports(moduleName); err != nil {
+			return nil, err
+		}
+		p.resolveRenderTargets(moduleName)
+	}
+
+	filterTypes := c.filterTypes()
+	for _, moduleName := range sortedModules {
+		typecheckStart := time.Now()
 		mod := p.modules[moduleName]
-		importedFunctionTypes := make(map[string]typechecker.TypeExpr)
+		importedFunctionTypes := make(map[string]*typechecker.FunctionType)
 
 		// Process imports
-		for importModuleName, functionNames := range mod.imports {
-			importedModule := p.modules[importModuleName]
-			for _, functionName := range functionNames {
-				// Get function type and implementation
-				if importedType, ok := importedModule.functionTypes[functionName]; ok {
-					importedFunctionTypes[functionName] = importedType
-				} else {
-					return nil, fmt.Errorf("function %s not found in module %s",
-						functionName, importModuleName)
-				}
-			}
+		for name, target := range mod.importedAs {
+			importedFunctionTypes[name] = p.modules[target.module].functionTypes[target.function]
 		}
 
 		// Typecheck
-		functionTypes, err := typechecker.Typecheck(mod.root, mod.nodePositions, importedFunctionTypes)
+		options := c.options
+		options.Filters = filterTypes
+		options.TimeFormats = p.timeFormats
+		if mod.info.StrictParams != nil {
+			options.StrictParams = *mod.info.StrictParams
+		}
+		mod.urlBindings = map[typechecker.Binding]typechecker.TypeExpr{}
+		options.URLBindings = mod.urlBindings
+		functionTypes, err := typechecker.Typecheck(mod.root, mod.nodePositions, importedFunctionTypes, options)
 		if err != nil {
-			return nil, fmt.Errorf("typechecking module %s: %w", moduleName, err)
+			return nil, withModule(err, "typechecking", moduleName, mod.path)
 		}
 
+		for functionName := range mod.functions {
+			functionTypes[functionName].Module = moduleName
+		}
+		if t, ok := functionTypes[includeFunction]; ok {
+			// The markup of a module is rendered by the pages that
+			// extend it.
+			t.Module = moduleName
+		}
+		if err := substituteBuildVars(mod.root, mod.nodePositions, c.options.BuildVars); err != nil {
+			return nil, withModule(err, "typechecking", moduleName, mod.path)
+		}
 		mod.functionTypes = functionTypes
 		p.modules[moduleName] = mod
+		c.logger.Debug("typechecked module", "module", moduleName, "duration", time.Since(typecheckStart))
+	}
+
+	for _, moduleName := range slices.Sorted(maps.Keys(p.modules)) {
+		p.collectWarnings(moduleName)
+		if err := p.checkBudget(moduleName, c.budget); err != nil {
+			return nil, err
+		}
 	}
 
 	return p, nil
 }
 
+// withModule attaches the name and source path of a module to an error.
+// Diagnostics with positions record the module themselves, other errors
+// are wrapped.
+func withModule(err error, phase string, moduleName string, path string) error {
+	var parseErr *parser.ParseError
+	if errors.As(err, &parseErr) {
+		parseErr.Module = moduleName
+		parseErr.File = path
+		return parseErr
+	}
+	var typeErr *typechecker.TypeError
+	if errors.As(err, &typeErr) {
+		typeErr.Module = moduleName
+		typeErr.File = path
+		return typeErr
+	}
+	if path != "" {
+		return fmt.Errorf("%s module %s (%s): %w", phase, moduleName, path, err)
+	}
+	return fmt.Errorf("%s module %s: %w", phase, moduleName, err)
+}
+
 func (p *Program) GetModules() map[string][]string {
 	result := map[string][]string{}
 	for k, mod := range p.modules {
-		for f := range mod.functions {
-			result[k] = append(result[k], f)
+		for _, f := range slices.Sorted(maps.Keys(mod.functions)) {
+			if !mod.private[f] {
+				result[k] = append(result[k], f)
+			}
 		}
 	}
 	return result
 }
 
-// ExecuteFunction executes a specific function from the template with the given parameters
-func (p *Program) ExecuteFunction(w io.Writer, moduleName string, functionName string, data any) error {
+// SourcePath returns the path of the file that a module was read from
+// by Compiler.AddFS. It returns false if the module was added directly.
+func (p *Program) SourcePath(moduleName string) (string, bool) {
+	module, exists := p.modules[moduleName]
+	if !exists || module.path == "" {
+		return "", false
+	}
+	return module.path, true
+}
+
+// FunctionType returns the inferred parameter type of a function.
+func (p *Program) FunctionType(moduleName string, functionName string) (*hoptype.Type, error) {
+	module, exists := p.modules[moduleName]
+	if !exists {
+		return nil, fmt.Errorf("no module with name %s", moduleName)
+	}
+	if _, exists := module.functions[functionName]; !exists || module.private[functionName] {
+		return nil, fmt.Errorf("no function with name %s in module %s", functionName, moduleName)
+	}
+	return typechecker.Export(module.functionTypes[functionName].Params), nil
+}
+
+// ExecuteFunction executes a specific function from the template with the given parameters.
+//
+// A panic during rendering is recovered and returned as a *PanicError.
+func (p *Program) ExecuteFunction(w io.Writer, moduleName string, functionName string, data any) (err error) {
 	module, exists := p.modules[moduleName]
 	if !exists {
 		return fmt.Errorf("no module with name %s", moduleName)
 	}
 	function, exists := module.functions[functionName]
-	if !exists {
+	if !exists || module.private[functionName] {
 		return fmt.Errorf("no function with name %s in module %s", functionName, moduleName)
 	}
-	functionScope := map[string]any{}
-	for _, attr := range function.Attr {
-		if attr.Key == "params-as" {
-			functionScope[attr.Val] = data
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoverRender(r, moduleName+"/"+functionName, true)
+		}
+	}()
+	if p.streams == nil {
+		rendering := *p
+		rendering.streams = &rowStreams{
+			loops:     map[*html.Node]*rowStream{},
+			ancestors: map[*html.Node]bool{},
+		}
+		if p.hasIcons && p.renderedIcons == nil {
+			rendering.renderedIcons = map[string]bool{}
 		}
+		p = &rendering
+		defer p.closeStreams()
+	}
+	start := time.Now()
+	defer func() {
+		d := time.Since(start)
+		p.stats.addFunction(moduleName, functionName, d)
+		if d >= p.slowRenderThreshold {
+			p.logger.Warn("slow render", "module", moduleName, "function", functionName, "duration", d)
+		}
+	}()
+	functionScope := map[string]any{}
+	if err := bindParams(function, data, functionScope); err != nil {
+		return err
 	}
 	for c := range function.ChildNodes() {
 		nodes, err := p.evaluateNode(moduleName, c, functionScope)
@@ -186,10 +520,15 @@ func (p *Program) ExecuteFunction(w io.Writer, moduleName string, functionName s
 			return err
 		}
 		for _, n := range nodes {
-			err := html.Render(w, n)
+			err = p.renderNode(w, n)
+			if err == nil && p.boundary != nil {
+				err = p.boundary()
+			}
 			if err != nil {
 				return err
 			}
+			p.stats.countNodes(n)
+			p.assets.collect(n)
 		}
 	}
 	return nil
@@ -203,6 +542,12 @@ func typeof(v any) string {
 		return "object"
 	case string:
 		return "string"
+	case HTML:
+		return "html"
+	case URL:
+		return "url"
+	case JS:
+		return "js"
 	case []any:
 		return "array"
 	default:
@@ -222,13 +567,53 @@ func getFieldByJSONTag(v reflect.Value, tagName string) (reflect.Value, error) {
 		jsonTag := field.Tag.Get("json")
 		// Split the json tag to handle cases like `json:"name,omitempty"`
 		tagParts := strings.Split(jsonTag, ",")
-		if tagParts[0] == tagName {
+		if tagParts[0] == tagName || hasProtobufName(field, tagName) {
 			return v.Field(i), nil
 		}
 	}
 	return reflect.Value{}, fmt.Errorf("json tag %s not found", tagName)
 }
 
+// Lookup evaluates a path such as `post.tags[0]` against a scope in the
+// same way as the attributes of a template.
+func Lookup(path string, scope map[string]any) (any, error) {
+	return lookup(path, scope)
+}
+
+// evaluatePath looks up a path of the template in the scope, counting
+// the lookup if statistics are recorded.
+func (p *Program) evaluatePath(path string, scope map[string]any) (any, error) {
+	if p.stats != nil {
+		p.stats.Lookups++
+	}
+	return lookup(path, scope)
+}
+
+// evaluateBinding evaluates the value of an inner-text or attr- binding.
+// The alternatives of a binding are tried in order, and the first one
+// that resolves to a non-null value is piped through the filters.
+func (p *Program) evaluateBinding(binding string, scope map[string]any) (any, error) {
+	parsed, err := parser.ParseBinding(binding)
+	if err != nil {
+		return nil, err
+	}
+	v, err := p.evaluateAlternatives(parsed.Alternatives, scope)
+	return p.applyFilters(v, err, parsed.Filters)
+}
+
+func (p *Program) evaluateAlternatives(alternatives []parser.Alternative, scope map[string]any) (v any, err error) {
+	for i, alternative := range alternatives {
+		if alternative.IsLiteral {
+			return alternative.Literal, nil
+		}
+		v, err = p.evaluatePath(alternative.Path, scope)
+		if err == nil && (v != nil || i == len(alternatives)-1) {
+			return v, nil
+		}
+	}
+	return nil, err
+}
+
 // lookup retrieves a value from the symbol table using a path string
 func lookup(path string, scope map[string]any) (any, error) {
 	components, err := parser.ParsePath(path)
@@ -246,6 +631,11 @@ func lookup(path string, scope map[string]any) (any, error) {
 				return nil, fmt.Errorf("key not found: %s", comp.Value)
 			}
 
+		case tableRow:
+			if current, err = v.field(comp.Value); err != nil {
+				return nil, err
+			}
+
 		case []any:
 			// Only attempt array indexing if the component was marked as an array reference
 			if !comp.IsArrayRef {
@@ -275,6 +665,10 @@ func lookup(path string, scope map[string]any) (any, error) {
 				if !field.CanInterface() {
 					return nil, fmt.Errorf("field with json tag %s is not exported", comp.Value)
 				}
+				if isProtobufMessage(val.Type()) {
+					current = protobufValue(field)
+					continue
+				}
 				current = field.Interface()
 			} else {
 				return nil, fmt.Errorf("cannot navigate through type %T", current)
@@ -285,8 +679,8 @@ func lookup(path string, scope map[string]any) (any, error) {
 	return current, nil
 }
 
-func handleInnerText(symbols map[string]any, path string) (*html.Node, error) {
-	v, err := lookup(path, symbols)
+func (p *Program) handleInnerText(symbols map[string]any, path string) (*html.Node, error) {
+	v, err := p.evaluateBinding(path, symbols)
 	if err != nil {
 		return nil, err
 	}
@@ -298,6 +692,10 @@ func handleInnerText(symbols map[string]any, path string) (*html.Node, error) {
 		str = fmt.Sprintf("%d", u)
 	case string:
 		str = u
+	case HTML:
+		// Trusted html is escaped like any other text, it is inserted
+		// as markup only by inner-html and raw-html.
+		str = string(u)
 	default:
 		return nil, fmt.Errorf("can not assign '%v' of type %T as inner text", v, v)
 	}
@@ -312,6 +710,9 @@ func handleInnerText(symbols map[string]any, path string) (*html.Node, error) {
 // The returned html nodes will have no parent and no siblings and it
 // is thus safe to append them as the child nodes of another HTML node.
 func (p *Program) evaluateNode(currentModule string, n *html.Node, symbols map[string]any) ([]*html.Node, error) {
+	if isHopComment(n) {
+		return nil, nil
+	}
 	if n.Type == html.ElementNode {
 		switch n.Data {
 		case "render":
@@ -319,30 +720,121 @@ func (p *Program) evaluateNode(currentModule string, n *html.Node, symbols map[s
 		case "fragment":
 			return p.evaluateFragment(currentModule, n, symbols)
 		case "children":
-			return p.evaluateChildren(symbols)
+			return p.evaluateChildren(n, symbols)
+		case "slot":
+			return p.evaluateSlot(currentModule, n, symbols)
 		case "for":
 			return p.evaluateFor(currentModule, n, symbols)
 		case "if":
 			return p.evaluateIf(currentModule, n, symbols)
+		case "match":
+			return p.evaluateMatch(currentModule, n, symbols)
+		case "range":
+			return p.evaluateRange(currentModule, n, symbols)
+		case "img-set":
+			return p.evaluateImgSet(n, symbols)
+		case "icon":
+			return p.evaluateIcon(currentModule, n, symbols)
+		case "field":
+			return p.evaluateField(currentModule, n, symbols)
+		case "options", "choices":
+			return p.evaluateOptions(n, symbols)
+		case "table-for":
+			return p.evaluateTableFor(currentModule, n, symbols)
+		case "raw-html":
+			return p.evaluateRawHTML(n, symbols)
+		case "include":
+			return p.evaluateInclude(n)
+		case "money", "measure":
+			return p.evaluateQuantity(currentModule, n, symbols)
+		case "variant":
+			return p.evaluateVariant(currentModule, n, symbols)
+		case "t":
+			return p.evaluateTranslation(n), nil
+		case "time":
+			if _, ok := getAttribute(n, "from"); ok {
+				return p.evaluateTime(currentModule, n, symbols)
+			}
 		}
 	}
 	return p.evaluateNative(currentModule, n, symbols)
 }
 
+// slot holds the children of a `render` tag together with the scope
+// they should be evaluated in. The `fill` tags among the children are
+// held by the name of the slot that they fill.
+type slot struct {
+	module string
+	render *html.Node
+	scope  map[string]any
+	as     string
+	fills  map[string]*html.Node
+}
+
 // evaluateChildren evaluates a `children` tag.
 // <children></children>
-func (p *Program) evaluateChildren(s map[string]any) ([]*html.Node, error) {
+// <children params="item"></children>
+func (p *Program) evaluateChildren(n *html.Node, s map[string]any) ([]*html.Node, error) {
 	v, err := lookup("children", s)
 	if err != nil {
 		return nil, err
 	}
-	switch u := v.(type) {
-	case nil:
-		return nil, nil
-	case []*html.Node:
-		return u, nil
+	sl, ok := v.(*slot)
+	if !ok {
+		panic("Unexpected type of children")
+	}
+	scope := sl.scope
+	if sl.as != "" {
+		if params, found := getAttribute(n, "params"); found {
+			value, err := p.evaluatePath(params, s)
+			if err != nil {
+				return nil, err
+			}
+			scope = maps.Clone(scope)
+			scope[sl.as] = value
+		}
+	}
+	var result []*html.Node
+	for c := range sl.render.ChildNodes() {
+		if c.Type == html.ElementNode && c.Data == "fill" {
+			continue
+		}
+		ns, err := p.evaluateNode(sl.module, c, scope)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, ns...)
 	}
-	panic("Unexpected type of children")
+	return result, nil
+}
+
+// evaluateSlot evaluates a `slot` tag by evaluating the children of the
+// `fill` tag for the slot, or its own children if the slot is not
+// filled.
+// <slot name="header"></slot>
+func (p *Program) evaluateSlot(currentModule string, n *html.Node, s map[string]any) ([]*html.Node, error) {
+	v, err := lookup("children", s)
+	if err != nil {
+		return nil, err
+	}
+	sl, ok := v.(*slot)
+	if !ok {
+		panic("Unexpected type of children")
+	}
+	name, _ := getAttribute(n, "name")
+	module, content, scope := currentModule, n, s
+	if fill, ok := sl.fills[name]; ok {
+		module, content, scope = sl.module, fill, sl.scope
+	}
+	var result []*html.Node
+	for c := range content.ChildNodes() {
+		ns, err := p.evaluateNode(module, c, scope)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, ns...)
+	}
+	return result, nil
 }
 
 // evaluateFragment evaluates a `fragment` tag.
@@ -352,7 +844,7 @@ func (p *Program) evaluateFragment(currentModule string, n *html.Node, s map[str
 		panic("Expected fragment to have exactly 0 or 1 attribute after type checking")
 	}
 	if len(n.Attr) == 1 {
-		textNode, err := handleInnerText(s, n.Attr[0].Val)
+		textNode, err := p.handleInnerText(s, n.Attr[0].Val)
 		return []*html.Node{textNode}, err
 	}
 	result := []*html.Node{}
@@ -367,14 +859,10 @@ func (p *Program) evaluateFragment(currentModule string, n *html.Node, s map[str
 }
 
 // evaluateRender evaluates a `render` tag.
-// <render function="list" params="item">
+// <render function="list" params="items" children-as="item">
 // ...
 // </render>
 func (p *Program) evaluateRender(currentModule string, n *html.Node, s map[string]any) ([]*html.Node, error) {
-	if len(n.Attr) < 1 || len(n.Attr) > 2 {
-		panic("Expected render to have exactly 1 or 2 attributes after type checking")
-	}
-
 	var functionName string
 	var valueToBind any
 
@@ -383,53 +871,82 @@ func (p *Program) evaluateRender(currentModule string, n *html.Node, s map[strin
 			functionName = attr.Val
 		}
 		if attr.Key == "params" {
-			v, err := lookup(attr.Val, s)
+			v, err := p.evaluatePath(attr.Val, s)
 			if err != nil {
 				return nil, err
 			}
 			valueToBind = v
 		}
-	}
-
-	// Determine which module contains the function
-	targetModule := currentModule
-	targetFunction := functionName
-
-	// Check if the function is imported from another module
-	mod := p.modules[currentModule]
-	for importedModule, functions := range mod.imports {
-		for _, fn := range functions {
-			if fn == functionName {
-				targetModule = importedModule
-				break
+		if attr.Key == "params-literal" {
+			// The literal is decoded for every render so that functions
+			// can not share changes to it.
+			if err := json.Unmarshal([]byte(attr.Val), &valueToBind); err != nil {
+				return nil, err
 			}
 		}
+		if name, ok := strings.CutPrefix(attr.Key, "with-"); ok {
+			v, literal := parser.ParseLiteral(attr.Val)
+			if !literal {
+				var err error
+				v, err = p.evaluatePath(attr.Val, s)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if valueToBind == nil {
+				valueToBind = map[string]any{}
+			}
+			valueToBind.(map[string]any)[name] = v
+		}
 	}
 
-	// Get the function from the correct module
-	function, found := p.modules[targetModule].functions[targetFunction]
+	// The module that defines the function was resolved during compilation
+	target, found := p.modules[currentModule].renderTargets[n]
 	if !found {
-		return nil, fmt.Errorf("no function with name '%s' in module '%s'", targetFunction, targetModule)
+		panic(fmt.Sprintf("Expected render of '%s' to be resolved after compilation", functionName))
+	}
+	targetModule := target.module
+	function := p.modules[targetModule].functions[target.function]
+	if target.function == includeFunction {
+		// A layout, see resolveExtends.
+		function = p.modules[targetModule].markup
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			recoverRender(r, targetModule+"/"+target.function, false)
+		}
+	}()
+	if p.stats != nil {
+		start := time.Now()
+		defer func() {
+			p.stats.addFunction(targetModule, target.function, time.Since(start))
+		}()
 	}
 
 	functionScope := map[string]any{}
-	for _, attr := range function.Attr {
-		if attr.Key == "params-as" {
-			functionScope[attr.Val] = valueToBind
-		}
+	if err := bindParams(function, valueToBind, functionScope); err != nil {
+		return nil, err
 	}
 
-	var children []*html.Node
+	// Add children to the function scope. They are evaluated lazily
+	// since the function may pass a value to them.
+	childrenAs, _ := getAttribute(n, "children-as")
+	sl := &slot{
+		module: currentModule,
+		render: n,
+		scope:  s,
+		as:     childrenAs,
+	}
 	for c := range n.ChildNodes() {
-		processed, err := p.evaluateNode(currentModule, c, s)
-		if err != nil {
-			return nil, err
+		if c.Type == html.ElementNode && c.Data == "fill" {
+			if sl.fills == nil {
+				sl.fills = map[string]*html.Node{}
+			}
+			name, _ := getAttribute(c, "slot")
+			sl.fills[name] = c
 		}
-		children = append(children, processed...)
 	}
-
-	// Add children to the function scope
-	functionScope["children"] = children
+	functionScope["children"] = sl
 
 	var results []*html.Node
 	for cc := range function.ChildNodes() {
@@ -443,22 +960,52 @@ func (p *Program) evaluateRender(currentModule string, n *html.Node, s map[strin
 	return results, nil
 }
 
+// bindParams binds the value passed to a function to its params-as
+// variable or, if the function declares named parameters, binds the
+// fields of the value to the variables of the parameters.
+func bindParams(function *html.Node, value any, scope map[string]any) error {
+	if paramsAs, ok := getAttribute(function, "params-as"); ok {
+		scope[paramsAs] = value
+		return nil
+	}
+	for name, variable := range typechecker.NamedParams(function) {
+		v, err := lookup("params."+name, map[string]any{"params": value})
+		if err != nil {
+			return fmt.Errorf("can not bind parameter %s: %w", name, err)
+		}
+		scope[variable] = v
+	}
+	return nil
+}
+
 // evaluateIf evaluates an `if` tag:
 //
 // <if true="item.isActive">
 // ...
 // </if>
+//
+// The condition can also be negated with not="item.isHidden" or be a
+// feature flag with feature="name".
 func (p *Program) evaluateIf(currentModule string, n *html.Node, s map[string]any) ([]*html.Node, error) {
 	if len(n.Attr) != 1 {
 		panic("Expected if to have exactly 1 attribute after type checking")
 	}
-	v, err := lookup(n.Attr[0].Val, s)
-	if err != nil {
-		return nil, err
-	}
-	b, ok := v.(bool)
-	if !ok {
-		return nil, fmt.Errorf("can not use '%v' of type %T as condition in if", v, v)
+	var b bool
+	if n.Attr[0].Key == "feature" {
+		b = p.features != nil && p.features(n.Attr[0].Val)
+	} else {
+		v, err := p.evaluatePath(n.Attr[0].Val, s)
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		b, ok = v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("can not use '%v' of type %T as condition in if", v, v)
+		}
+		if n.Attr[0].Key == "not" {
+			b = !b
+		}
 	}
 	if !b {
 		return []*html.Node{}, nil
@@ -475,34 +1022,411 @@ func (p *Program) evaluateIf(currentModule string, n *html.Node, s map[string]an
 	return results, nil
 }
 
+// evaluateMatch evaluates a `match` tag by evaluating the children of
+// the first case whose value equals the value of the path, or of the
+// default if there is no such case:
+//
+// <match on="item.status">
+// <case value="draft">...</case>
+// <default>...</default>
+// </match>
+func (p *Program) evaluateMatch(currentModule string, n *html.Node, s map[string]any) ([]*html.Node, error) {
+	if len(n.Attr) != 1 {
+		panic("Expected match to have exactly 1 attribute after type checking")
+	}
+	v, err := p.evaluatePath(n.Attr[0].Val, s)
+	if err != nil {
+		return nil, err
+	}
+	str, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("can not use '%v' of type %T as value of match", v, v)
+	}
+	for c := range n.ChildNodes() {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		if value, _ := getAttribute(c, "value"); c.Data == "default" || value == str {
+			var results []*html.Node
+			for cc := range c.ChildNodes() {
+				ns, err := p.evaluateNode(currentModule, cc, s)
+				if err != nil {
+					return nil, err
+				}
+				results = append(results, ns...)
+			}
+			return results, nil
+		}
+	}
+	return []*html.Node{}, nil
+}
+
 // evaluateFor evaluates a `for` tag:
 //
-// <for each="items" as="item">
+// <for each="items" as="item" index-as="i" meta-as="loop">
 // ...
+// <empty>...</empty>
 // </for>
 func (p *Program) evaluateFor(currentModule string, n *html.Node, s map[string]any) ([]*html.Node, error) {
-	if len(n.Attr) < 1 || len(n.Attr) > 2 {
-		panic("Expected for to have exactly 1 or 2 attributes after type checking")
+	if len(n.Attr) < 1 {
+		panic("Expected for to have attributes after type checking")
 	}
 	var each string
 	var as string
+	var indexAs string
+	var metaAs string
+	var keyAs, valueAs string
+	var sortBy, order, filter string
+	var limit, offset string
 	for _, attr := range n.Attr {
 		switch attr.Key {
 		case "each":
 			each = attr.Val
+		case "limit":
+			limit = attr.Val
+		case "offset":
+			offset = attr.Val
+		case "sort-by":
+			sortBy = attr.Val
+		case "order":
+			order = attr.Val
+		case "filter":
+			filter = attr.Val
 		case "as":
 			as = attr.Val
+		case "index-as":
+			indexAs = attr.Val
+		case "meta-as":
+			metaAs = attr.Val
+		case "key-as":
+			keyAs = attr.Val
+		case "value-as":
+			valueAs = attr.Val
 		}
 	}
 
-	v, err := lookup(each, s)
+	v, err := p.evaluatePath(each, s)
+	if err != nil {
+		return nil, err
+	}
+
+	if keyAs != "" || valueAs != "" {
+		return p.evaluateForEntries(currentModule, n, s, v, keyAs, valueAs)
+	}
+	// length is the number of elements and item returns each of them,
+	// so that the rows of a table are not materialized.
+	var length int
+	var item func(i int) any
+	// The window is evaluated before the loop variables are bound.
+	start, count, err := p.evaluateWindow(s, limit, offset)
 	if err != nil {
 		return nil, err
 	}
+	if rows, ok := v.(*Rows); ok {
+		return p.evaluateForRows(currentModule, n, s, rows, forRowsOptions{
+			as:      as,
+			indexAs: indexAs,
+			metaAs:  metaAs,
+			filter:  filter,
+			sortBy:  sortBy,
+			offset:  start,
+			limit:   count,
+		})
+	}
+	if table, ok := v.(Table); ok {
+		length, item = table.Len(), tableRows(table)
+	} else {
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice {
+			return nil, fmt.Errorf("can not iterate over '%s' of type %s %v", stringify(v), typeof(v), reflect.TypeOf(v))
+		}
+		length, item = rv.Len(), func(i int) any { return rv.Index(i).Interface() }
+	}
+
+	// Clone the symbol table to allow for mutation.
+	if as != "" || indexAs != "" || metaAs != "" {
+		s = maps.Clone(s)
+	}
+
+	if filter != "" || sortBy != "" {
+		items, err := p.filterAndSort(s, as, length, item, filter, sortBy, order == "desc")
+		if err != nil {
+			return nil, err
+		}
+		length, item = len(items), func(i int) any { return items[i] }
+	}
+	if start > 0 || count >= 0 {
+		all := item
+		start = min(start, length)
+		length = length - start
+		if count >= 0 {
+			length = min(length, count)
+		}
+		item = func(i int) any { return all(start + i) }
+	}
+
+	if length == 0 {
+		return p.evaluateForBody(currentModule, n, s, true)
+	}
 
+	var results []*html.Node
+	for i := 0; i < length; i++ {
+		// Mutation is thread-safe here since we have cloned the symbol table.
+		if as != "" {
+			s[as] = item(i)
+		}
+		if indexAs != "" {
+			// Numbers are float64 as in data decoded from JSON.
+			s[indexAs] = float64(i)
+		}
+		if metaAs != "" {
+			s[metaAs] = loopMeta(i, length)
+		}
+		ns, err := p.evaluateForBody(currentModule, n, s, false)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, ns...)
+	}
+
+	return results, nil
+}
+
+// filterAndSort returns the elements of a `for` tag that its filter
+// accepts, sorted by its sort key. The filter and the sort key are
+// evaluated with each element bound to as in s. The sort is stable, so
+// elements with equal keys keep their order.
+func (p *Program) filterAndSort(s map[string]any, as string, length int, item func(i int) any, filter, sortBy string, desc bool) ([]any, error) {
+	var items, keys []any
+	for i := 0; i < length; i++ {
+		s[as] = item(i)
+		if filter != "" {
+			v, err := p.evaluatePath(filter, s)
+			if err != nil {
+				return nil, err
+			}
+			keep, ok := v.(bool)
+			if !ok {
+				return nil, fmt.Errorf("can not use '%v' of type %T as filter", v, v)
+			}
+			if !keep {
+				continue
+			}
+		}
+		if sortBy != "" {
+			key, err := p.evaluatePath(sortBy, s)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		}
+		items = append(items, s[as])
+	}
+	if sortBy == "" {
+		return items, nil
+	}
+	order := make([]int, len(items))
+	for i := range order {
+		order[i] = i
+	}
+	var err error
+	slices.SortStableFunc(order, func(a, b int) int {
+		c, cmpErr := compareSortKeys(keys[a], keys[b])
+		if cmpErr != nil && err == nil {
+			err = cmpErr
+		}
+		if desc {
+			return -c
+		}
+		return c
+	})
+	if err != nil {
+		return nil, err
+	}
+	sorted := make([]any, len(items))
+	for i, j := range order {
+		sorted[i] = items[j]
+	}
+	return sorted, nil
+}
+
+// compareSortKeys compares two sort keys of a `for` tag, which must
+// both be numbers, strings or times. Numbers of any Go numeric type can
+// be compared with each other.
+func compareSortKeys(a, b any) (int, error) {
+	switch x, y := sortKey(a), sortKey(b); x := x.(type) {
+	case int64:
+		switch y := y.(type) {
+		case int64:
+			return cmp.Compare(x, y), nil
+		case uint64:
+			if x < 0 {
+				return -1, nil
+			}
+			return cmp.Compare(uint64(x), y), nil
+		case float64:
+			return cmp.Compare(float64(x), y), nil
+		}
+	case uint64:
+		switch y := y.(type) {
+		case int64:
+			if y < 0 {
+				return 1, nil
+			}
+			return cmp.Compare(x, uint64(y)), nil
+		case uint64:
+			return cmp.Compare(x, y), nil
+		case float64:
+			return cmp.Compare(float64(x), y), nil
+		}
+	case float64:
+		switch y := y.(type) {
+		case int64:
+			return cmp.Compare(x, float64(y)), nil
+		case uint64:
+			return cmp.Compare(x, float64(y)), nil
+		case float64:
+			return cmp.Compare(x, y), nil
+		}
+	case string:
+		if y, ok := y.(string); ok {
+			return strings.Compare(x, y), nil
+		}
+	case time.Time:
+		if y, ok := y.(time.Time); ok {
+			return x.Compare(y), nil
+		}
+	}
+	return 0, fmt.Errorf("can not compare sort keys '%v' of type %T and '%v' of type %T", a, a, b, b)
+}
+
+// sortKey normalizes a sort key, so that numbers are int64, uint64 or
+// float64 and strings are string, whatever their Go type.
+func sortKey(v any) any {
+	if _, ok := v.(time.Time); ok || v == nil {
+		return v
+	}
 	rv := reflect.ValueOf(v)
-	if rv.Kind() != reflect.Slice {
-		return nil, fmt.Errorf("can not iterate over '%s' of type %s %v", stringify(v), typeof(v), reflect.TypeOf(v))
+	switch {
+	case rv.CanInt():
+		return rv.Int()
+	case rv.CanUint():
+		return rv.Uint()
+	case rv.CanFloat():
+		return rv.Float()
+	case rv.Kind() == reflect.String:
+		return rv.String()
+	}
+	return v
+}
+
+// evaluateWindow evaluates the offset and limit of a `for` tag, which
+// skip the first offset elements and stop after limit elements. The
+// limit is -1 if there is none.
+func (p *Program) evaluateWindow(s map[string]any, limit, offset string) (int, int, error) {
+	start, count := 0, -1
+	var err error
+	if offset != "" {
+		if start, err = p.evaluateBound(offset, s, "offset"); err != nil {
+			return 0, 0, err
+		}
+		if start < 0 {
+			return 0, 0, fmt.Errorf("offset %d of for is negative", start)
+		}
+	}
+	if limit != "" {
+		if count, err = p.evaluateBound(limit, s, "limit"); err != nil {
+			return 0, 0, err
+		}
+		if count < 0 {
+			return 0, 0, fmt.Errorf("limit %d of for is negative", count)
+		}
+	}
+	return start, count, nil
+}
+
+// loopMeta returns the metadata of iteration i of a loop with n
+// iterations, which a `for` tag with a meta-as attribute binds. The
+// index starts at 0, and odd and even refer to it.
+func loopMeta(i, n int) map[string]any {
+	return map[string]any{
+		"first": i == 0,
+		"last":  i == n-1,
+		"index": float64(i),
+		"odd":   i%2 == 1,
+		"even":  i%2 == 0,
+	}
+}
+
+// evaluateForEntries evaluates a `for` tag over the entries of a map,
+// in the order of the keys:
+//
+// <for each="user.settings" key-as="k" value-as="v">
+// ...
+// </for>
+func (p *Program) evaluateForEntries(currentModule string, n *html.Node, s map[string]any, v any, keyAs, valueAs string) ([]*html.Node, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, fmt.Errorf("can not iterate over the entries of '%s' of type %s %v", stringify(v), typeof(v), reflect.TypeOf(v))
+	}
+	if rv.Len() == 0 {
+		return p.evaluateForBody(currentModule, n, s, true)
+	}
+	keys := rv.MapKeys()
+	slices.SortFunc(keys, func(a, b reflect.Value) int {
+		return strings.Compare(a.String(), b.String())
+	})
+
+	// Clone the symbol table to allow for mutation.
+	s = maps.Clone(s)
+
+	var results []*html.Node
+	for _, key := range keys {
+		if keyAs != "" {
+			s[keyAs] = key.String()
+		}
+		if valueAs != "" {
+			s[valueAs] = rv.MapIndex(key).Interface()
+		}
+		ns, err := p.evaluateForBody(currentModule, n, s, false)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, ns...)
+	}
+
+	return results, nil
+}
+
+// maxRangeIterations is the maximum number of iterations of a `range`
+// tag, whose bounds can come from the data.
+const maxRangeIterations = 100_000
+
+// evaluateRange evaluates a `range` tag, which iterates over the
+// integers from `from` to `to`, both inclusive. The bounds are integer
+// literals or paths:
+//
+// <range from="1" to="page.count" as="n">
+// ...
+// </range>
+func (p *Program) evaluateRange(currentModule string, n *html.Node, s map[string]any) ([]*html.Node, error) {
+	var from, to int
+	var as string
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "from", "to":
+			bound, err := p.evaluateBound(attr.Val, s, "bound of range")
+			if err != nil {
+				return nil, err
+			}
+			if attr.Key == "from" {
+				from = bound
+			} else {
+				to = bound
+			}
+		case "as":
+			as = attr.Val
+		}
 	}
 
 	// Clone the symbol table to allow for mutation.
@@ -510,12 +1434,22 @@ func (p *Program) evaluateFor(currentModule string, n *html.Node, s map[string]a
 		s = maps.Clone(s)
 	}
 
+	// The number of iterations is computed in uint64, where to - from
+	// can not overflow, so that a range up to math.MaxInt terminates.
+	count := 0
+	if to >= from {
+		n := uint64(to) - uint64(from)
+		if n >= maxRangeIterations {
+			return nil, fmt.Errorf("range from %d to %d has more than %d iterations", from, to, maxRangeIterations)
+		}
+		count = int(n) + 1
+	}
+
 	var results []*html.Node
-	for i := 0; i < rv.Len(); i++ {
-		item := rv.Index(i).Interface()
-		// Mutation is thread-safe here since we have cloned the symbol table.
+	for k := 0; k < count; k++ {
+		i := from + k
 		if as != "" {
-			s[as] = item
+			s[as] = float64(i)
 		}
 		for c := range n.ChildNodes() {
 			ns, err := p.evaluateNode(currentModule, c, s)
@@ -525,7 +1459,59 @@ func (p *Program) evaluateFor(currentModule string, n *html.Node, s map[string]a
 			results = append(results, ns...)
 		}
 	}
+	return results, nil
+}
+
+// evaluateBound evaluates a bound of a `range` tag, or the limit or
+// offset of a `for` tag, which the error calls name.
+func (p *Program) evaluateBound(bound string, s map[string]any, name string) (int, error) {
+	if i, err := strconv.Atoi(bound); err == nil {
+		return i, nil
+	}
+	v, err := p.evaluatePath(bound, s)
+	if err != nil {
+		return 0, err
+	}
+	f, ok := numberValue(v)
+	if !ok || f != math.Trunc(f) || math.Abs(f) > math.MaxInt32 {
+		return 0, fmt.Errorf("can not use '%v' of type %T as %s, expected an integer", v, v, name)
+	}
+	return int(f), nil
+}
+
+// numberValue returns the value of a number of any Go numeric type, such
+// as the int of a table or of a row of a database.
+func numberValue(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch {
+	case rv.CanInt():
+		return float64(rv.Int()), true
+	case rv.CanUint():
+		return float64(rv.Uint()), true
+	case rv.CanFloat():
+		return rv.Float(), true
+	}
+	return 0, false
+}
 
+// evaluateForBody evaluates the children of a `for` tag for a single
+// iteration, or the children of its `empty` tag if empty is true.
+func (p *Program) evaluateForBody(currentModule string, n *html.Node, s map[string]any, empty bool) ([]*html.Node, error) {
+	var results []*html.Node
+	for c := range n.ChildNodes() {
+		isEmpty := c.Type == html.ElementNode && c.Data == "empty"
+		if isEmpty != empty {
+			continue
+		}
+		if isEmpty {
+			return p.evaluateForBody(currentModule, c, s, false)
+		}
+		ns, err := p.evaluateNode(currentModule, c, s)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, ns...)
+	}
 	return results, nil
 }
 
@@ -540,33 +1526,81 @@ func (p *Program) evaluateNative(currentModule string, n *html.Node, s map[strin
 	for _, attr := range n.Attr {
 		switch {
 		case attr.Key == "inner-text":
-			textNode, err := handleInnerText(s, attr.Val)
+			textNode, err := p.handleInnerText(s, attr.Val)
 			if err != nil {
 				return nil, err
 			}
 			result.AppendChild(textNode)
+		case attr.Key == "inner-html":
+			node, err := p.evaluateInnerHTML(s, attr.Val)
+			if err != nil {
+				return nil, err
+			}
+			result.AppendChild(node)
+		case strings.HasPrefix(attr.Key, "class-"):
+			v, err := p.evaluatePath(attr.Val, s)
+			if err != nil {
+				return nil, err
+			}
+			on, ok := v.(bool)
+			if !ok {
+				return nil, fmt.Errorf("can not use '%v' of type %T as condition in %s", v, v, attr.Key)
+			}
+			if on {
+				result.Attr = appendAttribute(result.Attr, html.Attribute{
+					Key: "class",
+					Val: strings.TrimPrefix(attr.Key, "class-"),
+				})
+			}
+		case strings.HasPrefix(attr.Key, "data-bind-"):
+			v, err := p.evaluatePath(attr.Val, s)
+			if err != nil {
+				return nil, err
+			}
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("can not serialize value of type %s as %s: %w", typeof(v), attr.Key, err)
+			}
+			result.Attr = appendAttribute(result.Attr, html.Attribute{
+				Key: "data-" + strings.TrimPrefix(attr.Key, "data-bind-"),
+				Val: string(b),
+			})
 		case strings.HasPrefix(attr.Key, "attr-"):
-			v, err := lookup(attr.Val, s)
+			v, err := p.evaluateBinding(attr.Val, s)
 			if err != nil {
 				return nil, err
 			}
-			var str string
-			switch u := v.(type) {
-			case float64:
-				str = fmt.Sprintf("%g", u)
-			case int:
-				str = fmt.Sprintf("%d", u)
-			case string:
-				str = u
-			default:
-				return nil, fmt.Errorf("can not use '%s' of type %s as an attribute", stringify(v), typeof(v))
-			}
-			result.Attr = append(result.Attr, html.Attribute{
-				Key: strings.TrimPrefix(attr.Key, "attr-"),
+			name := strings.TrimPrefix(attr.Key, "attr-")
+			if on, ok := v.(bool); ok && typechecker.IsBooleanAttribute(name) {
+				// A boolean attribute is present or absent.
+				if on {
+					result.Attr = appendAttribute(result.Attr, html.Attribute{Key: name})
+				}
+				continue
+			}
+			str, trusted, err := attributeValue(name, v)
+			if err != nil {
+				return nil, err
+			}
+			if err := escape.CheckAttribute(str); err != nil {
+				return nil, fmt.Errorf("can not use %s as %s: %w", stringify(str), attr.Key, err)
+			}
+			if !trusted && typechecker.IsURLAttribute(name) && p.urlPolicy != nil {
+				if err := p.urlPolicy(name, str); err != nil {
+					return nil, fmt.Errorf("can not use %s as %s: %w", stringify(str), attr.Key, err)
+				}
+			}
+			result.Attr = appendAttribute(result.Attr, html.Attribute{
+				Key: name,
 				Val: str,
 			})
+		case attr.Key == "priority" && n.Data == "img":
+			result.Attr = appendPriority(result.Attr, n)
 		default:
-			result.Attr = append(result.Attr, attr)
+			if _, ok := exampleBinding(n, attr.Key); ok {
+				continue
+			}
+			result.Attr = appendAttribute(result.Attr, attr)
 		}
 	}
 
@@ -584,3 +1618,41 @@ func (p *Program) evaluateNative(currentModule string, n *html.Node, s map[strin
 
 	return []*html.Node{&result}, nil
 }
+
+// appendAttribute appends attr to attrs. An attribute that is set both
+// statically and by an attr- binding, which the type checker only
+// allows for class, is merged by joining the values with a space in
+// the order they appear in the template. The classes of class-
+// bindings are merged the same way.
+func appendAttribute(attrs []html.Attribute, attr html.Attribute) []html.Attribute {
+	for i := range attrs {
+		if attrs[i].Key != attr.Key {
+			continue
+		}
+		switch {
+		case attr.Val == "":
+		case attrs[i].Val == "":
+			attrs[i].Val = attr.Val
+		default:
+			attrs[i].Val += " " + attr.Val
+		}
+		return attrs
+	}
+	return append(attrs, attr)
+}
+
+// isHopComment reports whether n is a comment that starts with #, like
+// <!--# note -->. Such comments are kept in the template for tooling,
+// e.g. as the documentation of a function, but are never rendered.
+func isHopComment(n *html.Node) bool {
+	return n.Type == html.CommentNode && strings.HasPrefix(n.Data, "#")
+}
+
+func getAttribute(n *html.Node, key string) (string, bool) {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
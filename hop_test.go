@@ -2,14 +2,29 @@ package hop_test
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"reflect"
+	"runtime"
+	"slices"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/hoplang/hop-go"
+	"github.com/hoplang/hop-go/hoptype"
 	"github.com/hoplang/hop-go/parser"
 	"golang.org/x/net/html"
 	"golang.org/x/tools/txtar"
@@ -189,7 +204,11 @@ func testTypeError(t *testing.T, filename string) {
 	}
 
 	p := hop.NewCompiler()
-	p.AddModule("main", string(templateData))
+	for _, file := range archive.Files {
+		if strings.HasSuffix(file.Name, ".hop") {
+			p.AddModule(strings.TrimSuffix(file.Name, ".hop"), string(file.Data))
+		}
+	}
 	_, err = p.Compile()
 	if err == nil {
 		t.Fatalf("Expected error to contain '%s' but got nil", expectedError)
@@ -312,6 +331,9 @@ func testFile(t *testing.T, filename string) {
 	if err != nil {
 		t.Errorf("Failed to compile: %s", err)
 	}
+	if problems := c.Verify(); len(problems) > 0 {
+		t.Errorf("Expected the program to verify but got %v", problems)
+	}
 
 	err = c.ExecuteFunction(&buf, "main", "main", d)
 	if err != nil {
@@ -323,3 +345,2403 @@ func testFile(t *testing.T, filename string) {
 			expectedHTML, buf.String())
 	}
 }
+
+func TestCompareSignatures(t *testing.T) {
+	tests := []struct {
+		name     string
+		old      string
+		new      string
+		expected []string
+	}{
+		{
+			name:     "unchanged",
+			old:      `<function name="main" params-as="p"><div inner-text="p.title"></div></function>`,
+			new:      `<function name="main" params-as="p"><div inner-text="p.title"></div></function>`,
+			expected: nil,
+		},
+		{
+			name:     "removed function",
+			old:      `<function name="main"></function><function name="other"></function>`,
+			new:      `<function name="main"></function>`,
+			expected: []string{"main/other: function was removed"},
+		},
+		{
+			name:     "removed field usage",
+			old:      `<function name="main" params-as="p"><div inner-text="p.title"></div><div inner-text="p.body"></div></function>`,
+			new:      `<function name="main" params-as="p"><div inner-text="p.title"></div></function>`,
+			expected: nil,
+		},
+		{
+			name:     "new required field",
+			old:      `<function name="main" params-as="p"><div inner-text="p.title"></div></function>`,
+			new:      `<function name="main" params-as="p"><div inner-text="p.title"></div><div inner-text="p.body"></div></function>`,
+			expected: []string{"main/main: incompatible parameter type: requires new field 'body'"},
+		},
+		{
+			name:     "narrowed type",
+			old:      `<function name="main" params-as="p"><div inner-text="p.title"></div></function>`,
+			new:      `<function name="main" params-as="p"><if true="p.title"></if></function>`,
+			expected: []string{"main/main: incompatible parameter type: field title: accepted number but now requires boolean"},
+		},
+		{
+			name:     "several new required fields",
+			old:      `<function name="main" params-as="p"><div inner-text="p.title"></div></function>`,
+			new:      `<function name="main" params-as="p"><div inner-text="p.title"></div><div inner-text="p.date"></div><div inner-text="p.author"></div><div inner-text="p.body"></div></function>`,
+			expected: []string{"main/main: incompatible parameter type: requires new field 'author'"},
+		},
+		{
+			name:     "changed slots",
+			old:      `<function name="main"><slot name="header"></slot><slot name="footer">-</slot></function>`,
+			new:      `<function name="main"><slot name="footer"></slot><slot name="aside">-</slot></function>`,
+			expected: []string{"main/main: slot header was removed", "main/main: slot footer is required"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compile := func(src string) *hop.Program {
+				c := hop.NewCompiler()
+				c.AddModule("main", src)
+				p, err := c.Compile()
+				if err != nil {
+					t.Fatalf("Failed to compile: %s", err)
+				}
+				return p
+			}
+			var got []string
+			for _, change := range hop.CompareSignatures(compile(tt.old), compile(tt.new)) {
+				got = append(got, change.String())
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v but got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestStrictParams(t *testing.T) {
+	template := `
+<function name="card" params-as="post">
+	<h1 inner-text="post.title"></h1>
+</function>
+<function name="main" params-as="p">
+	<render function="card" params="p.post"></render>
+	<div inner-text="p.post.author.name"></div>
+</function>
+`
+	c := hop.NewCompiler()
+	c.AddModule("main", template)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Expected extra fields to be accepted but got %s", err)
+	}
+	typ, err := program.FunctionType("main", "card")
+	if err != nil {
+		t.Fatalf("Failed to get function type: %s", err)
+	}
+	if typ.String() != "{title: number | string}" {
+		t.Errorf("Expected caller to leave the type of card unchanged but got %s", typ)
+	}
+
+	c.SetStrictParams(true)
+	_, err = c.Compile()
+	if err == nil {
+		t.Fatal("Expected extra fields to be rejected in strict mode")
+	}
+	expected := "function 'card' does not use fields author"
+	if !strings.Contains(err.Error(), expected) {
+		t.Errorf("Expected error to contain '%s' but got %s", expected, err)
+	}
+}
+
+func TestAddFSSourcePath(t *testing.T) {
+	fsys := fstest.MapFS{
+		"templates/ui/button.hop": {Data: []byte(`<function name="button" params-as="b">
+	<if true="b"></if>
+	<span inner-text="b"></span>
+</function>`)},
+	}
+	c := hop.NewCompiler()
+	if err := c.AddFS(fsys); err != nil {
+		t.Fatalf("Failed to add file system: %s", err)
+	}
+	_, err := c.Compile()
+	if err == nil {
+		t.Fatal("Expected type error")
+	}
+	expected := "templates/ui/button.hop: line 3"
+	if !strings.HasPrefix(err.Error(), expected) {
+		t.Errorf("Expected error to start with '%s' but got %s", expected, err)
+	}
+
+	c.AddModule("templates/ui/button", `<function name="button"></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	if path, ok := program.SourcePath("templates/ui/button"); ok {
+		t.Errorf("Expected module added directly to have no path but got %s", path)
+	}
+
+	c = hop.NewCompiler()
+	fsys["templates/ui/button.hop"] = &fstest.MapFile{Data: []byte(`<function name="button"></function>`)}
+	if err := c.AddFS(fsys); err != nil {
+		t.Fatalf("Failed to add file system: %s", err)
+	}
+	program, err = c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	if path, _ := program.SourcePath("templates/ui/button"); path != "templates/ui/button.hop" {
+		t.Errorf("Expected path templates/ui/button.hop but got %s", path)
+	}
+}
+
+func TestModuleInfo(t *testing.T) {
+	c := hop.NewCompiler()
+	c.AddModule("main", `<module description="Cards" strict-params="true"></module>
+<function name="card" params-as="post">
+	<h1 inner-text="post.title"></h1>
+</function>
+<function name="main" params-as="p">
+	<render function="card" params="p"></render>
+	<div inner-text="p.body"></div>
+</function>`)
+	_, err := c.Compile()
+	if err == nil || !strings.Contains(err.Error(), "function 'card' does not use fields body") {
+		t.Fatalf("Expected module to enable strict params but got %v", err)
+	}
+
+	c.AddModule("main", `<module description="Cards"></module>
+<function name="main"></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	info, err := program.ModuleInfo("main")
+	if err != nil {
+		t.Fatalf("Failed to get module info: %s", err)
+	}
+	if info.Description != "Cards" || info.StrictParams != nil {
+		t.Errorf("Unexpected module info %+v", info)
+	}
+}
+
+func TestSingleFunctionFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"components/card.hop": {Data: []byte(`<module params-as="post"></module>
+<div class="card" inner-text="post.title"></div>`)},
+		"main.hop": {Data: []byte(`<import function="card" from="components/card"></import>
+<function name="main" params-as="p">
+	<render function="card" params="p"></render>
+</function>`)},
+	}
+	c := hop.NewCompiler()
+	c.SetSingleFunctionFiles(true)
+	if err := c.AddFS(fsys); err != nil {
+		t.Fatalf("Failed to add file system: %s", err)
+	}
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	var buf bytes.Buffer
+	err = program.ExecuteFunction(&buf, "main", "main", map[string]any{"title": "foo"})
+	if err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	if !compareHTML(`<div class="card">foo</div>`, strings.TrimSpace(buf.String())) {
+		t.Errorf("Unexpected output %s", buf.String())
+	}
+
+	c.SetSingleFunctionFiles(false)
+	_, err = c.Compile()
+	if err == nil || !strings.Contains(err.Error(), "only allowed in single-function files") {
+		t.Errorf("Expected params-as to be rejected but got %v", err)
+	}
+}
+
+func TestNestedFunctionsArePrivate(t *testing.T) {
+	c := hop.NewCompiler()
+	c.AddModule("main", `<function name="main">
+	<function name="helper"><p>helper</p></function>
+	<render function="helper"></render>
+</function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	if modules := program.GetModules(); !reflect.DeepEqual(modules["main"], []string{"main"}) {
+		t.Errorf("Expected only main to be listed but got %v", modules["main"])
+	}
+	var buf bytes.Buffer
+	if err := program.ExecuteFunction(&buf, "main", "main:helper", nil); err == nil {
+		t.Errorf("Expected nested function to be private")
+	}
+
+	c.AddModule("main", `<function name="main">
+	<function name="helper"><p>helper</p></function>
+</function>
+<function name="other"><render function="helper"></render></function>`)
+	if _, err := c.Compile(); err == nil {
+		t.Errorf("Expected nested function to be out of scope in other functions")
+	}
+}
+
+func TestURLWarnings(t *testing.T) {
+	c := hop.NewCompiler()
+	c.RegisterFilter("asset", func(path string) hop.URL { return hop.URL("/assets/" + path) })
+	c.RegisterFilter("add", func(a, b float64) float64 { return a + b })
+	c.AddModule("main", `<function name="main" params-as="p">
+	<img attr-src="p.image | asset">
+	<a attr-href="p.page ?? '/'">page</a>
+	<form attr-action="p.id | add(1)"></form>
+</function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	var got []string
+	for _, w := range program.Warnings() {
+		got = append(got, w.String())
+	}
+	expected := []string{"main: line 3, column 5: warning: URL attribute href is bound to untyped data 'p.page ?? '/'' and will be checked by the URL policy at render time"}
+	if !slices.Equal(got, expected) {
+		t.Errorf("Expected only the dynamic string to be warned about but got %q", got)
+	}
+}
+
+func TestURLPolicy(t *testing.T) {
+	template := `<function name="main" params-as="p">
+	<img attr-src="p.image">
+	<a attr-href="p.link" attr-title="p.title">link</a>
+</function>`
+	c := hop.NewCompiler()
+	c.AddModule("main", template)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	warnings := program.Warnings()
+	if len(warnings) != 2 {
+		t.Fatalf("Expected a warning for each URL attribute but got %v", warnings)
+	}
+	expected := "main: line 2, column 7: warning: URL attribute src is bound to untyped data 'p.image'"
+	if !strings.HasPrefix(warnings[0].String(), expected) {
+		t.Errorf("Expected warning to start with '%s' but got '%s'", expected, warnings[0])
+	}
+
+	var buf bytes.Buffer
+	data := map[string]any{
+		"image": "data:image/png;base64,iVBORw0KGgo=",
+		"link":  "/posts/1",
+		"title": "javascript:",
+	}
+	if err := program.ExecuteFunction(&buf, "main", "main", data); err != nil {
+		t.Fatalf("Expected default policy to allow image data URLs but got %s", err)
+	}
+
+	c.SetURLPolicy(func(attribute, url string) error {
+		if !strings.HasPrefix(url, "https://") {
+			return errors.New("only https URLs are allowed")
+		}
+		return nil
+	})
+	program, err = c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	err = program.ExecuteFunction(&buf, "main", "main", data)
+	if err == nil || !strings.Contains(err.Error(), "only https URLs are allowed") {
+		t.Errorf("Expected custom policy to reject the URL but got %v", err)
+	}
+}
+
+func TestTrustedTypes(t *testing.T) {
+	template := `<function name="main" params-as="p">
+	<div inner-text="p.body"></div>
+	<a attr-href="p.link" attr-onclick="p.onclick">link</a>
+</function>`
+	c := hop.NewCompiler()
+	c.AddModule("main", template)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	typ, err := program.FunctionType("main", "main")
+	if err != nil {
+		t.Fatalf("Failed to get function type: %s", err)
+	}
+	expected := "{body: number | string, link: number | string | url, onclick: js | number | string}"
+	if typ.String() != expected {
+		t.Errorf("Expected type %s but got %s", expected, typ)
+	}
+
+	var buf bytes.Buffer
+	err = program.ExecuteFunction(&buf, "main", "main", map[string]any{
+		"body":    hop.HTML("<b>bold</b>"),
+		"link":    hop.URL("javascript:void(0)"),
+		"onclick": hop.JS("track()"),
+	})
+	if err != nil {
+		t.Fatalf("Expected trusted values to be accepted but got %s", err)
+	}
+	expected = `<div>&lt;b&gt;bold&lt;/b&gt;</div><a href="javascript:void(0)" onclick="track()">link</a>`
+	if got := strings.Join(strings.Fields(buf.String()), ""); got != strings.Join(strings.Fields(expected), "") {
+		t.Errorf("Expected %s but got %s", expected, buf.String())
+	}
+
+	buf.Reset()
+	err = program.ExecuteFunction(&buf, "main", "main", map[string]any{
+		"body":    "<b>bold</b>",
+		"link":    "/",
+		"onclick": hop.URL("/"),
+	})
+	if err == nil || !strings.Contains(err.Error(), "can not use trusted url as attribute onclick") {
+		t.Errorf("Expected trusted url to be rejected in onclick but got %v", err)
+	}
+}
+
+func TestStrictAttributes(t *testing.T) {
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{
+			template: `<function name="main" params-as="p"><div innertext="p.title"></div></function>`,
+			expected: "unrecognized attribute 'innertext' in div, did you mean 'inner-text'?",
+		},
+		{
+			template: `<function name="main" params-as="p"><div atr-class="p.class"></div></function>`,
+			expected: "unrecognized attribute 'atr-class' in div, did you mean 'attr-class'?",
+		},
+		{
+			template: `<function name="main" params-as="p"><div attr_class="p.class"></div></function>`,
+			expected: "unrecognized attribute 'attr_class' in div, did you mean 'attr-class'?",
+		},
+		{
+			template: `<function name="card"></function><function name="main" params-as="p"><render function="card" param="p"></render></function>`,
+			expected: "unrecognized attribute 'param' in render, did you mean 'params'?",
+		},
+		{
+			template: `<function name="main" param-as="p"></function>`,
+			expected: "unrecognized attribute 'param-as' in function, did you mean 'params-as'?",
+		},
+	}
+	for _, test := range tests {
+		c := hop.NewCompiler()
+		c.AddModule("main", test.template)
+		if _, err := c.Compile(); err != nil {
+			t.Errorf("Expected %s to compile without strict mode but got %s", test.template, err)
+		}
+		c.SetStrictAttributes(true)
+		_, err := c.Compile()
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Expected error to contain '%s' but got %v", test.expected, err)
+		}
+	}
+
+	c := hop.NewCompiler()
+	c.SetStrictAttributes(true)
+	c.AddModule("main", `<function name="main" params-as="p">
+	<a aria-label="x" data-id="1" http-equiv="x" attr-href="p.link" inner-text="p.title"></a>
+</function>`)
+	if _, err := c.Compile(); err != nil {
+		t.Errorf("Expected ordinary attributes to be accepted in strict mode but got %s", err)
+	}
+}
+
+func TestLintUntranslatedText(t *testing.T) {
+	c := hop.NewCompiler()
+	c.AddModule("main", `<function name="main">
+	<h1><t>Welcome</t></h1>
+	<p>
+		Hello world
+	</p>
+	<span>Acme</span> <span>| 2024 -</span>
+	<script>var greeting = "hi";</script>
+</function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	warnings := program.LintUntranslatedText([]string{"Acme"})
+	var got []string
+	for _, w := range warnings {
+		got = append(got, w.String())
+	}
+	expected := []string{`main: line 4, column 3: warning: text "Hello world" is not translated`}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v but got %v", expected, got)
+	}
+}
+
+func TestTranslator(t *testing.T) {
+	c := hop.NewCompiler()
+	c.AddModule("main", `<function name="main"><h1><t>
+	Welcome
+</t></h1><button><t>Sign up</t></button></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	var buf bytes.Buffer
+	if err := program.ExecuteFunction(&buf, "main", "main", nil); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	if want := "<h1>Welcome</h1><button>Sign up</button>"; buf.String() != want {
+		t.Errorf("Expected %q but got %q", want, buf.String())
+	}
+
+	german := map[string]string{"Welcome": "Willkommen", "Sign up": "Anmelden & los"}
+	buf.Reset()
+	translated := program.WithTranslator(func(text string) string { return german[text] })
+	if err := translated.ExecuteFunction(&buf, "main", "main", nil); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	if want := "<h1>Willkommen</h1><button>Anmelden &amp; los</button>"; buf.String() != want {
+		t.Errorf("Expected %q but got %q", want, buf.String())
+	}
+}
+
+func TestRename(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ui.hop": {Data: []byte(`<function name="button" params-as="b"><span inner-text="b"></span></function>
+<function name="toolbar"><render function="button" params="'x'"></render></function>`)},
+		"page.hop": {Data: []byte(`<import function="button" from="ui"></import>
+<function name="main">
+	<render function="button"
+		params="'ok'"></render>
+	<function name="button"></function>
+	<render function="button"></render>
+</function>`)},
+		"other.hop": {Data: []byte(`<function name="button"></function>`)},
+	}
+	sources, err := hop.Rename(fsys, "ui", "button", "action-button")
+	if err != nil {
+		t.Fatalf("Failed to rename: %s", err)
+	}
+	expected := map[string]string{
+		"ui.hop": `<function name="action-button" params-as="b"><span inner-text="b"></span></function>
+<function name="toolbar"><render function="action-button" params="'x'"></render></function>`,
+		"page.hop": `<import function="action-button" from="ui"></import>
+<function name="main">
+	<render function="button"
+		params="'ok'"></render>
+	<function name="button"></function>
+	<render function="button"></render>
+</function>`,
+	}
+	if !reflect.DeepEqual(sources, expected) {
+		t.Errorf("Expected %v but got %v", expected, sources)
+	}
+
+	_, err = hop.Rename(fsys, "ui", "button", "toolbar")
+	if err == nil || !strings.Contains(err.Error(), "module ui already has a function with name toolbar") {
+		t.Errorf("Expected rename to an existing name to fail but got %v", err)
+	}
+
+	sources, err = hop.RenameModule(fsys, "ui", "components/ui")
+	if err != nil {
+		t.Fatalf("Failed to rename module: %s", err)
+	}
+	if _, ok := sources["components/ui.hop"]; !ok || len(sources) != 2 {
+		t.Errorf("Expected renamed module and importing module but got %v", sources)
+	}
+	if !strings.Contains(sources["page.hop"], `<import function="button" from="components/ui"></import>`) {
+		t.Errorf("Expected import to be updated but got %s", sources["page.hop"])
+	}
+}
+
+func TestExtractFunction(t *testing.T) {
+	c := hop.NewCompiler()
+	c.AddModule("main", `<function name="main" params-as="page">
+	<h1 inner-text="page.title"></h1>
+	<ul>
+		<for each="page.posts" as="post">
+			<li inner-text="post.title"></li>
+		</for>
+	</ul>
+</function>`)
+	extraction, err := c.ExtractFunction("main",
+		parser.Position{Line: 3, Column: 2}, parser.Position{Line: 7, Column: 7}, "post-list")
+	if err != nil {
+		t.Fatalf("Failed to extract function: %s", err)
+	}
+	expected := `<function name="main" params-as="page">
+	<h1 inner-text="page.title"></h1>
+	<render function="post-list" params="page"></render>
+</function>
+
+<function name="post-list" params-as="page">
+	<ul>
+		<for each="page.posts" as="post">
+			<li inner-text="post.title"></li>
+		</for>
+	</ul>
+</function>`
+	if extraction.Source != expected {
+		t.Errorf("Expected source\n%s\nbut got\n%s", expected, extraction.Source)
+	}
+	if got := extraction.Params.String(); got != "{posts: []{title: number | string}}" {
+		t.Errorf("Expected inferred parameter type but got %s", got)
+	}
+
+	_, err = c.ExtractFunction("main",
+		parser.Position{Line: 2, Column: 5}, parser.Position{Line: 3, Column: 6}, "heading")
+	if err == nil || !strings.Contains(err.Error(), "selection does not cover whole nodes") {
+		t.Errorf("Expected partial selection to be rejected but got %v", err)
+	}
+}
+
+func TestSampleData(t *testing.T) {
+	c := hop.NewCompiler()
+	c.AddModule("ui", `<function name="avatar" params-as="user">
+	<img attr-src="user.image" example-src="/jane.png">
+</function>`)
+	c.AddModule("main", `<import function="avatar" from="ui"></import>
+<function name="main" params-as="page">
+	<h1 inner-text="page.title" example="Hello world"></h1>
+	<for each="page.posts" as="post">
+		<render function="avatar" params="post.author"></render>
+		<span inner-text="post.likes" example="12"></span>
+		<span inner-text="post.author.name"></span>
+	</for>
+</function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	data, err := program.SampleData("main", "main")
+	if err != nil {
+		t.Fatalf("Failed to generate sample data: %s", err)
+	}
+	post := map[string]any{
+		"author": map[string]any{"image": "/jane.png", "name": float64(3)},
+		"likes":  float64(12),
+	}
+	expected := map[string]any{
+		"title": "Hello world",
+		"posts": []any{post, post, post},
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("Expected %v but got %v", expected, data)
+	}
+
+	var buf bytes.Buffer
+	if err := program.ExecuteFunction(&buf, "main", "main", data); err != nil {
+		t.Fatalf("Failed to render sample data: %s", err)
+	}
+	if strings.Contains(buf.String(), "example") {
+		t.Errorf("Expected example attributes to be removed but got %s", buf.String())
+	}
+}
+
+func TestInvalidEncoding(t *testing.T) {
+	tests := []struct {
+		data     string
+		expected string
+	}{
+		{
+			data:     "<function name=\"main\">\n\t<p>caf\xe9</p>\n</function>",
+			expected: "ui/menu.hop: line 2, column 8: parse error: invalid UTF-8 at byte offset 30",
+		},
+		{
+			data:     "\x89PNG\r\n\x1a\n\x00\x00",
+			expected: "ui/menu.hop: line 1, column 1: parse error: invalid UTF-8 at byte offset 0",
+		},
+		{
+			data:     "<p>a\x00</p>",
+			expected: "ui/menu.hop: line 1, column 5: parse error: NUL byte at byte offset 4, the file does not look like a text file",
+		},
+	}
+	for _, test := range tests {
+		c := hop.NewCompiler()
+		if err := c.AddFS(fstest.MapFS{"ui/menu.hop": {Data: []byte(test.data)}}); err != nil {
+			t.Fatalf("Failed to add file system: %s", err)
+		}
+		_, err := c.Compile()
+		if err == nil || err.Error() != test.expected {
+			t.Errorf("Expected error '%s' but got %v", test.expected, err)
+		}
+	}
+}
+
+func TestWindowsLineEndings(t *testing.T) {
+	c := hop.NewCompiler()
+	c.SetSingleFunctionFiles(true)
+	c.AddModule("ui/note", "\uFEFF<p>first\r\nsecond</p>")
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	var buf bytes.Buffer
+	if err := program.ExecuteFunction(&buf, "ui/note", "note", nil); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	if got := buf.String(); got != "<p>first\nsecond</p>" {
+		t.Errorf("Expected output without byte order mark and carriage returns but got %q", got)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	c := hop.NewCompiler()
+	c.SetLogger(logger)
+	c.AddModule("main", `<function name="main"><p>hello</p></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	program.SetSlowRenderThreshold(0)
+	if err := program.ExecuteFunction(io.Discard, "main", "main", nil); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	for _, expected := range []string{
+		`level=DEBUG msg="parsed module" module=main`,
+		`level=DEBUG msg="typechecked module" module=main`,
+		`level=DEBUG msg="compiled program" modules=1`,
+		`level=WARN msg="slow render" module=main function=main`,
+	} {
+		if !strings.Contains(logs.String(), expected) {
+			t.Errorf("Expected logs to contain %s but got\n%s", expected, logs.String())
+		}
+	}
+}
+
+// panickingValue panics when it is converted to JSON, which happens when
+// it is reported as an invalid attribute value.
+type panickingValue struct{}
+
+func (panickingValue) MarshalJSON() ([]byte, error) {
+	panic("boom")
+}
+
+func TestExecutePanic(t *testing.T) {
+	c := hop.NewCompiler()
+	c.AddModule("ui", `<function name="badge" params-as="b"><span attr-title="b.title"></span></function>`)
+	c.AddModule("main", `<import function="badge" from="ui"></import>
+<function name="main" params-as="p"><render function="badge" params="p"></render></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	err = program.ExecuteFunction(io.Discard, "main", "main", map[string]any{"title": panickingValue{}})
+	var panicErr *hop.PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("Expected a panic error but got %v", err)
+	}
+	if panicErr.Code != hop.PanicCode || panicErr.Value != "boom" {
+		t.Errorf("Expected code %s and value boom but got %s and %v", hop.PanicCode, panicErr.Code, panicErr.Value)
+	}
+	expected := "HOP_RENDER_PANIC: panic while rendering main/main > ui/badge: boom"
+	if err.Error() != expected {
+		t.Errorf("Expected error '%s' but got '%s'", expected, err)
+	}
+	if len(panicErr.GoStack) == 0 {
+		t.Error("Expected the Go stack trace to be recorded")
+	}
+}
+
+func TestExecuteFunctionWithStats(t *testing.T) {
+	c := hop.NewCompiler()
+	c.AddModule("main", `<function name="item" params-as="item"><li inner-text="item.title"></li></function>
+<function name="main" params-as="p"><ul><for each="p.items" as="item"><render function="item" params="item"></render></for></ul></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	data := map[string]any{"items": []any{
+		map[string]any{"title": "a"},
+		map[string]any{"title": "b"},
+	}}
+	var buf bytes.Buffer
+	var stats hop.ExecStats
+	if err := program.ExecuteFunctionWithStats(&buf, "main", "main", data, &stats); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	if stats.Nodes != 5 || stats.Bytes != int64(buf.Len()) || stats.Lookups != 5 {
+		t.Errorf("Expected 5 nodes, %d bytes and 5 lookups but got %+v", buf.Len(), stats)
+	}
+	if f := stats.Functions["main/item"]; f == nil || f.Calls != 2 {
+		t.Errorf("Expected item to be rendered twice but got %+v", f)
+	}
+	if f := stats.Functions["main/main"]; f == nil || f.Calls != 1 {
+		t.Errorf("Expected main to be rendered once but got %+v", f)
+	}
+}
+
+func TestFeatures(t *testing.T) {
+	source := `<function name="main" params-as="p"><if feature="beta"><b inner-text="p.beta"></b></if><i>stable</i></function>`
+
+	c := hop.NewCompiler()
+	c.AddModule("main", source)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	data := map[string]any{"beta": "new"}
+	for _, tc := range []struct {
+		features hop.Features
+		want     string
+	}{
+		{nil, "<i>stable</i>"},
+		{hop.FeatureSet("other"), "<i>stable</i>"},
+		{hop.FeatureSet("beta"), "<b>new</b><i>stable</i>"},
+	} {
+		var buf bytes.Buffer
+		if err := program.WithFeatures(tc.features).ExecuteFunction(&buf, "main", "main", data); err != nil {
+			t.Fatalf("Failed to execute: %s", err)
+		}
+		if buf.String() != tc.want {
+			t.Errorf("Expected %q but got %q", tc.want, buf.String())
+		}
+	}
+
+	// Disabled branches are removed before typechecking, so the data
+	// they use is not part of the parameter type.
+	c.SetFeatures(hop.FeatureSet())
+	program, err = c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	var buf bytes.Buffer
+	if err := program.ExecuteFunction(&buf, "main", "main", map[string]any{}); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	if buf.String() != "<i>stable</i>" {
+		t.Errorf("Expected the beta branch to be removed but got %q", buf.String())
+	}
+
+	c.SetFeatures(hop.FeatureSet("beta"))
+	program, err = c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	buf.Reset()
+	if err := program.ExecuteFunction(&buf, "main", "main", data); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	if buf.String() != "<b>new</b><i>stable</i>" {
+		t.Errorf("Expected the beta branch to be kept but got %q", buf.String())
+	}
+}
+
+func TestBuildVars(t *testing.T) {
+	c := hop.NewCompiler()
+	c.SetBuildVar("assetBase", "/static/v2")
+	c.SetBuildVar("revision", "a1b2c3")
+	c.AddModule("main", `<function name="main" params-as="p"><link class="a" attr-class="$revision" attr-href="$assetBase"><fragment inner-text="$revision"></fragment><b inner-text="p.title">x</b></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	var buf bytes.Buffer
+	var stats hop.ExecStats
+	if err := program.ExecuteFunctionWithStats(&buf, "main", "main", map[string]any{"title": "t"}, &stats); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	want := `<link class="a a1b2c3" href="/static/v2"/>a1b2c3<b>t</b>`
+	if buf.String() != want {
+		t.Errorf("Expected %q but got %q", want, buf.String())
+	}
+	if stats.Lookups != 1 {
+		t.Errorf("Expected build variables to be substituted at compile time but got %d lookups", stats.Lookups)
+	}
+
+	for _, tc := range []struct {
+		source string
+		want   string
+	}{
+		{`<function name="main"><if true="$revision"></if></function>`, "build variable 'revision' can only be used in inner-text and attr- bindings"},
+		{`<function name="main"><b attr-title="$missing"></b></function>`, "undefined build variable 'missing'"},
+	} {
+		c.AddModule("main", tc.source)
+		_, err := c.Compile()
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Expected error containing %q but got %v", tc.want, err)
+		}
+	}
+}
+
+func TestTarget(t *testing.T) {
+	source := `<function name="main"><target only="email|amp"><p>email</p></target><target only="web"><p>web</p></target><i>all</i></function>`
+	for _, tc := range []struct {
+		target string
+		want   string
+	}{
+		{"", "<i>all</i>"},
+		{"web", "<p>web</p><i>all</i>"},
+		{"email", "<p>email</p><i>all</i>"},
+		{"amp", "<p>email</p><i>all</i>"},
+	} {
+		c := hop.NewCompiler()
+		c.SetTarget(tc.target)
+		c.AddModule("main", source)
+		program, err := c.Compile()
+		if err != nil {
+			t.Fatalf("Failed to compile: %s", err)
+		}
+		var buf bytes.Buffer
+		if err := program.ExecuteFunction(&buf, "main", "main", nil); err != nil {
+			t.Fatalf("Failed to execute: %s", err)
+		}
+		if buf.String() != tc.want {
+			t.Errorf("Expected %q for target %q but got %q", tc.want, tc.target, buf.String())
+		}
+	}
+}
+
+// countingLoader is a Loader backed by a map that counts loads.
+type countingLoader struct {
+	sources map[string]string
+	loads   int
+}
+
+func (l *countingLoader) List(ctx context.Context) ([]hop.ModuleVersion, error) {
+	var versions []hop.ModuleVersion
+	for name, source := range l.sources {
+		versions = append(versions, hop.ModuleVersion{Name: name, Hash: fmt.Sprint(len(source)), Path: "db://" + name})
+	}
+	return versions, nil
+}
+
+func (l *countingLoader) Load(ctx context.Context, moduleName string) (string, error) {
+	l.loads++
+	return l.sources[moduleName], nil
+}
+
+func TestSync(t *testing.T) {
+	loader := &countingLoader{sources: map[string]string{
+		"main":  `<import function="card" from="cards"></import><function name="main"><render function="card"></render></function>`,
+		"cards": `<function name="card"><p>card</p></function>`,
+	}}
+	c := hop.NewCompiler()
+	changed, err := c.Sync(context.Background(), loader)
+	if err != nil {
+		t.Fatalf("Failed to sync: %s", err)
+	}
+	if !slices.Equal(changed, []string{"cards", "main"}) || loader.loads != 2 {
+		t.Errorf("Expected both modules to be loaded but got %v after %d loads", changed, loader.loads)
+	}
+	if _, err := c.Compile(); err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+
+	changed, err = c.Sync(context.Background(), loader)
+	if err != nil || len(changed) != 0 || loader.loads != 2 {
+		t.Errorf("Expected no changes but got %v, %v after %d loads", changed, err, loader.loads)
+	}
+
+	loader.sources["cards"] = `<function name="card"><p>card!</p></function>`
+	changed, _ = c.Sync(context.Background(), loader)
+	if !slices.Equal(changed, []string{"cards"}) || loader.loads != 3 {
+		t.Errorf("Expected cards to be reloaded but got %v after %d loads", changed, loader.loads)
+	}
+
+	delete(loader.sources, "cards")
+	changed, _ = c.Sync(context.Background(), loader)
+	if !slices.Equal(changed, []string{"cards"}) {
+		t.Errorf("Expected cards to be removed but got %v", changed)
+	}
+	_, err = c.Compile()
+	if err == nil || !strings.Contains(err.Error(), "undefined module 'cards'") {
+		t.Errorf("Expected an error for the removed module but got %v", err)
+	}
+}
+
+func TestFSLoader(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.hop": {Data: []byte(`<function name="main"><p>hello</p></function>`)},
+	}
+	c := hop.NewCompiler()
+	changed, err := c.Sync(context.Background(), hop.FSLoader(fsys))
+	if err != nil || !slices.Equal(changed, []string{"main"}) {
+		t.Fatalf("Expected main to be loaded but got %v, %v", changed, err)
+	}
+	changed, err = c.Sync(context.Background(), hop.FSLoader(fsys))
+	if err != nil || len(changed) != 0 {
+		t.Errorf("Expected no changes but got %v, %v", changed, err)
+	}
+}
+
+func TestProgramSet(t *testing.T) {
+	compiles := 0
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	newCompiler := func() *hop.Compiler {
+		compiles++
+		c := hop.NewCompiler()
+		c.SetLogger(logger)
+		c.AddModule("main", `<import function="logo" from="brand"></import><function name="main"><render function="logo"></render></function>`)
+		c.AddModule("brand", `<function name="logo"><b>base</b></function>`)
+		return c
+	}
+	sources := func(tenant string) (map[string]string, error) {
+		switch tenant {
+		case "acme":
+			return map[string]string{"brand": `<function name="logo"><b>acme</b></function>`}, nil
+		case "broken":
+			return map[string]string{"brand": `<function name="logo"><b>`}, nil
+		}
+		return nil, nil
+	}
+	set := hop.NewProgramSet(newCompiler, sources, 2)
+
+	render := func(tenant string) string {
+		t.Helper()
+		program, err := set.Program(tenant)
+		if err != nil {
+			t.Fatalf("Failed to compile program of %s: %s", tenant, err)
+		}
+		var buf bytes.Buffer
+		if err := program.ExecuteFunction(&buf, "main", "main", nil); err != nil {
+			t.Fatalf("Failed to execute: %s", err)
+		}
+		return buf.String()
+	}
+	if got := render("acme"); got != "<b>acme</b>" {
+		t.Errorf("Expected the tenant module to shadow the base module but got %q", got)
+	}
+	if got := render("other"); got != "<b>base</b>" {
+		t.Errorf("Expected the base module but got %q", got)
+	}
+	render("acme")
+	if compiles != 2 {
+		t.Errorf("Expected cached programs to be reused but compiled %d times", compiles)
+	}
+
+	// other is the least recently used program and is evicted.
+	render("third")
+	render("acme")
+	render("other")
+	if compiles != 4 || set.Len() != 2 {
+		t.Errorf("Expected other to be evicted but compiled %d times with %d cached", compiles, set.Len())
+	}
+	for _, expected := range []string{
+		`level=DEBUG msg="compiled tenant program" tenant=acme`,
+		`level=DEBUG msg="program cache hit" tenant=acme`,
+		`level=DEBUG msg="evicted program" tenant=other`,
+	} {
+		if !strings.Contains(logs.String(), expected) {
+			t.Errorf("Expected logs to contain %s but got\n%s", expected, logs.String())
+		}
+	}
+
+	if _, err := set.Program("broken"); err == nil {
+		t.Errorf("Expected an error for a broken tenant module")
+	}
+	if set.Len() != 2 {
+		t.Errorf("Expected failed compilations not to be cached but got %d cached", set.Len())
+	}
+
+	set.Invalidate("other")
+	render("other")
+	if compiles != 6 {
+		t.Errorf("Expected other to be compiled again after invalidation but compiled %d times", compiles)
+	}
+}
+
+func TestLayers(t *testing.T) {
+	core := fstest.MapFS{
+		"buttons.hop": {Data: []byte(`<function name="primary"><b>core primary</b></function><function name="secondary"><i>core secondary</i></function>`)},
+		"main.hop":    {Data: []byte(`<import function="primary" from="buttons"></import><import function="secondary" from="buttons"></import><function name="main"><render function="primary"></render><render function="secondary"></render></function>`)},
+	}
+	plugin := fstest.MapFS{
+		"buttons.hop": {Data: []byte(`<function name="secondary"><i>plugin secondary</i></function>`)},
+	}
+	theme := fstest.MapFS{
+		"buttons.hop": {Data: []byte(`<import function="icon" from="icons"></import><function name="primary"><b>theme primary</b><render function="icon"></render></function>`)},
+		"icons.hop":   {Data: []byte(`<function name="icon"><svg></svg></function>`)},
+	}
+	c := hop.NewCompiler()
+	for _, layer := range []struct {
+		name string
+		fsys fs.FS
+	}{{"core", core}, {"plugin", plugin}, {"theme", theme}} {
+		if err := c.AddLayer(layer.name, layer.fsys); err != nil {
+			t.Fatalf("Failed to add layer: %s", err)
+		}
+	}
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	var buf bytes.Buffer
+	if err := program.ExecuteFunction(&buf, "main", "main", nil); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	want := "<b>theme primary</b><svg></svg><i>plugin secondary</i>"
+	if buf.String() != want {
+		t.Errorf("Expected %q but got %q", want, buf.String())
+	}
+	var got []string
+	for _, o := range program.Overrides() {
+		got = append(got, o.String())
+	}
+	wantOverrides := []string{
+		"buttons/primary: layer theme overrides layer core",
+		"buttons/secondary: layer plugin overrides layer core",
+	}
+	if !slices.Equal(got, wantOverrides) {
+		t.Errorf("Expected overrides %q but got %q", wantOverrides, got)
+	}
+
+	c.AddLayer("broken", fstest.MapFS{
+		"buttons.hop": {Data: []byte(`<module description="x"></module>`)},
+	})
+	if _, err := c.Compile(); err == nil || !strings.Contains(err.Error(), "buttons.hop") {
+		t.Errorf("Expected an error for metadata in a layer but got %v", err)
+	}
+}
+
+func TestLayerExports(t *testing.T) {
+	c := hop.NewCompiler()
+	c.AddModule("icons", `<function name="icon"><svg></svg></function>`)
+	c.AddModule("ui", `<function name="button"><button></button></function>`)
+	c.AddModule("main", `<import function="icon" from="ui"></import><function name="main"><render function="icon"></render></function>`)
+	err := c.AddLayer("theme", fstest.MapFS{
+		"ui.hop": {Data: []byte(`<export function="icon" from="icons"></export>`)},
+	})
+	if err != nil {
+		t.Fatalf("Failed to add layer: %s", err)
+	}
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	var buf bytes.Buffer
+	if err := program.ExecuteFunction(&buf, "main", "main", nil); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	if buf.String() != "<svg></svg>" {
+		t.Errorf("Expected the exported icon but got %q", buf.String())
+	}
+
+	c.AddLayer("broken", fstest.MapFS{
+		"ui.hop": {Data: []byte(`<p>footer</p>`)},
+	})
+	_, err = c.Compile()
+	if err == nil || !strings.Contains(err.Error(), "a layer can only add functions, imports and exports") {
+		t.Errorf("Expected an error for markup in a layer but got %v", err)
+	}
+}
+
+func TestSyncLayers(t *testing.T) {
+	loader := &countingLoader{sources: map[string]string{
+		"main":  `<import function="card" from="cards"></import><function name="main"><render function="card"></render></function>`,
+		"cards": `<function name="card"><p>card</p></function>`,
+	}}
+	c := hop.NewCompiler()
+	if _, err := c.Sync(context.Background(), loader); err != nil {
+		t.Fatalf("Failed to sync: %s", err)
+	}
+	err := c.AddLayer("theme", fstest.MapFS{
+		"cards.hop": {Data: []byte(`<function name="card"><p>themed card</p></function>`)},
+	})
+	if err != nil {
+		t.Fatalf("Failed to add layer: %s", err)
+	}
+	render := func() string {
+		t.Helper()
+		program, err := c.Compile()
+		if err != nil {
+			t.Fatalf("Failed to compile: %s", err)
+		}
+		var buf bytes.Buffer
+		if err := program.ExecuteFunction(&buf, "main", "main", nil); err != nil {
+			t.Fatalf("Failed to execute: %s", err)
+		}
+		return buf.String()
+	}
+	if got := render(); got != "<p>themed card</p>" {
+		t.Errorf("Expected the themed card but got %q", got)
+	}
+
+	// A changed base module keeps the layers on top of it.
+	loader.sources["cards"] = `<function name="card"><p>new card</p></function><function name="badge"><i></i></function>`
+	if changed, err := c.Sync(context.Background(), loader); err != nil || !slices.Equal(changed, []string{"cards"}) {
+		t.Fatalf("Expected cards to be reloaded but got %v, %v", changed, err)
+	}
+	if got := render(); got != "<p>themed card</p>" {
+		t.Errorf("Expected the themed card after a sync but got %q", got)
+	}
+
+	// A removed module takes its layers with it.
+	delete(loader.sources, "cards")
+	if _, err := c.Sync(context.Background(), loader); err != nil {
+		t.Fatalf("Failed to sync: %s", err)
+	}
+	loader.sources["cards"] = `<function name="card"><p>card</p></function>`
+	if _, err := c.Sync(context.Background(), loader); err != nil {
+		t.Fatalf("Failed to sync: %s", err)
+	}
+	if got := render(); got != "<p>card</p>" {
+		t.Errorf("Expected the base card but got %q", got)
+	}
+}
+
+func TestSwapper(t *testing.T) {
+	compile := func(text string) *hop.Program {
+		t.Helper()
+		c := hop.NewCompiler()
+		c.AddModule("main", `<function name="main"><p>`+text+`</p></function>`)
+		program, err := c.Compile()
+		if err != nil {
+			t.Fatalf("Failed to compile: %s", err)
+		}
+		return program
+	}
+	old := compile("old")
+	swapper := hop.NewSwapper(old)
+
+	p, release := swapper.Acquire()
+	stored := make(chan struct{})
+	go func() {
+		swapper.Store(compile("new"))
+		close(stored)
+	}()
+
+	// Renders started after Store use the new program while the
+	// acquired one is still in use.
+	for swapper.Load() == old {
+		runtime.Gosched()
+	}
+	var buf bytes.Buffer
+	if err := swapper.ExecuteFunction(&buf, "main", "main", nil); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	if buf.String() != "<p>new</p>" {
+		t.Errorf("Expected the new program but got %q", buf.String())
+	}
+	select {
+	case <-stored:
+		t.Fatalf("Expected Store to wait for the acquired program")
+	default:
+	}
+
+	buf.Reset()
+	if err := p.ExecuteFunction(&buf, "main", "main", nil); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	if buf.String() != "<p>old</p>" {
+		t.Errorf("Expected the in-flight render to use the old program but got %q", buf.String())
+	}
+	release()
+	release()
+	<-stored
+}
+
+func TestPrint(t *testing.T) {
+	c := hop.NewCompiler()
+	c.SetTarget(hop.TargetPrint)
+	c.AddModule("invoices", `<function name="invoice" params-as="invoice"><h1 inner-text="invoice.number"></h1><target only="web"><button>Pay</button></target></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	converter := hop.ConverterFunc(func(ctx context.Context, w io.Writer, html io.Reader, opts hop.PrintOptions) error {
+		b, err := io.ReadAll(html)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%%PDF %s %s", opts.PageSize, b)
+		return err
+	})
+	var buf bytes.Buffer
+	err = program.Print(context.Background(), &buf, converter, "invoices", "invoice", map[string]any{"number": "INV-1"}, hop.PrintOptions{PageSize: "A4"})
+	if err != nil {
+		t.Fatalf("Failed to print: %s", err)
+	}
+	if want := "%PDF A4 <h1>INV-1</h1>"; buf.String() != want {
+		t.Errorf("Expected %q but got %q", want, buf.String())
+	}
+}
+
+func TestImageResolver(t *testing.T) {
+	c := hop.NewCompiler()
+	c.SetImageResolver(func(src string, width int) string {
+		return fmt.Sprintf("https://img.example.com/%d%s", width, src)
+	})
+	c.AddModule("main", `<function name="main" params-as="image"><img-set from="image"></img-set></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	var buf bytes.Buffer
+	image := map[string]any{"src": "/a.png", "widths": []any{100.0, 200.0}, "alt": ""}
+	if err := program.ExecuteFunction(&buf, "main", "main", image); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	want := `<img src="https://img.example.com/200/a.png" srcset="https://img.example.com/100/a.png 100w, https://img.example.com/200/a.png 200w" alt=""/>`
+	if buf.String() != want {
+		t.Errorf("Expected %q but got %q", want, buf.String())
+	}
+
+	image["widths"] = []any{100.5}
+	if err := program.ExecuteFunction(&buf, "main", "main", image); err == nil {
+		t.Errorf("Expected an error for a fractional width")
+	}
+
+	type goImage struct {
+		Src    string    `json:"src"`
+		Widths []float64 `json:"widths"`
+		Alt    string    `json:"alt"`
+	}
+	for _, image := range []any{
+		goImage{Src: "/a.png", Widths: []float64{100, 200}},
+		map[string]any{"src": "/a.png", "widths": []int{100, 200}, "alt": ""},
+	} {
+		buf.Reset()
+		if err := program.ExecuteFunction(&buf, "main", "main", image); err != nil {
+			t.Fatalf("Failed to execute with %#v: %s", image, err)
+		}
+		if buf.String() != want {
+			t.Errorf("Expected %q for %#v but got %q", want, image, buf.String())
+		}
+	}
+}
+
+func TestIcons(t *testing.T) {
+	c := hop.NewCompiler()
+	err := c.AddIcons(fstest.MapFS{
+		"check.svg":       {Data: []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="24" height="24" viewBox="0 0 24 24" fill="none"><!-- check --><path d="M5 12l5 5L20 7"/></svg>`)},
+		"arrows/left.svg": {Data: []byte(`<svg viewBox="0 0 16 16"><path d="M10 4 6 8l4 4"/></svg>`)},
+		"arrows-left.svg": {Data: []byte(`<svg viewBox="0 0 16 16"><path d="M12 4 8 8l4 4"/></svg>`)},
+		"README.md":       {Data: []byte(`not an icon`)},
+	})
+	if err != nil {
+		t.Fatalf("Failed to add icons: %s", err)
+	}
+	c.AddModule("main", `<function name="main" params-as="items"><for each="items" as="item"><icon name="check" attr-class="item"></icon></for><icon name="arrows/left"></icon><icon name="arrows-left"></icon></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	for range 2 {
+		var buf bytes.Buffer
+		if err := program.ExecuteFunction(&buf, "main", "main", []any{"a", "b"}); err != nil {
+			t.Fatalf("Failed to execute: %s", err)
+		}
+		want := `<svg class="a"><symbol id="icon-check" viewBox="0 0 24 24" fill="none"><path d="M5 12l5 5L20 7"></path></symbol><use href="#icon-check"></use></svg>` +
+			`<svg class="b"><use href="#icon-check"></use></svg>` +
+			`<svg><symbol id="icon-arrows_-left" viewBox="0 0 16 16"><path d="M10 4 6 8l4 4"></path></symbol><use href="#icon-arrows_-left"></use></svg>` +
+			`<svg><symbol id="icon-arrows-left" viewBox="0 0 16 16"><path d="M12 4 8 8l4 4"></path></symbol><use href="#icon-arrows-left"></use></svg>`
+		if buf.String() != want {
+			t.Errorf("Expected %q but got %q", want, buf.String())
+		}
+	}
+
+	c.AddModule("main", `<function name="main"><icon name="cross"></icon></function>`)
+	_, err = c.Compile()
+	if err == nil || !strings.Contains(err.Error(), "unknown icon 'cross'") {
+		t.Errorf("Expected an unknown icon error but got %v", err)
+	}
+}
+
+func TestRegisterFilter(t *testing.T) {
+	c := hop.NewCompiler()
+	c.RegisterFilter("money", func(cents int, currency string) (string, error) {
+		if currency != "EUR" {
+			return "", fmt.Errorf("unsupported currency %s", currency)
+		}
+		return fmt.Sprintf("€%d.%02d", cents/100, cents%100), nil
+	})
+	c.RegisterFilter("join", func(items []string, sep string) string {
+		return strings.Join(items, sep)
+	})
+	c.RegisterFilter("count", func(items []any) int {
+		return len(items)
+	})
+	c.AddModule("main", `<function name="main" params-as="order">`+
+		`<td inner-text="order.total | money('EUR')"></td>`+
+		`<td inner-text="order.tags | join(', ') | uppercase"></td>`+
+		`<td inner-text="order.tags | count"></td>`+
+		`</function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	var buf bytes.Buffer
+	order := map[string]any{"total": 1250.0, "tags": []any{"new", "paid"}}
+	if err := program.ExecuteFunction(&buf, "main", "main", order); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	if want := `<td>€12.50</td><td>NEW, PAID</td><td>2</td>`; buf.String() != want {
+		t.Errorf("Expected %q but got %q", want, buf.String())
+	}
+
+	order["total"] = 12.5
+	if err := program.ExecuteFunction(&buf, "main", "main", order); err == nil || !strings.Contains(err.Error(), "can not use '12.5' as int") {
+		t.Errorf("Expected an error for a fractional amount but got %v", err)
+	}
+
+	for _, tt := range []struct{ module, want string }{
+		{`<td inner-text="order.tags[0]"></td><td inner-text="order.tags | money('EUR')"></td>`, "invalid input of filter money"},
+		{`<td inner-text="order.total | money(1)"></td>`, "invalid argument 1 of filter money"},
+	} {
+		c.AddModule("main", `<function name="main" params-as="order">`+tt.module+`</function>`)
+		if _, err := c.Compile(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Expected %q but got %v", tt.want, err)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected a panic for a filter with an unsupported signature")
+		}
+	}()
+	c.RegisterFilter("invalid", func() string { return "" })
+}
+
+func TestClassNames(t *testing.T) {
+	c := hop.NewCompiler()
+	c.AddModule("buttons", `<function name="button" params-as="b"><button class="rounded  px-2" attr-class="b.color ?? 'bg-gray-100'"></button></function>`)
+	c.AddModule("main", `<function name="main" params-as="p"><div class="px-2 py-2" attr-class="p.state | default('idle')"><span attr-class="p.extra" class-font-bold="p.strong"></span></div></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	want := []string{"bg-gray-100", "font-bold", "idle", "px-2", "py-2", "rounded"}
+	if got := program.ClassNames(); !slices.Equal(got, want) {
+		t.Errorf("Expected %v but got %v", want, got)
+	}
+}
+
+func TestCheckLinks(t *testing.T) {
+	c := hop.NewCompiler()
+	c.AddModule("main", `<function name="main" params-as="p">
+<a href="/">Home</a>
+<a href="/posts/hello?ref=nav#top">Post</a>
+<a href="/posts/hello/comments">Comments</a>
+<a href="https://example.com/missing">External</a>
+<a href="#top">Top</a>
+<img src="/static/logo.png">
+<a attr-href="p.url ?? '/archive'">Archive</a>
+</function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	var got []string
+	for _, w := range program.CheckLinks(hop.Routes("/", "/posts/{slug}", "/static/")) {
+		got = append(got, w.String())
+	}
+	want := []string{
+		"main: line 4, column 10: warning: broken link /posts/hello/comments in href",
+		"main: line 8, column 15: warning: broken link /archive in href",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected %q but got %q", want, got)
+	}
+
+	files := hop.Files(fstest.MapFS{
+		"index.html":         {},
+		"posts/hello.html":   {},
+		"archive/index.html": {},
+		"static/logo.png":    {},
+	})
+	for path, want := range map[string]bool{"/": true, "/posts/hello": true, "/archive/": true, "/static/logo.png": true, "/static": false, "/../x": false} {
+		if got := files(path); got != want {
+			t.Errorf("Expected %s to resolve %v but got %v", path, want, got)
+		}
+	}
+}
+
+func TestETag(t *testing.T) {
+	c := hop.NewCompiler()
+	c.AddModule("main", `<function name="main" params-as="p"><h1 inner-text="p.title"></h1></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	render := func(title string) string {
+		var buf bytes.Buffer
+		etag, err := program.ExecuteFunctionWithETag(&buf, "main", "main", map[string]any{"title": title})
+		if err != nil {
+			t.Fatalf("Failed to execute: %s", err)
+		}
+		if buf.String() != "<h1>"+title+"</h1>" {
+			t.Errorf("Unexpected output %q", buf.String())
+		}
+		return etag
+	}
+	first, second := render("Hello"), render("Hello")
+	if first != second || !strings.HasPrefix(first, `"`) || !strings.HasSuffix(first, `"`) {
+		t.Errorf("Expected equal quoted tags but got %s and %s", first, second)
+	}
+	if other := render("World"); other == first {
+		t.Errorf("Expected different tags for different output")
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("If-None-Match", `"other", W/`+first)
+	if !hop.NotModified(r, first) {
+		t.Errorf("Expected the request to match %s", first)
+	}
+	r.Header.Set("If-None-Match", `"other"`)
+	if hop.NotModified(r, first) {
+		t.Errorf("Expected the request not to match %s", first)
+	}
+
+	cache := hop.NewETagCache(1)
+	cache.Set("a", first)
+	if etag, ok := cache.Get("a"); !ok || etag != first {
+		t.Errorf("Expected %s but got %s, %v", first, etag, ok)
+	}
+	cache.Set("b", second)
+	if _, ok := cache.Get("a"); ok {
+		t.Errorf("Expected a to be evicted")
+	}
+}
+
+func TestAssets(t *testing.T) {
+	c := hop.NewCompiler()
+	c.AddModule("main", `<function name="main" params-as="page">
+<link rel="stylesheet" attr-href="page.css">
+<link rel="icon" href="/favicon.ico">
+<script type="module" src="/app.js"></script>
+<script src="/legacy.js"></script>
+<img-set from="page.hero" priority sizes="100vw"></img-set>
+<img src="/other.png">
+<img src="/logo.png" priority>
+<link rel="stylesheet" attr-href="page.css">
+</function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	var assets hop.Assets
+	page := map[string]any{
+		"css":  "/app.css",
+		"hero": map[string]any{"src": "/hero.jpg", "widths": []any{640.0}, "alt": ""},
+	}
+	var output strings.Builder
+	if err := program.ExecuteFunctionWithAssets(&output, "main", "main", page, &assets); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	want := `</app.css>; rel=preload; as=style, </app.js>; rel=modulepreload, </hero.jpg?w=640>; rel=preload; as=image; imagesrcset="/hero.jpg?w=640 640w"; imagesizes="100vw", </logo.png>; rel=preload; as=image`
+	if got := assets.Header(); got != want {
+		t.Errorf("Expected %q but got %q", want, got)
+	}
+	if got := output.String(); strings.Contains(got, " priority") || strings.Count(got, `fetchpriority="high"`) != 2 {
+		t.Errorf("Expected priority to be rendered as fetchpriority but got %s", got)
+	}
+
+	rec := httptest.NewRecorder()
+	hop.WriteEarlyHints(rec, &assets)
+	if got := rec.Header().Values("Link"); len(got) != 4 {
+		t.Errorf("Expected 4 Link headers but got %q", got)
+	}
+}
+
+// flushCounter is a ResponseWriter that counts flushes.
+type flushCounter struct {
+	*httptest.ResponseRecorder
+	flushes int
+}
+
+func (f *flushCounter) Flush() {
+	f.flushes++
+	f.ResponseRecorder.Flush()
+}
+
+func TestStreamHTTP(t *testing.T) {
+	c := hop.NewCompiler()
+	c.AddModule("main", `<function name="main" params-as="items"><!DOCTYPE html><html><head><title>List</title></head><body><main><for each="items" as="item"><section inner-text="item"></section></for></main></body></html></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	items := []any{"a", "b", "c"}
+	var want bytes.Buffer
+	if err := program.ExecuteFunction(&want, "main", "main", items); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	plain := &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+	if err := program.StreamHTTP(plain, r, "main", "main", items, hop.StreamOptions{MinChunk: 1}); err != nil {
+		t.Fatalf("Failed to stream: %s", err)
+	}
+	if plain.Body.String() != want.String() {
+		t.Errorf("Expected %q but got %q", want.String(), plain.Body.String())
+	}
+	if plain.flushes < 5 {
+		t.Errorf("Expected a flush at each chunk boundary but got %d flushes", plain.flushes)
+	}
+
+	r.Header.Set("Accept-Encoding", "br;q=1.0, gzip;q=0.8")
+	for range 2 {
+		compressed := &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+		if err := program.StreamHTTP(compressed, r, "main", "main", items, hop.StreamOptions{}); err != nil {
+			t.Fatalf("Failed to stream: %s", err)
+		}
+		if got := compressed.Header().Get("Content-Encoding"); got != "gzip" {
+			t.Fatalf("Expected gzip encoding but got %q", got)
+		}
+		if compressed.flushes != 0 {
+			t.Errorf("Expected no flushes below the minimum chunk size but got %d", compressed.flushes)
+		}
+		zr, err := gzip.NewReader(compressed.Body)
+		if err != nil {
+			t.Fatalf("Failed to read gzip: %s", err)
+		}
+		body, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("Failed to read gzip: %s", err)
+		}
+		if string(body) != want.String() {
+			t.Errorf("Expected %q but got %q", want.String(), body)
+		}
+	}
+}
+
+func TestFormField(t *testing.T) {
+	c := hop.NewCompiler()
+	c.AddModule("main", `<function name="main" params-as="form"><field name="email" from="form.email"></field></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	var buf bytes.Buffer
+	form := map[string]any{"email": hop.FormField{Value: "a@b", Error: "Taken"}}
+	if err := program.ExecuteFunction(&buf, "main", "main", form); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	want := `<input name="email" id="email" value="a@b" aria-invalid="true" aria-describedby="email-error"/><p id="email-error" class="field-error" role="alert">Taken</p>`
+	if buf.String() != want {
+		t.Errorf("Expected %q but got %q", want, buf.String())
+	}
+}
+
+func TestHopCommentDoc(t *testing.T) {
+	c := hop.NewCompiler()
+	c.AddModule("buttons", `<!--# A button that submits the surrounding form. -->
+<function name="submit-button"><button><!--# internal note -->Submit</button></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	doc, err := program.FunctionDoc("buttons", "submit-button")
+	if err != nil || doc != "A button that submits the surrounding form." {
+		t.Errorf("Unexpected documentation %q, %v", doc, err)
+	}
+	var buf bytes.Buffer
+	if err := program.ExecuteFunction(&buf, "buttons", "submit-button", nil); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	if want := "<button>Submit</button>"; buf.String() != want {
+		t.Errorf("Expected %q but got %q", want, buf.String())
+	}
+}
+
+func TestOptions(t *testing.T) {
+	c := hop.NewCompiler()
+	c.AddModule("main", `<function name="main" params-as="form"><select name="size"><options from="form.sizes" selected="form.size"></options></select></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	var buf bytes.Buffer
+	form := map[string]any{
+		"sizes": []hop.Option{{Value: "s", Label: "Small"}, {Value: "m", Label: "Medium"}},
+		"size":  "m",
+	}
+	if err := program.ExecuteFunction(&buf, "main", "main", form); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	want := `<select name="size"><option value="s">Small</option><option value="m" selected="">Medium</option></select>`
+	if buf.String() != want {
+		t.Errorf("Expected %q but got %q", want, buf.String())
+	}
+}
+
+func TestRawHTML(t *testing.T) {
+	module := `<function name="main" params-as="post"><article><raw-html value="post.body"></raw-html></article></function>`
+	c := hop.NewCompiler()
+	c.AddModule("main", module)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	var buf bytes.Buffer
+	if err := program.ExecuteFunction(&buf, "main", "main", map[string]any{"body": hop.HTML("<p>Hello</p>")}); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	if want := "<article><p>Hello</p></article>"; buf.String() != want {
+		t.Errorf("Expected %q but got %q", want, buf.String())
+	}
+
+	c = hop.NewCompiler()
+	c.AddModule("main", module)
+	c.SetRawHTMLStrings(true)
+	program, err = c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	buf.Reset()
+	if err := program.ExecuteFunction(&buf, "main", "main", map[string]any{"body": "<p>Hi</p>"}); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	if want := "<article><p>Hi</p></article>"; buf.String() != want {
+		t.Errorf("Expected %q but got %q", want, buf.String())
+	}
+}
+
+func TestInnerHTML(t *testing.T) {
+	c := hop.NewCompiler()
+	c.AddModule("main", `<function name="main" params-as="post"><article class="post" inner-html="post.body"></article></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	var buf bytes.Buffer
+	if err := program.ExecuteFunction(&buf, "main", "main", map[string]any{"body": hop.HTML("<p>Hello</p>")}); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	if want := `<article class="post"><p>Hello</p></article>`; buf.String() != want {
+		t.Errorf("Expected %q but got %q", want, buf.String())
+	}
+	err = program.ExecuteFunction(&buf, "main", "main", map[string]any{"body": "<p>Hello</p>"})
+	if err == nil || !strings.Contains(err.Error(), "of type string as inner html") {
+		t.Errorf("Expected an error for a string but got %v", err)
+	}
+}
+
+func TestTimeFormat(t *testing.T) {
+	c := hop.NewCompiler()
+	c.SetTimeFormat("long", "2 January 2006")
+	c.AddModule("main", `<function name="main" params-as="event"><time from="event.start"></time></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	var buf bytes.Buffer
+	event := map[string]any{"start": time.Date(2024, time.May, 1, 10, 0, 0, 0, time.UTC)}
+	if err := program.ExecuteFunction(&buf, "main", "main", event); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	if want := `<time datetime="2024-05-01T10:00:00Z">1 May 2024</time>`; buf.String() != want {
+		t.Errorf("Expected %q but got %q", want, buf.String())
+	}
+}
+
+func TestIncludeSingleFunctionFile(t *testing.T) {
+	c := hop.NewCompiler()
+	c.SetSingleFunctionFiles(true)
+	c.AddModule("pages/home", `<main><include module="partials/footer"></include></main>`)
+	c.AddModule("partials/footer", `<footer>© Example</footer>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	var buf bytes.Buffer
+	if err := program.ExecuteFunction(&buf, "pages/home", "home", nil); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	if want := "<main><footer>© Example</footer></main>"; buf.String() != want {
+		t.Errorf("Expected %q but got %q", want, buf.String())
+	}
+}
+
+func TestNumberFormat(t *testing.T) {
+	c := hop.NewCompiler()
+	c.SetNumberFormat(hop.NumberFormats["de"])
+	c.AddModule("main", `<function name="main" params-as="order"><money from="order.total"></money></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	var buf bytes.Buffer
+	order := map[string]any{"total": map[string]any{"amount": 1234.5, "currency": "EUR"}}
+	if err := program.ExecuteFunction(&buf, "main", "main", order); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	if want := "<data value=\"1234.50\">1.234,50\u00a0€</data>"; buf.String() != want {
+		t.Errorf("Expected %q but got %q", want, buf.String())
+	}
+}
+
+func TestVariant(t *testing.T) {
+	c := hop.NewCompiler()
+	c.AddModule("main", `<function name="main" params-as="product">
+<variant experiment="checkout-cta"><case name="control">Buy</case><case name="urgent">Buy now for <span inner-text="product.price"></span></case></variant>
+</function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	experiments := program.Experiments()
+	if len(experiments) != 1 || experiments[0].Name != "checkout-cta" || !slices.Equal(experiments[0].Variants, []string{"control", "urgent"}) {
+		t.Fatalf("Unexpected experiments %+v", experiments)
+	}
+	var buf bytes.Buffer
+	assign := func(experiment string, variants []string) string {
+		return variants[len(variants)-1]
+	}
+	err = program.WithAssignment(assign).ExecuteFunction(&buf, "main", "main", map[string]any{"price": "10 EUR"})
+	if err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	if want := "\nBuy now for <span>10 EUR</span>\n"; buf.String() != want {
+		t.Errorf("Expected %q but got %q", want, buf.String())
+	}
+}
+
+func TestRenameAliasedImport(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ui.hop": {Data: []byte(`<function name="button"><button></button></function>`)},
+		"page.hop": {Data: []byte(`<import function="button" from="ui" as="ui-button"></import>
+<function name="main"><render function="ui-button"></render></function>`)},
+	}
+	sources, err := hop.Rename(fsys, "ui", "button", "action-button")
+	if err != nil {
+		t.Fatalf("Failed to rename: %s", err)
+	}
+	want := `<import function="action-button" from="ui" as="ui-button"></import>
+<function name="main"><render function="ui-button"></render></function>`
+	if sources["page.hop"] != want {
+		t.Errorf("Expected %q but got %q", want, sources["page.hop"])
+	}
+}
+
+// renderRefactored writes the sources returned by a refactoring over a
+// copy of fsys, removes the file at removed and renders page/main of
+// the result.
+func renderRefactored(t *testing.T, fsys fstest.MapFS, sources map[string]string, removed string) string {
+	t.Helper()
+	refactored := fstest.MapFS{}
+	for path, file := range fsys {
+		refactored[path] = file
+	}
+	delete(refactored, removed)
+	for path, source := range sources {
+		refactored[path] = &fstest.MapFile{Data: []byte(source)}
+	}
+	c := hop.NewCompiler()
+	if err := c.AddFS(refactored); err != nil {
+		t.Fatalf("Failed to add files: %s", err)
+	}
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile the refactored files: %s", err)
+	}
+	var buf bytes.Buffer
+	if err := program.ExecuteFunction(&buf, "page", "main", nil); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	return buf.String()
+}
+
+func TestRenameWildcardImport(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ui.hop":  {Data: []byte(`<function name="button"><button></button></function><function name="link"><a></a></function>`)},
+		"kit.hop": {Data: []byte(`<export function="button" from="ui"></export>`)},
+		"page.hop": {Data: []byte(`<import from="ui"></import>
+<function name="main"><render function="button"></render><render function="link"></render></function>`)},
+		"shop.hop": {Data: []byte(`<import from="kit"></import>
+<function name="main"><render function="button"></render></function>`)},
+		"blog.hop": {Data: []byte(`<import from="ui"></import>
+<function name="button"><b></b></function>
+<function name="main"><render function="button"></render></function>`)},
+	}
+	sources, err := hop.Rename(fsys, "ui", "button", "action-button")
+	if err != nil {
+		t.Fatalf("Failed to rename: %s", err)
+	}
+	expected := map[string]string{
+		"ui.hop":  `<function name="action-button"><button></button></function><function name="link"><a></a></function>`,
+		"kit.hop": `<export function="action-button" from="ui"></export>`,
+		"page.hop": `<import from="ui"></import>
+<function name="main"><render function="action-button"></render><render function="link"></render></function>`,
+		"shop.hop": `<import from="kit"></import>
+<function name="main"><render function="action-button"></render></function>`,
+	}
+	if !reflect.DeepEqual(sources, expected) {
+		t.Errorf("Expected %v but got %v", expected, sources)
+	}
+	if got := renderRefactored(t, fsys, sources, ""); got != "<button></button><a></a>" {
+		t.Errorf("Unexpected output of the renamed files %q", got)
+	}
+
+	_, err = hop.Rename(fsys, "ui", "link", "main")
+	if err == nil || !strings.Contains(err.Error(), "module blog imports link from ui but already has a function with name main") {
+		t.Errorf("Expected rename to a name of an importing module to fail but got %v", err)
+	}
+}
+
+func TestRenameIncludedModule(t *testing.T) {
+	fsys := fstest.MapFS{
+		"partials/footer.hop": {Data: []byte(`<footer>footer</footer>`)},
+		"page.hop":            {Data: []byte(`<function name="main"><main></main><include module="partials/footer"></include></function>`)},
+	}
+	sources, err := hop.RenameModule(fsys, "partials/footer", "layout/footer")
+	if err != nil {
+		t.Fatalf("Failed to rename module: %s", err)
+	}
+	expected := map[string]string{
+		"layout/footer.hop": `<footer>footer</footer>`,
+		"page.hop":          `<function name="main"><main></main><include module="layout/footer"></include></function>`,
+	}
+	if !reflect.DeepEqual(sources, expected) {
+		t.Errorf("Expected %v but got %v", expected, sources)
+	}
+	if got := renderRefactored(t, fsys, sources, "partials/footer.hop"); got != "<main></main><footer>footer</footer>" {
+		t.Errorf("Unexpected output of the renamed files %q", got)
+	}
+}
+
+func TestRenameLayoutModule(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/base.hop": {Data: []byte(`<html><body><block name="content"></block></body></html>`)},
+		"page.hop":         {Data: []byte(`<function name="main"><extends module="layouts/base"><block name="content"><p>page</p></block></extends></function>`)},
+	}
+	sources, err := hop.RenameModule(fsys, "layouts/base", "layouts/default")
+	if err != nil {
+		t.Fatalf("Failed to rename module: %s", err)
+	}
+	want := `<function name="main"><extends module="layouts/default"><block name="content"><p>page</p></block></extends></function>`
+	if sources["page.hop"] != want {
+		t.Errorf("Expected %q but got %q", want, sources["page.hop"])
+	}
+	if got := renderRefactored(t, fsys, sources, "layouts/base.hop"); !strings.Contains(got, "<p>page</p>") {
+		t.Errorf("Unexpected output of the renamed files %q", got)
+	}
+}
+
+func TestRenameReExport(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ui/buttons.hop": {Data: []byte(`<function name="button"><button></button></function>`)},
+		"ui.hop":         {Data: []byte(`<export function="button" from="ui/buttons"></export>`)},
+		"kit.hop":        {Data: []byte(`<export function="button" from="ui" as="kit-button"></export>`)},
+		"page.hop": {Data: []byte(`<import function="button" from="ui"></import>
+<import function="kit-button" from="kit"></import>
+<function name="main"><render function="button"></render><render function="kit-button"></render></function>`)},
+	}
+	sources, err := hop.Rename(fsys, "ui/buttons", "button", "action-button")
+	if err != nil {
+		t.Fatalf("Failed to rename: %s", err)
+	}
+	expected := map[string]string{
+		"ui/buttons.hop": `<function name="action-button"><button></button></function>`,
+		"ui.hop":         `<export function="action-button" from="ui/buttons"></export>`,
+		"kit.hop":        `<export function="action-button" from="ui" as="kit-button"></export>`,
+		"page.hop": `<import function="action-button" from="ui"></import>
+<import function="kit-button" from="kit"></import>
+<function name="main"><render function="action-button"></render><render function="kit-button"></render></function>`,
+	}
+	if !reflect.DeepEqual(sources, expected) {
+		t.Errorf("Expected %v but got %v", expected, sources)
+	}
+	if got := renderRefactored(t, fsys, sources, ""); got != "<button></button><button></button>" {
+		t.Errorf("Unexpected output of the renamed files %q", got)
+	}
+
+	sources, err = hop.RenameModule(fsys, "ui/buttons", "components/buttons")
+	if err != nil {
+		t.Fatalf("Failed to rename module: %s", err)
+	}
+	if want := `<export function="button" from="components/buttons"></export>`; sources["ui.hop"] != want {
+		t.Errorf("Expected %q but got %q", want, sources["ui.hop"])
+	}
+	if got := renderRefactored(t, fsys, sources, "ui/buttons.hop"); got != "<button></button><button></button>" {
+		t.Errorf("Unexpected output of the renamed files %q", got)
+	}
+}
+
+func TestTemplateStats(t *testing.T) {
+	c := hop.NewCompiler()
+	c.AddModule("main", `<function name="main" params-as="page"><header><h1>Blog</h1></header><ul><for each="page.posts" as="post"><li><a attr-href="post.url" inner-text="post.title"></a></li></for></ul></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	stats, ok := program.Stats()["main/main"]
+	if !ok {
+		t.Fatalf("Expected statistics of main/main")
+	}
+	if stats.Nodes != 7 || stats.Bindings != 2 || stats.Loops != 1 || stats.MaxDepth != 4 {
+		t.Errorf("Unexpected statistics %+v", stats)
+	}
+	if want := len("<header><h1>Blog</h1></header>"); stats.StaticBytes != want {
+		t.Errorf("Expected %d static bytes but got %d", want, stats.StaticBytes)
+	}
+	if ratio := stats.StaticRatio(); ratio <= 0 || ratio >= 1 {
+		t.Errorf("Unexpected static ratio %f", ratio)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	module := `<function name="main"><div><p><em>Deep</em></p></div><render function="item"></render><render function="item"></render></function>
+<function name="item"><span>Item</span></function>`
+
+	c := hop.NewCompiler()
+	c.AddModule("main", module)
+	c.SetBudget(hop.Budget{MaxDepth: 2, MaxRenders: 1})
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	var messages []string
+	for _, w := range program.Warnings() {
+		messages = append(messages, w.Message)
+	}
+	want := []string{
+		"function 'main' has 3 levels of nesting, the budget is 2",
+		"function 'main' has 2 render tags, the budget is 1",
+	}
+	if !slices.Equal(messages, want) {
+		t.Errorf("Expected warnings %q but got %q", want, messages)
+	}
+
+	c.SetBudget(hop.Budget{MaxNodes: 3, Strict: true})
+	_, err = c.Compile()
+	if err == nil || !strings.Contains(err.Error(), "budget error: function 'main' has 6 nodes, the budget is 3") {
+		t.Errorf("Expected a budget error but got %v", err)
+	}
+}
+
+func TestClones(t *testing.T) {
+	c := hop.NewCompiler()
+	c.AddModule("main", `<function name="posts" params-as="page">
+<for each="page.posts" as="post"><div class="card"><h2 inner-text="post.title"></h2><p inner-text="post.summary"></p></div></for>
+</function>
+<function name="authors" params-as="page">
+<for each="page.authors" as="author"><div class="card"><h2 inner-text="author.name"></h2><p inner-text="author.bio"></p></div></for>
+</function>
+<function name="banner"><div class="banner"><h2>Hello</h2><p>World</p></div></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	clones := program.Clones(4)
+	if len(clones) != 1 {
+		t.Fatalf("Expected 1 clone but got %+v", clones)
+	}
+	var functions []string
+	for _, o := range clones[0].Occurrences {
+		functions = append(functions, fmt.Sprintf("%s/%s:%d", o.Module, o.Function, o.Pos.Line))
+	}
+	if want := []string{"main/posts:2", "main/authors:5"}; !slices.Equal(functions, want) || clones[0].Nodes != 4 {
+		t.Errorf("Expected a clone of 4 nodes at %q but got %d nodes at %q", want, clones[0].Nodes, functions)
+	}
+}
+
+func TestTemplateSet(t *testing.T) {
+	c := hop.NewCompiler()
+	c.AddModule("main", `<function name="page" params-as="p"><h1 inner-text="p.title"></h1></function>`)
+	c.AddModule("blog/post", `<function name="card" params-as="p"><p inner-text="p.title"></p></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	set := hop.NewTemplateSet(program, "main")
+	data := map[string]any{"title": "Hello"}
+	for name, expected := range map[string]string{
+		"page":           "<h1>Hello</h1>",
+		"main/page":      "<h1>Hello</h1>",
+		"blog/post/card": "<p>Hello</p>",
+	} {
+		var buf bytes.Buffer
+		if err := set.ExecuteTemplate(&buf, name, data); err != nil {
+			t.Fatalf("Failed to execute %s: %s", name, err)
+		}
+		if buf.String() != expected {
+			t.Errorf("Expected %q for %s but got %q", expected, name, buf.String())
+		}
+	}
+	if tmpl := set.Lookup("card"); tmpl != nil {
+		t.Errorf("Expected no template card in the default module")
+	}
+	if tmpl := set.Lookup("blog/post/card"); tmpl == nil || tmpl.Name() != "blog/post/card" {
+		t.Errorf("Expected to look up blog/post/card but got %v", tmpl)
+	}
+	if err := set.ExecuteTemplate(io.Discard, "missing", data); err == nil || !strings.Contains(err.Error(), "no template with name missing") {
+		t.Errorf("Expected an error for a missing template but got %v", err)
+	}
+	expected := `; defined templates are: "blog/post/card", "page"`
+	if defined := set.DefinedTemplates(); defined != expected {
+		t.Errorf("Expected %q but got %q", expected, defined)
+	}
+}
+
+// The following types are shaped like the code that protoc-gen-go
+// generates, without depending on the protobuf module.
+
+type pbStatus int32
+
+func (s pbStatus) Number() int32 { return int32(s) }
+
+func (s pbStatus) String() string {
+	return map[pbStatus]string{0: "DRAFT", 1: "PUBLISHED"}[s]
+}
+
+type pbTimestamp struct {
+	Seconds int64 `protobuf:"varint,1,opt,name=seconds,proto3" json:"seconds,omitempty"`
+}
+
+func (*pbTimestamp) ProtoReflect() any { return nil }
+
+func (t *pbTimestamp) AsTime() time.Time { return time.Unix(t.Seconds, 0).UTC() }
+
+type pbPost struct {
+	state any
+
+	Title       string       `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`
+	ViewCount   int64        `protobuf:"varint,2,opt,name=view_count,json=viewCount,proto3" json:"view_count,omitempty"`
+	Status      pbStatus     `protobuf:"varint,3,opt,name=status,proto3,enum=blog.Status" json:"status,omitempty"`
+	PublishedAt *pbTimestamp `protobuf:"bytes,4,opt,name=published_at,json=publishedAt,proto3" json:"published_at,omitempty"`
+	UpdatedAt   *pbTimestamp `protobuf:"bytes,5,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	Scores      []int32      `protobuf:"varint,6,rep,packed,name=scores,proto3" json:"scores,omitempty"`
+}
+
+func (*pbPost) ProtoReflect() any { return nil }
+
+func TestProtobufMessages(t *testing.T) {
+	c := hop.NewCompiler()
+	c.SetTimeFormat("date", "2006-01-02")
+	c.AddModule("main", `<function name="main" params-as="post">`+
+		`<h1 inner-text="post.title"></h1>`+
+		`<p inner-text="post.viewCount"></p>`+
+		`<p inner-text="post.view_count"></p>`+
+		`<p inner-text="post.status"></p>`+
+		`<time from="post.publishedAt" format="date"></time>`+
+		`<p inner-text="post.updatedAt ?? 'never'"></p>`+
+		`<for each="post.scores" as="score"><i inner-text="score"></i></for>`+
+		`</function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	post := &pbPost{
+		Title:       "Hello",
+		ViewCount:   42,
+		Status:      1,
+		PublishedAt: &pbTimestamp{Seconds: 1714557600},
+		Scores:      []int32{3, 5},
+	}
+	var buf bytes.Buffer
+	if err := program.ExecuteFunction(&buf, "main", "main", post); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	want := `<h1>Hello</h1><p>42</p><p>42</p><p>PUBLISHED</p><time datetime="2024-05-01T10:00:00Z">2024-05-01</time><p>never</p><i>3</i><i>5</i>`
+	if buf.String() != want {
+		t.Errorf("Expected %q but got %q", want, buf.String())
+	}
+}
+
+func TestTable(t *testing.T) {
+	c := hop.NewCompiler()
+	c.AddModule("main", `<function name="main" params-as="report"><table><for each="report.sales" as="sale" index-as="i"><tr><td inner-text="i"></td><td inner-text="sale.region"></td><td inner-text="sale.total"></td></tr></for></table></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	sales := &hop.Columns{
+		Schema: []hop.Column{{Name: "region", Kind: hoptype.String}, {Name: "total", Kind: hoptype.Number}},
+		Values: [][]any{{"North", "South"}, {1200.5, 980.0}},
+	}
+	var buf bytes.Buffer
+	if err := program.ExecuteFunction(&buf, "main", "main", map[string]any{"sales": sales}); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	want := `<table><tr><td>0</td><td>North</td><td>1200.5</td></tr><tr><td>1</td><td>South</td><td>980</td></tr></table>`
+	if buf.String() != want {
+		t.Errorf("Expected %q but got %q", want, buf.String())
+	}
+
+	functionType, err := program.FunctionType("main", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tableType := hop.TableType(sales)
+	for name := range functionType.Fields["sales"].Elem.Fields {
+		if _, ok := tableType.Elem.Fields[name]; !ok {
+			t.Errorf("Expected the table type %s to have the column %s", tableType, name)
+		}
+	}
+
+	sales.Schema[1].Name = "amount"
+	err = program.ExecuteFunction(&buf, "main", "main", map[string]any{"sales": sales})
+	if err == nil || !strings.Contains(err.Error(), "column not found: total") {
+		t.Errorf("Expected an error for the missing column but got %v", err)
+	}
+}
+
+// rowsDriver is a database/sql driver whose queries return the rows of
+// the rowsDriverData table.
+type rowsDriver struct{}
+
+var rowsDriverData = [][]driver.Value{
+	{int64(1), []byte("Ada"), true},
+	{int64(2), []byte("Grace"), false},
+	{int64(3), []byte("Linus"), true},
+}
+
+func (rowsDriver) Open(name string) (driver.Conn, error) { return rowsConn{}, nil }
+
+type rowsConn struct{}
+
+func (rowsConn) Prepare(query string) (driver.Stmt, error) { return rowsStmt{}, nil }
+func (rowsConn) Close() error                              { return nil }
+func (rowsConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+type rowsStmt struct{}
+
+func (rowsStmt) Close() error  { return nil }
+func (rowsStmt) NumInput() int { return 0 }
+func (rowsStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+func (rowsStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &driverRows{}, nil
+}
+
+type driverRows struct{ i int }
+
+func (r *driverRows) Columns() []string { return []string{"id", "name", "active"} }
+func (r *driverRows) Close() error      { return nil }
+func (r *driverRows) Next(dest []driver.Value) error {
+	if r.i == len(rowsDriverData) {
+		return io.EOF
+	}
+	copy(dest, rowsDriverData[r.i])
+	r.i++
+	return nil
+}
+
+func init() {
+	sql.Register("hoptest-rows", rowsDriver{})
+}
+
+func TestSQLRows(t *testing.T) {
+	db, err := sql.Open("hoptest-rows", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	c := hop.NewCompiler()
+	c.AddModule("main", `<function name="main" params-as="page"><table><tbody><for each="page.users" as="user" meta-as="loop" filter="user.active"><tr><td inner-text="user.id"></td><td inner-text="user.name"></td><if true="loop.last"><td>last</td></if></tr></for></tbody></table></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	query := func() *hop.Rows {
+		sqlRows, err := db.Query("SELECT id, name, active FROM users")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows, err := hop.SQLRows(sqlRows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rows
+	}
+
+	var buf bytes.Buffer
+	users := query()
+	if err := program.ExecuteFunction(&buf, "main", "main", map[string]any{"users": users}); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	want := `<table><tbody><tr><td>1</td><td>Ada</td></tr><tr><td>3</td><td>Linus</td><td>last</td></tr></tbody></table>`
+	if buf.String() != want {
+		t.Errorf("Expected %q but got %q", want, buf.String())
+	}
+	err = program.ExecuteFunction(io.Discard, "main", "main", map[string]any{"users": users})
+	if err == nil || !strings.Contains(err.Error(), "rows can only be iterated once") {
+		t.Errorf("Expected an error for rows that were iterated but got %v", err)
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	if err := program.StreamHTTP(w, r, "main", "main", map[string]any{"users": query()}, hop.StreamOptions{}); err != nil {
+		t.Fatalf("Failed to stream: %s", err)
+	}
+	if w.Body.String() != want {
+		t.Errorf("Expected %q but got %q", want, w.Body.String())
+	}
+
+	c.AddModule("window", `<function name="main" params-as="page"><for each="page.users" as="user" offset="1" limit="1"><p inner-text="user.name"></p></for></function>`)
+	program, err = c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	buf.Reset()
+	if err := program.ExecuteFunction(&buf, "window", "main", map[string]any{"users": query()}); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	if want := "<p>Grace</p>"; buf.String() != want {
+		t.Errorf("Expected %q but got %q", want, buf.String())
+	}
+
+	c.AddModule("assets", `<function name="main" params-as="page"><div><for each="page.users" as="user"><link rel="stylesheet" href="/user.css"><p inner-text="user.name"></p></for></div></function>`)
+	program, err = c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	var assets hop.Assets
+	if err := program.ExecuteFunctionWithAssets(io.Discard, "assets", "main", map[string]any{"users": query()}, &assets); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	if len(assets.List) != 1 || assets.List[0].URL != "/user.css" {
+		t.Errorf("Expected the stylesheet of the rows to be collected but got %+v", assets.List)
+	}
+	var stats hop.ExecStats
+	if err := program.ExecuteFunctionWithStats(io.Discard, "assets", "main", map[string]any{"users": query()}, &stats); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	// The div, and a link, a p and its text for each of the three rows.
+	if stats.Nodes != 10 {
+		t.Errorf("Expected 10 nodes but got %d", stats.Nodes)
+	}
+}
+
+func TestBoundsOfGoTypes(t *testing.T) {
+	c := hop.NewCompiler()
+	c.AddModule("main", `<function name="main" params-as="page"><range from="1" to="page.count" as="n"><for each="page.items" as="item" offset="page.offset" limit="page.limit"><span inner-text="item"></span></for></range></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	items := []any{"a", "b", "c", "d"}
+	for _, page := range []map[string]any{
+		{"count": 2, "offset": int64(1), "limit": uint8(2), "items": items},
+		{"count": int32(2), "offset": float32(1), "limit": 2.0, "items": items},
+	} {
+		var buf bytes.Buffer
+		if err := program.ExecuteFunction(&buf, "main", "main", page); err != nil {
+			t.Fatalf("Failed to execute with %v: %s", page, err)
+		}
+		if want := "<span>b</span><span>c</span><span>b</span><span>c</span>"; buf.String() != want {
+			t.Errorf("Expected %q for %v but got %q", want, page, buf.String())
+		}
+	}
+
+	page := map[string]any{"count": 2, "offset": 0, "limit": 1.5, "items": items}
+	err = program.ExecuteFunction(io.Discard, "main", "main", page)
+	if err == nil || !strings.Contains(err.Error(), "can not use '1.5' of type float64 as limit, expected an integer") {
+		t.Errorf("Expected an error for a fractional limit but got %v", err)
+	}
+}
+
+func TestSortByGoTypes(t *testing.T) {
+	type level int8
+	type product struct {
+		Name  string  `json:"name"`
+		ID    int64   `json:"id"`
+		Stock uint32  `json:"stock"`
+		Price float32 `json:"price"`
+		Level level   `json:"level"`
+	}
+	products := []product{
+		{Name: "c", ID: 3, Stock: 10, Price: 2.5, Level: -1},
+		{Name: "a", ID: 1, Stock: 30, Price: 0.5, Level: 2},
+		{Name: "b", ID: 2, Stock: 20, Price: 1.5, Level: 1},
+	}
+	for key, want := range map[string]string{
+		"id":    "abc",
+		"stock": "cba",
+		"price": "abc",
+		"level": "cba",
+	} {
+		c := hop.NewCompiler()
+		c.AddModule("main", `<function name="main" params-as="shop"><for each="shop.products" as="p" sort-by="p.`+key+`"><span inner-text="p.name"></span></for></function>`)
+		program, err := c.Compile()
+		if err != nil {
+			t.Fatalf("Failed to compile: %s", err)
+		}
+		var buf bytes.Buffer
+		if err := program.ExecuteFunction(&buf, "main", "main", map[string]any{"products": products}); err != nil {
+			t.Fatalf("Failed to execute with sort-by %s: %s", key, err)
+		}
+		got := strings.NewReplacer("<span>", "", "</span>", "").Replace(buf.String())
+		if got != want {
+			t.Errorf("Expected %q with sort-by %s but got %q", want, key, got)
+		}
+	}
+}
+
+func TestMatchLiteralTypes(t *testing.T) {
+	c := hop.NewCompiler()
+	c.AddModule("main", `<function name="main" params-as="post">
+	<h1 inner-text="post.title"></h1>
+	<match on="post.status">
+		<case value="draft"><i>Draft</i></case>
+		<case value="published"><b>Published</b></case>
+	</match>
+	<match on="post.kind">
+		<case value="note"><i>Note</i></case>
+		<default><b>Article</b></default>
+	</match>
+</function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	typ, err := program.FunctionType("main", "main")
+	if err != nil {
+		t.Fatalf("Failed to get function type: %s", err)
+	}
+	expected := `{kind: string, status: "draft" | "published", title: number | string}`
+	if typ.String() != expected {
+		t.Errorf("Expected type %s but got %s", expected, typ)
+	}
+	status := typ.Fields["status"]
+	if status.Kind != hoptype.Union || status.Members[0].Kind != hoptype.Literal || status.Members[0].Value != "draft" {
+		t.Errorf("Expected status to be a union of literals but got %#v", status)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	compile := func() *hop.Program {
+		c := hop.NewCompiler()
+		c.AddModule("ui", `<function name="card" params-as="post"><h1 inner-text="post.title"></h1></function>`)
+		c.AddModule("main", `<import function="card" from="ui"></import>
+<function name="main" params-as="p"><render function="card" params="p"></render></function>`)
+		program, err := c.Compile()
+		if err != nil {
+			t.Fatalf("Failed to compile: %s", err)
+		}
+		if problems := program.Verify(); len(problems) > 0 {
+			t.Fatalf("Expected no problems but got %v", problems)
+		}
+		return program
+	}
+	tests := []struct {
+		name    string
+		corrupt func(p *hop.Program)
+		want    []string
+	}{
+		{
+			name:    "missing module",
+			corrupt: func(p *hop.Program) { hop.RemoveModule(p, "ui") },
+			want: []string{
+				"main: import of card from missing module ui",
+				"main/main: render of card resolves to missing module ui",
+			},
+		},
+		{
+			name:    "missing function type",
+			corrupt: func(p *hop.Program) { hop.RemoveFunctionType(p, "ui", "card") },
+			want: []string{
+				"main/main: render of card resolves to missing function ui/card",
+				"ui/card: function has no type",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program := compile()
+			tt.corrupt(program)
+			var got []string
+			for _, problem := range program.Verify() {
+				got = append(got, problem.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected problems %q but got %q", tt.want, got)
+			}
+		})
+	}
+}
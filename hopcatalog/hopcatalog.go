@@ -0,0 +1,178 @@
+// Package hopcatalog generates a static site that catalogs the exported
+// functions of a hop program.
+//
+// Every function is rendered with the data returned by
+// hop.Program.SampleData, so functions can declare the data they are
+// shown with using example attributes. The documentation of a function
+// is taken from the comment preceding it and the description of its
+// module from the module metadata.
+package hopcatalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/hoplang/hop-go"
+)
+
+// Function is a function as shown in the catalog.
+type Function struct {
+	Module string
+	Name   string
+	Doc    string
+	Type   string
+	Data   string
+	Output string
+	// Error is set if the function could not be rendered.
+	Error string
+}
+
+// Module is a module as shown in the catalog.
+type Module struct {
+	Name        string
+	Description string
+	Functions   []Function
+}
+
+// Path returns the path of the page of the function in the catalog.
+func (f Function) Path() string {
+	return f.Module + "/" + f.Name + ".html"
+}
+
+// Generate returns the pages of the catalog of program, keyed by their
+// path. The site consists of an index.html page listing all modules and
+// a page for each exported function.
+func Generate(program *hop.Program) (map[string][]byte, error) {
+	modules, err := Collect(program)
+	if err != nil {
+		return nil, err
+	}
+	pages := map[string][]byte{}
+	var buf bytes.Buffer
+	if err := indexTemplate.Execute(&buf, modules); err != nil {
+		return nil, err
+	}
+	pages["index.html"] = bytes.Clone(buf.Bytes())
+	for _, module := range modules {
+		for _, function := range module.Functions {
+			buf.Reset()
+			data := struct {
+				Function
+				Root string
+			}{function, strings.Repeat("../", strings.Count(function.Path(), "/"))}
+			if err := functionTemplate.Execute(&buf, data); err != nil {
+				return nil, err
+			}
+			pages[function.Path()] = bytes.Clone(buf.Bytes())
+		}
+	}
+	return pages, nil
+}
+
+// Collect renders every exported function of program with its sample
+// data. Modules and functions are sorted by name.
+func Collect(program *hop.Program) ([]Module, error) {
+	var result []Module
+	functions := program.GetModules()
+	for _, moduleName := range slices.Sorted(maps.Keys(functions)) {
+		info, err := program.ModuleInfo(moduleName)
+		if err != nil {
+			return nil, err
+		}
+		module := Module{Name: moduleName, Description: info.Description}
+		for _, functionName := range functions[moduleName] {
+			function, err := collectFunction(program, moduleName, functionName)
+			if err != nil {
+				return nil, err
+			}
+			module.Functions = append(module.Functions, function)
+		}
+		result = append(result, module)
+	}
+	return result, nil
+}
+
+func collectFunction(program *hop.Program, moduleName string, functionName string) (Function, error) {
+	f := Function{Module: moduleName, Name: functionName}
+	doc, err := program.FunctionDoc(moduleName, functionName)
+	if err != nil {
+		return f, err
+	}
+	f.Doc = doc
+	t, err := program.FunctionType(moduleName, functionName)
+	if err != nil {
+		return f, err
+	}
+	f.Type = t.String()
+	data, err := program.SampleData(moduleName, functionName)
+	if err != nil {
+		f.Error = fmt.Sprintf("can not generate sample data: %s", err)
+		return f, nil
+	}
+	b, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return f, err
+	}
+	f.Data = string(b)
+	var out strings.Builder
+	if err := program.ExecuteFunction(&out, moduleName, functionName, data); err != nil {
+		f.Error = err.Error()
+		return f, nil
+	}
+	f.Output = out.String()
+	return f, nil
+}
+
+const style = `body { font-family: sans-serif; max-width: 960px; margin: 0 auto; padding: 16px; }
+pre { background: #f5f5f5; padding: 8px; overflow: auto; }
+iframe { width: 100%; min-height: 240px; border: 1px solid #ccc; }
+.error { color: #b00; }`
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Catalog</title>
+<style>` + style + `</style>
+</head>
+<body>
+<h1>Catalog</h1>
+{{range .}}<section>
+<h2>{{.Name}}</h2>
+{{with .Description}}<p>{{.}}</p>
+{{end}}<ul>
+{{range .Functions}}<li><a href="{{.Path}}">{{.Name}}</a>{{with .Doc}} &ndash; {{.}}{{end}}</li>
+{{end}}</ul>
+</section>
+{{end}}</body>
+</html>
+`))
+
+var functionTemplate = template.Must(template.New("function").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Module}} / {{.Name}}</title>
+<style>` + style + `</style>
+</head>
+<body>
+<p><a href="{{.Root}}index.html">Catalog</a> / {{.Module}}</p>
+<h1>{{.Name}}</h1>
+{{with .Doc}}<p>{{.}}</p>
+{{end}}<h2>Parameters</h2>
+<pre>{{.Type}}</pre>
+{{if .Error}}<p class="error">{{.Error}}</p>
+{{else}}<h2>Preview</h2>
+<iframe sandbox srcdoc="{{.Output}}"></iframe>
+<h2>Sample data</h2>
+<pre>{{.Data}}</pre>
+<h2>Output</h2>
+<pre>{{.Output}}</pre>
+{{end}}</body>
+</html>
+`))
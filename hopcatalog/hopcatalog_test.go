@@ -0,0 +1,46 @@
+package hopcatalog_test
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hoplang/hop-go"
+	"github.com/hoplang/hop-go/hopcatalog"
+)
+
+func TestGenerate(t *testing.T) {
+	c := hop.NewCompiler()
+	c.AddModule("ui/buttons", `<module description="Buttons and links"></module>
+<!-- A link styled as a button. -->
+<function name="link-button" params-as="link">
+	<a class="button" attr-href="link.url" inner-text="link.label" example="Sign up"></a>
+</function>
+<function name="divider"><hr></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	pages, err := hopcatalog.Generate(program)
+	if err != nil {
+		t.Fatalf("Failed to generate catalog: %s", err)
+	}
+	paths := slices.Sorted(maps.Keys(pages))
+	expected := []string{"index.html", "ui/buttons/divider.html", "ui/buttons/link-button.html"}
+	if !slices.Equal(paths, expected) {
+		t.Fatalf("Expected pages %v but got %v", expected, paths)
+	}
+	index := string(pages["index.html"])
+	for _, s := range []string{"Buttons and links", `<a href="ui/buttons/link-button.html">link-button</a> &ndash; A link styled as a button.`} {
+		if !strings.Contains(index, s) {
+			t.Errorf("Expected index to contain %s but got %s", s, index)
+		}
+	}
+	page := string(pages["ui/buttons/link-button.html"])
+	for _, s := range []string{`href="../../index.html"`, "{label: number | string, url: number | string | url}", "&lt;a class=&#34;button&#34; href=&#34;3&#34;&gt;Sign up&lt;/a&gt;"} {
+		if !strings.Contains(page, s) {
+			t.Errorf("Expected function page to contain %s but got %s", s, page)
+		}
+	}
+}
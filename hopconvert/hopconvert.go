@@ -0,0 +1,424 @@
+// Package hopconvert translates html/template templates, and the
+// templates of simple Vue and React components, into hop modules, to
+// help moving existing projects to hop. See ConvertVue and ConvertJSX
+// for the components:
+//
+//	result, err := hopconvert.Convert("post.html", source, hopconvert.Options{})
+//	if err != nil {
+//		return err
+//	}
+//	for _, issue := range result.Issues {
+//		log.Printf("post.html:%d: %s", issue.Line, issue.Message)
+//	}
+//	os.WriteFile("post.hop", []byte(result.Source), 0o644)
+//
+// The common constructs are converted:
+//
+//   - {{.Field}} becomes an inner-text binding, or an attr- binding when
+//     it is the whole value of an attribute
+//   - {{if}} and {{else}} become `if` tags with true and not conditions
+//   - {{range}} becomes a `for` tag, and its {{else}} an `empty` tag
+//   - {{define}} and {{block}} become functions and {{template}}
+//     becomes a render call
+//
+// Everything else, such as pipelines with functions, {{with}} and
+// variable declarations, is left in a hop comment and listed in the
+// issues of the result. The dot of a template becomes its parameter,
+// named by Options.Param. Field names are kept as they are, so the
+// fields of Go structs need json tags with the same names. Since hop
+// conditions are booleans, conditions on other values must be changed
+// after converting.
+package hopconvert
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template/parse"
+)
+
+// Options configures Convert.
+type Options struct {
+	// Param is the name of the parameter that holds the dot of a
+	// template. It defaults to "data".
+	Param string
+}
+
+// Result is the result of converting a template.
+type Result struct {
+	// Source is the hop module.
+	Source string
+	// Issues are the parts of the template that were not converted,
+	// ordered by line.
+	Issues []Issue
+}
+
+// Issue is a part of a template that could not be converted.
+type Issue struct {
+	// Line is the line of the template that the part starts on.
+	Line int
+	// Source is the text of the part, such as {{len .Items}}.
+	Source  string
+	Message string
+}
+
+// Convert translates the html/template source of the template with the
+// given name, such as "post.html", into a hop module. The template
+// becomes a function named after it without the extension, and each
+// template that it defines becomes a function of its own. An error is
+// returned if the source can not be parsed.
+func Convert(name string, source string, opts Options) (*Result, error) {
+	param := opts.Param
+	if param == "" {
+		param = "data"
+	}
+	t := parse.New(name)
+	t.Mode = parse.ParseComments | parse.SkipFuncCheck
+	trees := map[string]*parse.Tree{}
+	if _, err := t.Parse(source, "", "", trees); err != nil {
+		return nil, err
+	}
+	// The template comes first, followed by the templates it defines
+	// in the order they appear.
+	names := slices.SortedFunc(maps.Keys(trees), func(a, b string) int {
+		switch {
+		case a == name:
+			return -1
+		case b == name:
+			return 1
+		}
+		return int(trees[a].Root.Pos) - int(trees[b].Root.Pos)
+	})
+
+	result := &Result{}
+	var functions []string
+	for _, treeName := range names {
+		tree := trees[treeName]
+		if treeName == name && isBlank(tree.Root) {
+			// The template only defines other templates.
+			continue
+		}
+		function := functionName(treeName)
+		if treeName == name {
+			function = functionName(strings.TrimSuffix(name, path.Ext(name)))
+		}
+		c := &converter{
+			source: source,
+			param:  param,
+			dot:    param,
+			vars:   map[string]string{"$": param},
+			trees:  trees,
+		}
+		c.list(tree.Root)
+		result.Issues = append(result.Issues, c.issues...)
+		params := ""
+		if c.usesParam {
+			params = fmt.Sprintf(` params-as="%s"`, param)
+		}
+		functions = append(functions, fmt.Sprintf("<function name=\"%s\"%s>%s</function>\n", function, params, c.out.String()))
+	}
+	slices.SortStableFunc(result.Issues, func(a, b Issue) int {
+		return a.Line - b.Line
+	})
+	result.Source = strings.Join(functions, "\n")
+	return result, nil
+}
+
+// functionName returns the name of the function for a template, which
+// may only contain letters, digits, dashes and underscores.
+func functionName(name string) string {
+	return strings.Trim(invalidNameChars.ReplaceAllString(name, "-"), "-")
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// isBlank reports whether a list only contains whitespace.
+func isBlank(list *parse.ListNode) bool {
+	for _, n := range list.Nodes {
+		text, ok := n.(*parse.TextNode)
+		if !ok || strings.TrimSpace(string(text.Text)) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// converter converts the body of a template.
+type converter struct {
+	source string
+	param  string
+	// dot is the path of the dot and vars are the paths of the
+	// variables in the current scope.
+	dot  string
+	vars map[string]string
+	// trees are the templates of the source.
+	trees map[string]*parse.Tree
+	out   strings.Builder
+	// skipQuote is the quote that ends an attribute value that has been
+	// replaced by an attr- binding, and is dropped from the following
+	// text.
+	skipQuote string
+	usesParam bool
+	issues    []Issue
+}
+
+var (
+	// openTagEnd matches the end of the output inside a start tag.
+	openTagEnd = regexp.MustCompile(`<[a-zA-Z][^<>]*$`)
+	// attributeStart matches the start of a quoted attribute value at
+	// the end of the output.
+	attributeStart = regexp.MustCompile(`\s([a-zA-Z_:][-a-zA-Z0-9_:.]*)=(["'])$`)
+	// startTag matches a start tag at the end of the output.
+	startTag = regexp.MustCompile(`<([a-zA-Z][a-zA-Z0-9-]*)[^<>]*>$`)
+)
+
+func (c *converter) list(list *parse.ListNode) {
+	if list == nil {
+		return
+	}
+	for i, n := range list.Nodes {
+		var next string
+		if i+1 < len(list.Nodes) {
+			if text, ok := list.Nodes[i+1].(*parse.TextNode); ok {
+				next = string(text.Text)
+			}
+		}
+		c.node(n, next)
+	}
+}
+
+// node converts a node, which is followed by the given text.
+func (c *converter) node(n parse.Node, next string) {
+	if _, ok := n.(*parse.TextNode); !ok && c.inTag() {
+		if _, ok := n.(*parse.ActionNode); !ok {
+			c.unconverted(n, "only actions can be converted inside a tag")
+			return
+		}
+	}
+	switch n := n.(type) {
+	case *parse.TextNode:
+		text := string(n.Text)
+		if c.skipQuote != "" {
+			text = strings.TrimPrefix(text, c.skipQuote)
+			c.skipQuote = ""
+		}
+		c.out.WriteString(text)
+	case *parse.CommentNode:
+		comment := strings.TrimSuffix(strings.TrimPrefix(n.Text, "/*"), "*/")
+		c.out.WriteString("<!--#" + escapeComment(comment) + "-->")
+	case *parse.ActionNode:
+		c.action(n, next)
+	case *parse.IfNode:
+		c.ifNode(n)
+	case *parse.RangeNode:
+		c.rangeNode(n)
+	case *parse.TemplateNode:
+		c.template(n)
+	case *parse.WithNode:
+		c.unconverted(n, "with is not supported, use if and the full paths of the fields")
+	default:
+		c.unconverted(n, fmt.Sprintf("%s is not supported", strings.Fields(n.String())[0]))
+	}
+}
+
+func (c *converter) action(n *parse.ActionNode, next string) {
+	if len(n.Pipe.Decl) > 0 {
+		c.unconverted(n, "variable declarations are not supported")
+		return
+	}
+	path, ok := c.path(n.Pipe)
+	if !ok {
+		c.unconverted(n, "only fields and variables can be converted")
+		return
+	}
+	out := c.out.String()
+	if c.inTag() {
+		m := attributeStart.FindStringSubmatch(out)
+		if m == nil || !strings.HasPrefix(next, m[2]) {
+			c.unconverted(n, "an action in a tag must be the whole value of an attribute")
+			return
+		}
+		c.out.Reset()
+		c.out.WriteString(strings.TrimSuffix(out, m[1]+"="+m[2]))
+		fmt.Fprintf(&c.out, `attr-%s="%s"`, m[1], path)
+		c.skipQuote = m[2]
+		return
+	}
+	if m := startTag.FindStringSubmatch(out); m != nil && strings.HasPrefix(next, "</"+m[1]+">") && !strings.Contains(m[0], "inner-text=") {
+		// An element that only contains the action gets the binding.
+		c.out.Reset()
+		c.out.WriteString(strings.TrimSuffix(out, ">"))
+		fmt.Fprintf(&c.out, ` inner-text="%s">`, path)
+		return
+	}
+	fmt.Fprintf(&c.out, `<fragment inner-text="%s"></fragment>`, path)
+}
+
+func (c *converter) ifNode(n *parse.IfNode) {
+	key, path, ok := "true", "", false
+	if cmd := singleCommand(n.Pipe); cmd != nil && len(cmd.Args) == 2 && isIdentifier(cmd.Args[0], "not") {
+		key = "not"
+		path, ok = c.arg(cmd.Args[1])
+	} else {
+		path, ok = c.path(n.Pipe)
+	}
+	if !ok {
+		c.unconverted(n, "only fields, variables and their negation with not can be converted as conditions")
+		return
+	}
+	fmt.Fprintf(&c.out, `<if %s="%s">`, key, path)
+	c.list(n.List)
+	c.out.WriteString("</if>")
+	if n.ElseList != nil {
+		negated := map[string]string{"true": "not", "not": "true"}[key]
+		fmt.Fprintf(&c.out, `<if %s="%s">`, negated, path)
+		c.list(n.ElseList)
+		c.out.WriteString("</if>")
+	}
+}
+
+func (c *converter) rangeNode(n *parse.RangeNode) {
+	each, ok := c.path(n.Pipe)
+	if !ok {
+		c.unconverted(n, "only fields and variables can be converted as the value of range")
+		return
+	}
+	var as, indexAs string
+	switch len(n.Pipe.Decl) {
+	case 0:
+		as = elementName(each)
+	case 1:
+		as = strings.TrimPrefix(n.Pipe.Decl[0].Ident[0], "$")
+	default:
+		indexAs = strings.TrimPrefix(n.Pipe.Decl[0].Ident[0], "$")
+		as = strings.TrimPrefix(n.Pipe.Decl[1].Ident[0], "$")
+	}
+	fmt.Fprintf(&c.out, `<for each="%s" as="%s"`, each, as)
+	if indexAs != "" {
+		fmt.Fprintf(&c.out, ` index-as="%s"`, indexAs)
+	}
+	c.out.WriteString(">")
+
+	dot, vars := c.dot, c.vars
+	c.dot = as
+	c.vars = map[string]string{}
+	for name, path := range vars {
+		c.vars[name] = path
+	}
+	for _, decl := range n.Pipe.Decl {
+		name := decl.Ident[0]
+		c.vars[name] = strings.TrimPrefix(name, "$")
+	}
+	c.list(n.List)
+	c.dot, c.vars = dot, vars
+
+	if n.ElseList != nil {
+		c.out.WriteString("<empty>")
+		c.list(n.ElseList)
+		c.out.WriteString("</empty>")
+	}
+	c.out.WriteString("</for>")
+}
+
+func (c *converter) template(n *parse.TemplateNode) {
+	if _, ok := c.trees[n.Name]; !ok {
+		c.issue(n, n.String(), fmt.Sprintf("template %s is not defined in this file, import its function", n.Name))
+	}
+	fmt.Fprintf(&c.out, `<render function="%s"`, functionName(n.Name))
+	if n.Pipe != nil {
+		path, ok := c.path(n.Pipe)
+		if !ok {
+			c.unconverted(n, "only fields and variables can be passed to a template")
+			return
+		}
+		fmt.Fprintf(&c.out, ` params="%s"`, path)
+	}
+	c.out.WriteString("></render>")
+}
+
+// path returns the hop path of a pipeline that consists of a field or
+// a variable.
+func (c *converter) path(pipe *parse.PipeNode) (string, bool) {
+	cmd := singleCommand(pipe)
+	if cmd == nil || len(cmd.Args) != 1 {
+		return "", false
+	}
+	return c.arg(cmd.Args[0])
+}
+
+// arg returns the hop path of an argument that is a field or a
+// variable.
+func (c *converter) arg(arg parse.Node) (string, bool) {
+	var path string
+	switch arg := arg.(type) {
+	case *parse.DotNode:
+		path = c.dot
+	case *parse.FieldNode:
+		path = c.dot + "." + strings.Join(arg.Ident, ".")
+	case *parse.VariableNode:
+		root, ok := c.vars[arg.Ident[0]]
+		if !ok {
+			return "", false
+		}
+		path = strings.Join(append([]string{root}, arg.Ident[1:]...), ".")
+	default:
+		return "", false
+	}
+	if path == c.param || strings.HasPrefix(path, c.param+".") {
+		c.usesParam = true
+	}
+	return path, true
+}
+
+// singleCommand returns the command of a pipeline without
+// declarations that consists of a single command.
+func singleCommand(pipe *parse.PipeNode) *parse.CommandNode {
+	if pipe == nil || len(pipe.Cmds) != 1 {
+		return nil
+	}
+	return pipe.Cmds[0]
+}
+
+func isIdentifier(n parse.Node, name string) bool {
+	ident, ok := n.(*parse.IdentifierNode)
+	return ok && ident.Ident == name
+}
+
+// elementName returns the name of the variable for the elements of the
+// array at path, e.g. post for data.Posts.
+func elementName(path string) string {
+	name := strings.ToLower(path[strings.LastIndex(path, ".")+1:])
+	if singular, ok := strings.CutSuffix(name, "s"); ok && singular != "" {
+		return singular
+	}
+	return "item"
+}
+
+// inTag reports whether the output ends inside a start tag.
+func (c *converter) inTag() bool {
+	return openTagEnd.MatchString(c.out.String())
+}
+
+// unconverted leaves n in a hop comment and reports it.
+func (c *converter) unconverted(n parse.Node, message string) {
+	c.issue(n, n.String(), message)
+	if !c.inTag() {
+		c.out.WriteString("<!--# unconverted: " + escapeComment(n.String()) + " -->")
+	}
+}
+
+func (c *converter) issue(n parse.Node, source string, message string) {
+	c.issues = append(c.issues, Issue{
+		Line:    1 + strings.Count(c.source[:int(n.Position())], "\n"),
+		Source:  source,
+		Message: message,
+	})
+}
+
+// escapeComment makes text safe to put in an HTML comment.
+func escapeComment(text string) string {
+	return strings.ReplaceAll(text, "--", "- -")
+}
@@ -0,0 +1,159 @@
+package hopconvert_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/hoplang/hop-go"
+	"github.com/hoplang/hop-go/hopconvert"
+)
+
+func TestConvert(t *testing.T) {
+	source := `{{define "post"}}<li><a href="{{.URL}}">{{.Title}}</a>{{if .Draft}} (draft){{end}}</li>{{end}}
+<h1>{{.Title}}</h1>
+{{/* The posts */}}
+<ul>{{range .Posts}}{{template "post" .}}{{else}}<li>No posts</li>{{end}}</ul>
+<p>{{len .Posts}} posts</p>`
+	result, err := hopconvert.Convert("blog.html", source, hopconvert.Options{})
+	if err != nil {
+		t.Fatalf("Failed to convert: %s", err)
+	}
+	want := `<function name="blog" params-as="data">
+<h1 inner-text="data.Title"></h1>
+<!--# The posts -->
+<ul><for each="data.Posts" as="post"><render function="post" params="post"></render><empty><li>No posts</li></empty></for></ul>
+<p><!--# unconverted: {{len .Posts}} --> posts</p></function>
+
+<function name="post" params-as="data"><li><a attr-href="data.URL" inner-text="data.Title"></a><if true="data.Draft"> (draft)</if></li></function>
+`
+	if result.Source != want {
+		t.Errorf("Expected\n%s\nbut got\n%s", want, result.Source)
+	}
+	if len(result.Issues) != 1 || result.Issues[0].Line != 5 || result.Issues[0].Source != "{{len .Posts}}" {
+		t.Errorf("Unexpected issues %+v", result.Issues)
+	}
+
+	c := hop.NewCompiler()
+	c.AddModule("blog", result.Source)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile converted module: %s", err)
+	}
+	var buf bytes.Buffer
+	data := map[string]any{
+		"Title": "Blog",
+		"Posts": []any{map[string]any{"URL": "/hello", "Title": "Hello", "Draft": true}},
+	}
+	if err := program.ExecuteFunction(&buf, "blog", "blog", data); err != nil {
+		t.Fatalf("Failed to execute: %s", err)
+	}
+	if !strings.Contains(buf.String(), `<li><a href="/hello">Hello</a> (draft)</li>`) {
+		t.Errorf("Unexpected output %s", buf.String())
+	}
+}
+
+func TestConvertUnsupported(t *testing.T) {
+	source := `{{with .Author}}<p class="by {{.Name}}">{{.Name}}</p>{{end}}
+<div class="{{.Class}} wide">{{if eq .Kind "a"}}A{{end}}</div>`
+	result, err := hopconvert.Convert("page.html", source, hopconvert.Options{Param: "page"})
+	if err != nil {
+		t.Fatalf("Failed to convert: %s", err)
+	}
+	var messages []string
+	for _, issue := range result.Issues {
+		messages = append(messages, issue.Message)
+	}
+	want := []string{
+		"with is not supported, use if and the full paths of the fields",
+		"an action in a tag must be the whole value of an attribute",
+		"only fields, variables and their negation with not can be converted as conditions",
+	}
+	if strings.Join(messages, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected issues %q but got %q", want, messages)
+	}
+
+	if _, err := hopconvert.Convert("page.html", "{{if .X}}", hopconvert.Options{}); err == nil {
+		t.Errorf("Expected an error for an unclosed if")
+	}
+}
+
+func TestConvertVue(t *testing.T) {
+	source := `<script setup>
+defineProps(['title', 'posts', 'draft'])
+</script>
+
+<template>
+  <h1>{{ title }}</h1>
+  <p v-if="draft">Draft</p>
+  <p v-else>Published</p>
+  <ul>
+    <li v-for="(post, i) in posts" :key="post.id" :class="post.kind"><a :href="post.url">{{ post.title }}</a></li>
+  </ul>
+  <PostCard :post="posts[0]" size="large" @click="open">
+    <template #footer>Bye</template>
+  </PostCard>
+</template>`
+	result, err := hopconvert.ConvertVue("Blog.vue", source, hopconvert.Options{})
+	if err != nil {
+		t.Fatalf("Failed to convert: %s", err)
+	}
+	want := `<function name="blog" params-as="props">
+  <h1 inner-text="props.title"></h1>
+  <if true="props.draft"><p>Draft</p></if>
+  <if not="props.draft"><p>Published</p></if>
+  <ul>
+    <for each="props.posts" as="post" index-as="i"><li attr-class="post.kind"><a attr-href="post.url" inner-text="post.title"></a></li></for>
+  </ul>
+  <render function="post-card" with-post="props.posts[0]" with-size="'large'">
+    <fill slot="footer">Bye</fill>
+  </render>
+</function>
+`
+	if result.Source != want {
+		t.Errorf("Expected\n%s\nbut got\n%s", want, result.Source)
+	}
+	if len(result.Issues) != 1 || result.Issues[0].Line != 12 || result.Issues[0].Message != "only props are converted for components" {
+		t.Errorf("Unexpected issues %+v", result.Issues)
+	}
+}
+
+func TestConvertJSX(t *testing.T) {
+	source := `export default function PostList({ title, posts }) {
+  return (
+    <section className="posts">
+      <h2>{title}</h2>
+      {posts.length === 0 ? <p>No posts</p> : null}
+      {props.featured && <Badge label="Featured" />}
+      <ul>
+        {posts.map((post) => (
+          <li key={post.id}><a href={post.url} onClick={track}>{post.title}</a></li>
+        ))}
+      </ul>
+    </section>
+  );
+}`
+	result, err := hopconvert.ConvertJSX("PostList.jsx", source, hopconvert.Options{})
+	if err != nil {
+		t.Fatalf("Failed to convert: %s", err)
+	}
+	want := `<function name="post-list" params-as="props"><section class="posts">
+      <h2 inner-text="props.title"></h2>
+      <!--# unconverted: {posts.length === 0 ? <p>No posts</p> : null} -->
+      <if true="props.featured"><render function="badge" with-label="'Featured'"></render></if>
+      <ul>
+        <for each="props.posts" as="post"><li><a attr-href="post.url" inner-text="post.title"></a></li></for>
+      </ul>
+    </section></function>
+`
+	if result.Source != want {
+		t.Errorf("Expected\n%s\nbut got\n%s", want, result.Source)
+	}
+	var messages []string
+	for _, issue := range result.Issues {
+		messages = append(messages, issue.Message)
+	}
+	if len(messages) != 2 || messages[1] != "event handlers are not converted" {
+		t.Errorf("Unexpected issues %q", messages)
+	}
+}
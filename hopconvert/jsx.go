@@ -0,0 +1,284 @@
+package hopconvert
+
+import (
+	"cmp"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ConvertJSX translates the markup that a React component, such as
+// "PostCard.jsx", returns into a hop module with a function named after
+// the component, e.g. post-card. Only the first element that the source
+// returns is converted, so a file should contain a single component.
+// The props and the other values that the markup uses are looked up in
+// the parameter of the function, named by Options.Param, which
+// defaults to "props" here.
+//
+// Expressions that are property paths, conditions written with && or
+// ?:, lists written with map, className, dangerouslySetInnerHTML and
+// the calls of other components are converted. Components become render
+// calls with their props as named parameters. Event handlers, spread
+// props, style objects and other expressions are listed in the issues
+// of the result.
+func ConvertJSX(name string, source string, opts Options) (*Result, error) {
+	start := jsxStart.FindStringIndex(source)
+	if start == nil {
+		return nil, fmt.Errorf("%s does not return any markup", name)
+	}
+	p := &markupParser{src: source, pos: start[1] - 1, jsx: true}
+	root, err := p.element()
+	if err != nil {
+		return nil, err
+	}
+	c := &jsxConverter{markupConverter{
+		source: source,
+		param:  cmp.Or(opts.Param, "props"),
+		vars:   map[string]bool{},
+	}}
+	c.nodes([]*markupNode{root})
+	return &Result{
+		Source: c.function(componentName(strings.TrimSuffix(path.Base(name), path.Ext(name)))),
+		Issues: c.issues,
+	}, nil
+}
+
+var (
+	// jsxStart matches the start of the markup that a component returns.
+	jsxStart = regexp.MustCompile(`(?:return|=>)\s*\(?\s*<`)
+	// jsxAnd matches a condition written as cond && <markup>.
+	jsxAnd = regexp.MustCompile(`^(!?\s*[\w$.\[\]]+)\s*&&\s*\(?\s*<`)
+	// jsxTernary matches the start of a condition written as
+	// cond ? <markup> : <markup>.
+	jsxTernary = regexp.MustCompile(`^(!?\s*[\w$.\[\]]+)\s*\?\s*`)
+	// jsxMap matches a list written as items.map((item, i) => <markup>).
+	jsxMap = regexp.MustCompile(`^([\w$.\[\]]+)\.map\(\s*\(?\s*([A-Za-z_$][\w$]*)\s*(?:,\s*([A-Za-z_$][\w$]*)\s*)?\)?\s*=>\s*\(?\s*<`)
+	// jsxInnerHTML matches the value of dangerouslySetInnerHTML.
+	jsxInnerHTML = regexp.MustCompile(`^\{\s*__html\s*:\s*([\w$.\[\]]+)\s*\}$`)
+)
+
+type jsxConverter struct {
+	markupConverter
+}
+
+func (c *jsxConverter) nodes(nodes []*markupNode) {
+	for _, n := range nodes {
+		switch n.kind {
+		case markupText:
+			c.out.WriteString(n.text)
+		case markupComment:
+			c.out.WriteString("<!--#" + escapeComment(n.text) + "-->")
+		case markupExpr:
+			c.expr(n)
+		case markupElement:
+			c.element(n)
+		}
+	}
+}
+
+// expr converts an expression in the children of an element.
+func (c *jsxConverter) expr(n *markupNode) {
+	text := n.text
+	// offset is the position of the expression in the source.
+	offset := n.offset + strings.Index(c.source[n.offset:], text)
+	if text == "children" || text == "props.children" {
+		c.out.WriteString("<children></children>")
+		return
+	}
+	if path, ok := c.path(text, "props"); ok {
+		fmt.Fprintf(&c.out, `<fragment inner-text="%s"></fragment>`, path)
+		return
+	}
+	if m := jsxAnd.FindStringSubmatchIndex(text); m != nil {
+		key, path, ok := c.condition(text[m[2]:m[3]], "props")
+		if el, ok2 := c.markup(text, offset, m[1]-1, true); ok && ok2 {
+			fmt.Fprintf(&c.out, `<if %s="%s">`, key, path)
+			c.element(el)
+			c.out.WriteString("</if>")
+			return
+		}
+	}
+	if m := jsxTernary.FindStringSubmatchIndex(text); m != nil {
+		if c.ternary(text, offset, text[m[2]:m[3]], m[1]) {
+			return
+		}
+	}
+	if m := jsxMap.FindStringSubmatchIndex(text); m != nil {
+		each, ok := c.path(text[m[2]:m[3]], "props")
+		if el, ok2 := c.markup(text, offset, m[1]-1, true); ok && ok2 {
+			as := text[m[4]:m[5]]
+			fmt.Fprintf(&c.out, `<for each="%s" as="%s"`, each, as)
+			vars := c.vars
+			c.vars = map[string]bool{as: true}
+			if m[6] >= 0 {
+				fmt.Fprintf(&c.out, ` index-as="%s"`, text[m[6]:m[7]])
+				c.vars[text[m[6]:m[7]]] = true
+			}
+			c.out.WriteString(">")
+			for name := range vars {
+				c.vars[name] = true
+			}
+			c.element(el)
+			c.vars = vars
+			c.out.WriteString("</for>")
+			return
+		}
+	}
+	c.unconverted(n.offset, "{"+text+"}", "only property paths, conditions with && or ?: and lists with map can be converted")
+}
+
+// ternary converts cond ? a : b, where each branch is markup or null,
+// starting at position i of the expression.
+func (c *jsxConverter) ternary(text string, offset int, cond string, i int) bool {
+	key, path, ok := c.condition(cond, "props")
+	if !ok {
+		return false
+	}
+	var branches [2]*markupNode
+	for b := range branches {
+		rest := strings.TrimLeft(text[i:], " \t\r\n(")
+		i = len(text) - len(rest)
+		if after, ok := strings.CutPrefix(rest, "null"); ok {
+			i = len(text) - len(after)
+		} else {
+			p := &markupParser{src: c.source, pos: offset + i, jsx: true}
+			if !strings.HasPrefix(rest, "<") {
+				return false
+			}
+			el, err := p.element()
+			if err != nil {
+				return false
+			}
+			branches[b] = el
+			i = p.pos - offset
+		}
+		rest = strings.TrimLeft(text[i:], " \t\r\n)")
+		i = len(text) - len(rest)
+		if b == 0 {
+			if !strings.HasPrefix(rest, ":") {
+				return false
+			}
+			i++
+		} else if rest != "" {
+			return false
+		}
+	}
+	negated := map[string]string{"true": "not", "not": "true"}[key]
+	for b, el := range branches {
+		if el == nil {
+			continue
+		}
+		fmt.Fprintf(&c.out, `<if %s="%s">`, []string{key, negated}[b], path)
+		c.element(el)
+		c.out.WriteString("</if>")
+	}
+	return true
+}
+
+// markup parses the element at position i of an expression that starts
+// at offset in the source. If whole is set, only closing parentheses
+// may follow the element.
+func (c *jsxConverter) markup(text string, offset int, i int, whole bool) (*markupNode, bool) {
+	p := &markupParser{src: c.source, pos: offset + i, jsx: true}
+	el, err := p.element()
+	if err != nil {
+		return nil, false
+	}
+	if whole && strings.Trim(text[p.pos-offset:], " \t\r\n)") != "" {
+		return nil, false
+	}
+	return el, true
+}
+
+func (c *jsxConverter) element(n *markupNode) {
+	switch {
+	case n.name == "":
+		// A fragment.
+		c.nodes(n.children)
+	case isComponent(n.name):
+		c.component(n)
+	default:
+		c.native(n)
+	}
+}
+
+func (c *jsxConverter) native(n *markupNode) {
+	fmt.Fprintf(&c.out, "<%s", n.name)
+	hasInner := false
+	for _, attr := range n.attrs {
+		name := map[string]string{"className": "class", "htmlFor": "for"}[attr.name]
+		name = cmp.Or(name, attr.name)
+		switch {
+		case attr.name == "key":
+		case attr.name == "":
+			c.issue(attr.offset, "{"+attr.value+"}", "spread props are not converted")
+		case attr.name == "dangerouslySetInnerHTML":
+			m := jsxInnerHTML.FindStringSubmatch(attr.value)
+			var path string
+			ok := m != nil
+			if ok {
+				path, ok = c.path(m[1], "props")
+			}
+			if !ok {
+				c.issue(attr.offset, attr.name+"={"+attr.value+"}", "only property paths can be converted as inner html")
+				continue
+			}
+			fmt.Fprintf(&c.out, ` inner-html="%s"`, path)
+			hasInner = true
+		case strings.HasPrefix(attr.name, "on") && attr.expr:
+			c.issue(attr.offset, attr.name+"={"+attr.value+"}", "event handlers are not converted")
+		case attr.expr:
+			path, ok := c.path(attr.value, "props")
+			if !ok {
+				c.issue(attr.offset, attr.name+"={"+attr.value+"}", "only property paths can be bound")
+				continue
+			}
+			fmt.Fprintf(&c.out, ` attr-%s="%s"`, name, path)
+		default:
+			c.writeStatic(name, attr.value)
+		}
+	}
+	if expr, ok := onlyExpression(n); ok && !hasInner {
+		if path, ok := c.path(expr.text, "props"); ok {
+			fmt.Fprintf(&c.out, ` inner-text="%s">`, path)
+			if !voidElements[n.name] {
+				fmt.Fprintf(&c.out, "</%s>", n.name)
+			}
+			return
+		}
+	}
+	c.out.WriteString(">")
+	if voidElements[n.name] {
+		return
+	}
+	c.nodes(n.children)
+	fmt.Fprintf(&c.out, "</%s>", n.name)
+}
+
+// component converts the use of a component into a render call.
+func (c *jsxConverter) component(n *markupNode) {
+	fmt.Fprintf(&c.out, `<render function="%s"`, componentName(n.name))
+	for _, attr := range n.attrs {
+		switch {
+		case attr.name == "key":
+		case attr.name == "":
+			c.issue(attr.offset, "{"+attr.value+"}", "spread props are not converted")
+		case attr.expr:
+			path, ok := c.path(attr.value, "props")
+			if !ok {
+				c.issue(attr.offset, attr.name+"={"+attr.value+"}", "only property paths can be passed as props")
+				continue
+			}
+			fmt.Fprintf(&c.out, ` with-%s="%s"`, attr.name, path)
+		case attr.bare:
+			// A prop without a value is true.
+			fmt.Fprintf(&c.out, ` with-%s="true"`, attr.name)
+		default:
+			c.writeStatic("with-"+attr.name, literal(attr.value))
+		}
+	}
+	c.out.WriteString(">")
+	c.nodes(n.children)
+	c.out.WriteString("</render>")
+}
@@ -0,0 +1,403 @@
+package hopconvert
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// The Vue and JSX importers parse their markup with a small parser of
+// their own, since an HTML parser would lowercase the names of
+// components and does not know about self-closing elements and the
+// expressions of JSX.
+
+type markupKind int
+
+const (
+	markupText markupKind = iota
+	markupElement
+	// markupExpr is an interpolation, {{ expr }} in Vue and { expr } in
+	// JSX.
+	markupExpr
+	markupComment
+)
+
+// markupNode is a node of a Vue or JSX template.
+type markupNode struct {
+	kind markupKind
+	// text is the text of a text node or a comment, or the source of
+	// an expression.
+	text     string
+	name     string
+	attrs    []markupAttr
+	children []*markupNode
+	// offset is the position of the node in the source.
+	offset int
+}
+
+// markupAttr is an attribute of an element. The value of an attribute
+// in braces, as in JSX, is an expression, and an attribute without a
+// value is bare.
+type markupAttr struct {
+	name   string
+	value  string
+	expr   bool
+	bare   bool
+	offset int
+}
+
+func (n *markupNode) attr(name string) (markupAttr, bool) {
+	for _, attr := range n.attrs {
+		if attr.name == name {
+			return attr, true
+		}
+	}
+	return markupAttr{}, false
+}
+
+// voidElements are the elements that never have children.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"source": true, "track": true, "wbr": true,
+}
+
+// markupParser parses Vue or JSX markup.
+type markupParser struct {
+	src string
+	pos int
+	jsx bool
+}
+
+// nodes parses nodes up to the end tag of the element with the given
+// name, which is consumed, or up to the end of the source if name is
+// empty and the parser is not inside a JSX fragment.
+func (p *markupParser) nodes(until string, inFragment bool) ([]*markupNode, error) {
+	var nodes []*markupNode
+	for p.pos < len(p.src) {
+		rest := p.src[p.pos:]
+		switch {
+		case strings.HasPrefix(rest, "</"):
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				return nil, p.errorf("unclosed end tag")
+			}
+			name := strings.TrimSpace(rest[2:end])
+			if name != until || (until == "" && !inFragment) {
+				return nil, p.errorf("unexpected end tag </%s>", name)
+			}
+			p.pos += end + 1
+			return nodes, nil
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest, "-->")
+			if end < 0 {
+				return nil, p.errorf("unclosed comment")
+			}
+			nodes = append(nodes, &markupNode{kind: markupComment, text: rest[4:end], offset: p.pos})
+			p.pos += end + 3
+		case strings.HasPrefix(rest, "<"):
+			n, err := p.element()
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, n)
+		case !p.jsx && strings.HasPrefix(rest, "{{"):
+			end := strings.Index(rest, "}}")
+			if end < 0 {
+				return nil, p.errorf("unclosed interpolation")
+			}
+			nodes = append(nodes, &markupNode{kind: markupExpr, text: strings.TrimSpace(rest[2:end]), offset: p.pos})
+			p.pos += end + 2
+		case p.jsx && strings.HasPrefix(rest, "{"):
+			offset := p.pos
+			expr, err := p.braces()
+			if err != nil {
+				return nil, err
+			}
+			expr = strings.TrimSpace(expr)
+			if comment, ok := strings.CutPrefix(expr, "/*"); ok {
+				nodes = append(nodes, &markupNode{kind: markupComment, text: strings.TrimSuffix(comment, "*/"), offset: offset})
+				continue
+			}
+			nodes = append(nodes, &markupNode{kind: markupExpr, text: expr, offset: offset})
+		default:
+			end := strings.IndexAny(rest[1:], "<{")
+			if end < 0 {
+				end = len(rest)
+			} else {
+				end++
+			}
+			nodes = append(nodes, &markupNode{kind: markupText, text: rest[:end], offset: p.pos})
+			p.pos += end
+		}
+	}
+	if until != "" || inFragment {
+		return nil, p.errorf("unclosed element <%s>", until)
+	}
+	return nodes, nil
+}
+
+var markupNameRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_.:-]*`)
+
+// element parses an element, or a JSX fragment, at the current
+// position.
+func (p *markupParser) element() (*markupNode, error) {
+	n := &markupNode{kind: markupElement, offset: p.pos}
+	p.pos++
+	n.name = markupNameRegex.FindString(p.src[p.pos:])
+	p.pos += len(n.name)
+	if n.name == "" && !(p.jsx && strings.HasPrefix(p.src[p.pos:], ">")) {
+		return nil, p.errorf("invalid tag")
+	}
+	for {
+		p.skipSpace()
+		rest := p.src[p.pos:]
+		switch {
+		case rest == "":
+			return nil, p.errorf("unclosed tag <%s>", n.name)
+		case strings.HasPrefix(rest, "/>"):
+			p.pos += 2
+			return n, nil
+		case strings.HasPrefix(rest, ">"):
+			p.pos++
+			if voidElements[n.name] && !p.jsx {
+				return n, nil
+			}
+			children, err := p.nodes(n.name, n.name == "")
+			if err != nil {
+				return nil, err
+			}
+			n.children = children
+			return n, nil
+		}
+		attr, err := p.attribute()
+		if err != nil {
+			return nil, err
+		}
+		n.attrs = append(n.attrs, attr)
+	}
+}
+
+func (p *markupParser) attribute() (markupAttr, error) {
+	attr := markupAttr{offset: p.pos}
+	if p.jsx && p.src[p.pos] == '{' {
+		// A spread attribute such as {...props}.
+		value, err := p.braces()
+		attr.value, attr.expr = value, true
+		return attr, err
+	}
+	end := strings.IndexAny(p.src[p.pos:], " \t\r\n=/>")
+	if end <= 0 {
+		return attr, p.errorf("invalid attribute")
+	}
+	attr.name = p.src[p.pos : p.pos+end]
+	p.pos += end
+	p.skipSpace()
+	if !strings.HasPrefix(p.src[p.pos:], "=") {
+		attr.bare = true
+		return attr, nil
+	}
+	p.pos++
+	p.skipSpace()
+	rest := p.src[p.pos:]
+	switch {
+	case strings.HasPrefix(rest, `"`) || strings.HasPrefix(rest, "'"):
+		end := strings.IndexByte(rest[1:], rest[0])
+		if end < 0 {
+			return attr, p.errorf("unclosed attribute value")
+		}
+		attr.value = rest[1 : end+1]
+		p.pos += end + 2
+	case p.jsx && strings.HasPrefix(rest, "{"):
+		value, err := p.braces()
+		if err != nil {
+			return attr, err
+		}
+		attr.value, attr.expr = strings.TrimSpace(value), true
+	default:
+		end := strings.IndexAny(rest, " \t\r\n>")
+		if end < 0 {
+			return attr, p.errorf("unclosed tag")
+		}
+		attr.value = rest[:end]
+		p.pos += end
+	}
+	return attr, nil
+}
+
+// braces returns the text inside the balanced braces at the current
+// position, skipping braces in string literals.
+func (p *markupParser) braces() (string, error) {
+	start := p.pos
+	depth := 0
+	var quote byte
+	for ; p.pos < len(p.src); p.pos++ {
+		ch := p.src[p.pos]
+		switch {
+		case quote != 0:
+			if ch == '\\' {
+				p.pos++
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'' || ch == '`':
+			quote = ch
+		case ch == '{':
+			depth++
+		case ch == '}':
+			depth--
+			if depth == 0 {
+				p.pos++
+				return p.src[start+1 : p.pos-1], nil
+			}
+		}
+	}
+	p.pos = start
+	return "", p.errorf("unclosed {")
+}
+
+func (p *markupParser) skipSpace() {
+	for p.pos < len(p.src) && strings.ContainsRune(" \t\r\n", rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *markupParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", lineOf(p.src, p.pos), fmt.Sprintf(format, args...))
+}
+
+// lineOf returns the line of the given offset of source.
+func lineOf(source string, offset int) int {
+	return 1 + strings.Count(source[:offset], "\n")
+}
+
+// markupConverter holds what the Vue and JSX importers have in common.
+type markupConverter struct {
+	source string
+	param  string
+	// vars are the loop variables in scope.
+	vars      map[string]bool
+	out       strings.Builder
+	usesParam bool
+	issues    []Issue
+}
+
+var jsPathRegex = regexp.MustCompile(`^[A-Za-z_$][\w$]*(\.[A-Za-z_$][\w$]*|\[\d+\])*$`)
+
+// path converts an expression that is a property path into a hop path.
+// Paths that do not start with a loop variable are looked up in the
+// parameter, which the props root names explicitly.
+func (c *markupConverter) path(expr string, propsRoot string) (string, bool) {
+	expr = strings.TrimSpace(expr)
+	if !jsPathRegex.MatchString(expr) {
+		return "", false
+	}
+	root, rest, _ := strings.Cut(expr, ".")
+	if i := strings.IndexByte(root, '['); i >= 0 {
+		root, rest = root[:i], strings.TrimPrefix(expr[i:], ".")
+	}
+	if c.vars[root] {
+		return expr, true
+	}
+	c.usesParam = true
+	if root == propsRoot {
+		if rest == "" {
+			return c.param, true
+		}
+		return c.param + joinPathRest(rest), true
+	}
+	return c.param + "." + expr, true
+}
+
+func joinPathRest(rest string) string {
+	if strings.HasPrefix(rest, "[") {
+		return rest
+	}
+	return "." + rest
+}
+
+// condition converts a condition that is a path or a negated path into
+// the key and value of an `if` tag.
+func (c *markupConverter) condition(expr string, propsRoot string) (string, string, bool) {
+	key := "true"
+	expr = strings.TrimSpace(expr)
+	if negated, ok := strings.CutPrefix(expr, "!"); ok {
+		key, expr = "not", negated
+	}
+	path, ok := c.path(expr, propsRoot)
+	return key, path, ok
+}
+
+func (c *markupConverter) issue(offset int, source string, message string) {
+	c.issues = append(c.issues, Issue{
+		Line:    lineOf(c.source, offset),
+		Source:  source,
+		Message: message,
+	})
+}
+
+// unconverted leaves source in a hop comment and reports it.
+func (c *markupConverter) unconverted(offset int, source string, message string) {
+	c.issue(offset, source, message)
+	c.out.WriteString("<!--# unconverted: " + escapeComment(source) + " -->")
+}
+
+// componentName returns the name of the function for a component, e.g.
+// post-card for PostCard.
+func componentName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 && name[i-1] != '-' {
+				b.WriteByte('-')
+			}
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return functionName(b.String())
+}
+
+// isComponent reports whether an element is a component rather than an
+// HTML element.
+func isComponent(name string) bool {
+	return name != "" && (name[0] >= 'A' && name[0] <= 'Z' || strings.Contains(name, "-"))
+}
+
+// writeStatic writes a static attribute.
+func (c *markupConverter) writeStatic(name string, value string) {
+	fmt.Fprintf(&c.out, ` %s="%s"`, name, strings.ReplaceAll(value, `"`, "&quot;"))
+}
+
+// literal returns a hop literal of a static component prop.
+func literal(value string) string {
+	if strings.Contains(value, "'") {
+		return `"` + value + `"`
+	}
+	return "'" + value + "'"
+}
+
+// function wraps the converted body in a function.
+func (c *markupConverter) function(name string) string {
+	params := ""
+	if c.usesParam {
+		params = fmt.Sprintf(` params-as="%s"`, c.param)
+	}
+	return fmt.Sprintf("<function name=\"%s\"%s>%s</function>\n", name, params, c.out.String())
+}
+
+// onlyExpression returns the expression of an element whose only child
+// is an expression, ignoring whitespace.
+func onlyExpression(n *markupNode) (*markupNode, bool) {
+	var expr *markupNode
+	for _, c := range n.children {
+		switch {
+		case c.kind == markupText && strings.TrimSpace(c.text) == "":
+		case c.kind == markupExpr && expr == nil:
+			expr = c
+		default:
+			return nil, false
+		}
+	}
+	return expr, expr != nil
+}
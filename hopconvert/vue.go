@@ -0,0 +1,304 @@
+package hopconvert
+
+import (
+	"cmp"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ConvertVue translates the template of a Vue single-file component,
+// such as "PostCard.vue", into a hop module with a function named after
+// the component, e.g. post-card. The props and the other values that
+// the template uses are looked up in the parameter of the function,
+// named by Options.Param, which defaults to "props" here.
+//
+// Interpolations, v-bind, v-if, v-else-if, v-else, v-for, v-html,
+// v-text, slots and the calls of other components are converted.
+// Components become render calls with their props as named parameters.
+// Event handlers, v-model, v-show and expressions other than property
+// paths and their negation are listed in the issues of the result.
+func ConvertVue(name string, source string, opts Options) (*Result, error) {
+	start := vueTemplateStart.FindStringIndex(source)
+	if start == nil {
+		return nil, fmt.Errorf("%s has no template", name)
+	}
+	p := &markupParser{src: source, pos: start[1]}
+	nodes, err := p.nodes("template", false)
+	if err != nil {
+		return nil, err
+	}
+	c := &vueConverter{markupConverter{
+		source: source,
+		param:  cmp.Or(opts.Param, "props"),
+		vars:   map[string]bool{},
+	}}
+	c.nodes(nodes)
+	return &Result{
+		Source: c.function(componentName(strings.TrimSuffix(path.Base(name), path.Ext(name)))),
+		Issues: c.issues,
+	}, nil
+}
+
+var vueTemplateStart = regexp.MustCompile(`<template[^>]*>`)
+
+// vueFor matches the value of v-for, e.g. (post, i) in posts.
+var vueFor = regexp.MustCompile(`^\(?\s*([A-Za-z_$][\w$]*)\s*(?:,\s*([A-Za-z_$][\w$]*)\s*)?\)?\s+(?:in|of)\s+(.+)$`)
+
+type vueConverter struct {
+	markupConverter
+}
+
+// nodes converts sibling nodes. An element with v-else-if or v-else is
+// wrapped in the negations of the conditions before it.
+func (c *vueConverter) nodes(nodes []*markupNode) {
+	var chain [][2]string
+	for _, n := range nodes {
+		if n.kind == markupText && strings.TrimSpace(n.text) == "" {
+			c.out.WriteString(n.text)
+			continue
+		}
+		if n.kind != markupElement {
+			chain = nil
+			c.node(n)
+			continue
+		}
+		var wrappers [][2]string
+		if attr, ok := n.attr("v-if"); ok {
+			key, path, ok := c.condition(attr.value, "$props")
+			if !ok {
+				c.unconverted(attr.offset, attr.value, "only property paths and their negation can be converted as conditions")
+				chain = nil
+				continue
+			}
+			chain = [][2]string{{key, path}}
+			wrappers = chain
+		} else if attr, ok := n.attr("v-else-if"); ok && chain != nil {
+			key, path, ok := c.condition(attr.value, "$props")
+			if !ok {
+				c.unconverted(attr.offset, attr.value, "only property paths and their negation can be converted as conditions")
+				chain = nil
+				continue
+			}
+			wrappers = append(negate(chain), [2]string{key, path})
+			chain = append(chain, [2]string{key, path})
+		} else if _, ok := n.attr("v-else"); ok && chain != nil {
+			wrappers = negate(chain)
+			chain = nil
+		} else {
+			chain = nil
+		}
+		for _, w := range wrappers {
+			fmt.Fprintf(&c.out, `<if %s="%s">`, w[0], w[1])
+		}
+		c.element(n)
+		for range wrappers {
+			c.out.WriteString("</if>")
+		}
+	}
+}
+
+// negate returns the negations of the conditions of a chain of v-if and
+// v-else-if.
+func negate(chain [][2]string) [][2]string {
+	var result [][2]string
+	for _, cond := range chain {
+		result = append(result, [2]string{map[string]string{"true": "not", "not": "true"}[cond[0]], cond[1]})
+	}
+	return result
+}
+
+func (c *vueConverter) node(n *markupNode) {
+	switch n.kind {
+	case markupText:
+		c.out.WriteString(n.text)
+	case markupComment:
+		c.out.WriteString("<!--#" + escapeComment(n.text) + "-->")
+	case markupExpr:
+		path, ok := c.path(n.text, "$props")
+		if !ok {
+			c.unconverted(n.offset, "{{ "+n.text+" }}", "only property paths can be converted")
+			return
+		}
+		fmt.Fprintf(&c.out, `<fragment inner-text="%s"></fragment>`, path)
+	case markupElement:
+		c.element(n)
+	}
+}
+
+// element converts an element, except for its v-if, v-else-if or
+// v-else, which nodes has handled.
+func (c *vueConverter) element(n *markupNode) {
+	if attr, ok := n.attr("v-for"); ok {
+		m := vueFor.FindStringSubmatch(attr.value)
+		var each string
+		if m != nil {
+			each, ok = c.path(m[3], "$props")
+		}
+		if m == nil || !ok {
+			c.unconverted(attr.offset, attr.value, "only loops over property paths can be converted")
+			return
+		}
+		fmt.Fprintf(&c.out, `<for each="%s" as="%s"`, each, m[1])
+		if m[2] != "" {
+			fmt.Fprintf(&c.out, ` index-as="%s"`, m[2])
+		}
+		c.out.WriteString(">")
+		vars := c.vars
+		c.vars = map[string]bool{m[1]: true, m[2]: m[2] != ""}
+		for name := range vars {
+			c.vars[name] = true
+		}
+		defer func() {
+			c.vars = vars
+			c.out.WriteString("</for>")
+		}()
+	}
+	switch {
+	case n.name == "template":
+		c.nodes(n.children)
+	case n.name == "slot":
+		if name, ok := n.attr("name"); ok && name.value != "default" {
+			fmt.Fprintf(&c.out, `<slot name="%s">`, name.value)
+			c.nodes(n.children)
+			c.out.WriteString("</slot>")
+			return
+		}
+		c.out.WriteString("<children></children>")
+	case isComponent(n.name):
+		c.component(n)
+	default:
+		c.native(n)
+	}
+}
+
+func (c *vueConverter) native(n *markupNode) {
+	fmt.Fprintf(&c.out, "<%s", n.name)
+	hasInner := false
+	for _, attr := range n.attrs {
+		name, bound := vueBinding(attr.name)
+		switch {
+		case isVueControl(attr.name) || name == "key":
+		case attr.name == "v-html" || attr.name == "v-text":
+			path, ok := c.path(attr.value, "$props")
+			if !ok {
+				c.issue(attr.offset, attr.value, "only property paths can be converted")
+				continue
+			}
+			fmt.Fprintf(&c.out, ` inner-%s="%s"`, map[string]string{"v-html": "html", "v-text": "text"}[attr.name], path)
+			hasInner = true
+		case strings.HasPrefix(attr.name, "@") || strings.HasPrefix(attr.name, "v-on:"):
+			c.issue(attr.offset, attr.name+`="`+attr.value+`"`, "event handlers are not converted")
+		case strings.HasPrefix(attr.name, "v-"):
+			c.issue(attr.offset, attr.name+`="`+attr.value+`"`, fmt.Sprintf("%s is not supported", strings.SplitN(attr.name, ":", 2)[0]))
+		case bound:
+			path, ok := c.path(attr.value, "$props")
+			if !ok {
+				c.issue(attr.offset, attr.name+`="`+attr.value+`"`, "only property paths can be bound")
+				continue
+			}
+			fmt.Fprintf(&c.out, ` attr-%s="%s"`, name, path)
+		default:
+			c.writeStatic(attr.name, attr.value)
+		}
+	}
+	if expr, ok := onlyExpression(n); ok && !hasInner {
+		if path, ok := c.path(expr.text, "$props"); ok {
+			fmt.Fprintf(&c.out, ` inner-text="%s">`, path)
+			if !voidElements[n.name] {
+				fmt.Fprintf(&c.out, "</%s>", n.name)
+			}
+			return
+		}
+	}
+	c.out.WriteString(">")
+	if voidElements[n.name] {
+		return
+	}
+	c.nodes(n.children)
+	fmt.Fprintf(&c.out, "</%s>", n.name)
+}
+
+// component converts the use of a component into a render call. The
+// children of a <template #name> or <template v-slot:name> fill the
+// slot of that name.
+func (c *vueConverter) component(n *markupNode) {
+	fmt.Fprintf(&c.out, `<render function="%s"`, componentName(n.name))
+	for _, attr := range n.attrs {
+		name, bound := vueBinding(attr.name)
+		switch {
+		case isVueControl(attr.name) || name == "key":
+		case strings.HasPrefix(attr.name, "@") || strings.HasPrefix(attr.name, "v-"):
+			c.issue(attr.offset, attr.name+`="`+attr.value+`"`, "only props are converted for components")
+		case bound:
+			path, ok := c.path(attr.value, "$props")
+			if !ok {
+				c.issue(attr.offset, attr.name+`="`+attr.value+`"`, "only property paths can be passed as props")
+				continue
+			}
+			fmt.Fprintf(&c.out, ` with-%s="%s"`, propName(name), path)
+		default:
+			c.writeStatic("with-"+propName(attr.name), literal(attr.value))
+		}
+	}
+	c.out.WriteString(">")
+	for _, child := range n.children {
+		if slot, ok := vueSlotName(child); ok {
+			fmt.Fprintf(&c.out, `<fill slot="%s">`, slot)
+			c.nodes(child.children)
+			c.out.WriteString("</fill>")
+			continue
+		}
+		c.nodes([]*markupNode{child})
+	}
+	c.out.WriteString("</render>")
+}
+
+// vueBinding returns the name of the attribute that an attribute binds
+// with v-bind or its shorthand, e.g. href for :href.
+func vueBinding(name string) (string, bool) {
+	if bound, ok := strings.CutPrefix(name, ":"); ok {
+		return bound, true
+	}
+	if bound, ok := strings.CutPrefix(name, "v-bind:"); ok {
+		return bound, true
+	}
+	return name, false
+}
+
+// isVueControl reports whether an attribute is a directive that has
+// been handled before the element is converted.
+func isVueControl(name string) bool {
+	return name == "v-if" || name == "v-else-if" || name == "v-else" || name == "v-for"
+}
+
+// vueSlotName returns the slot that a <template> child of a component
+// fills.
+func vueSlotName(n *markupNode) (string, bool) {
+	if n.kind != markupElement || n.name != "template" {
+		return "", false
+	}
+	for _, attr := range n.attrs {
+		if name, ok := strings.CutPrefix(attr.name, "#"); ok {
+			return name, name != "default"
+		}
+		if name, ok := strings.CutPrefix(attr.name, "v-slot:"); ok {
+			return name, name != "default"
+		}
+	}
+	return "", false
+}
+
+// propName returns the name of a prop in camel case, e.g. postTitle
+// for post-title, since Vue passes props written in kebab case to the
+// camel case props of the component.
+func propName(name string) string {
+	parts := strings.Split(name, "-")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
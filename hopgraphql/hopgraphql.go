@@ -0,0 +1,122 @@
+// Package hopgraphql checks that a GraphQL query selects the data that
+// a hop function uses, so that a template and the query that feeds it
+// can not drift apart unnoticed:
+//
+//	t, err := program.FunctionType("pages/post", "main")
+//	...
+//	mismatches, err := hopgraphql.Check(t, query, "post")
+//
+// The query is checked on its own, without the schema of the API, so
+// only the shape of the selection is compared with the type that the
+// compiler inferred for the function: every field that the function
+// uses must be selected, with a selection set if the function uses its
+// fields and without one if it uses it as a string, number or boolean.
+// Lists are transparent, as in GraphQL, so the elements of an array are
+// compared with the selection of the field.
+package hopgraphql
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hoplang/hop-go/hoptype"
+)
+
+// Mismatch is a field that the function uses but the query does not
+// select as needed.
+type Mismatch struct {
+	// Path is the path of the field in the parameter of the function,
+	// e.g. post.author.name.
+	Path    string
+	Message string
+}
+
+func (m Mismatch) String() string {
+	return fmt.Sprintf("%s: %s", m.Path, m.Message)
+}
+
+// Check compares the selection of the first operation of query with
+// the parameter type t of a function. The root is the path of the
+// field of the response data that is passed to the function, e.g.
+// "post" for query { post { title } }, or empty if the whole data is
+// passed. The mismatches are sorted by path.
+func Check(t *hoptype.Type, query string, root string) ([]Mismatch, error) {
+	doc, err := parse(query)
+	if err != nil {
+		return nil, err
+	}
+	selection := doc.operation
+	path := ""
+	if root != "" {
+		for _, name := range strings.Split(root, ".") {
+			f, ok := selection.fields[name]
+			if !ok || f.selection == nil {
+				return nil, fmt.Errorf("the query does not select %s with a selection set", root)
+			}
+			selection = f.selection
+		}
+		path = root
+	}
+	var mismatches []Mismatch
+	compare(t, selection, path, &mismatches)
+	sort.Slice(mismatches, func(i, j int) bool {
+		return mismatches[i].Path < mismatches[j].Path
+	})
+	return mismatches, nil
+}
+
+// compare compares a type with the selection of the value at path.
+func compare(t *hoptype.Type, s *selectionSet, path string, mismatches *[]Mismatch) {
+	report := func(path string, format string, args ...any) {
+		*mismatches = append(*mismatches, Mismatch{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	switch t.Kind {
+	case hoptype.Array:
+		compare(t.Elem, s, path, mismatches)
+	case hoptype.Union:
+		var scalars []string
+		for _, member := range t.Members {
+			switch member.Kind {
+			case hoptype.String, hoptype.Number, hoptype.Boolean, hoptype.HTML, hoptype.URL, hoptype.JS, hoptype.Literal:
+				scalars = append(scalars, member.String())
+			default:
+				compare(member, s, path, mismatches)
+			}
+		}
+		if len(scalars) > 0 && s != nil {
+			report(path, "the function uses it as a %s but the query selects it with a selection set", strings.Join(scalars, " | "))
+		}
+	case hoptype.Object:
+		if s == nil {
+			report(path, "the function uses its fields but the query selects it without a selection set")
+			return
+		}
+		names := make([]string, 0, len(t.Fields))
+		for name := range t.Fields {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			fieldPath := name
+			if path != "" {
+				fieldPath = path + "." + name
+			}
+			f, ok := s.fields[name]
+			if !ok {
+				report(fieldPath, "the function uses the field but the query does not select it")
+				continue
+			}
+			compare(t.Fields[name], f.selection, fieldPath, mismatches)
+		}
+	case hoptype.Map:
+		if s == nil {
+			report(path, "the function uses its entries but the query selects it without a selection set")
+		}
+	case hoptype.String, hoptype.Number, hoptype.Boolean, hoptype.HTML, hoptype.URL, hoptype.JS, hoptype.Literal:
+		if s != nil {
+			report(path, "the function uses it as a %s but the query selects it with a selection set", t)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package hopgraphql_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hoplang/hop-go"
+	"github.com/hoplang/hop-go/hopgraphql"
+	"github.com/hoplang/hop-go/hoptype"
+)
+
+func functionType(t *testing.T, source string) *hoptype.Type {
+	t.Helper()
+	c := hop.NewCompiler()
+	c.AddModule("main", source)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	typ, err := program.FunctionType("main", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return typ
+}
+
+const postFunction = `<function name="main" params-as="post">
+<h1 inner-text="post.title"></h1>
+<p inner-text="post.author.name"></p>
+<for each="post.tags" as="tag"><span inner-text="tag.label"></span></for>
+</function>`
+
+func TestCheck(t *testing.T) {
+	typ := functionType(t, postFunction)
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			name: "matching",
+			query: `query Post($id: ID!) {
+				post(id: $id) {
+					title
+					author { name }
+					tags(first: 5) { label }
+				}
+			}`,
+		},
+		{
+			name: "fragments and aliases",
+			query: `query {
+				post(id: "1") {
+					...PostFields
+					tags { ... on Tag { label: name } }
+				}
+			}
+			fragment PostFields on Post {
+				title
+				author @include(if: true) { name }
+			}`,
+		},
+		{
+			name:  "missing fields",
+			query: `{ post { title author { id } } }`,
+			want: []string{
+				"post.author.name: the function uses the field but the query does not select it",
+				"post.tags: the function uses the field but the query does not select it",
+			},
+		},
+		{
+			name:  "wrong shapes",
+			query: `{ post { title { text } author tags { label } } }`,
+			want: []string{
+				"post.author: the function uses its fields but the query selects it without a selection set",
+				"post.title: the function uses it as a number | string but the query selects it with a selection set",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mismatches, err := hopgraphql.Check(typ, tt.query, "post")
+			if err != nil {
+				t.Fatalf("Failed to check: %s", err)
+			}
+			var got []string
+			for _, m := range mismatches {
+				got = append(got, m.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Expected\n%s\nbut got\n%s", strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+func TestCheckErrors(t *testing.T) {
+	typ := functionType(t, postFunction)
+	for query, want := range map[string]string{
+		`{ post { title }`:                   "unclosed selection set",
+		`{ post { ...Missing } }`:            "unknown fragment Missing",
+		`{ ...A } fragment A on Q { ...A }`:  "fragment A spreads itself",
+		`{ title }`:                          "does not select post",
+		`schema { query: Query }`:            "expected an operation or a fragment",
+		`fragment A on Post { title }`:       "the query has no operation",
+		`{ post(filter: "unclosed) { id } }`: "unclosed string",
+	} {
+		_, err := hopgraphql.Check(typ, query, "post")
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected an error containing %q for %s but got %v", want, query, err)
+		}
+	}
+}
@@ -0,0 +1,355 @@
+package hopgraphql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The parser reads the selection sets of a GraphQL document and skips
+// everything else, such as arguments, variables and directives, which
+// do not change the shape of the response.
+
+// selectionSet is the fields that a selection set selects, keyed by
+// their response name, i.e. their alias if they have one.
+type selectionSet struct {
+	fields map[string]*field
+	// spreads are the names of the fragments spread into the set,
+	// which are merged into the fields once the document is parsed.
+	spreads []string
+	// inline are the inline fragments of the set.
+	inline []*selectionSet
+}
+
+// field is a selected field. The selection is nil for a leaf field.
+type field struct {
+	selection *selectionSet
+}
+
+type document struct {
+	operation *selectionSet
+	fragments map[string]*selectionSet
+}
+
+type parser struct {
+	src string
+	pos int
+}
+
+func parse(query string) (*document, error) {
+	p := &parser{src: query}
+	doc := &document{fragments: map[string]*selectionSet{}}
+	for {
+		p.skipIgnored()
+		if p.pos == len(p.src) {
+			break
+		}
+		if p.peek() == '{' {
+			s, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			if doc.operation == nil {
+				doc.operation = s
+			}
+			continue
+		}
+		keyword := p.name()
+		switch keyword {
+		case "query", "mutation", "subscription":
+			// The name, variables and directives of the operation.
+			for p.skipIgnored(); p.pos < len(p.src) && p.peek() != '{'; p.skipIgnored() {
+				if err := p.skipToken(); err != nil {
+					return nil, err
+				}
+			}
+			s, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			if doc.operation == nil {
+				doc.operation = s
+			}
+		case "fragment":
+			p.skipIgnored()
+			name := p.name()
+			if name == "" {
+				return nil, p.errorf("expected the name of a fragment")
+			}
+			for p.skipIgnored(); p.pos < len(p.src) && p.peek() != '{'; p.skipIgnored() {
+				if err := p.skipToken(); err != nil {
+					return nil, err
+				}
+			}
+			s, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.fragments[name] = s
+		default:
+			return nil, p.errorf("expected an operation or a fragment")
+		}
+	}
+	if doc.operation == nil {
+		return nil, fmt.Errorf("the query has no operation")
+	}
+	if err := doc.resolve(doc.operation, map[string]bool{}); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// resolve merges the fragments of a selection set, and of the sets of
+// its fields, into their fields.
+func (doc *document) resolve(s *selectionSet, spreading map[string]bool) error {
+	for _, name := range s.spreads {
+		fragment, ok := doc.fragments[name]
+		if !ok {
+			return fmt.Errorf("unknown fragment %s", name)
+		}
+		if spreading[name] {
+			return fmt.Errorf("fragment %s spreads itself", name)
+		}
+		spreading[name] = true
+		if err := doc.resolve(fragment, spreading); err != nil {
+			return err
+		}
+		delete(spreading, name)
+		merge(s, fragment)
+	}
+	for _, inline := range s.inline {
+		if err := doc.resolve(inline, spreading); err != nil {
+			return err
+		}
+		merge(s, inline)
+	}
+	s.spreads, s.inline = nil, nil
+	for _, f := range s.fields {
+		if f.selection != nil {
+			if err := doc.resolve(f.selection, spreading); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// merge adds the fields and fragments of from to s.
+func merge(s, from *selectionSet) {
+	s.spreads = append(s.spreads, from.spreads...)
+	s.inline = append(s.inline, from.inline...)
+	for name, f := range from.fields {
+		existing, ok := s.fields[name]
+		switch {
+		case !ok:
+			s.fields[name] = f
+		case existing.selection != nil && f.selection != nil:
+			merged := &selectionSet{fields: map[string]*field{}}
+			merge(merged, existing.selection)
+			merge(merged, f.selection)
+			s.fields[name] = &field{selection: merged}
+		}
+	}
+}
+
+func (p *parser) selectionSet() (*selectionSet, error) {
+	p.skipIgnored()
+	if p.pos == len(p.src) || p.peek() != '{' {
+		return nil, p.errorf("expected {")
+	}
+	p.pos++
+	s := &selectionSet{fields: map[string]*field{}}
+	for {
+		p.skipIgnored()
+		if p.pos == len(p.src) {
+			return nil, p.errorf("unclosed selection set")
+		}
+		if p.peek() == '}' {
+			p.pos++
+			return s, nil
+		}
+		if strings.HasPrefix(p.src[p.pos:], "...") {
+			p.pos += 3
+			p.skipIgnored()
+			name := p.name()
+			if name != "" && name != "on" {
+				s.spreads = append(s.spreads, name)
+				if err := p.skipDirectives(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if name == "on" {
+				p.skipIgnored()
+				p.name()
+			}
+			if err := p.skipDirectives(); err != nil {
+				return nil, err
+			}
+			inline, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			s.inline = append(s.inline, inline)
+			continue
+		}
+		name := p.name()
+		if name == "" {
+			return nil, p.errorf("expected a field")
+		}
+		p.skipIgnored()
+		if p.pos < len(p.src) && p.peek() == ':' {
+			// An alias, which is the name of the field in the response.
+			p.pos++
+			p.skipIgnored()
+			if p.name() == "" {
+				return nil, p.errorf("expected a field after the alias %s", name)
+			}
+			p.skipIgnored()
+		}
+		if p.pos < len(p.src) && p.peek() == '(' {
+			if err := p.skipBalanced('(', ')'); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.skipDirectives(); err != nil {
+			return nil, err
+		}
+		f := &field{}
+		if p.pos < len(p.src) && p.peek() == '{' {
+			selection, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			f.selection = selection
+		}
+		if existing, ok := s.fields[name]; ok && existing.selection != nil && f.selection != nil {
+			merge(existing.selection, f.selection)
+			continue
+		}
+		s.fields[name] = f
+	}
+}
+
+// skipDirectives skips directives such as @include(if: $x).
+func (p *parser) skipDirectives() error {
+	for p.skipIgnored(); p.pos < len(p.src) && p.peek() == '@'; p.skipIgnored() {
+		p.pos++
+		p.name()
+		p.skipIgnored()
+		if p.pos < len(p.src) && p.peek() == '(' {
+			if err := p.skipBalanced('(', ')'); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// skipToken skips a name, a string, a punctuator or the arguments or
+// variable definitions in parentheses.
+func (p *parser) skipToken() error {
+	switch c := p.peek(); {
+	case c == '(':
+		return p.skipBalanced('(', ')')
+	case c == '"':
+		return p.skipString()
+	case p.name() != "":
+		return nil
+	default:
+		p.pos++
+		return nil
+	}
+}
+
+// skipBalanced skips the text between open and the matching close,
+// including strings that contain them.
+func (p *parser) skipBalanced(open, close byte) error {
+	start := p.pos
+	depth := 0
+	for p.pos < len(p.src) {
+		switch p.peek() {
+		case '"':
+			if err := p.skipString(); err != nil {
+				return err
+			}
+			continue
+		case '#':
+			p.skipIgnored()
+			continue
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				p.pos++
+				return nil
+			}
+		}
+		p.pos++
+	}
+	p.pos = start
+	return p.errorf("unclosed %c", open)
+}
+
+func (p *parser) skipString() error {
+	if strings.HasPrefix(p.src[p.pos:], `"""`) {
+		end := strings.Index(p.src[p.pos+3:], `"""`)
+		if end < 0 {
+			return p.errorf("unclosed block string")
+		}
+		p.pos += end + 6
+		return nil
+	}
+	for i := p.pos + 1; i < len(p.src); i++ {
+		switch p.src[i] {
+		case '\\':
+			i++
+		case '"':
+			p.pos = i + 1
+			return nil
+		case '\n':
+			return p.errorf("unclosed string")
+		}
+	}
+	return p.errorf("unclosed string")
+}
+
+// name reads a name, or returns the empty string if there is none at
+// the current position.
+func (p *parser) name() string {
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || p.pos > start && '0' <= c && c <= '9' {
+			p.pos++
+			continue
+		}
+		break
+	}
+	return p.src[start:p.pos]
+}
+
+// skipIgnored skips white space, commas and comments.
+func (p *parser) skipIgnored() {
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case ' ', '\t', '\n', '\r', ',':
+			p.pos++
+		case '#':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *parser) peek() byte {
+	return p.src[p.pos]
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	line := 1 + strings.Count(p.src[:p.pos], "\n")
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,15 @@
+module github.com/hoplang/hop-go/hophttp/hopchi
+
+go 1.23.3
+
+// Builds in this repository use the hop module next to the adapter.
+// Other modules get the required release of hop, since replace
+// directives only apply to the main module.
+replace github.com/hoplang/hop-go => ../..
+
+require (
+	github.com/go-chi/chi/v5 v5.3.2
+	github.com/hoplang/hop-go v0.1.0
+)
+
+require golang.org/x/net v0.34.0 // indirect
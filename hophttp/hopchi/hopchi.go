@@ -0,0 +1,49 @@
+// Package hopchi renders hop templates in chi routers:
+//
+//	renderer := hophttp.New(templates)
+//	r := chi.NewRouter()
+//	r.Get("/posts/{slug}", hopchi.Handler(renderer, "pages/post", loadPost))
+//
+// Chi handlers are net/http handlers, so hophttp does the rendering. The
+// handlers of this package add the URL parameters of the matched route
+// to the locals of the request, as an object named params, so that the
+// template of the example can use params.slug.
+//
+// The package is a separate module, so that the hop module does not
+// depend on chi.
+package hopchi
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/hoplang/hop-go/hophttp"
+)
+
+// Handler returns a handler that renders the template with the given
+// name, e.g. "pages/post", with the data that load returns, as
+// hophttp.Renderer.Handler does. The URL parameters of the route are
+// added to the locals of the request as params. If load or the render
+// fails, the response is written by the ErrorHandler of rr.
+func Handler(rr *hophttp.Renderer, name string, load func(r *http.Request) (any, error)) http.HandlerFunc {
+	handler := rr.Handler(name, load)
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, WithURLParams(r))
+	}
+}
+
+// WithURLParams returns a copy of r whose locals have the URL parameters
+// of the route that chi matched, as an object named params. Handlers
+// that call hophttp.Renderer.Render themselves use it to give their
+// templates the parameters.
+func WithURLParams(r *http.Request) *http.Request {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return r
+	}
+	params := map[string]any{}
+	for i, key := range rctx.URLParams.Keys {
+		params[key] = rctx.URLParams.Values[i]
+	}
+	return r.WithContext(hophttp.WithLocals(r.Context(), map[string]any{"params": params}))
+}
@@ -0,0 +1,58 @@
+package hopchi_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/hoplang/hop-go"
+	"github.com/hoplang/hop-go/hophttp"
+	"github.com/hoplang/hop-go/hophttp/hopchi"
+)
+
+func newRenderer(t *testing.T) *hophttp.Renderer {
+	t.Helper()
+	c := hop.NewCompiler()
+	c.AddModule("pages", `<function name="post" params-as="page"><h1 inner-text="page.title"></h1><p inner-text="page.params.slug"></p></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	return hophttp.New(hop.NewTemplateSet(program, ""))
+}
+
+func TestHandler(t *testing.T) {
+	router := chi.NewRouter()
+	router.Get("/posts/{slug}", hopchi.Handler(newRenderer(t), "pages/post", func(r *http.Request) (any, error) {
+		return map[string]any{"title": "Hello"}, nil
+	}))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/posts/hello-world", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 but got %d", w.Code)
+	}
+	if want := "<h1>Hello</h1><p>hello-world</p>"; w.Body.String() != want {
+		t.Errorf("Expected %q but got %q", want, w.Body.String())
+	}
+}
+
+func TestHandlerError(t *testing.T) {
+	renderer := newRenderer(t)
+	var handled error
+	renderer.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		handled = err
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+	errNotFound := errors.New("post not found")
+	router := chi.NewRouter()
+	router.Get("/posts/{slug}", hopchi.Handler(renderer, "pages/post", func(r *http.Request) (any, error) {
+		return nil, errNotFound
+	}))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/posts/missing", nil))
+	if !errors.Is(handled, errNotFound) || w.Code != http.StatusNotFound {
+		t.Errorf("Expected the load error to be handled but got %v and status %d", handled, w.Code)
+	}
+}
@@ -0,0 +1,25 @@
+module github.com/hoplang/hop-go/hophttp/hopecho
+
+go 1.23.3
+
+// Builds in this repository use the hop module next to the adapter.
+// Other modules get the required release of hop, since replace
+// directives only apply to the main module.
+replace github.com/hoplang/hop-go => ../..
+
+require (
+	github.com/hoplang/hop-go v0.1.0
+	github.com/labstack/echo/v4 v4.13.4
+)
+
+require (
+	github.com/labstack/gommon v0.4.2 // indirect
+	github.com/mattn/go-colorable v0.1.14 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/valyala/bytebufferpool v1.0.0 // indirect
+	github.com/valyala/fasttemplate v1.2.2 // indirect
+	golang.org/x/crypto v0.38.0 // indirect
+	golang.org/x/net v0.40.0 // indirect
+	golang.org/x/sys v0.33.0 // indirect
+	golang.org/x/text v0.25.0 // indirect
+)
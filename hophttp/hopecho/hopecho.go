@@ -0,0 +1,55 @@
+// Package hopecho renders hop templates with echo:
+//
+//	e := echo.New()
+//	e.Renderer = hopecho.New(hophttp.New(templates))
+//	e.GET("/", func(c echo.Context) error {
+//		return c.Render(http.StatusOK, "pages/home", map[string]any{"title": "Home"})
+//	})
+//
+// The locals that middleware adds with SetLocals are added to the data
+// of each render of the request. Errors are returned to echo, whose
+// HTTPErrorHandler writes the response. Echo buffers the output of a
+// render, so nothing has been written by then.
+//
+// The package is a separate module, so that the hop module does not
+// depend on echo.
+package hopecho
+
+import (
+	"io"
+
+	"github.com/hoplang/hop-go/hophttp"
+	"github.com/labstack/echo/v4"
+)
+
+// Renderer is an echo.Renderer for the templates of a hophttp.Renderer.
+type Renderer struct {
+	renderer *hophttp.Renderer
+}
+
+var _ echo.Renderer = (*Renderer)(nil)
+
+// New returns an echo.Renderer that renders with r.
+func New(r *hophttp.Renderer) *Renderer {
+	return &Renderer{renderer: r}
+}
+
+// Render renders the template with the given name, e.g. "pages/home",
+// with the locals of the request.
+func (er *Renderer) Render(w io.Writer, name string, data any, c echo.Context) error {
+	return er.renderer.Execute(w, c.Request(), name, data)
+}
+
+// SetLocals adds values for the renders of the request of c, such as
+// the current user, as hophttp.WithLocals does for net/http:
+//
+//	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+//		return func(c echo.Context) error {
+//			hopecho.SetLocals(c, map[string]any{"user": currentUser(c)})
+//			return next(c)
+//		}
+//	})
+func SetLocals(c echo.Context, locals map[string]any) {
+	r := c.Request()
+	c.SetRequest(r.WithContext(hophttp.WithLocals(r.Context(), locals)))
+}
@@ -0,0 +1,71 @@
+package hopecho_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hoplang/hop-go"
+	"github.com/hoplang/hop-go/hophttp"
+	"github.com/hoplang/hop-go/hophttp/hopecho"
+	"github.com/labstack/echo/v4"
+)
+
+func newEcho(t *testing.T) *echo.Echo {
+	t.Helper()
+	c := hop.NewCompiler()
+	c.AddModule("pages", `<function name="home" params-as="page"><h1 inner-text="page.title"></h1><p inner-text="page.user"></p></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	e := echo.New()
+	e.Renderer = hopecho.New(hophttp.New(hop.NewTemplateSet(program, "")))
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			hopecho.SetLocals(c, map[string]any{"user": "ada"})
+			return next(c)
+		}
+	})
+	return e
+}
+
+func TestRender(t *testing.T) {
+	e := newEcho(t)
+	e.GET("/", func(c echo.Context) error {
+		return c.Render(http.StatusOK, "pages/home", map[string]any{"title": "Home"})
+	})
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 but got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Expected an html content type but got %s", ct)
+	}
+	if want := "<h1>Home</h1><p>ada</p>"; w.Body.String() != want {
+		t.Errorf("Expected %q but got %q", want, w.Body.String())
+	}
+}
+
+func TestRenderError(t *testing.T) {
+	e := newEcho(t)
+	var handled error
+	e.HTTPErrorHandler = func(err error, c echo.Context) {
+		handled = err
+		_ = c.String(http.StatusInternalServerError, "error page")
+	}
+	e.GET("/", func(c echo.Context) error {
+		return c.Render(http.StatusOK, "pages/missing", nil)
+	})
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if handled == nil || errors.Is(handled, echo.ErrNotFound) {
+		t.Errorf("Expected the render error to be handled but got %v", handled)
+	}
+	if w.Code != http.StatusInternalServerError || w.Body.String() != "error page" {
+		t.Errorf("Expected the error page but got %d %q", w.Code, w.Body.String())
+	}
+}
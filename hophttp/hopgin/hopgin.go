@@ -0,0 +1,102 @@
+// Package hopgin renders hop templates with gin:
+//
+//	renderer := hopgin.New(hophttp.New(templates))
+//	router := gin.New()
+//	router.HTMLRender = renderer
+//	router.GET("/", func(c *gin.Context) {
+//		renderer.HTML(c, http.StatusOK, "pages/home", gin.H{"title": "Home"})
+//	})
+//
+// Gin passes only the name and the data of a template to its
+// HTMLRender, so c.HTML renders without the locals of the request.
+// Renderer.HTML adds them, i.e. the locals that middleware adds with
+// SetLocals. A failed render is reported as gin does, by adding the
+// error to c.Errors and aborting, with 500 Internal Server Error since
+// the output is buffered and nothing has been written.
+//
+// The package is a separate module, so that the hop module does not
+// depend on gin.
+package hopgin
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+	"github.com/hoplang/hop-go/hophttp"
+)
+
+const contentType = "text/html; charset=utf-8"
+
+// Renderer is a gin HTMLRender for the templates of a hophttp.Renderer.
+type Renderer struct {
+	renderer *hophttp.Renderer
+}
+
+var _ render.HTMLRender = (*Renderer)(nil)
+
+// New returns a gin HTMLRender that renders with r.
+func New(r *hophttp.Renderer) *Renderer {
+	return &Renderer{renderer: r}
+}
+
+// Instance returns the render of the template with the given name, e.g.
+// "pages/home", for c.HTML.
+func (gr *Renderer) Instance(name string, data any) render.Render {
+	return &htmlRender{renderer: gr.renderer, name: name, data: data}
+}
+
+// HTML writes the template with the given name as the response with the
+// given status, with the locals of the request of c.
+func (gr *Renderer) HTML(c *gin.Context, status int, name string, data any) {
+	var buf bytes.Buffer
+	if err := gr.renderer.Execute(&buf, c.Request, name, plain(data)); err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	c.Data(status, contentType, buf.Bytes())
+}
+
+// SetLocals adds values for the renders of the request of c, such as
+// the current user, as hophttp.WithLocals does for net/http:
+//
+//	router.Use(func(c *gin.Context) {
+//		hopgin.SetLocals(c, map[string]any{"user": currentUser(c)})
+//		c.Next()
+//	})
+func SetLocals(c *gin.Context, locals map[string]any) {
+	c.Request = c.Request.WithContext(hophttp.WithLocals(c.Request.Context(), locals))
+}
+
+// plain converts gin.H, the usual data of gin renders, to the map type
+// of template data.
+func plain(data any) any {
+	if h, ok := data.(gin.H); ok {
+		return map[string]any(h)
+	}
+	return data
+}
+
+// htmlRender is the render of a template for c.HTML.
+type htmlRender struct {
+	renderer *hophttp.Renderer
+	name     string
+	data     any
+}
+
+func (hr *htmlRender) Render(w http.ResponseWriter) error {
+	var buf bytes.Buffer
+	if err := hr.renderer.Execute(&buf, &http.Request{}, hr.name, plain(hr.data)); err != nil {
+		return err
+	}
+	hr.WriteContentType(w)
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+func (hr *htmlRender) WriteContentType(w http.ResponseWriter) {
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+}
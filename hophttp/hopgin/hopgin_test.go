@@ -0,0 +1,82 @@
+package hopgin_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hoplang/hop-go"
+	"github.com/hoplang/hop-go/hophttp"
+	"github.com/hoplang/hop-go/hophttp/hopgin"
+)
+
+func newRouter(t *testing.T) (*gin.Engine, *hopgin.Renderer) {
+	t.Helper()
+	c := hop.NewCompiler()
+	c.AddModule("pages", `<function name="home" params-as="page"><h1 inner-text="page.title"></h1><p inner-text="page.user"></p></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	gin.SetMode(gin.TestMode)
+	renderer := hopgin.New(hophttp.New(hop.NewTemplateSet(program, "")))
+	router := gin.New()
+	router.HTMLRender = renderer
+	router.Use(func(c *gin.Context) {
+		hopgin.SetLocals(c, map[string]any{"user": "ada"})
+		c.Next()
+	})
+	return router, renderer
+}
+
+func serve(router *gin.Engine, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+	return w
+}
+
+func TestHTML(t *testing.T) {
+	router, renderer := newRouter(t)
+	router.GET("/", func(c *gin.Context) {
+		renderer.HTML(c, http.StatusCreated, "pages/home", gin.H{"title": "Home"})
+	})
+	w := serve(router, "/")
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected status 201 but got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Expected an html content type but got %s", ct)
+	}
+	if want := "<h1>Home</h1><p>ada</p>"; w.Body.String() != want {
+		t.Errorf("Expected %q but got %q", want, w.Body.String())
+	}
+}
+
+func TestHTMLRender(t *testing.T) {
+	router, _ := newRouter(t)
+	router.GET("/", func(c *gin.Context) {
+		c.HTML(http.StatusOK, "pages/home", gin.H{"title": "Home", "user": "grace"})
+	})
+	w := serve(router, "/")
+	if want := "<h1>Home</h1><p>grace</p>"; w.Code != http.StatusOK || w.Body.String() != want {
+		t.Errorf("Expected %q but got %d %q", want, w.Code, w.Body.String())
+	}
+}
+
+func TestHTMLError(t *testing.T) {
+	router, renderer := newRouter(t)
+	var errs []*gin.Error
+	router.GET("/", func(c *gin.Context) {
+		renderer.HTML(c, http.StatusOK, "pages/missing", nil)
+		errs = c.Errors
+	})
+	w := serve(router, "/")
+	if w.Code != http.StatusInternalServerError || w.Body.Len() != 0 {
+		t.Errorf("Expected an empty 500 response but got %d %q", w.Code, w.Body.String())
+	}
+	if len(errs) != 1 {
+		t.Errorf("Expected the render error in c.Errors but got %v", errs)
+	}
+}
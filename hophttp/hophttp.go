@@ -0,0 +1,118 @@
+// Package hophttp renders hop templates as HTTP responses, for use with
+// net/http and the routers and frameworks built on it.
+//
+// The adapters for popular frameworks are in separate modules, so that
+// this module does not depend on them: hopecho implements echo.Renderer,
+// hopgin implements gin's HTMLRender and hopchi has handlers that give
+// templates the URL parameters of chi routes.
+//
+// Templates are named as in hop.TemplateSet, by module and function,
+// e.g. "pages/home".
+package hophttp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+
+	"github.com/hoplang/hop-go"
+)
+
+// Renderer renders the templates of a program for HTTP requests.
+type Renderer struct {
+	templates *hop.TemplateSet
+	// ErrorHandler writes the response for a request whose handler or
+	// render failed, see Handler. It defaults to a plain 500 Internal
+	// Server Error.
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// New returns a Renderer for the templates of a program.
+func New(templates *hop.TemplateSet) *Renderer {
+	return &Renderer{templates: templates}
+}
+
+type localsKey struct{}
+
+// WithLocals returns a copy of ctx that carries values for the renders
+// of a request, such as the current user. Middleware adds them with
+//
+//	r = r.WithContext(hophttp.WithLocals(r.Context(), map[string]any{"user": user}))
+//
+// Locals added later take precedence over earlier ones with the same
+// name.
+func WithLocals(ctx context.Context, locals map[string]any) context.Context {
+	merged := maps.Clone(Locals(ctx))
+	if merged == nil {
+		merged = map[string]any{}
+	}
+	maps.Copy(merged, locals)
+	return context.WithValue(ctx, localsKey{}, merged)
+}
+
+// Locals returns the values that WithLocals added to ctx.
+func Locals(ctx context.Context) map[string]any {
+	locals, _ := ctx.Value(localsKey{}).(map[string]any)
+	return locals
+}
+
+// Execute renders the template with the given name to w. The locals of
+// the request are added to data, which must then be a map or nil; the
+// entries of data take precedence over locals of the same name.
+func (rr *Renderer) Execute(w io.Writer, r *http.Request, name string, data any) error {
+	if locals := Locals(r.Context()); len(locals) > 0 {
+		merged := maps.Clone(locals)
+		switch data := data.(type) {
+		case nil:
+		case map[string]any:
+			maps.Copy(merged, data)
+		default:
+			return fmt.Errorf("can not add locals to data of type %T, use a map[string]any", data)
+		}
+		data = merged
+	}
+	return rr.templates.ExecuteTemplate(w, name, data)
+}
+
+// Render writes the template with the given name as the response with
+// the given status. The output is buffered, so that nothing has been
+// written when an error is returned and the caller can still respond
+// with an error page. The Content-Type header defaults to text/html.
+func (rr *Renderer) Render(w http.ResponseWriter, r *http.Request, status int, name string, data any) error {
+	var buf bytes.Buffer
+	if err := rr.Execute(&buf, r, name, data); err != nil {
+		return err
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+	w.WriteHeader(status)
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// Handler returns a handler that renders the template with the given
+// name with the data that load returns for the request. If load or the
+// render fails, the response is written by ErrorHandler.
+func (rr *Renderer) Handler(name string, load func(r *http.Request) (any, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := load(r)
+		if err == nil {
+			err = rr.Render(w, r, http.StatusOK, name, data)
+		}
+		if err != nil {
+			rr.handleError(w, r, err)
+		}
+	})
+}
+
+func (rr *Renderer) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	if rr.ErrorHandler != nil {
+		rr.ErrorHandler(w, r, err)
+		return
+	}
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
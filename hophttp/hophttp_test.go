@@ -0,0 +1,83 @@
+package hophttp_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hoplang/hop-go"
+	"github.com/hoplang/hop-go/hophttp"
+)
+
+func newRenderer(t *testing.T) *hophttp.Renderer {
+	t.Helper()
+	c := hop.NewCompiler()
+	c.AddModule("pages", `<function name="home" params-as="page"><h1 inner-text="page.title"></h1><p inner-text="page.user"></p></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	return hophttp.New(hop.NewTemplateSet(program, ""))
+}
+
+func TestHandler(t *testing.T) {
+	renderer := newRenderer(t)
+	handler := renderer.Handler("pages/home", func(r *http.Request) (any, error) {
+		return map[string]any{"title": "Home"}, nil
+	})
+	r := httptest.NewRequest("GET", "/", nil)
+	r = r.WithContext(hophttp.WithLocals(r.Context(), map[string]any{"user": "ada", "title": "Ignored"}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 but got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Expected an html content type but got %s", ct)
+	}
+	expected := "<h1>Home</h1><p>ada</p>"
+	if w.Body.String() != expected {
+		t.Errorf("Expected %q but got %q", expected, w.Body.String())
+	}
+}
+
+func TestHandlerError(t *testing.T) {
+	renderer := newRenderer(t)
+	var handled error
+	renderer.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		handled = err
+		http.Error(w, "failed", http.StatusServiceUnavailable)
+	}
+	// The render fails since the user is missing, and nothing of the
+	// page has been written.
+	handler := renderer.Handler("pages/home", func(r *http.Request) (any, error) {
+		return map[string]any{"title": "Home"}, nil
+	})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if handled == nil || w.Code != http.StatusServiceUnavailable || strings.Contains(w.Body.String(), "<h1>") {
+		t.Errorf("Expected the error handler to respond but got %d %q", w.Code, w.Body.String())
+	}
+
+	loadErr := errors.New("not found")
+	handler = renderer.Handler("pages/home", func(r *http.Request) (any, error) {
+		return nil, loadErr
+	})
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if handled != loadErr {
+		t.Errorf("Expected the error of load but got %v", handled)
+	}
+}
+
+func TestExecuteLocalsNeedMap(t *testing.T) {
+	renderer := newRenderer(t)
+	r := httptest.NewRequest("GET", "/", nil)
+	r = r.WithContext(hophttp.WithLocals(r.Context(), map[string]any{"user": "ada"}))
+	var page struct{ Title string }
+	err := renderer.Execute(&strings.Builder{}, r, "pages/home", page)
+	if err == nil || !strings.Contains(err.Error(), "can not add locals") {
+		t.Errorf("Expected an error for struct data but got %v", err)
+	}
+}
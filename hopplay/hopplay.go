@@ -0,0 +1,137 @@
+// Package hopplay provides an HTTP playground for hop templates.
+//
+// The playground serves a page where modules and JSON data can be edited
+// in the browser. Every edit is compiled on the server, and the page
+// shows the diagnostics of the compiler at their positions in the source
+// together with the rendered output.
+//
+//	http.Handle("/play/", http.StripPrefix("/play", hopplay.Handler()))
+package hopplay
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/hoplang/hop-go"
+	"github.com/hoplang/hop-go/parser"
+	"github.com/hoplang/hop-go/typechecker"
+)
+
+//go:embed index.html
+var indexHTML []byte
+
+// maxRequestSize limits the size of compile requests.
+const maxRequestSize = 1 << 20
+
+// Request is the body of a compile request.
+type Request struct {
+	// Modules maps module names to their source.
+	Modules map[string]string `json:"modules"`
+	// Module and Function name the function that is rendered.
+	Module   string `json:"module"`
+	Function string `json:"function"`
+	// Data is the parameter of the rendered function.
+	Data json.RawMessage `json:"data"`
+}
+
+// Diagnostic is an error or warning at a range of a module.
+type Diagnostic struct {
+	Severity string          `json:"severity"`
+	Module   string          `json:"module,omitempty"`
+	Start    parser.Position `json:"start"`
+	End      parser.Position `json:"end"`
+	Message  string          `json:"message"`
+}
+
+// Response is the result of a compile request.
+type Response struct {
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	Output      string       `json:"output"`
+}
+
+// Handler returns a handler serving the playground page at / and the
+// compile endpoint at /compile.
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(indexHTML)
+	})
+	mux.HandleFunc("POST /compile", func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&req); err != nil {
+			http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(Compile(req))
+	})
+	return mux
+}
+
+// Compile compiles the modules of req and renders the requested
+// function.
+func Compile(req Request) Response {
+	resp := Response{Diagnostics: []Diagnostic{}}
+	c := hop.NewCompiler()
+	for name, source := range req.Modules {
+		c.AddModule(name, source)
+	}
+	program, err := c.Compile()
+	if err != nil {
+		resp.Diagnostics = append(resp.Diagnostics, diagnostic(err))
+		return resp
+	}
+	for _, w := range program.Warnings() {
+		resp.Diagnostics = append(resp.Diagnostics, Diagnostic{
+			Severity: "warning",
+			Module:   w.Module,
+			Start:    w.Pos,
+			End:      w.Pos,
+			Message:  w.Message,
+		})
+	}
+	if req.Module == "" || req.Function == "" {
+		return resp
+	}
+	var data any
+	if len(req.Data) > 0 {
+		if err := json.Unmarshal(req.Data, &data); err != nil {
+			resp.Diagnostics = append(resp.Diagnostics, Diagnostic{
+				Severity: "error",
+				Message:  "invalid data: " + err.Error(),
+			})
+			return resp
+		}
+	}
+	var output strings.Builder
+	if err := program.ExecuteFunction(&output, req.Module, req.Function, data); err != nil {
+		resp.Diagnostics = append(resp.Diagnostics, diagnostic(err))
+	}
+	resp.Output = output.String()
+	return resp
+}
+
+// diagnostic converts an error of the compiler to a diagnostic, using
+// its position if it has one.
+func diagnostic(err error) Diagnostic {
+	d := Diagnostic{Severity: "error", Message: err.Error()}
+	var parseError *parser.ParseError
+	var typeError *typechecker.TypeError
+	switch {
+	case errors.As(err, &parseError):
+		d.Module = parseError.Module
+		d.Start = parseError.Pos
+		d.End = parseError.Pos
+		d.Message = parseError.Message
+	case errors.As(err, &typeError):
+		d.Module = typeError.Module
+		d.Start = typeError.Start
+		d.End = typeError.End
+		d.Message = "type error: " + typeError.Context
+	}
+	return d
+}
@@ -0,0 +1,65 @@
+package hopplay_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hoplang/hop-go/hopplay"
+)
+
+func TestCompile(t *testing.T) {
+	server := httptest.NewServer(hopplay.Handler())
+	defer server.Close()
+
+	post := func(body string) hopplay.Response {
+		t.Helper()
+		resp, err := http.Post(server.URL+"/compile", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Failed to post: %s", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200 but got %d", resp.StatusCode)
+		}
+		var result hopplay.Response
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatalf("Failed to decode response: %s", err)
+		}
+		return result
+	}
+
+	result := post(`{
+		"modules": {"main": "<function name=\"main\" params-as=\"p\"><h1 inner-text=\"p.title\"></h1></function>"},
+		"module": "main",
+		"function": "main",
+		"data": {"title": "Hello"}
+	}`)
+	if len(result.Diagnostics) != 0 || result.Output != "<h1>Hello</h1>" {
+		t.Errorf("Expected rendered output but got %+v", result)
+	}
+
+	result = post(`{
+		"modules": {"main": "<function name=\"main\" params-as=\"p\">\n<if true=\"p.a\"></if><h1 inner-text=\"p.a\"></h1></function>"},
+		"module": "main",
+		"function": "main"
+	}`)
+	if len(result.Diagnostics) != 1 {
+		t.Fatalf("Expected a single diagnostic but got %+v", result.Diagnostics)
+	}
+	d := result.Diagnostics[0]
+	if d.Module != "main" || d.Start.Line != 2 || !strings.HasPrefix(d.Message, "type error:") {
+		t.Errorf("Expected type error on line 2 of main but got %+v", d)
+	}
+
+	resp, err := http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatalf("Failed to get page: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200 for the playground page but got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,162 @@
+// Package hoprepl implements an interactive shell for exploring a
+// compiled hop program.
+//
+// Each line is either a command starting with a colon or a path that is
+// evaluated against the variables bound with :let and :load.
+//
+//	> :let post {"title": "Hello", "tags": ["a", "b"]}
+//	> post.tags[1]
+//	"b"
+//	> :type blog card
+//	{title: number | string}
+//	> :render blog card post
+//	<h1>Hello</h1>
+package hoprepl
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/hoplang/hop-go"
+)
+
+const help = `commands:
+  <path>                              evaluate a path, e.g. post.tags[0]
+  :let <name> <json>                  bind a variable to a JSON value
+  :load <name> <file>                 bind a variable to the contents of a JSON file
+  :vars                               list the bound variables
+  :functions                          list the functions of the program
+  :type <module> <function>           print the parameter type of a function
+  :render <module> <function> [path]  render a function with the value of a path
+  :help                               print this help`
+
+// REPL evaluates lines against a program and a set of variables.
+type REPL struct {
+	program *hop.Program
+	vars    map[string]any
+}
+
+// New returns a REPL for the given program without any variables.
+func New(program *hop.Program) *REPL {
+	return &REPL{program: program, vars: map[string]any{}}
+}
+
+// Run reads lines from r until it is exhausted and writes the result of
+// each line to w. Errors are written to w as well and do not stop the
+// REPL.
+func (r *REPL) Run(in io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(w, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(w)
+			return scanner.Err()
+		}
+		result, err := r.Eval(scanner.Text())
+		if err != nil {
+			fmt.Fprintf(w, "error: %s\n", err)
+			continue
+		}
+		if result != "" {
+			fmt.Fprintln(w, result)
+		}
+	}
+}
+
+// Eval evaluates a single line and returns its output.
+func (r *REPL) Eval(line string) (string, error) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return "", nil
+	}
+	if !strings.HasPrefix(line, ":") {
+		v, err := hop.Lookup(line, r.vars)
+		if err != nil {
+			return "", err
+		}
+		return format(v)
+	}
+	command, rest, _ := strings.Cut(line, " ")
+	rest = strings.TrimSpace(rest)
+	args := strings.Fields(rest)
+	switch command {
+	case ":help":
+		return help, nil
+	case ":let":
+		name, value, _ := strings.Cut(rest, " ")
+		if name == "" {
+			return "", fmt.Errorf("usage: :let <name> <json>")
+		}
+		return "", r.bind(name, []byte(value))
+	case ":load":
+		if len(args) != 2 {
+			return "", fmt.Errorf("usage: :load <name> <file>")
+		}
+		data, err := os.ReadFile(args[1])
+		if err != nil {
+			return "", err
+		}
+		return "", r.bind(args[0], data)
+	case ":vars":
+		return strings.Join(slices.Sorted(maps.Keys(r.vars)), "\n"), nil
+	case ":functions":
+		modules := r.program.GetModules()
+		var lines []string
+		for _, module := range slices.Sorted(maps.Keys(modules)) {
+			for _, function := range modules[module] {
+				lines = append(lines, module+" "+function)
+			}
+		}
+		return strings.Join(lines, "\n"), nil
+	case ":type":
+		if len(args) != 2 {
+			return "", fmt.Errorf("usage: :type <module> <function>")
+		}
+		t, err := r.program.FunctionType(args[0], args[1])
+		if err != nil {
+			return "", err
+		}
+		return t.String(), nil
+	case ":render":
+		if len(args) != 2 && len(args) != 3 {
+			return "", fmt.Errorf("usage: :render <module> <function> [path]")
+		}
+		var data any
+		if len(args) == 3 {
+			v, err := hop.Lookup(args[2], r.vars)
+			if err != nil {
+				return "", err
+			}
+			data = v
+		}
+		var out strings.Builder
+		if err := r.program.ExecuteFunction(&out, args[0], args[1], data); err != nil {
+			return "", err
+		}
+		return out.String(), nil
+	}
+	return "", fmt.Errorf("unknown command %s, type :help for a list of commands", command)
+}
+
+func (r *REPL) bind(name string, data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	r.vars[name] = v
+	return nil
+}
+
+func format(v any) (string, error) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
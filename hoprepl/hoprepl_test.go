@@ -0,0 +1,61 @@
+package hoprepl_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hoplang/hop-go"
+	"github.com/hoplang/hop-go/hoprepl"
+)
+
+func TestEval(t *testing.T) {
+	c := hop.NewCompiler()
+	c.AddModule("blog", `<function name="card" params-as="post"><h1 inner-text="post.title"></h1></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	r := hoprepl.New(program)
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{`:let post {"title": "Hello", "tags": ["a", "b"]}`, ""},
+		{`post.tags[1]`, `"b"`},
+		{`:vars`, "post"},
+		{`:functions`, "blog card"},
+		{`:type blog card`, "{title: number | string}"},
+		{`:render blog card post`, "<h1>Hello</h1>"},
+	}
+	for _, test := range tests {
+		got, err := r.Eval(test.line)
+		if err != nil {
+			t.Fatalf("Failed to evaluate %s: %s", test.line, err)
+		}
+		if got != test.expected {
+			t.Errorf("Expected %s to give %q but got %q", test.line, test.expected, got)
+		}
+	}
+
+	_, err = r.Eval("post.author")
+	if err == nil || !strings.Contains(err.Error(), "key not found: author") {
+		t.Errorf("Expected missing key error but got %v", err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	c := hop.NewCompiler()
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	var out strings.Builder
+	err = hoprepl.New(program).Run(strings.NewReader(":let x 1\nx\n:unknown\n"), &out)
+	if err != nil {
+		t.Fatalf("Failed to run: %s", err)
+	}
+	expected := "> > 1\n> error: unknown command :unknown, type :help for a list of commands\n> \n"
+	if out.String() != expected {
+		t.Errorf("Expected %q but got %q", expected, out.String())
+	}
+}
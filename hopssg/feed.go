@@ -0,0 +1,141 @@
+package hopssg
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+// Feed is a feed of the entries of a site, such as blog posts, that
+// can be written as RSS or Atom.
+type Feed struct {
+	Title       string
+	Link        string
+	Description string
+	Author      string
+	Updated     time.Time
+	Items       []FeedItem
+}
+
+// FeedItem is an entry of a feed.
+type FeedItem struct {
+	Title string
+	Link  string
+	// ID identifies the entry. It defaults to Link.
+	ID          string
+	Description string
+	Published   time.Time
+}
+
+func (item FeedItem) id() string {
+	if item.ID != "" {
+		return item.ID
+	}
+	return item.Link
+}
+
+// WriteRSS writes the feed as an RSS 2.0 document.
+func (f *Feed) WriteRSS(w io.Writer) error {
+	type rssItem struct {
+		Title       string `xml:"title"`
+		Link        string `xml:"link"`
+		GUID        string `xml:"guid"`
+		Description string `xml:"description,omitempty"`
+		PubDate     string `xml:"pubDate,omitempty"`
+	}
+	type rssChannel struct {
+		Title         string    `xml:"title"`
+		Link          string    `xml:"link"`
+		Description   string    `xml:"description"`
+		LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+		Items         []rssItem `xml:"item"`
+	}
+	doc := struct {
+		XMLName xml.Name   `xml:"rss"`
+		Version string     `xml:"version,attr"`
+		Channel rssChannel `xml:"channel"`
+	}{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         f.Title,
+			Link:          f.Link,
+			Description:   f.Description,
+			LastBuildDate: formatTime(f.Updated, time.RFC1123Z),
+		},
+	}
+	for _, item := range f.Items {
+		doc.Channel.Items = append(doc.Channel.Items, rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			GUID:        item.id(),
+			Description: item.Description,
+			PubDate:     formatTime(item.Published, time.RFC1123Z),
+		})
+	}
+	return writeXML(w, doc)
+}
+
+// WriteAtom writes the feed as an Atom document.
+func (f *Feed) WriteAtom(w io.Writer) error {
+	type atomLink struct {
+		Href string `xml:"href,attr"`
+	}
+	type atomAuthor struct {
+		Name string `xml:"name"`
+	}
+	type atomEntry struct {
+		Title     string   `xml:"title"`
+		Link      atomLink `xml:"link"`
+		ID        string   `xml:"id"`
+		Updated   string   `xml:"updated"`
+		Published string   `xml:"published,omitempty"`
+		Summary   string   `xml:"summary,omitempty"`
+	}
+	doc := struct {
+		XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+		Title   string      `xml:"title"`
+		Link    atomLink    `xml:"link"`
+		ID      string      `xml:"id"`
+		Updated string      `xml:"updated"`
+		Author  *atomAuthor `xml:"author,omitempty"`
+		Entries []atomEntry `xml:"entry"`
+	}{
+		Title:   f.Title,
+		Link:    atomLink{Href: f.Link},
+		ID:      f.Link,
+		Updated: formatTime(f.updated(), time.RFC3339),
+	}
+	if f.Author != "" {
+		doc.Author = &atomAuthor{Name: f.Author}
+	}
+	for _, item := range f.Items {
+		doc.Entries = append(doc.Entries, atomEntry{
+			Title:     item.Title,
+			Link:      atomLink{Href: item.Link},
+			ID:        item.id(),
+			Updated:   formatTime(item.Published, time.RFC3339),
+			Published: formatTime(item.Published, time.RFC3339),
+			Summary:   item.Description,
+		})
+	}
+	return writeXML(w, doc)
+}
+
+// updated returns the time the feed was last updated, which defaults
+// to the time of its newest item since Atom requires it.
+func (f *Feed) updated() time.Time {
+	updated := f.Updated
+	for _, item := range f.Items {
+		if item.Published.After(updated) {
+			updated = item.Published
+		}
+	}
+	return updated
+}
+
+func formatTime(t time.Time, layout string) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(layout)
+}
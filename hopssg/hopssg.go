@@ -0,0 +1,147 @@
+// Package hopssg renders the pages of a static site from a hop program.
+//
+// A site is described by the pages to render, each naming the function
+// that renders it, its data and the path of the output file. Pages are
+// rendered concurrently:
+//
+//	err := hopssg.Build(ctx, program, "public", pages, hopssg.Options{
+//		Progress: func(p hopssg.Progress) {
+//			log.Printf("%d/%d %s", p.Done, p.Total, p.Page.Path)
+//		},
+//	})
+//
+// Sitemaps and RSS or Atom feeds can be generated from the same pages
+// and data with SitemapURLs, WriteSitemap and Feed.
+package hopssg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"iter"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/hoplang/hop-go"
+)
+
+// Page is a page of the site.
+type Page struct {
+	Module   string
+	Function string
+	Data     any
+	// Path is the path of the output file, relative to the output
+	// directory, e.g. "blog/hello/index.html".
+	Path string
+}
+
+// Progress reports that a page has been written.
+type Progress struct {
+	Page Page
+	// Done is the number of pages written so far, and Total the
+	// number of pages of the site or 0 if it is not known in advance.
+	Done  int
+	Total int
+}
+
+// Options configures a build.
+type Options struct {
+	// Concurrency is the number of pages rendered at once. It defaults
+	// to the number of CPUs.
+	Concurrency int
+	// Progress, if set, is called after each page is written. Calls
+	// are not concurrent.
+	Progress func(Progress)
+}
+
+// Build renders pages into the directory dir, creating directories as
+// needed. It stops at the first page that fails and returns its error.
+func Build(ctx context.Context, program *hop.Program, dir string, pages []Page, opts Options) error {
+	return build(ctx, program, dir, func(yield func(Page, error) bool) {
+		for _, page := range pages {
+			if !yield(page, nil) {
+				return
+			}
+		}
+	}, len(pages), opts)
+}
+
+// BuildSeq is like Build but takes the pages from an iterator, so the
+// data of a page can be loaded while other pages are rendered. An error
+// yielded by the iterator stops the build.
+func BuildSeq(ctx context.Context, program *hop.Program, dir string, pages iter.Seq2[Page, error], opts Options) error {
+	return build(ctx, program, dir, pages, 0, opts)
+}
+
+func build(ctx context.Context, program *hop.Program, dir string, pages iter.Seq2[Page, error], total int, opts Options) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+
+	var (
+		mu   sync.Mutex
+		done int
+		wg   sync.WaitGroup
+	)
+	work := make(chan Page)
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for page := range work {
+				if err := writePage(program, dir, page); err != nil {
+					cancel(err)
+					continue
+				}
+				mu.Lock()
+				done++
+				if opts.Progress != nil {
+					opts.Progress(Progress{Page: page, Done: done, Total: total})
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for page, err := range pages {
+		if err != nil {
+			cancel(err)
+			break
+		}
+		select {
+		case work <- page:
+			continue
+		case <-ctx.Done():
+		}
+		break
+	}
+	close(work)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+	return nil
+}
+
+// writePage renders a page and writes it to its path below dir.
+func writePage(program *hop.Program, dir string, page Page) error {
+	if !filepath.IsLocal(filepath.FromSlash(page.Path)) {
+		return fmt.Errorf("%s: page path must be relative and within the output directory", page.Path)
+	}
+	var buf bytes.Buffer
+	if err := program.ExecuteFunction(&buf, page.Module, page.Function, page.Data); err != nil {
+		return fmt.Errorf("%s: %w", page.Path, err)
+	}
+	path := filepath.Join(dir, filepath.FromSlash(page.Path))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
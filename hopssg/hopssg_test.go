@@ -0,0 +1,225 @@
+package hopssg_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hoplang/hop-go"
+	"github.com/hoplang/hop-go/hopssg"
+)
+
+func compile(t *testing.T) *hop.Program {
+	t.Helper()
+	c := hop.NewCompiler()
+	c.AddModule("blog", `<function name="post" params-as="post"><h1 inner-text="post.title"></h1></function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	return program
+}
+
+func TestBuild(t *testing.T) {
+	program := compile(t)
+	dir := t.TempDir()
+	var pages []hopssg.Page
+	for i := range 20 {
+		pages = append(pages, hopssg.Page{
+			Module:   "blog",
+			Function: "post",
+			Data:     map[string]any{"title": fmt.Sprintf("Post %d", i)},
+			Path:     fmt.Sprintf("posts/%d/index.html", i),
+		})
+	}
+	var progress []hopssg.Progress
+	err := hopssg.Build(context.Background(), program, dir, pages, hopssg.Options{
+		Concurrency: 4,
+		Progress: func(p hopssg.Progress) {
+			progress = append(progress, p)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to build: %s", err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "posts", "7", "index.html"))
+	if err != nil {
+		t.Fatalf("Failed to read page: %s", err)
+	}
+	if string(content) != "<h1>Post 7</h1>" {
+		t.Errorf("Expected <h1>Post 7</h1> but got %s", content)
+	}
+	if len(progress) != 20 || progress[19].Done != 20 || progress[19].Total != 20 {
+		t.Errorf("Expected progress for 20 pages but got %+v", progress)
+	}
+}
+
+func TestBuildSeq(t *testing.T) {
+	program := compile(t)
+	dir := t.TempDir()
+	pages := func(yield func(hopssg.Page, error) bool) {
+		for i := range 3 {
+			page := hopssg.Page{
+				Module:   "blog",
+				Function: "post",
+				Data:     map[string]any{"title": "ok"},
+				Path:     fmt.Sprintf("%d.html", i),
+			}
+			if !yield(page, nil) {
+				return
+			}
+		}
+		yield(hopssg.Page{}, fmt.Errorf("database is down"))
+	}
+	err := hopssg.BuildSeq(context.Background(), program, dir, pages, hopssg.Options{})
+	if err == nil || err.Error() != "database is down" {
+		t.Errorf("Expected the error of the iterator but got %v", err)
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	program := compile(t)
+	for _, tc := range []struct {
+		page hopssg.Page
+		want string
+	}{
+		{hopssg.Page{Module: "blog", Function: "post", Data: map[string]any{"title": "x"}, Path: "../escape.html"}, "must be relative"},
+		{hopssg.Page{Module: "blog", Function: "post", Data: map[string]any{}, Path: "broken.html"}, "broken.html: "},
+	} {
+		err := hopssg.Build(context.Background(), program, t.TempDir(), []hopssg.Page{tc.page}, hopssg.Options{})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Expected error containing %q but got %v", tc.want, err)
+		}
+	}
+}
+
+func TestSitemap(t *testing.T) {
+	pages := []hopssg.Page{
+		{Path: "index.html"},
+		{Path: "blog/hello world/index.html"},
+		{Path: "about.html"},
+	}
+	urls, err := hopssg.SitemapURLs("https://example.com/site", pages)
+	if err != nil {
+		t.Fatalf("Failed to create sitemap URLs: %s", err)
+	}
+	urls[2].LastMod = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	if err := hopssg.WriteSitemap(&buf, urls); err != nil {
+		t.Fatalf("Failed to write sitemap: %s", err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>https://example.com/site/</loc>
+  </url>
+  <url>
+    <loc>https://example.com/site/blog/hello%20world/</loc>
+  </url>
+  <url>
+    <loc>https://example.com/site/about.html</loc>
+    <lastmod>2024-05-01T12:00:00Z</lastmod>
+  </url>
+</urlset>
+`
+	if buf.String() != want {
+		t.Errorf("Expected %s but got %s", want, buf.String())
+	}
+}
+
+func TestFeed(t *testing.T) {
+	feed := &hopssg.Feed{
+		Title: "Blog & news",
+		Link:  "https://example.com/",
+		Items: []hopssg.FeedItem{{
+			Title:     "Hello",
+			Link:      "https://example.com/hello/",
+			Published: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		}},
+	}
+	var rss, atom bytes.Buffer
+	if err := feed.WriteRSS(&rss); err != nil {
+		t.Fatalf("Failed to write RSS: %s", err)
+	}
+	if err := feed.WriteAtom(&atom); err != nil {
+		t.Fatalf("Failed to write Atom: %s", err)
+	}
+	for _, s := range []string{
+		`<rss version="2.0">`,
+		`<title>Blog &amp; news</title>`,
+		`<guid>https://example.com/hello/</guid>`,
+		`<pubDate>Wed, 01 May 2024 12:00:00 +0000</pubDate>`,
+	} {
+		if !strings.Contains(rss.String(), s) {
+			t.Errorf("Expected RSS to contain %s but got %s", s, rss.String())
+		}
+	}
+	for _, s := range []string{
+		`<feed xmlns="http://www.w3.org/2005/Atom">`,
+		`<updated>2024-05-01T12:00:00Z</updated>`,
+		`<link href="https://example.com/hello/"></link>`,
+	} {
+		if !strings.Contains(atom.String(), s) {
+			t.Errorf("Expected Atom to contain %s but got %s", s, atom.String())
+		}
+	}
+}
+
+func TestPaginator(t *testing.T) {
+	c := hop.NewCompiler()
+	c.AddModule("blog", `<function name="list" params-as="p">
+<h1 inner-text="p.title"></h1>
+<for each="p.items" as="post"><li inner-text="post.title"></li></for>
+<if not="p.page.first"><a attr-href="p.page.prev">prev</a></if>
+<span inner-text="p.page.number"></span>/<span inner-text="p.page.count"></span>
+<if not="p.page.last"><a attr-href="p.page.next">next</a></if>
+</function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	type post struct {
+		Title string `json:"title"`
+	}
+	paginator := hopssg.Paginator[post]{
+		Items:    []post{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}},
+		Size:     2,
+		Module:   "blog",
+		Function: "list",
+		Path: func(number int) string {
+			if number == 1 {
+				return "index.html"
+			}
+			return fmt.Sprintf("page/%d.html", number)
+		},
+		Data: map[string]any{"title": "Posts"},
+	}
+	if paginator.Count() != 3 {
+		t.Errorf("Expected 3 pages but got %d", paginator.Count())
+	}
+	if _, ok := paginator.Page(4); ok {
+		t.Errorf("Expected no page 4")
+	}
+
+	dir := t.TempDir()
+	if err := hopssg.BuildSeq(context.Background(), program, dir, paginator.All(), hopssg.Options{}); err != nil {
+		t.Fatalf("Failed to build: %s", err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "page", "2.html"))
+	if err != nil {
+		t.Fatalf("Failed to read page: %s", err)
+	}
+	want := `<h1>Posts</h1>
+<li>c</li><li>d</li>
+<a href="index.html">prev</a>
+<span>2</span>/<span>3</span>
+<a href="page/3.html">next</a>`
+	if strings.TrimSpace(string(content)) != want {
+		t.Errorf("Expected %s but got %s", want, content)
+	}
+}
@@ -0,0 +1,93 @@
+package hopssg
+
+import (
+	"iter"
+	"maps"
+)
+
+// Paginator splits items into pages of Size items that are each
+// rendered by the same function. The function is passed an object with
+// the items of the page and its metadata, together with the fields of
+// Data:
+//
+//	{
+//		items: []T,
+//		page: {number, count, size, total: number, first, last: boolean, prev, next: string},
+//	}
+//
+// where number counts from 1, total is the number of items and prev
+// and next are the paths of the neighbouring pages, or empty at the
+// ends.
+type Paginator[T any] struct {
+	Items    []T
+	Size     int
+	Module   string
+	Function string
+	// Path returns the output path of the page with the given number.
+	Path func(number int) string
+	// Data holds additional fields passed to every page.
+	Data map[string]any
+}
+
+// Count returns the number of pages. There is always at least one page,
+// so that an empty list can still be rendered.
+func (p Paginator[T]) Count() int {
+	if p.Size <= 0 || len(p.Items) == 0 {
+		return 1
+	}
+	return (len(p.Items) + p.Size - 1) / p.Size
+}
+
+// Page returns the page with the given number, e.g. to serve it
+// dynamically. It returns false if there is no such page.
+func (p Paginator[T]) Page(number int) (Page, bool) {
+	count := p.Count()
+	if number < 1 || number > count {
+		return Page{}, false
+	}
+	items := p.Items
+	if p.Size > 0 {
+		start := min((number-1)*p.Size, len(items))
+		items = items[start:min(start+p.Size, len(items))]
+	}
+	var prev, next string
+	if number > 1 {
+		prev = p.Path(number - 1)
+	}
+	if number < count {
+		next = p.Path(number + 1)
+	}
+	data := maps.Clone(p.Data)
+	if data == nil {
+		data = map[string]any{}
+	}
+	data["items"] = items
+	data["page"] = map[string]any{
+		"number": float64(number),
+		"count":  float64(count),
+		"size":   float64(p.Size),
+		"total":  float64(len(p.Items)),
+		"first":  number == 1,
+		"last":   number == count,
+		"prev":   prev,
+		"next":   next,
+	}
+	return Page{
+		Module:   p.Module,
+		Function: p.Function,
+		Data:     data,
+		Path:     p.Path(number),
+	}, true
+}
+
+// All returns all pages in order, e.g. to pass them to BuildSeq.
+func (p Paginator[T]) All() iter.Seq2[Page, error] {
+	return func(yield func(Page, error) bool) {
+		for number := 1; number <= p.Count(); number++ {
+			page, _ := p.Page(number)
+			if !yield(page, nil) {
+				return
+			}
+		}
+	}
+}
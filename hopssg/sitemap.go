@@ -0,0 +1,74 @@
+package hopssg
+
+import (
+	"encoding/xml"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SitemapURL is an entry of a sitemap.
+type SitemapURL struct {
+	Loc string `xml:"loc"`
+	// LastMod is omitted if it is zero.
+	LastMod    time.Time `xml:"-"`
+	ChangeFreq string    `xml:"changefreq,omitempty"`
+	// Priority is omitted if it is zero.
+	Priority float64 `xml:"priority,omitempty"`
+}
+
+// SitemapURLs returns the sitemap entries of pages published below
+// baseURL, e.g. "https://example.com/". A trailing index.html is removed
+// from the path of a page.
+func SitemapURLs(baseURL string, pages []Page) ([]SitemapURL, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+	urls := make([]SitemapURL, len(pages))
+	for i, page := range pages {
+		path := page.Path
+		if path == "index.html" || strings.HasSuffix(path, "/index.html") {
+			path = strings.TrimSuffix(path, "index.html")
+		}
+		urls[i] = SitemapURL{Loc: base.ResolveReference(&url.URL{Path: path}).String()}
+	}
+	return urls, nil
+}
+
+// WriteSitemap writes a sitemap.xml document listing urls.
+func WriteSitemap(w io.Writer, urls []SitemapURL) error {
+	type entry struct {
+		SitemapURL
+		LastMod string `xml:"lastmod,omitempty"`
+	}
+	doc := struct {
+		XMLName xml.Name `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+		URLs    []entry  `xml:"url"`
+	}{}
+	for _, u := range urls {
+		e := entry{SitemapURL: u}
+		if !u.LastMod.IsZero() {
+			e.LastMod = u.LastMod.UTC().Format(time.RFC3339)
+		}
+		doc.URLs = append(doc.URLs, e)
+	}
+	return writeXML(w, doc)
+}
+
+func writeXML(w io.Writer, doc any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,136 @@
+// Package hoptest provides helpers for testing hop templates.
+//
+// Snapshot compares the output of a function with a golden file under
+// testdata/snapshots. Run the tests with the -update flag to write the
+// current output to the golden files:
+//
+//	go test ./... -update
+//
+// The package registers the -update flag, so test packages that import
+// it should not define a flag with the same name.
+package hoptest
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hoplang/hop-go"
+	"github.com/hoplang/hop-go/parser"
+	"golang.org/x/net/html"
+)
+
+var update = flag.Bool("update", false, "update the snapshot files of hoptest.Snapshot")
+
+// SnapshotDir is the directory that snapshot files are stored in,
+// relative to the directory of the test.
+var SnapshotDir = filepath.Join("testdata", "snapshots")
+
+// Snapshot renders a function with data and compares the normalized
+// output with the snapshot file of the test, which is named after the
+// test, e.g. testdata/snapshots/TestCard/featured.html for the subtest
+// featured of TestCard. A mismatch is reported as a line diff.
+//
+// When the -update flag is set, the snapshot file is written instead.
+func Snapshot(t testing.TB, program *hop.Program, moduleName string, functionName string, data any) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := program.ExecuteFunction(&buf, moduleName, functionName, data); err != nil {
+		t.Fatalf("Failed to render %s/%s: %s", moduleName, functionName, err)
+	}
+	got := Normalize(buf.String())
+	path := filepath.Join(SnapshotDir, filepath.FromSlash(t.Name())+".html")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create snapshot directory: %s", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("Failed to write snapshot: %s", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Snapshot %s does not exist, run the test with -update to create it", path)
+	}
+	if err != nil {
+		t.Fatalf("Failed to read snapshot: %s", err)
+	}
+	if string(want) != got {
+		t.Errorf("Output of %s/%s does not match snapshot %s (-want +got):\n%s",
+			moduleName, functionName, path, Diff(string(want), got))
+	}
+}
+
+// preservedTextElements are the elements whose text is not collapsed
+// by Normalize. The text of script and style is not escaped either.
+var preservedTextElements = map[string]bool{
+	"pre":      true,
+	"script":   true,
+	"style":    true,
+	"textarea": true,
+}
+
+// Normalize formats HTML with one tag per line, indented by its depth.
+// Whitespace in text is collapsed, so that changes of indentation in
+// templates do not change the normalized output. Text in elements such
+// as pre, where whitespace is significant, is only trimmed.
+func Normalize(s string) string {
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(s))
+	depth := 0
+	// preserved is the innermost element whose text is preserved.
+	preserved := ""
+	line := func(text string) {
+		b.WriteString(strings.Repeat("  ", depth))
+		b.WriteString(text)
+		b.WriteByte('\n')
+	}
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return b.String()
+		case html.TextToken:
+			text := string(z.Text())
+			if preserved != "" {
+				text = strings.TrimSpace(text)
+				if preserved != "script" && preserved != "style" {
+					text = html.EscapeString(text)
+				}
+				if text != "" {
+					line(text)
+				}
+				continue
+			}
+			if text := strings.Join(strings.Fields(text), " "); text != "" {
+				line(html.EscapeString(text))
+			}
+		case html.StartTagToken:
+			token := z.Token()
+			line(token.String())
+			if preservedTextElements[token.Data] && preserved == "" {
+				preserved = token.Data
+			}
+			if !parser.IsVoidElement(token.Data) {
+				depth++
+			}
+		case html.EndTagToken:
+			token := z.Token()
+			if parser.IsVoidElement(token.Data) {
+				continue
+			}
+			if token.Data == preserved {
+				preserved = ""
+			}
+			depth = max(depth-1, 0)
+			line(token.String())
+		default:
+			line(z.Token().String())
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package hoptest_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hoplang/hop-go"
+	"github.com/hoplang/hop-go/hoptest"
+)
+
+func compile(t *testing.T) *hop.Program {
+	t.Helper()
+	c := hop.NewCompiler()
+	c.AddModule("main", `<function name="card" params-as="post">
+	<div class="card"><h2 inner-text="post.title"></h2>
+		<pre>  a &lt; b</pre><br>
+	</div>
+</function>`)
+	program, err := c.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile: %s", err)
+	}
+	return program
+}
+
+// recorder is a testing.TB that records failures instead of reporting
+// them.
+type recorder struct {
+	testing.TB
+	failure string
+}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.failure = fmt.Sprintf(format, args...)
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.failure = fmt.Sprintf(format, args...)
+}
+
+func TestSnapshot(t *testing.T) {
+	program := compile(t)
+	t.Run("matching", func(t *testing.T) {
+		hoptest.Snapshot(t, program, "main", "card", map[string]any{"title": "Hello"})
+	})
+	t.Run("mismatch", func(t *testing.T) {
+		r := &recorder{TB: t}
+		hoptest.Snapshot(r, program, "main", "card", map[string]any{"title": "Goodbye"})
+		expected := `Output of main/card does not match snapshot testdata/snapshots/TestSnapshot/mismatch.html (-want +got):
+  <div class="card">
+    <h2>
+-     Hello
++     Goodbye
+    </h2>
+    <pre>
+      a &lt; b
+    </pre>
+    <br/>
+  </div>
+`
+		if r.failure != expected {
+			t.Errorf("Expected failure\n%s\nbut got\n%s", expected, r.failure)
+		}
+	})
+}
+
+func TestNormalize(t *testing.T) {
+	got := hoptest.Normalize("<!DOCTYPE html><ul>\n  <li>a  b</li><li><script>if (a < b) {}</script></li></ul>")
+	expected := `<!DOCTYPE html>
+<ul>
+  <li>
+    a b
+  </li>
+  <li>
+    <script>
+      if (a < b) {}
+    </script>
+  </li>
+</ul>
+`
+	if got != expected {
+		t.Errorf("Expected\n%s\nbut got\n%s", expected, got)
+	}
+}
@@ -0,0 +1,126 @@
+// Package hoptype provides a stable representation of the types
+// inferred for hop functions.
+//
+// Values of this package are fully resolved snapshots and are not
+// connected to the type inference of the compiler, so they can
+// safely be inspected, compared and serialized by callers.
+package hoptype
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kind is the kind of a type.
+type Kind int
+
+const (
+	// Any is the kind of a type that is not constrained by the template.
+	Any Kind = iota
+	Void
+	String
+	Number
+	Boolean
+	Array
+	Object
+	Union
+	// HTML, URL and JS are the kinds of trusted strings that have
+	// already been made safe for a context by the caller.
+	HTML
+	URL
+	JS
+	// Map is the kind of objects with arbitrary string keys whose
+	// values have the type Elem.
+	Map
+	// Literal is the kind of strings that can only be Value, such as
+	// the members of "draft" | "published".
+	Literal
+)
+
+var kindNames = map[Kind]string{
+	Any:     "any",
+	Void:    "void",
+	String:  "string",
+	Number:  "number",
+	Boolean: "boolean",
+	Array:   "array",
+	Object:  "object",
+	Union:   "union",
+	HTML:    "html",
+	URL:     "url",
+	JS:      "js",
+	Map:     "map",
+	Literal: "literal",
+}
+
+func (k Kind) String() string {
+	if name, ok := kindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// MarshalText encodes the kind as its name.
+func (k Kind) MarshalText() ([]byte, error) {
+	if _, ok := kindNames[k]; !ok {
+		return nil, fmt.Errorf("invalid kind %d", int(k))
+	}
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText decodes a kind from its name.
+func (k *Kind) UnmarshalText(text []byte) error {
+	for kind, name := range kindNames {
+		if name == string(text) {
+			*k = kind
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid kind %q", text)
+}
+
+// Type is a resolved type.
+//
+// Fields is only set for objects, Elem is only set for arrays and maps,
+// Members is only set for unions and Value is only set for literals.
+type Type struct {
+	Kind    Kind             `json:"kind"`
+	Fields  map[string]*Type `json:"fields,omitempty"`
+	Elem    *Type            `json:"elem,omitempty"`
+	Members []*Type          `json:"members,omitempty"`
+	Value   string           `json:"value,omitempty"`
+}
+
+// String returns the canonical string form of the type, with object
+// fields sorted by name.
+func (t *Type) String() string {
+	switch t.Kind {
+	case Array:
+		return "[]" + t.Elem.String()
+	case Map:
+		return "map[string]" + t.Elem.String()
+	case Object:
+		names := make([]string, 0, len(t.Fields))
+		for name := range t.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fields := make([]string, len(names))
+		for i, name := range names {
+			fields[i] = fmt.Sprintf("%s: %s", name, t.Fields[name])
+		}
+		return fmt.Sprintf("{%s}", strings.Join(fields, ", "))
+	case Union:
+		members := make([]string, len(t.Members))
+		for i, m := range t.Members {
+			members[i] = m.String()
+		}
+		return strings.Join(members, " | ")
+	case Literal:
+		return strconv.Quote(t.Value)
+	default:
+		return t.Kind.String()
+	}
+}
@@ -0,0 +1,37 @@
+package hoptype
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	typ := &Type{
+		Kind: Object,
+		Fields: map[string]*Type{
+			"title": {Kind: String},
+			"tags":  {Kind: Array, Elem: &Type{Kind: String}},
+			"links": {Kind: Map, Elem: &Type{Kind: URL}},
+			"count": {Kind: Union, Members: []*Type{{Kind: String}, {Kind: Number}}},
+			"extra": {Kind: Any},
+			"state": {Kind: Union, Members: []*Type{{Kind: Literal, Value: "draft"}, {Kind: Literal, Value: "published"}}},
+		},
+	}
+
+	data, err := json.Marshal(typ)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %s", err)
+	}
+	var got Type
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Failed to unmarshal: %s", err)
+	}
+	if !reflect.DeepEqual(&got, typ) {
+		t.Errorf("Expected %s but got %s", typ, &got)
+	}
+	expected := `{count: string | number, extra: any, links: map[string]url, state: "draft" | "published", tags: []string, title: string}`
+	if got.String() != expected {
+		t.Errorf("Expected %s but got %s", expected, got.String())
+	}
+}
@@ -0,0 +1,105 @@
+package hop
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/hoplang/hop-go/parser"
+	"golang.org/x/net/html"
+)
+
+// Translator returns the translation of the text of a `t` tag into the
+// language of a render.
+type Translator func(text string) string
+
+// WithTranslator returns a copy of the program that renders the text of
+// `t` tags translated by translate:
+//
+//	<button><t>Sign up</t></button>
+//
+// The text is trimmed of surrounding whitespace before it is translated,
+// and the translation is escaped like any other text. Programs without a
+// translator render the text as it is.
+func (p *Program) WithTranslator(translate Translator) *Program {
+	withTranslator := *p
+	withTranslator.translator = translate
+	return &withTranslator
+}
+
+// evaluateTranslation evaluates a `t` tag to its translated text.
+func (p *Program) evaluateTranslation(n *html.Node) []*html.Node {
+	var text strings.Builder
+	for c := range n.ChildNodes() {
+		text.WriteString(c.Data)
+	}
+	translated := strings.TrimSpace(text.String())
+	if p.translator != nil {
+		translated = p.translator(translated)
+	}
+	return []*html.Node{{Type: html.TextNode, Data: translated}}
+}
+
+// untranslatedTextSkipped are the elements whose text is not shown to
+// the user as is, or is already marked for translation.
+var untranslatedTextSkipped = map[string]bool{
+	"t":      true,
+	"script": true,
+	"style":  true,
+}
+
+// LintUntranslatedText reports literal text that is visible to the
+// user but not wrapped in a `t` tag, see WithTranslator, to help find
+// strings that still need to be translated.
+//
+// Text without any letters, such as numbers and punctuation, is never
+// reported. Text that is equal to one of the allowed strings after
+// trimming surrounding whitespace is not reported either, which is
+// useful for brand names and other text that is never translated.
+func (p *Program) LintUntranslatedText(allowed []string) []Warning {
+	var warnings []Warning
+	for _, moduleName := range slices.Sorted(maps.Keys(p.modules)) {
+		mod := p.modules[moduleName]
+		var visit func(n *html.Node)
+		visit = func(n *html.Node) {
+			if n.Type == html.ElementNode && untranslatedTextSkipped[n.Data] {
+				return
+			}
+			if n.Type == html.TextNode {
+				text := strings.TrimSpace(n.Data)
+				if strings.IndexFunc(text, unicode.IsLetter) >= 0 && !slices.Contains(allowed, text) {
+					warnings = append(warnings, Warning{
+						Module:  moduleName,
+						File:    mod.path,
+						Pos:     textStart(n, mod.nodePositions[n].Start),
+						Message: fmt.Sprintf("text %q is not translated", text),
+					})
+				}
+			}
+			for c := range n.ChildNodes() {
+				visit(c)
+			}
+		}
+		visit(mod.root)
+	}
+	return warnings
+}
+
+// textStart returns the position of the first non-whitespace character
+// of a text node that starts at pos.
+func textStart(n *html.Node, pos parser.Position) parser.Position {
+	for _, r := range n.Data {
+		if !unicode.IsSpace(r) {
+			break
+		}
+		if r == '\n' {
+			pos.Line++
+			pos.Column = 1
+		} else {
+			pos.Column++
+		}
+	}
+	return pos
+}
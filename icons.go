@@ -0,0 +1,205 @@
+package hop
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/hoplang/hop-go/parser"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// icon is an SVG of an icon set, converted to a `symbol` element.
+type icon struct {
+	symbol *html.Node
+}
+
+// AddIcons registers the SVG files of fsys as icons that templates can
+// inline with the `icon` tag. An icon is named by its path without the
+// .svg extension:
+//
+//	<icon name="arrows/left" class="size-4"></icon>
+//
+// renders an `svg` element that uses the icon as a `symbol`. The symbol
+// itself is only emitted the first time an icon is rendered by
+// ExecuteFunction, so icons that are repeated, such as in a list, are
+// not duplicated in the output.
+//
+// Icons are inlined when the program is compiled, and an icon that is
+// not registered is a compile error.
+func (c *Compiler) AddIcons(fsys fs.FS) error {
+	icons := map[string]icon{}
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(name) != ".svg" {
+			return nil
+		}
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		iconName := strings.TrimSuffix(name, ".svg")
+		symbol, err := parseIcon(b, iconName)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		icons[iconName] = icon{symbol: symbol}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if c.icons == nil {
+		c.icons = map[string]icon{}
+	}
+	for name, icon := range icons {
+		c.icons[name] = icon
+	}
+	return nil
+}
+
+// parseIcon parses an SVG file into a `symbol` element with the
+// viewBox, presentation attributes and children of its `svg` element.
+func parseIcon(b []byte, name string) (*html.Node, error) {
+	nodes, err := html.ParseFragment(bytes.NewReader(b), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var svg *html.Node
+	for _, n := range nodes {
+		if n.Type == html.ElementNode && n.Data == "svg" {
+			svg = n
+			break
+		}
+	}
+	if svg == nil {
+		return nil, fmt.Errorf("no svg element")
+	}
+	symbol := &html.Node{
+		Type: html.ElementNode,
+		Data: "symbol",
+		Attr: []html.Attribute{{Key: "id", Val: iconID(name)}},
+	}
+	for _, attr := range svg.Attr {
+		switch {
+		case attr.Namespace != "", attr.Key == "xmlns", attr.Key == "id", attr.Key == "class",
+			attr.Key == "width", attr.Key == "height", attr.Key == "style":
+		default:
+			symbol.Attr = append(symbol.Attr, html.Attribute{Key: attr.Key, Val: attr.Val})
+		}
+	}
+	for c := svg.FirstChild; c != nil; {
+		next := c.NextSibling
+		svg.RemoveChild(c)
+		if c.Type != html.CommentNode {
+			symbol.AppendChild(c)
+		}
+		c = next
+	}
+	return symbol, nil
+}
+
+// iconIDEscaper escapes the slashes of icon names for ids. Since an
+// underscore is doubled, different names never share an id.
+var iconIDEscaper = strings.NewReplacer("_", "__", "/", "_-")
+
+// iconID returns the id of the symbol of an icon.
+func iconID(name string) string {
+	return "icon-" + iconIDEscaper.Replace(name)
+}
+
+// resolveIcons inlines the symbols of the `icon` elements below n.
+func resolveIcons(n *html.Node, positions map[*html.Node]parser.NodePosition, icons map[string]icon) error {
+	for c := range n.ChildNodes() {
+		if err := resolveIcons(c, positions, icons); err != nil {
+			return err
+		}
+	}
+	if n.Type != html.ElementNode || n.Data != "icon" {
+		return nil
+	}
+	errorf := func(format string, args ...any) error {
+		return &parser.ParseError{
+			Pos:     positions[n].Start,
+			Message: "parse error: " + fmt.Sprintf(format, args...),
+		}
+	}
+	name, ok := getAttribute(n, "name")
+	if !ok {
+		return errorf("icon is missing attribute 'name'")
+	}
+	if _, ok := getAttribute(n, "inner-text"); ok {
+		return errorf("icon can not have inner-text")
+	}
+	if _, ok := getAttribute(n, "inner-html"); ok {
+		return errorf("icon can not have inner-html")
+	}
+	if n.FirstChild != nil {
+		return errorf("icon can not have children")
+	}
+	icon, ok := icons[name]
+	if !ok {
+		return errorf("unknown icon '%s'", name)
+	}
+	n.AppendChild(cloneNode(icon.symbol))
+	return nil
+}
+
+// cloneNode returns a deep copy of n.
+func cloneNode(n *html.Node) *html.Node {
+	clone := &html.Node{
+		Type:      n.Type,
+		Data:      n.Data,
+		DataAtom:  n.DataAtom,
+		Namespace: n.Namespace,
+		Attr:      append([]html.Attribute(nil), n.Attr...),
+	}
+	for c := range n.ChildNodes() {
+		clone.AppendChild(cloneNode(c))
+	}
+	return clone
+}
+
+// evaluateIcon evaluates an `icon` tag whose symbol has been inlined by
+// resolveIcons. The symbol is left out if the icon has already been
+// rendered by the current execution.
+func (p *Program) evaluateIcon(currentModule string, n *html.Node, s map[string]any) ([]*html.Node, error) {
+	nodes, err := p.evaluateNative(currentModule, n, s)
+	if err != nil {
+		return nil, err
+	}
+	svg := nodes[0]
+	svg.Data = "svg"
+	svg.DataAtom = atom.Svg
+	var name string
+	var attrs []html.Attribute
+	for _, attr := range svg.Attr {
+		if attr.Key == "name" {
+			name = attr.Val
+		} else {
+			attrs = append(attrs, attr)
+		}
+	}
+	svg.Attr = attrs
+	if p.renderedIcons != nil {
+		if p.renderedIcons[name] {
+			svg.RemoveChild(svg.FirstChild)
+		}
+		p.renderedIcons[name] = true
+	}
+	svg.AppendChild(&html.Node{
+		Type: html.ElementNode,
+		Data: "use",
+		Attr: []html.Attribute{{Key: "href", Val: "#" + iconID(name)}},
+	})
+	return nodes, nil
+}
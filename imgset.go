@@ -0,0 +1,111 @@
+package hop
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/hoplang/hop-go/escape"
+	"golang.org/x/net/html"
+)
+
+// ImageResolver returns the URL of an image scaled to the given width.
+type ImageResolver func(src string, width int) string
+
+// DefaultImageResolver adds the width as the query parameter w, as
+// understood by many image CDNs.
+func DefaultImageResolver(src string, width int) string {
+	u, err := url.Parse(src)
+	if err != nil {
+		return src
+	}
+	q := u.Query()
+	q.Set("w", fmt.Sprint(width))
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// SetImageResolver sets the resolver that `img-set` tags use to build
+// the URLs of the scaled images. It defaults to DefaultImageResolver.
+func (c *Compiler) SetImageResolver(resolver ImageResolver) {
+	c.imageResolver = resolver
+}
+
+// evaluateImgSet evaluates an `img-set` tag, which renders a responsive
+// image from an object with the fields src, widths and alt:
+//
+// <img-set from="post.cover" sizes="(min-width: 40em) 40em, 100vw">
+//
+// renders
+//
+// <img src="/cover.jpg?w=1280" srcset="/cover.jpg?w=640 640w, /cover.jpg?w=1280 1280w" alt="..." sizes="...">
+//
+// where src is the largest width. Other attributes are copied, except
+// priority, see appendPriority.
+func (p *Program) evaluateImgSet(n *html.Node, s map[string]any) ([]*html.Node, error) {
+	from, _ := getAttribute(n, "from")
+	v, err := p.evaluatePath(from, s)
+	if err != nil {
+		return nil, err
+	}
+	// The fields are looked up like paths, so that the image can be a
+	// map, a struct or a row.
+	field := func(name string) any {
+		v, _ := lookup(from+"."+name, s)
+		return v
+	}
+	src, srcOK := field("src").(string)
+	alt, altOK := field("alt").(string)
+	widths := reflect.ValueOf(field("widths"))
+	if !srcOK || !altOK || widths.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("image %s must have a string src, a string alt and an array of widths", stringify(v))
+	}
+	if p.urlPolicy != nil {
+		if err := p.urlPolicy("src", src); err != nil {
+			return nil, fmt.Errorf("can not use %s as src of img-set: %w", stringify(src), err)
+		}
+	}
+
+	resolver := p.imageResolver
+	if resolver == nil {
+		resolver = DefaultImageResolver
+	}
+	var candidates []string
+	largest := 0
+	for i := range widths.Len() {
+		w := widths.Index(i).Interface()
+		f, ok := numberValue(w)
+		if !ok || f <= 0 || f != float64(int(f)) {
+			return nil, fmt.Errorf("can not use '%v' as width of image, expected a positive integer", w)
+		}
+		width := int(f)
+		candidates = append(candidates, fmt.Sprintf("%s %dw", resolver(src, width), width))
+		largest = max(largest, width)
+	}
+
+	result := &html.Node{Type: html.ElementNode, Data: "img"}
+	if largest > 0 {
+		result.Attr = append(result.Attr,
+			html.Attribute{Key: "src", Val: resolver(src, largest)},
+			html.Attribute{Key: "srcset", Val: strings.Join(candidates, ", ")})
+	} else {
+		result.Attr = append(result.Attr, html.Attribute{Key: "src", Val: src})
+	}
+	result.Attr = append(result.Attr, html.Attribute{Key: "alt", Val: alt})
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "from":
+		case "priority":
+			result.Attr = appendPriority(result.Attr, n)
+		default:
+			result.Attr = append(result.Attr, attr)
+		}
+	}
+	for _, attr := range result.Attr {
+		if err := escape.CheckAttribute(attr.Val); err != nil {
+			return nil, fmt.Errorf("can not use %s as %s of img-set: %w", stringify(attr.Val), attr.Key, err)
+		}
+	}
+	return []*html.Node{result}, nil
+}
@@ -0,0 +1,146 @@
+package hop
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/hoplang/hop-go/parser"
+	"golang.org/x/net/html"
+)
+
+// includeFunction is the name of the function that holds the top-level
+// markup of a module included by an `include` tag. The function is not
+// added to the functions of the module, so it can only be reached by
+// includes and by the `extends` tags of layouts.
+const includeFunction = "#include"
+
+// resolveIncludes checks the `include` tags of every module and records
+// the modules they include in the dependency graph:
+//
+// <include module="partials/footer"></include>
+//
+// The top-level markup of an included module, i.e. everything except
+// its functions, imports, exports and metadata, is wrapped in a function that
+// is typechecked with the rest of the module. Since an include passes
+// no parameters, the markup can not reference any variables. In a
+// single-function file the markup is the single function.
+func (p *Program) resolveIncludes(dependencyGraph map[string]map[string]bool) error {
+	for _, moduleName := range slices.Sorted(maps.Keys(p.modules)) {
+		mod := p.modules[moduleName]
+		var visit func(n *html.Node) error
+		visit = func(n *html.Node) error {
+			if n.Type == html.ElementNode && n.Data == "include" {
+				if err := p.resolveInclude(moduleName, n, dependencyGraph); err != nil {
+					return withModule(err, "parsing", moduleName, mod.path)
+				}
+			}
+			for c := range n.ChildNodes() {
+				if err := visit(c); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if err := visit(mod.root); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveInclude resolves an `include` tag of a module.
+func (p *Program) resolveInclude(moduleName string, n *html.Node, dependencyGraph map[string]map[string]bool) error {
+	positions := p.modules[moduleName].nodePositions
+	errorf := func(format string, args ...any) error {
+		return &parser.ParseError{
+			Pos:     positions[n].Start,
+			Message: "parse error: " + fmt.Sprintf(format, args...),
+		}
+	}
+	for _, attr := range n.Attr {
+		if attr.Key != "module" {
+			return errorf("unrecognized attribute '%s' in include", attr.Key)
+		}
+	}
+	includedName, ok := getAttribute(n, "module")
+	if !ok {
+		return errorf("include is missing attribute 'module'")
+	}
+	if n.FirstChild != nil {
+		return errorf("include can not have children")
+	}
+	included, exists := p.modules[includedName]
+	if !exists {
+		return errorf("unknown module '%s'", includedName)
+	}
+	if included.info.ParamsAs != "" {
+		return errorf("module '%s' has parameters and can not be included", includedName)
+	}
+	if included.markup == nil {
+		included.markup = wrapIncludedMarkup(included.root, included.nodePositions)
+		p.modules[includedName] = included
+	}
+	dependencyGraph[moduleName][includedName] = true
+	return nil
+}
+
+// wrapIncludedMarkup moves the top-level markup of a module into a new
+// function named includeFunction. Top-level comments are left in
+// place, since they document the functions of the module, and so is
+// the whitespace around the markup.
+func wrapIncludedMarkup(root *html.Node, positions map[*html.Node]parser.NodePosition) *html.Node {
+	var body []*html.Node
+	for c := range root.ChildNodes() {
+		switch {
+		case c.Type == html.CommentNode:
+		case c.Type == html.ElementNode && (c.Data == "function" || c.Data == "import" || c.Data == "export" || c.Data == "module"):
+		default:
+			body = append(body, c)
+		}
+	}
+	isSpace := func(n *html.Node) bool {
+		return n.Type == html.TextNode && strings.TrimSpace(n.Data) == ""
+	}
+	for len(body) > 0 && isSpace(body[0]) {
+		body = body[1:]
+	}
+	for len(body) > 0 && isSpace(body[len(body)-1]) {
+		body = body[:len(body)-1]
+	}
+	function := &html.Node{
+		Type: html.ElementNode,
+		Data: "function",
+		Attr: []html.Attribute{{Key: "name", Val: includeFunction}},
+	}
+	var pos parser.NodePosition
+	if len(body) > 0 {
+		pos.Start = positions[body[0]].Start
+		pos.End = positions[body[len(body)-1]].End
+	}
+	for _, c := range body {
+		root.RemoveChild(c)
+		function.AppendChild(c)
+	}
+	root.AppendChild(function)
+	positions[function] = pos
+	return function
+}
+
+// evaluateInclude evaluates an `include` tag by evaluating the markup
+// of the included module in an empty scope.
+func (p *Program) evaluateInclude(n *html.Node) ([]*html.Node, error) {
+	includedName, _ := getAttribute(n, "module")
+	var results []*html.Node
+	for c := range p.modules[includedName].markup.ChildNodes() {
+		// The blocks of a layout that is included render their
+		// default content.
+		nodes, err := p.evaluateNode(includedName, c, map[string]any{"children": &slot{}})
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, nodes...)
+	}
+	return results, nil
+}
@@ -0,0 +1,410 @@
+// Package difftest implements property-based differential testing of
+// hop render backends.
+//
+// Generate produces random templates together with matching data and
+// the output that a reference model of hop predicts for them. Check
+// renders each case with every backend and requires all outputs to be
+// byte-identical to each other and to the model, so a new backend can
+// be tested against the tree interpreter by adding it to the list of
+// backends.
+package difftest
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/hoplang/hop-go"
+	"github.com/hoplang/hop-go/escape"
+)
+
+// Backend renders a function of a compiled program.
+type Backend struct {
+	Name   string
+	Render func(w io.Writer, program *hop.Program, moduleName string, functionName string, data any) error
+}
+
+// Interpreter is the backend that renders with Program.ExecuteFunction.
+var Interpreter = Backend{
+	Name: "interpreter",
+	Render: func(w io.Writer, program *hop.Program, moduleName string, functionName string, data any) error {
+		return program.ExecuteFunction(w, moduleName, functionName, data)
+	},
+}
+
+// StreamHTTP is the backend that renders with Program.StreamHTTP into
+// an httptest recorder, flushing at every chunk boundary.
+var StreamHTTP = Backend{
+	Name:   "stream",
+	Render: streamHTTP(false),
+}
+
+// StreamHTTPGzip is StreamHTTP for a client that accepts gzip. The
+// response is decompressed before it is compared.
+var StreamHTTPGzip = Backend{
+	Name:   "stream-gzip",
+	Render: streamHTTP(true),
+}
+
+func streamHTTP(acceptGzip bool) func(w io.Writer, program *hop.Program, moduleName string, functionName string, data any) error {
+	return func(w io.Writer, program *hop.Program, moduleName string, functionName string, data any) error {
+		r := httptest.NewRequest("GET", "/", nil)
+		if acceptGzip {
+			r.Header.Set("Accept-Encoding", "gzip")
+		}
+		rec := httptest.NewRecorder()
+		if err := program.StreamHTTP(rec, r, moduleName, functionName, data, hop.StreamOptions{MinChunk: 1}); err != nil {
+			return err
+		}
+		var body io.Reader = rec.Body
+		if acceptGzip {
+			if encoding := rec.Header().Get("Content-Encoding"); encoding != "gzip" {
+				return fmt.Errorf("expected a gzip response but got Content-Encoding %q", encoding)
+			}
+			zr, err := gzip.NewReader(rec.Body)
+			if err != nil {
+				return err
+			}
+			body = zr
+		}
+		_, err := io.Copy(w, body)
+		return err
+	}
+}
+
+// Case is a generated template with data and its expected output.
+type Case struct {
+	Template string
+	Data     any
+	Expected string
+}
+
+// Check renders c with every backend and returns an error describing
+// the first output that differs from the expected output.
+func Check(c Case, backends []Backend) error {
+	compiler := hop.NewCompiler()
+	compiler.AddModule("main", c.Template)
+	program, err := compiler.Compile()
+	if err != nil {
+		return fmt.Errorf("failed to compile:\n%s\n%w", c.Template, err)
+	}
+	for _, backend := range backends {
+		var out strings.Builder
+		if err := backend.Render(&out, program, "main", "main", c.Data); err != nil {
+			return fmt.Errorf("%s failed to render:\n%s\n%w", backend.Name, c.Template, err)
+		}
+		if out.String() != c.Expected {
+			return fmt.Errorf("%s rendered\n%s\nbut expected\n%s\nfor template\n%s\nand data %#v",
+				backend.Name, out.String(), c.Expected, c.Template, c.Data)
+		}
+	}
+	return nil
+}
+
+// fieldKind is the kind of value that a field of the data holds.
+type fieldKind int
+
+const (
+	textField fieldKind = iota
+	boolField
+	listField
+)
+
+// scope describes the object that a variable of the template refers
+// to, by the fields that the template uses.
+type scope struct {
+	name   string
+	fields []*field
+}
+
+type field struct {
+	name string
+	kind fieldKind
+	// elem describes the elements of a list field.
+	elem *scope
+}
+
+func (s *scope) newField(kind fieldKind) *field {
+	f := &field{name: fmt.Sprintf("f%d", len(s.fields)), kind: kind}
+	s.fields = append(s.fields, f)
+	return f
+}
+
+// node is a node of a generated template.
+type node interface {
+	template(b *strings.Builder)
+	render(b *strings.Builder, env map[string]map[string]any)
+}
+
+type ref struct {
+	scope *scope
+	field *field
+}
+
+func (r ref) path() string {
+	return r.scope.name + "." + r.field.name
+}
+
+func (r ref) value(env map[string]map[string]any) any {
+	return env[r.scope.name][r.field.name]
+}
+
+type text struct{ data string }
+
+func (n text) template(b *strings.Builder) { b.WriteString(n.data) }
+
+func (n text) render(b *strings.Builder, env map[string]map[string]any) {
+	b.WriteString(escape.Text(n.data))
+}
+
+type attribute struct {
+	key   string
+	value string
+	bound *ref
+}
+
+type element struct {
+	tag       string
+	attrs     []attribute
+	innerText *ref
+	children  []node
+}
+
+func (n element) template(b *strings.Builder) {
+	b.WriteString("<" + n.tag)
+	for _, attr := range n.attrs {
+		if attr.bound != nil {
+			fmt.Fprintf(b, ` attr-%s="%s"`, attr.key, attr.bound.path())
+		} else {
+			fmt.Fprintf(b, ` %s="%s"`, attr.key, attr.value)
+		}
+	}
+	if n.innerText != nil {
+		fmt.Fprintf(b, ` inner-text="%s"`, n.innerText.path())
+	}
+	b.WriteString(">")
+	for _, c := range n.children {
+		c.template(b)
+	}
+	b.WriteString("</" + n.tag + ">")
+}
+
+func (n element) render(b *strings.Builder, env map[string]map[string]any) {
+	b.WriteString("<" + n.tag)
+	for _, attr := range n.attrs {
+		value := attr.value
+		if attr.bound != nil {
+			value = format(attr.bound.value(env))
+		}
+		fmt.Fprintf(b, ` %s="%s"`, attr.key, escape.Text(value))
+	}
+	b.WriteString(">")
+	if n.innerText != nil {
+		b.WriteString(escape.Text(format(n.innerText.value(env))))
+	} else {
+		for _, c := range n.children {
+			c.render(b, env)
+		}
+	}
+	b.WriteString("</" + n.tag + ">")
+}
+
+type ifNode struct {
+	cond     ref
+	children []node
+}
+
+func (n ifNode) template(b *strings.Builder) {
+	fmt.Fprintf(b, `<if true="%s">`, n.cond.path())
+	for _, c := range n.children {
+		c.template(b)
+	}
+	b.WriteString("</if>")
+}
+
+func (n ifNode) render(b *strings.Builder, env map[string]map[string]any) {
+	if n.cond.value(env) == true {
+		for _, c := range n.children {
+			c.render(b, env)
+		}
+	}
+}
+
+type forNode struct {
+	each     ref
+	as       *scope
+	children []node
+}
+
+func (n forNode) template(b *strings.Builder) {
+	fmt.Fprintf(b, `<for each="%s" as="%s">`, n.each.path(), n.as.name)
+	for _, c := range n.children {
+		c.template(b)
+	}
+	b.WriteString("</for>")
+}
+
+func (n forNode) render(b *strings.Builder, env map[string]map[string]any) {
+	for _, item := range n.each.value(env).([]any) {
+		inner := map[string]map[string]any{}
+		for k, v := range env {
+			inner[k] = v
+		}
+		inner[n.as.name] = item.(map[string]any)
+		for _, c := range n.children {
+			c.render(b, inner)
+		}
+	}
+}
+
+type fragment struct{ children []node }
+
+func (n fragment) template(b *strings.Builder) {
+	b.WriteString("<fragment>")
+	for _, c := range n.children {
+		c.template(b)
+	}
+	b.WriteString("</fragment>")
+}
+
+func (n fragment) render(b *strings.Builder, env map[string]map[string]any) {
+	for _, c := range n.children {
+		c.render(b, env)
+	}
+}
+
+// format formats a value of the data the way hop renders it.
+func format(v any) string {
+	switch v := v.(type) {
+	case float64:
+		return fmt.Sprintf("%g", v)
+	case string:
+		return v
+	}
+	panic(fmt.Sprintf("unexpected value %#v", v))
+}
+
+var (
+	tags       = []string{"div", "span", "p", "li", "section"}
+	attrKeys   = []string{"class", "id", "title", "lang"}
+	words      = []string{"a", "hello", "x y", "tea", "z"}
+	dataValues = []string{"", "plain", `<b>"quoted"</b>`, "a & b", "it's", "ü ∑", "line\nbreak"}
+)
+
+// generator generates random templates.
+type generator struct {
+	r      *rand.Rand
+	nextID int
+}
+
+// Generate returns a random case generated from r.
+func Generate(r *rand.Rand) Case {
+	g := &generator{r: r}
+	root := &scope{name: "p"}
+	children := g.children(3, []*scope{root})
+	var tmpl, out strings.Builder
+	tmpl.WriteString(`<function name="main" params-as="p">`)
+	for _, c := range children {
+		c.template(&tmpl)
+	}
+	tmpl.WriteString("</function>")
+	data := g.data(root)
+	env := map[string]map[string]any{"p": data}
+	for _, c := range children {
+		c.render(&out, env)
+	}
+	return Case{Template: tmpl.String(), Data: data, Expected: out.String()}
+}
+
+func (g *generator) pick(scopes []*scope) *scope {
+	return scopes[g.r.IntN(len(scopes))]
+}
+
+func (g *generator) children(depth int, scopes []*scope) []node {
+	var result []node
+	n := g.r.IntN(4)
+	for range n {
+		if c := g.node(depth, scopes); c != nil {
+			// Adjacent text nodes are merged by the parser, so only
+			// generate text after other nodes.
+			if _, ok := c.(text); ok && len(result) > 0 {
+				if _, ok := result[len(result)-1].(text); ok {
+					continue
+				}
+			}
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+func (g *generator) node(depth int, scopes []*scope) node {
+	kind := g.r.IntN(6)
+	if depth == 0 {
+		kind = g.r.IntN(2)
+	}
+	switch kind {
+	case 0:
+		return text{data: words[g.r.IntN(len(words))]}
+	case 1, 2:
+		e := element{tag: tags[g.r.IntN(len(tags))]}
+		for _, key := range attrKeys {
+			switch g.r.IntN(4) {
+			case 0:
+				e.attrs = append(e.attrs, attribute{key: key, value: words[g.r.IntN(len(words))]})
+			case 1:
+				s := g.pick(scopes)
+				e.attrs = append(e.attrs, attribute{key: key, bound: &ref{s, s.newField(textField)}})
+			}
+		}
+		if depth > 0 && g.r.IntN(3) == 0 {
+			s := g.pick(scopes)
+			e.innerText = &ref{s, s.newField(textField)}
+		} else if depth > 0 {
+			e.children = g.children(depth-1, scopes)
+		}
+		return e
+	case 3:
+		s := g.pick(scopes)
+		return ifNode{cond: ref{s, s.newField(boolField)}, children: g.children(depth-1, scopes)}
+	case 4:
+		s := g.pick(scopes)
+		f := s.newField(listField)
+		g.nextID++
+		f.elem = &scope{name: fmt.Sprintf("item%d", g.nextID)}
+		return forNode{
+			each:     ref{s, f},
+			as:       f.elem,
+			children: g.children(depth-1, append(scopes[:len(scopes):len(scopes)], f.elem)),
+		}
+	default:
+		return fragment{children: g.children(depth-1, scopes)}
+	}
+}
+
+// data returns a random object with the fields of s.
+func (g *generator) data(s *scope) map[string]any {
+	result := map[string]any{}
+	for _, f := range s.fields {
+		switch f.kind {
+		case textField:
+			if g.r.IntN(3) == 0 {
+				result[f.name] = float64(g.r.IntN(2000) - 1000)
+			} else {
+				result[f.name] = dataValues[g.r.IntN(len(dataValues))]
+			}
+		case boolField:
+			result[f.name] = g.r.IntN(2) == 0
+		case listField:
+			items := make([]any, g.r.IntN(4))
+			for i := range items {
+				items[i] = g.data(f.elem)
+			}
+			result[f.name] = items
+		}
+	}
+	return result
+}
@@ -0,0 +1,24 @@
+package difftest_test
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/hoplang/hop-go/internal/difftest"
+)
+
+// backends are the render backends that must produce identical output.
+var backends = []difftest.Backend{
+	difftest.Interpreter,
+	difftest.StreamHTTP,
+	difftest.StreamHTTPGzip,
+}
+
+func TestBackends(t *testing.T) {
+	for seed := range uint64(500) {
+		c := difftest.Generate(rand.New(rand.NewPCG(seed, 0)))
+		if err := difftest.Check(c, backends); err != nil {
+			t.Fatalf("seed %d: %s", seed, err)
+		}
+	}
+}
@@ -2,35 +2,63 @@ package toposort
 
 import (
 	"fmt"
+	"maps"
 	"slices"
+	"strings"
 )
 
+// UndefinedError is returned when a node depends on a node that is
+// not part of the graph.
+type UndefinedError[T comparable] struct {
+	Label      string
+	Node       T
+	Dependency T
+}
+
+func (e *UndefinedError[T]) Error() string {
+	return fmt.Sprintf("%s '%v' depends on undefined %s '%v'", e.Label, e.Node, e.Label, e.Dependency)
+}
+
+// sortedKeys returns the keys of m ordered by their string form.
+func sortedKeys[T comparable, V any](m map[T]V) []T {
+	return slices.SortedFunc(maps.Keys(m), func(a, b T) int {
+		return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+	})
+}
+
 // TopologicalSort runs Kahn's algorithm on the given dependency graph.
-func TopologicalSort(graph map[string]map[string]bool, label string) ([]string, error) {
-	inDegree := make(map[string]int)
-	for node, dependencies := range graph {
+//
+// Nodes can be of any comparable type, which allows callers to key
+// the graph by more than a name, e.g. by module and function name.
+//
+// The result is deterministic: nodes are visited in the order of their
+// string form, so ties are always broken the same way.
+func TopologicalSort[T comparable](graph map[T]map[T]bool, label string) ([]T, error) {
+	nodes := sortedKeys(graph)
+	inDegree := make(map[T]int)
+	for _, node := range nodes {
 		if _, ok := inDegree[node]; !ok {
 			inDegree[node] = 0
 		}
-		for dep := range dependencies {
+		for _, dep := range sortedKeys(graph[node]) {
 			if _, exists := graph[dep]; !exists {
-				return nil, fmt.Errorf("%s '%s' depends on undefined %s '%s'", label, node, label, dep)
+				return nil, &UndefinedError[T]{Label: label, Node: node, Dependency: dep}
 			}
 			inDegree[dep]++
 		}
 	}
-	var queue []string
-	for node, deg := range inDegree {
-		if deg == 0 {
+	var queue []T
+	for _, node := range nodes {
+		if inDegree[node] == 0 {
 			queue = append(queue, node)
 		}
 	}
-	var result []string
+	var result []T
 	for len(queue) > 0 {
 		node := queue[0]
 		queue = queue[1:]
 		result = append(result, node)
-		for dep := range graph[node] {
+		for _, dep := range sortedKeys(graph[node]) {
 			inDegree[dep]--
 			if inDegree[dep] == 0 {
 				queue = append(queue, dep)
@@ -38,12 +66,12 @@ func TopologicalSort(graph map[string]map[string]bool, label string) ([]string,
 		}
 	}
 	if len(result) != len(graph) {
-		unprocessed := make([]string, 0)
-		processedSet := make(map[string]bool, len(result))
+		unprocessed := make([]T, 0)
+		processedSet := make(map[T]bool, len(result))
 		for _, v := range result {
 			processedSet[v] = true
 		}
-		for node := range graph {
+		for _, node := range nodes {
 			if !processedSet[node] {
 				unprocessed = append(unprocessed, node)
 			}
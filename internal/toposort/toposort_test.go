@@ -0,0 +1,85 @@
+package toposort
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+type ref struct {
+	module   string
+	function string
+}
+
+func TestTopologicalSort(t *testing.T) {
+	graph := map[string]map[string]bool{
+		"main":   {"ui": true, "layout": true},
+		"layout": {"ui": true},
+		"ui":     {},
+	}
+	sorted, err := TopologicalSort(graph, "module")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !slices.Equal(sorted, []string{"ui", "layout", "main"}) {
+		t.Errorf("Expected dependencies to come first but got %v", sorted)
+	}
+}
+
+func TestTopologicalSortCycle(t *testing.T) {
+	graph := map[string]map[string]bool{
+		"a": {"b": true},
+		"b": {"a": true},
+	}
+	_, err := TopologicalSort(graph, "module")
+	if err == nil || !strings.Contains(err.Error(), "cycle detected") {
+		t.Errorf("Expected cycle to be detected but got %v", err)
+	}
+}
+
+func TestTopologicalSortNameCollision(t *testing.T) {
+	// Two functions called 'button' in different modules must be
+	// treated as different nodes.
+	graph := map[ref]map[ref]bool{
+		{"", "button"}:   {{"ui", "button"}: true},
+		{"", "main"}:     {{"", "button"}: true},
+		{"ui", "button"}: {},
+	}
+	sorted, err := TopologicalSort(graph, "function")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := []ref{{"ui", "button"}, {"", "button"}, {"", "main"}}
+	if !slices.Equal(sorted, expected) {
+		t.Errorf("Expected %v but got %v", expected, sorted)
+	}
+}
+
+func TestTopologicalSortUndefined(t *testing.T) {
+	graph := map[ref]map[ref]bool{
+		{"", "main"}: {{"ui", "button"}: true},
+	}
+	_, err := TopologicalSort(graph, "function")
+	if err == nil || !strings.Contains(err.Error(), "depends on undefined function") {
+		t.Errorf("Expected undefined dependency to be reported but got %v", err)
+	}
+}
+
+func TestTopologicalSortDeterministic(t *testing.T) {
+	graph := map[string]map[string]bool{
+		"d": {}, "c": {}, "b": {}, "a": {}, "main": {"a": true, "b": true, "c": true, "d": true},
+	}
+	first, err := TopologicalSort(graph, "module")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for i := 0; i < 20; i++ {
+		sorted, err := TopologicalSort(graph, "module")
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if !slices.Equal(sorted, first) {
+			t.Fatalf("Expected %v but got %v", first, sorted)
+		}
+	}
+}
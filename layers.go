@@ -0,0 +1,203 @@
+package hop
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/hoplang/hop-go/parser"
+	"golang.org/x/net/html"
+)
+
+// Override records a function of a module that a layer replaced.
+type Override struct {
+	Module   string
+	Function string
+	// Layer is the layer whose definition is used and Overridden the
+	// layer whose definition was replaced. Modules added with AddModule
+	// or AddFS belong to the unnamed layer "".
+	Layer      string
+	Overridden string
+}
+
+func (o Override) String() string {
+	return fmt.Sprintf("%s/%s: %s overrides %s", o.Module, o.Function, layerName(o.Layer), layerName(o.Overridden))
+}
+
+func layerName(layer string) string {
+	if layer == "" {
+		return "base"
+	}
+	return fmt.Sprintf("layer %s", layer)
+}
+
+// overlay is a module of a layer that is merged into the module of the
+// same name added before it.
+type overlay struct {
+	layer  string
+	source string
+	path   string
+}
+
+// AddLayer adds the .hop files of fsys as a layer on top of the modules
+// added before, e.g. core, then plugins, then a theme.
+//
+// A module of the layer with the same name as an existing module is
+// merged into it: functions defined by both use the definition of the
+// layer and the others are kept. Imports of the layer are added to
+// those of the module, and module metadata can only be declared by the
+// first layer that adds the module. So are its exports, while other
+// top-level markup is an error. Program.Overrides reports the replaced
+// functions.
+//
+// Since a merged module combines several files, its type errors are
+// reported with the module name rather than a file name.
+func (c *Compiler) AddLayer(layer string, fsys fs.FS) error {
+	return fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".hop") {
+			return nil
+		}
+		content, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+		moduleName := strings.TrimSuffix(path, ".hop")
+		if _, exists := c.modules[moduleName]; exists {
+			c.overlays[moduleName] = append(c.overlays[moduleName], overlay{
+				layer:  layer,
+				source: string(content),
+				path:   path,
+			})
+			return nil
+		}
+		c.AddModule(moduleName, string(content))
+		c.paths[moduleName] = path
+		c.layers[moduleName] = layer
+		return nil
+	})
+}
+
+// Overrides returns the functions that layers replaced, sorted by
+// module and function name.
+func (p *Program) Overrides() []Override {
+	overrides := slices.Clone(p.overrides)
+	slices.SortStableFunc(overrides, func(a, b Override) int {
+		if a.Module != b.Module {
+			return strings.Compare(a.Module, b.Module)
+		}
+		return strings.Compare(a.Function, b.Function)
+	})
+	return overrides
+}
+
+// applyLayers merges the overlays of a module into its parsed root.
+func (c *Compiler) applyLayers(moduleName string, mod *module) error {
+	overlays := c.overlays[moduleName]
+	if len(overlays) == 0 {
+		return nil
+	}
+	// definedBy holds the layer of each top-level function.
+	definedBy := map[string]string{}
+	for _, function := range topLevelElements(mod.root, "function") {
+		name, _ := getAttribute(function, "name")
+		definedBy[name] = c.layers[moduleName]
+	}
+	for _, o := range overlays {
+		result, err := parser.Parse(o.source)
+		if err != nil {
+			return withModule(err, "parsing", moduleName, o.path)
+		}
+		if err := c.resolveCompileTimeConditions(result.Root, result.NodePositions); err != nil {
+			return withModule(err, "parsing", moduleName, o.path)
+		}
+		info, err := parseModuleInfo(result.Root, result.NodePositions)
+		if err != nil {
+			return withModule(err, "parsing", moduleName, o.path)
+		}
+		if !info.isEmpty() {
+			return withModule(fmt.Errorf("module metadata can only be declared by the first layer that adds a module"),
+				"parsing", moduleName, o.path)
+		}
+		if c.singleFunctionFiles {
+			wrapSingleFunction(result.Root, result.NodePositions, path.Base(moduleName), mod.info)
+		}
+		for n, pos := range result.NodePositions {
+			mod.nodePositions[n] = pos
+		}
+		for n := result.Root.FirstChild; n != nil; {
+			next := n.NextSibling
+			if n.Type == html.ElementNode {
+				switch n.Data {
+				case "function":
+					name, _ := getAttribute(n, "name")
+					result.Root.RemoveChild(n)
+					if old := findElement(mod.root, "function", "name", name); old != nil {
+						mod.root.InsertBefore(n, old)
+						mod.root.RemoveChild(old)
+						mod.overrides = append(mod.overrides, Override{
+							Module:     moduleName,
+							Function:   name,
+							Layer:      o.layer,
+							Overridden: definedBy[name],
+						})
+						c.logger.Debug("overrode function", "module", moduleName, "function", name,
+							"layer", o.layer, "overridden", definedBy[name])
+					} else {
+						mod.root.AppendChild(n)
+					}
+					definedBy[name] = o.layer
+				case "import", "export":
+					result.Root.RemoveChild(n)
+					if !hasDeclaration(mod.root, n) {
+						mod.root.InsertBefore(n, mod.root.FirstChild)
+					}
+				case "module":
+					// Checked by parseModuleInfo above.
+				default:
+					return withModule(overlayMarkupError(n, result.NodePositions), "parsing", moduleName, o.path)
+				}
+			} else if (n.Type == html.TextNode && strings.TrimSpace(n.Data) != "") || n.Type == html.DoctypeNode {
+				return withModule(overlayMarkupError(n, result.NodePositions), "parsing", moduleName, o.path)
+			}
+			n = next
+		}
+	}
+	mod.path = ""
+	return nil
+}
+
+// isEmpty reports whether no metadata was declared.
+func (info ModuleInfo) isEmpty() bool {
+	return info.Description == "" && info.StrictParams == nil && info.ParamsAs == ""
+}
+
+// overlayMarkupError reports top-level markup of an overlay. Only the
+// module that a layer merges into can have top-level markup, since the
+// markup of several layers can not be combined.
+func overlayMarkupError(n *html.Node, positions map[*html.Node]parser.NodePosition) error {
+	return &parser.ParseError{
+		Pos:     positions[n].Start,
+		Message: "parse error: a layer can only add functions, imports and exports to an existing module",
+	}
+}
+
+// hasDeclaration reports whether root already has an import or export,
+// like decl, of the same function from the same module and by the same
+// name.
+func hasDeclaration(root *html.Node, decl *html.Node) bool {
+	function, _ := getAttribute(decl, "function")
+	from, _ := getAttribute(decl, "from")
+	for _, c := range topLevelElements(root, decl.Data) {
+		f, _ := getAttribute(c, "function")
+		m, _ := getAttribute(c, "from")
+		if f == function && m == from && importedName(c) == importedName(decl) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+package hop
+
+import (
+	"cmp"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/hoplang/hop-go/parser"
+	"github.com/hoplang/hop-go/typechecker"
+	"golang.org/x/net/html"
+)
+
+// Link is a URL that a template uses literally, such as the value of a
+// static href attribute or a fallback of an attr-href binding.
+type Link struct {
+	Module    string
+	File      string
+	Pos       parser.Position
+	Attribute string
+	URL       string
+}
+
+// LinkTarget reports whether the path of an internal link, such as
+// /posts/hello, resolves to a page or file.
+type LinkTarget func(path string) bool
+
+// Routes returns a LinkTarget that accepts the paths matched by one of
+// the patterns. Patterns are written like those of http.ServeMux
+// without methods and hosts: {name} matches one segment, {name...}
+// matches the remaining segments and a trailing slash matches every
+// path below it:
+//
+//	hop.Routes("/", "/posts/{slug}", "/static/")
+func Routes(patterns ...string) LinkTarget {
+	return func(path string) bool {
+		return slices.ContainsFunc(patterns, func(pattern string) bool {
+			return matchRoute(pattern, path)
+		})
+	}
+}
+
+// matchRoute reports whether path matches a pattern of Routes.
+func matchRoute(pattern, path string) bool {
+	if pattern == "/" {
+		return path == "/"
+	}
+	patternSegments := strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for i, segment := range patternSegments {
+		switch {
+		case segment == "" && i == len(patternSegments)-1:
+			// A trailing slash matches the subtree.
+			return len(pathSegments) > i
+		case strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "...}"):
+			return true
+		case i >= len(pathSegments):
+			return false
+		case strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}"):
+			if pathSegments[i] == "" {
+				return false
+			}
+		case segment != pathSegments[i]:
+			return false
+		}
+	}
+	return len(pathSegments) == len(patternSegments)
+}
+
+// Files returns a LinkTarget that accepts the paths of the files of
+// fsys, such as the output of a static site generator. A path also
+// resolves to an index.html in the directory it names and to the file
+// with an added .html extension.
+func Files(fsys fs.FS) LinkTarget {
+	return func(p string) bool {
+		name := strings.Trim(p, "/")
+		if name == "" {
+			name = "."
+		}
+		if !fs.ValidPath(name) {
+			return false
+		}
+		for _, candidate := range []string{name, path.Join(name, "index.html"), name + ".html"} {
+			if info, err := fs.Stat(fsys, candidate); err == nil && !info.IsDir() {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Links returns the URLs that the templates of the program use
+// literally in URL attributes such as href and src, ordered by module
+// and position.
+func (p *Program) Links() []Link {
+	var links []Link
+	for _, moduleName := range slices.Sorted(maps.Keys(p.modules)) {
+		mod := p.modules[moduleName]
+		add := func(n *html.Node, key, attribute, url string) {
+			pos := mod.nodePositions[n].Start
+			if attrPos, ok := mod.nodePositions[n].Attributes[key]; ok {
+				pos = attrPos.ValueStart
+			}
+			links = append(links, Link{
+				Module:    moduleName,
+				File:      mod.path,
+				Pos:       pos,
+				Attribute: attribute,
+				URL:       url,
+			})
+		}
+		var visit func(n *html.Node)
+		visit = func(n *html.Node) {
+			for _, attr := range n.Attr {
+				if typechecker.IsURLAttribute(attr.Key) {
+					add(n, attr.Key, attr.Key, attr.Val)
+					continue
+				}
+				name, ok := strings.CutPrefix(attr.Key, "attr-")
+				if !ok || !typechecker.IsURLAttribute(name) {
+					continue
+				}
+				binding, err := parser.ParseBinding(attr.Val)
+				if err != nil {
+					continue
+				}
+				for _, alternative := range binding.Alternatives {
+					if alternative.IsLiteral {
+						add(n, attr.Key, name, alternative.Literal)
+					}
+				}
+			}
+			for c := range n.ChildNodes() {
+				visit(c)
+			}
+		}
+		visit(mod.root)
+	}
+	slices.SortStableFunc(links, func(a, b Link) int {
+		return cmp.Or(
+			cmp.Compare(a.Module, b.Module),
+			cmp.Compare(a.Pos.Line, b.Pos.Line),
+			cmp.Compare(a.Pos.Column, b.Pos.Column),
+		)
+	})
+	return links
+}
+
+// CheckLinks reports the internal links of the program that do not
+// resolve to target. Internal links are those with an absolute path
+// and no scheme or host, e.g. /about but not https://example.com/ or
+// about; their query and fragment are ignored.
+func (p *Program) CheckLinks(target LinkTarget) []Warning {
+	var warnings []Warning
+	for _, link := range p.Links() {
+		path, ok := internalPath(link.URL)
+		if !ok || target(path) {
+			continue
+		}
+		warnings = append(warnings, Warning{
+			Module:  link.Module,
+			File:    link.File,
+			Pos:     link.Pos,
+			Message: fmt.Sprintf("broken link %s in %s", link.URL, link.Attribute),
+		})
+	}
+	return warnings
+}
+
+// internalPath returns the unescaped path of an internal link.
+func internalPath(link string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || u.Scheme != "" || u.Host != "" || !strings.HasPrefix(u.Path, "/") {
+		return "", false
+	}
+	return u.Path, true
+}
@@ -0,0 +1,119 @@
+package hop
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ModuleVersion identifies the content of a module of a Loader.
+type ModuleVersion struct {
+	Name string
+	// Hash changes whenever the content of the module changes, e.g. a
+	// digest of the content or the ETag of an object in a bucket.
+	Hash string
+	// Path is the location of the module used in errors, such as a
+	// file name or URL. It may be empty.
+	Path string
+}
+
+// Loader loads modules from an origin such as a file system, a database
+// or a template registry.
+type Loader interface {
+	// List returns the versions of all modules of the origin.
+	List(ctx context.Context) ([]ModuleVersion, error)
+	// Load returns the source of a module.
+	Load(ctx context.Context, moduleName string) (string, error)
+}
+
+// Sync updates the modules of the compiler from a loader and returns
+// the names of the modules that were added, changed or removed, in
+// sorted order. Only modules whose hash differs from the one seen by
+// the previous call are loaded, and modules that an earlier call added
+// but the loader no longer lists are removed. A compiler is meant to be
+// synced with a single loader.
+//
+// The program only needs to be compiled again if the result is not
+// empty.
+func (c *Compiler) Sync(ctx context.Context, loader Loader) ([]string, error) {
+	versions, err := loader.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	listed := map[string]bool{}
+	var changed []string
+	for _, v := range versions {
+		listed[v.Name] = true
+		if hash, ok := c.hashes[v.Name]; ok && hash == v.Hash {
+			continue
+		}
+		source, err := loader.Load(ctx, v.Name)
+		if err != nil {
+			return changed, err
+		}
+		c.AddModule(v.Name, source)
+		if v.Path != "" {
+			c.paths[v.Name] = v.Path
+		}
+		c.hashes[v.Name] = v.Hash
+		changed = append(changed, v.Name)
+	}
+	for _, moduleName := range slices.Sorted(maps.Keys(c.hashes)) {
+		if !listed[moduleName] {
+			delete(c.modules, moduleName)
+			delete(c.paths, moduleName)
+			delete(c.hashes, moduleName)
+			delete(c.layers, moduleName)
+			delete(c.overlays, moduleName)
+			changed = append(changed, moduleName)
+		}
+	}
+	slices.Sort(changed)
+	return changed, nil
+}
+
+// FSLoader returns a Loader for the .hop files of a file system, whose
+// hashes are SHA-256 digests of the content.
+func FSLoader(fsys fs.FS) Loader {
+	return fsLoader{fsys: fsys}
+}
+
+type fsLoader struct {
+	fsys fs.FS
+}
+
+func (l fsLoader) List(ctx context.Context) ([]ModuleVersion, error) {
+	var versions []ModuleVersion
+	err := fs.WalkDir(l.fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".hop") {
+			return nil
+		}
+		content, err := fs.ReadFile(l.fsys, path)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(content)
+		versions = append(versions, ModuleVersion{
+			Name: strings.TrimSuffix(path, ".hop"),
+			Hash: hex.EncodeToString(sum[:]),
+			Path: path,
+		})
+		return nil
+	})
+	return versions, err
+}
+
+func (l fsLoader) Load(ctx context.Context, moduleName string) (string, error) {
+	content, err := fs.ReadFile(l.fsys, moduleName+".hop")
+	return string(content), err
+}
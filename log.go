@@ -0,0 +1,48 @@
+package hop
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// defaultSlowRenderThreshold is the duration after which a render is
+// logged as slow.
+const defaultSlowRenderThreshold = 100 * time.Millisecond
+
+// discardHandler is a slog.Handler that drops all records. It is used
+// when no logger has been set.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+var discardLogger = slog.New(discardHandler{})
+
+// SetLogger sets the logger that the compiler logs its phases and the
+// time spent on each module to, at debug level. Compiled programs log
+// to the same logger. A nil logger disables logging, which is the
+// default.
+func (c *Compiler) SetLogger(logger *slog.Logger) {
+	if logger == nil {
+		logger = discardLogger
+	}
+	c.logger = logger
+}
+
+// SetLogger sets the logger that the program logs slow renders to, at
+// warn level. A nil logger disables logging.
+func (p *Program) SetLogger(logger *slog.Logger) {
+	if logger == nil {
+		logger = discardLogger
+	}
+	p.logger = logger
+}
+
+// SetSlowRenderThreshold sets the duration after which a call to
+// ExecuteFunction is logged as slow. It defaults to 100ms.
+func (p *Program) SetSlowRenderThreshold(d time.Duration) {
+	p.slowRenderThreshold = d
+}
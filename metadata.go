@@ -0,0 +1,147 @@
+package hop
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hoplang/hop-go/parser"
+	"golang.org/x/net/html"
+)
+
+// ModuleInfo is the metadata declared by a module.
+//
+// Metadata is declared with a `module` tag that must be the first
+// element of the module:
+//
+//	<module description="Buttons" strict-params="true"></module>
+type ModuleInfo struct {
+	Description string
+	// StrictParams overrides Compiler.SetStrictParams for the module
+	// when it is non-nil.
+	StrictParams *bool
+	// ParamsAs names the parameter of a single-function file, see
+	// Compiler.SetSingleFunctionFiles.
+	ParamsAs string
+}
+
+// parseModuleInfo extracts the metadata of a module from its root node.
+func parseModuleInfo(root *html.Node, positions map[*html.Node]parser.NodePosition) (ModuleInfo, error) {
+	var info ModuleInfo
+	first := true
+	for c := range root.ChildNodes() {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		if c.Data != "module" {
+			first = false
+			continue
+		}
+		errorf := func(format string, args ...any) error {
+			return &parser.ParseError{
+				Pos:     positions[c].Start,
+				Message: "parse error: " + fmt.Sprintf(format, args...),
+			}
+		}
+		if !first {
+			return info, errorf("module metadata must be the first element of the module")
+		}
+		first = false
+		for _, attr := range c.Attr {
+			switch attr.Key {
+			case "description":
+				info.Description = attr.Val
+			case "params-as":
+				info.ParamsAs = attr.Val
+			case "strict-params":
+				switch attr.Val {
+				case "true":
+					info.StrictParams = new(bool)
+					*info.StrictParams = true
+				case "false":
+					info.StrictParams = new(bool)
+				default:
+					return info, errorf("invalid value '%s' for strict-params, expected true or false", attr.Val)
+				}
+			default:
+				return info, errorf("unrecognized attribute '%s' in module", attr.Key)
+			}
+		}
+	}
+	return info, nil
+}
+
+// ModuleInfo returns the metadata declared by a module.
+func (p *Program) ModuleInfo(moduleName string) (ModuleInfo, error) {
+	module, exists := p.modules[moduleName]
+	if !exists {
+		return ModuleInfo{}, fmt.Errorf("no module with name %s", moduleName)
+	}
+	return module.info, nil
+}
+
+// wrapSingleFunction turns a module without functions into a module with
+// a single function whose body is the top-level markup of the module.
+//
+// It returns false if the module already defines functions.
+func wrapSingleFunction(root *html.Node, positions map[*html.Node]parser.NodePosition, name string, info ModuleInfo) bool {
+	var body []*html.Node
+	for c := range root.ChildNodes() {
+		if c.Type == html.ElementNode {
+			switch c.Data {
+			case "function":
+				return false
+			case "module", "import", "export":
+				continue
+			}
+		}
+		body = append(body, c)
+	}
+	function := &html.Node{
+		Type: html.ElementNode,
+		Data: "function",
+		Attr: []html.Attribute{{Key: "name", Val: name}},
+	}
+	if info.ParamsAs != "" {
+		function.Attr = append(function.Attr, html.Attribute{Key: "params-as", Val: info.ParamsAs})
+	}
+	var pos parser.NodePosition
+	if len(body) > 0 {
+		pos.Start = positions[body[0]].Start
+		pos.End = positions[body[len(body)-1]].End
+	}
+	for _, c := range body {
+		root.RemoveChild(c)
+		function.AppendChild(c)
+	}
+	root.AppendChild(function)
+	positions[function] = pos
+	return true
+}
+
+// FunctionDoc returns the documentation of a function, which is the text
+// of a comment directly preceding it:
+//
+//	<!-- A button that submits the surrounding form. -->
+//	<function name="submit-button"></function>
+//
+// The # of a hop comment, which is not rendered, is not part of the
+// documentation.
+func (p *Program) FunctionDoc(moduleName string, functionName string) (string, error) {
+	module, exists := p.modules[moduleName]
+	if !exists {
+		return "", fmt.Errorf("no module with name %s", moduleName)
+	}
+	function, exists := module.functions[functionName]
+	if !exists || module.private[functionName] {
+		return "", fmt.Errorf("no function with name %s in module %s", functionName, moduleName)
+	}
+	for n := function.PrevSibling; n != nil; n = n.PrevSibling {
+		switch {
+		case n.Type == html.CommentNode:
+			return strings.TrimSpace(strings.TrimPrefix(n.Data, "#")), nil
+		case n.Type != html.TextNode || strings.TrimSpace(n.Data) != "":
+			return "", nil
+		}
+	}
+	return "", nil
+}
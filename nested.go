@@ -0,0 +1,109 @@
+package hop
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/hoplang/hop-go/parser"
+	"golang.org/x/net/html"
+)
+
+// liftNestedFunctions moves `function` tags that are nested inside other
+// functions to the top level of the module.
+//
+// A nested function is private to the function it is defined in. It is
+// renamed to a unique name qualified by the names of its enclosing
+// functions, e.g. `card:title`, and every `render` tag that refers to it
+// from within the enclosing function is rewritten to use that name.
+//
+// It returns the set of names of the lifted functions, or an error if two
+// functions of the module end up with the same name, which for nested
+// functions means that they are defined in the same enclosing function.
+func liftNestedFunctions(root *html.Node, positions map[*html.Node]parser.NodePosition) (map[string]bool, error) {
+	lifted := map[string]bool{}
+	var topLevel []*html.Node
+	for c := range root.ChildNodes() {
+		if c.Type == html.ElementNode && c.Data == "function" {
+			topLevel = append(topLevel, c)
+		}
+	}
+	var lift func(function *html.Node, qualifiedName string, scope map[string]string)
+	lift = func(function *html.Node, qualifiedName string, scope map[string]string) {
+		var helpers []*html.Node
+		var renders []*html.Node
+		var visit func(n *html.Node)
+		visit = func(n *html.Node) {
+			for c := range n.ChildNodes() {
+				if c.Type == html.ElementNode {
+					switch c.Data {
+					case "function":
+						helpers = append(helpers, c)
+						continue
+					case "render":
+						renders = append(renders, c)
+					}
+				}
+				visit(c)
+			}
+		}
+		visit(function)
+		if len(helpers) > 0 {
+			scope = maps.Clone(scope)
+			for _, helper := range helpers {
+				name, _ := getAttribute(helper, "name")
+				scope[name] = qualifiedName + ":" + name
+			}
+		}
+		// The renders are renamed even if the function has no helpers of
+		// its own, since they can refer to the helpers of an enclosing
+		// function.
+		for _, render := range renders {
+			renameAttribute(render, "function", scope)
+		}
+		for _, helper := range helpers {
+			name, _ := getAttribute(helper, "name")
+			helper.Parent.RemoveChild(helper)
+			root.AppendChild(helper)
+			renameAttribute(helper, "name", scope)
+			lifted[scope[name]] = true
+			lift(helper, scope[name], scope)
+		}
+	}
+	for _, function := range topLevel {
+		name, _ := getAttribute(function, "name")
+		lift(function, name, map[string]string{})
+	}
+
+	defined := map[string]*html.Node{}
+	for _, function := range topLevelElements(root, "function") {
+		name, _ := getAttribute(function, "name")
+		first, exists := defined[name]
+		if !exists {
+			defined[name] = function
+			continue
+		}
+		// Lifted functions are appended to the module, so the first
+		// definition is found by position.
+		second := function
+		if a, b := positions[second].Start, positions[first].Start; a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column) {
+			first, second = second, first
+		}
+		return nil, &parser.ParseError{
+			Pos:     positions[second].Start,
+			Message: fmt.Sprintf("parse error: duplicate function '%s', first defined at %s", name, positions[first].Start),
+		}
+	}
+	return lifted, nil
+}
+
+// renameAttribute replaces the value of an attribute with its entry in
+// names, if there is one.
+func renameAttribute(n *html.Node, key string, names map[string]string) {
+	for i, attr := range n.Attr {
+		if attr.Key == key {
+			if name, ok := names[attr.Val]; ok {
+				n.Attr[i].Val = name
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package hop
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+)
+
+// PanicCode is the code of errors caused by a panic during rendering.
+// It is stable across releases, so it can be used to group such errors
+// in monitoring.
+const PanicCode = "HOP_RENDER_PANIC"
+
+// PanicError is returned by ExecuteFunction when rendering panics, for
+// example because of a bug in the runtime or in a value of the data.
+type PanicError struct {
+	// Code is always PanicCode.
+	Code string
+	// Value is the value that was passed to panic.
+	Value any
+	// RenderStack lists the functions that were being rendered when the
+	// panic occurred as module/function, starting with the outermost.
+	RenderStack []string
+	// GoStack is the stack trace of the goroutine at the panic.
+	GoStack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%s: panic while rendering %s: %v", e.Code, strings.Join(e.RenderStack, " > "), e.Value)
+}
+
+// renderPanic is used to unwind a panic through the render calls that
+// were being evaluated, which add themselves to the render stack.
+type renderPanic struct {
+	err *PanicError
+}
+
+// recoverRender converts a panic into a *PanicError, recording frame in
+// its render stack. If outermost is false the panic continues to unwind
+// so that enclosing render calls can add their frames.
+func recoverRender(r any, frame string, outermost bool) *PanicError {
+	rp, ok := r.(renderPanic)
+	if !ok {
+		rp = renderPanic{err: &PanicError{Code: PanicCode, Value: r, GoStack: debug.Stack()}}
+	}
+	rp.err.RenderStack = append([]string{frame}, rp.err.RenderStack...)
+	if !outermost {
+		panic(rp)
+	}
+	return rp.err
+}
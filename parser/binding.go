@@ -0,0 +1,155 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var validFilterNameRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+
+// Binding is the parsed value of a binding such as inner-text.
+type Binding struct {
+	// Alternatives are tried in order, see ParseBinding.
+	Alternatives []Alternative
+	// Filters are applied in order to the value of the alternatives.
+	Filters []Filter
+}
+
+// Alternative is an alternative of a binding. It is either a path or,
+// as the last alternative, a string literal.
+type Alternative struct {
+	Path      string
+	Literal   string
+	IsLiteral bool
+}
+
+// Filter is a filter that a binding pipes its value through, such as
+// truncate(40). Each argument is a literal that is either a string or
+// a float64.
+type Filter struct {
+	Name string
+	Args []any
+}
+
+// ParseBinding parses the value of a binding such as inner-text.
+//
+// The alternatives of a binding are separated by ?? and the first one
+// that resolves to a value is used. The value can be piped through
+// filters, which are separated by |:
+//
+//	"user.nickname ?? user.name ?? 'Anonymous' | truncate(20)"
+//
+// A value without ?? or | is a single path.
+func ParseBinding(binding string) (Binding, error) {
+	if !strings.ContainsAny(binding, "?|") {
+		return Binding{Alternatives: []Alternative{{Path: binding}}}, nil
+	}
+	var result Binding
+	stages := splitOutsideQuotes(binding, "|")
+	parts := splitOutsideQuotes(stages[0], "??")
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		switch {
+		case part == "":
+			return Binding{}, fmt.Errorf("empty alternative in '%s'", binding)
+		case isQuoted(part):
+			if i != len(parts)-1 {
+				return Binding{}, fmt.Errorf("a string literal can only be the last alternative in '%s'", binding)
+			}
+			result.Alternatives = append(result.Alternatives, Alternative{Literal: part[1 : len(part)-1], IsLiteral: true})
+		default:
+			result.Alternatives = append(result.Alternatives, Alternative{Path: part})
+		}
+	}
+	for _, stage := range stages[1:] {
+		filter, err := parseFilter(strings.TrimSpace(stage))
+		if err != nil {
+			return Binding{}, fmt.Errorf("%w in '%s'", err, binding)
+		}
+		result.Filters = append(result.Filters, filter)
+	}
+	return result, nil
+}
+
+// parseFilter parses a filter such as uppercase or truncate(40).
+func parseFilter(s string) (Filter, error) {
+	name, args, hasArgs := strings.Cut(s, "(")
+	name = strings.TrimSpace(name)
+	if !validFilterNameRegex.MatchString(name) {
+		return Filter{}, fmt.Errorf("invalid filter '%s'", s)
+	}
+	filter := Filter{Name: name}
+	if !hasArgs {
+		return filter, nil
+	}
+	args, ok := strings.CutSuffix(args, ")")
+	if !ok {
+		return Filter{}, fmt.Errorf("missing ) in filter '%s'", s)
+	}
+	if strings.TrimSpace(args) == "" {
+		return filter, nil
+	}
+	for _, arg := range splitOutsideQuotes(args, ",") {
+		arg = strings.TrimSpace(arg)
+		if isQuoted(arg) {
+			filter.Args = append(filter.Args, arg[1:len(arg)-1])
+			continue
+		}
+		f, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return Filter{}, fmt.Errorf("invalid argument '%s' of filter %s, expected a string or a number", arg, name)
+		}
+		filter.Args = append(filter.Args, f)
+	}
+	return filter, nil
+}
+
+// isQuoted reports whether s is a string literal in single or double
+// quotes.
+func isQuoted(s string) bool {
+	return len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0]
+}
+
+// splitOutsideQuotes splits s around the occurrences of sep that are
+// not inside a string literal.
+func splitOutsideQuotes(s string, sep string) []string {
+	var parts []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '\'' || s[i] == '"':
+			quote = s[i]
+		case strings.HasPrefix(s[i:], sep):
+			parts = append(parts, s[start:i])
+			i += len(sep) - 1
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// ParseLiteral parses a literal parameter value: a string in single or
+// double quotes, a number, true or false. It returns false if value is
+// not a literal, e.g. because it is a path.
+func ParseLiteral(value string) (any, bool) {
+	value = strings.TrimSpace(value)
+	switch {
+	case isQuoted(value):
+		return value[1 : len(value)-1], true
+	case value == "true":
+		return true, true
+	case value == "false":
+		return false, true
+	case value != "" && (value[0] == '-' || (value[0] >= '0' && value[0] <= '9')):
+		f, err := strconv.ParseFloat(value, 64)
+		return f, err == nil
+	}
+	return nil, false
+}
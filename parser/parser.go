@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"unicode/utf8"
 
+	"github.com/hoplang/hop-go/tokenizer"
 	"golang.org/x/net/html"
 )
 
@@ -27,12 +29,28 @@ var voidElements = map[string]bool{
 	"wbr":    true,
 }
 
+// IsVoidElement reports whether elements with the given tag have no
+// end tag.
+func IsVoidElement(tag string) bool {
+	return voidElements[tag]
+}
+
 type ParseError struct {
+	// Module is the module that the error occurred in and File is the
+	// file it was read from, if known.
+	Module  string
+	File    string
 	Pos     Position
 	Message string
 }
 
 func (e *ParseError) Error() string {
+	if e.File != "" {
+		return fmt.Sprintf("%s: %s: %s", e.File, e.Pos, e.Message)
+	}
+	if e.Module != "" {
+		return fmt.Sprintf("%s: %s: %s", e.Module, e.Pos, e.Message)
+	}
 	return fmt.Sprintf("%s: %s", e.Pos, e.Message)
 }
 
@@ -70,7 +88,33 @@ func newParseError(pos Position, format string, args ...interface{}) *ParseError
 	}
 }
 
+// checkEncoding reports content that is not valid UTF-8 text, such as
+// a binary file, at the byte offset where it occurs.
+func checkEncoding(template string) error {
+	pos := Position{Line: 1, Column: 1}
+	for i, r := range template {
+		switch {
+		case r == utf8.RuneError && !strings.HasPrefix(template[i:], "\uFFFD"):
+			return newParseError(pos, "invalid UTF-8 at byte offset %d", i)
+		case r == 0:
+			return newParseError(pos, "NUL byte at byte offset %d, the file does not look like a text file", i)
+		case r == '\n':
+			pos.Line++
+			pos.Column = 1
+		default:
+			pos.Column += utf8.RuneLen(r)
+		}
+	}
+	return nil
+}
+
+// Parse parses a template. A leading byte order mark is ignored and
+// line endings are normalized to \n, see tokenizer.Normalize.
 func Parse(template string) (*ParseResult, error) {
+	if err := checkEncoding(template); err != nil {
+		return nil, err
+	}
+	template = tokenizer.Normalize(template)
 	result := &ParseResult{
 		NodePositions: make(map[*html.Node]NodePosition),
 	}
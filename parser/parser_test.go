@@ -3,6 +3,7 @@ package parser
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -346,3 +347,82 @@ func TestParseAttributePositions(t *testing.T) {
 		})
 	}
 }
+
+func TestParseNormalizesLineEndings(t *testing.T) {
+	result, err := Parse("\uFEFF<p>a\r\nb\r<b class=\"x\"></b></p>")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	p := result.Root.FirstChild
+	if p.FirstChild.Data != "a\nb\n" {
+		t.Errorf("Expected normalized text but got %q", p.FirstChild.Data)
+	}
+	b := p.LastChild
+	if got := result.NodePositions[b].Start; got != (Position{Line: 3, Column: 1}) {
+		t.Errorf("Expected <b> to start at line 3, column 1 but got %s", got)
+	}
+	if got := result.NodePositions[b].Attributes["class"].ValueStart; got != (Position{Line: 3, Column: 11}) {
+		t.Errorf("Expected class value to start at line 3, column 11 but got %s", got)
+	}
+}
+
+func TestParseBinding(t *testing.T) {
+	tests := []struct {
+		binding string
+		want    Binding
+		wantErr string
+	}{
+		{"user.name", Binding{Alternatives: []Alternative{{Path: "user.name"}}}, ""},
+		{"user.nickname ?? user.name", Binding{Alternatives: []Alternative{{Path: "user.nickname"}, {Path: "user.name"}}}, ""},
+		{`user.nickname ?? 'Anonymous'`, Binding{Alternatives: []Alternative{{Path: "user.nickname"}, {Literal: "Anonymous", IsLiteral: true}}}, ""},
+		{`user.nickname ?? 'a ?? b | c'`, Binding{Alternatives: []Alternative{{Path: "user.nickname"}, {Literal: "a ?? b | c", IsLiteral: true}}}, ""},
+		{"title | uppercase | truncate(40)", Binding{
+			Alternatives: []Alternative{{Path: "title"}},
+			Filters:      []Filter{{Name: "uppercase"}, {Name: "truncate", Args: []any{40.0}}},
+		}, ""},
+		{`title ?? 'Untitled' | default("x, y", 2)`, Binding{
+			Alternatives: []Alternative{{Path: "title"}, {Literal: "Untitled", IsLiteral: true}},
+			Filters:      []Filter{{Name: "default", Args: []any{"x, y", 2.0}}},
+		}, ""},
+		{`'a' ?? user.name`, Binding{}, "a string literal can only be the last alternative"},
+		{"user.name ??", Binding{}, "empty alternative"},
+		{"title | ", Binding{}, "invalid filter ''"},
+		{"title | truncate(40", Binding{}, "missing ) in filter"},
+		{"title | truncate(forty)", Binding{}, "invalid argument 'forty' of filter truncate"},
+	}
+	for _, tt := range tests {
+		got, err := ParseBinding(tt.binding)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseBinding(%q) error = %v, want %q", tt.binding, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseBinding(%q) = %v, %v, want %v", tt.binding, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseLiteral(t *testing.T) {
+	tests := []struct {
+		value string
+		want  any
+		ok    bool
+	}{
+		{`'primary'`, "primary", true},
+		{`"a b"`, "a b", true},
+		{"42", 42.0, true},
+		{"-1.5", -1.5, true},
+		{"true", true, true},
+		{"false", false, true},
+		{"post.title", nil, false},
+		{"1x", nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseLiteral(tt.value)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("ParseLiteral(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package hop
+
+import (
+	"bytes"
+	"context"
+	"io"
+)
+
+// TargetPrint is the target of programs whose output is printed, e.g.
+// converted to PDF, see Compiler.SetTarget:
+//
+//	<target only="print">
+//		<footer inner-text="invoice.number"></footer>
+//	</target>
+const TargetPrint = "print"
+
+// PrintOptions describes the pages that rendered HTML is printed on.
+type PrintOptions struct {
+	// PageSize is a paper size such as "A4" or "Letter".
+	PageSize  string
+	Landscape bool
+	// Margin is a CSS length such as "1cm" used on all sides.
+	Margin string
+	// HeaderHTML and FooterHTML are repeated on every page if the
+	// converter supports it.
+	HeaderHTML string
+	FooterHTML string
+}
+
+// Converter converts a rendered HTML document into another format,
+// e.g. by running wkhtmltopdf or a headless browser.
+type Converter interface {
+	Convert(ctx context.Context, w io.Writer, html io.Reader, opts PrintOptions) error
+}
+
+// ConverterFunc adapts a function to a Converter.
+type ConverterFunc func(ctx context.Context, w io.Writer, html io.Reader, opts PrintOptions) error
+
+func (f ConverterFunc) Convert(ctx context.Context, w io.Writer, html io.Reader, opts PrintOptions) error {
+	return f(ctx, w, html, opts)
+}
+
+// Print renders a function and writes the result of converting it with
+// converter to w. Nothing is passed to the converter if rendering
+// fails.
+func (p *Program) Print(ctx context.Context, w io.Writer, converter Converter, moduleName string, functionName string, data any, opts PrintOptions) error {
+	var buf bytes.Buffer
+	if err := p.ExecuteFunction(&buf, moduleName, functionName, data); err != nil {
+		return err
+	}
+	return converter.Convert(ctx, w, &buf, opts)
+}
@@ -0,0 +1,128 @@
+package hop
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// TenantSources returns the modules that a tenant overrides, keyed by
+// module name.
+type TenantSources func(tenant string) (map[string]string, error)
+
+// ProgramSet compiles and caches the programs of tenants whose modules
+// are layered on top of shared base modules. A tenant module shadows
+// the base module of the same name. At most capacity programs are kept,
+// evicting the least recently used one. Cache hits, compilations and
+// evictions are logged at debug level to the logger of the compilers
+// that newCompiler returns.
+type ProgramSet struct {
+	newCompiler func() *Compiler
+	sources     TenantSources
+	capacity    int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+// programSetEntry is a cached program. ready is closed once the program
+// has been compiled.
+type programSetEntry struct {
+	tenant  string
+	ready   chan struct{}
+	program *Program
+	err     error
+}
+
+// NewProgramSet returns a ProgramSet. newCompiler returns a compiler
+// with the base modules added and configured as needed; it is called
+// for each tenant program that is compiled.
+func NewProgramSet(newCompiler func() *Compiler, sources TenantSources, capacity int) *ProgramSet {
+	return &ProgramSet{
+		newCompiler: newCompiler,
+		sources:     sources,
+		capacity:    max(capacity, 1),
+		entries:     map[string]*list.Element{},
+		lru:         list.New(),
+	}
+}
+
+// Program returns the program of a tenant, compiling it if it is not
+// cached. Concurrent calls for the same tenant share a single
+// compilation. Failed compilations are not cached.
+func (s *ProgramSet) Program(tenant string) (*Program, error) {
+	s.mu.Lock()
+	if e, ok := s.entries[tenant]; ok {
+		s.lru.MoveToFront(e)
+		entry := e.Value.(*programSetEntry)
+		s.mu.Unlock()
+		<-entry.ready
+		if entry.err == nil {
+			entry.program.logger.Debug("program cache hit", "tenant", tenant)
+		}
+		return entry.program, entry.err
+	}
+	entry := &programSetEntry{tenant: tenant, ready: make(chan struct{})}
+	s.entries[tenant] = s.lru.PushFront(entry)
+	s.mu.Unlock()
+
+	entry.program, entry.err = s.compile(tenant)
+	close(entry.ready)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry.err != nil {
+		if e, ok := s.entries[tenant]; ok && e.Value == entry {
+			s.lru.Remove(e)
+			delete(s.entries, tenant)
+		}
+		return nil, entry.err
+	}
+	// Evict only once the program has compiled, so that failing
+	// tenants do not push out working ones.
+	for s.lru.Len() > s.capacity {
+		oldest := s.lru.Back()
+		s.lru.Remove(oldest)
+		evicted := oldest.Value.(*programSetEntry).tenant
+		delete(s.entries, evicted)
+		entry.program.logger.Debug("evicted program", "tenant", evicted)
+	}
+	return entry.program, nil
+}
+
+func (s *ProgramSet) compile(tenant string) (*Program, error) {
+	overrides, err := s.sources(tenant)
+	if err != nil {
+		return nil, err
+	}
+	c := s.newCompiler()
+	for moduleName, source := range overrides {
+		c.AddModule(moduleName, source)
+	}
+	start := time.Now()
+	program, err := c.Compile()
+	if err != nil {
+		return nil, err
+	}
+	c.logger.Debug("compiled tenant program", "tenant", tenant, "duration", time.Since(start))
+	return program, nil
+}
+
+// Invalidate removes the program of a tenant from the cache, e.g.
+// after its modules have changed.
+func (s *ProgramSet) Invalidate(tenant string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[tenant]; ok {
+		s.lru.Remove(e)
+		delete(s.entries, tenant)
+	}
+}
+
+// Len returns the number of cached programs.
+func (s *ProgramSet) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lru.Len()
+}
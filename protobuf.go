@@ -0,0 +1,88 @@
+package hop
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The structs that protoc-gen-go generates for protobuf messages can be
+// rendered without a JSON round-trip. Hop does not depend on the
+// protobuf module, so messages are recognized by their ProtoReflect
+// method and their fields by the protobuf struct tag.
+
+// wrappersPackage is the package of the well-known wrapper types such as
+// StringValue.
+const wrappersPackage = "google.golang.org/protobuf/types/known/wrapperspb"
+
+// isProtobufMessage reports whether t is the struct type of a generated
+// message.
+func isProtobufMessage(t reflect.Type) bool {
+	_, ok := reflect.PointerTo(t).MethodByName("ProtoReflect")
+	return ok
+}
+
+// hasProtobufName reports whether a field of a message has the given
+// name, either as written in the .proto file, e.g. created_at, or as
+// its JSON name, e.g. createdAt.
+func hasProtobufName(field reflect.StructField, name string) bool {
+	for _, part := range strings.Split(field.Tag.Get("protobuf"), ",") {
+		if part == "name="+name || part == "json="+name {
+			return true
+		}
+	}
+	return false
+}
+
+// protobufValue converts the value of a field of a message to the value
+// that templates see. Timestamps become a time.Time, durations and
+// enums their string form, wrappers their value, the integer and float
+// types int and float64, and repeated fields a []any. An unset message
+// field is nil.
+func protobufValue(v reflect.Value) any {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil
+	}
+	switch u := v.Interface().(type) {
+	case interface{ AsTime() time.Time }:
+		return u.AsTime()
+	case interface{ AsDuration() time.Duration }:
+		return u.AsDuration().String()
+	}
+	if v.Kind() == reflect.Ptr && v.Elem().Type().PkgPath() == wrappersPackage {
+		if value := v.Elem().FieldByName("Value"); value.IsValid() {
+			return protobufValue(value)
+		}
+	}
+	switch v.Kind() {
+	case reflect.Int32:
+		if _, ok := v.Type().MethodByName("Number"); ok {
+			if stringer, ok := v.Interface().(interface{ String() string }); ok {
+				// An enum.
+				return stringer.String()
+			}
+		}
+		return int(v.Int())
+	case reflect.Int64:
+		return int(v.Int())
+	case reflect.Uint32, reflect.Uint64:
+		return int(v.Uint())
+	case reflect.Float32:
+		// Format the float with the precision of a float32, so that
+		// 0.1 does not become 0.10000000149011612.
+		f, _ := strconv.ParseFloat(strconv.FormatFloat(v.Float(), 'g', -1, 32), 64)
+		return f
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			// A bytes field.
+			return v.Interface()
+		}
+		result := make([]any, v.Len())
+		for i := range result {
+			result[i] = protobufValue(v.Index(i))
+		}
+		return result
+	}
+	return v.Interface()
+}
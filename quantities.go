@@ -0,0 +1,181 @@
+package hop
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// NumberFormat describes how a locale writes numbers and amounts of
+// money, see Compiler.SetNumberFormat.
+type NumberFormat struct {
+	// Decimal separates the fraction of a number, e.g. "." or ",".
+	Decimal string
+	// Group separates the thousands of a number, e.g. "," or a
+	// non-breaking space.
+	Group string
+	// SymbolAfter places currency symbols after the amount, as in
+	// "12,50 €", instead of before it, as in "€12.50".
+	SymbolAfter bool
+}
+
+// NumberFormats are the number formats of some common locales.
+var NumberFormats = map[string]NumberFormat{
+	"en": {Decimal: ".", Group: ","},
+	"de": {Decimal: ",", Group: ".", SymbolAfter: true},
+	"fr": {Decimal: ",", Group: "\u202f", SymbolAfter: true},
+	"sv": {Decimal: ",", Group: "\u00a0", SymbolAfter: true},
+}
+
+// currencies are the symbols and the number of fraction digits of the
+// currencies that are not written with their code and two digits.
+var currencies = map[string]struct {
+	symbol string
+	digits int
+}{
+	"USD": {"$", 2},
+	"EUR": {"€", 2},
+	"GBP": {"£", 2},
+	"JPY": {"¥", 0},
+	"CNY": {"¥", 2},
+	"INR": {"₹", 2},
+	"KRW": {"₩", 0},
+	"SEK": {"kr", 2},
+	"NOK": {"kr", 2},
+	"DKK": {"kr", 2},
+}
+
+// SetNumberFormat sets how `money` and `measure` tags write numbers.
+// The default is NumberFormats["en"].
+func (c *Compiler) SetNumberFormat(format NumberFormat) {
+	c.numberFormat = format
+}
+
+// evaluateQuantity evaluates a `money` tag, which renders an amount of
+// a currency:
+//
+// <money from="order.total"></money>
+//
+// renders
+//
+// <data value="1234.5">$1,234.50</data>
+//
+// for {"amount": 1234.5, "currency": "USD"}, or a `measure` tag, which
+// renders a value with a unit such as {"value": 12.5, "unit": "km"} as
+// 12.5 km. The number of fraction digits defaults to that of the
+// currency for money, and to as many as needed for measures, and can
+// be set with the digits attribute. The other attributes of the tag
+// are copied.
+func (p *Program) evaluateQuantity(currentModule string, n *html.Node, s map[string]any) ([]*html.Node, error) {
+	from, _ := getAttribute(n, "from")
+	numberField, unitField := "value", "unit"
+	if n.Data == "money" {
+		numberField, unitField = "amount", "currency"
+	}
+	number, err := p.evaluatePath(from+"."+numberField, s)
+	if err != nil {
+		return nil, err
+	}
+	unit, err := p.evaluatePath(from+"."+unitField, s)
+	if err != nil {
+		return nil, err
+	}
+	numberFloat, numberOK := toFloat(number)
+	unitString, unitOK := unit.(string)
+	if !numberOK || !unitOK {
+		return nil, fmt.Errorf("%s %s must have a number %s and a string %s", n.Data, from, numberField, unitField)
+	}
+
+	digits := -1
+	if n.Data == "money" {
+		digits = 2
+		if currency, ok := currencies[unitString]; ok {
+			digits = currency.digits
+		}
+	}
+	if v, ok := getAttribute(n, "digits"); ok {
+		digits, _ = strconv.Atoi(v)
+	}
+	formatted := p.numberFormat.format(numberFloat, digits)
+	var text string
+	switch {
+	case n.Data == "measure":
+		text = formatted + "\u00a0" + unitString
+	case p.numberFormat.SymbolAfter:
+		text = formatted + "\u00a0" + currencySymbol(unitString)
+	default:
+		sign := ""
+		if strings.HasPrefix(formatted, "-") {
+			sign, formatted = "-", formatted[1:]
+		}
+		symbol := currencySymbol(unitString)
+		if _, ok := currencies[unitString]; !ok {
+			symbol += "\u00a0"
+		}
+		text = sign + symbol + formatted
+	}
+
+	nodes, err := p.evaluateNative(currentModule, n, s)
+	if err != nil {
+		return nil, err
+	}
+	result := nodes[0]
+	result.Data = "data"
+	result.DataAtom = atom.Data
+	var attrs []html.Attribute
+	for _, attr := range result.Attr {
+		if attr.Key != "from" && attr.Key != "digits" {
+			attrs = append(attrs, attr)
+		}
+	}
+	value := strconv.FormatFloat(numberFloat, 'f', digits, 64)
+	result.Attr = append(attrs, html.Attribute{Key: "value", Val: value})
+	result.AppendChild(&html.Node{Type: html.TextNode, Data: text})
+	return nodes, nil
+}
+
+// currencySymbol returns the symbol of a currency, or its code if it
+// has no known symbol.
+func currencySymbol(code string) string {
+	if currency, ok := currencies[code]; ok {
+		return currency.symbol
+	}
+	return code
+}
+
+// toFloat converts a number of the data to a float64.
+func toFloat(v any) (float64, bool) {
+	switch u := v.(type) {
+	case float64:
+		return u, true
+	case int:
+		return float64(u), true
+	}
+	return 0, false
+}
+
+// format writes a number with the given number of fraction digits, or
+// as many as needed if digits is negative.
+func (f NumberFormat) format(v float64, digits int) string {
+	s := strconv.FormatFloat(math.Abs(v), 'f', digits, 64)
+	integer, fraction, hasFraction := strings.Cut(s, ".")
+	var b strings.Builder
+	if v < 0 && strings.Trim(s, "0.") != "" {
+		b.WriteString("-")
+	}
+	for i, r := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			b.WriteString(f.Group)
+		}
+		b.WriteRune(r)
+	}
+	if hasFraction {
+		b.WriteString(f.Decimal)
+		b.WriteString(fraction)
+	}
+	return b.String()
+}
@@ -0,0 +1,372 @@
+package hop
+
+import (
+	"fmt"
+	"io/fs"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hoplang/hop-go/parser"
+	"golang.org/x/net/html"
+)
+
+// sourceFile is a parsed module of a file system that is being
+// refactored.
+type sourceFile struct {
+	path   string
+	source string
+	result *parser.ParseResult
+	edits  []edit
+}
+
+// edit replaces the bytes between start and end of a source.
+type edit struct {
+	start, end int
+	text       string
+}
+
+// loadSourceFiles parses every module of fsys, keyed by module name.
+func loadSourceFiles(fsys fs.FS) (map[string]*sourceFile, error) {
+	files := map[string]*sourceFile{}
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".hop") {
+			return nil
+		}
+		content, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+		result, err := parser.Parse(string(content))
+		if err != nil {
+			return withModule(err, "parsing", strings.TrimSuffix(path, ".hop"), path)
+		}
+		files[strings.TrimSuffix(path, ".hop")] = &sourceFile{
+			path:   path,
+			source: string(content),
+			result: result,
+		}
+		return nil
+	})
+	return files, err
+}
+
+// replaceAttribute records an edit that replaces the value of the
+// attribute key of n with text.
+func (f *sourceFile) replaceAttribute(n *html.Node, key string, text string) error {
+	attrPos, ok := f.result.NodePositions[n].Attributes[key]
+	if !ok {
+		return fmt.Errorf("%s: %s: no position for attribute %s", f.path, f.result.NodePositions[n].Start, key)
+	}
+	start, end := offset(f.source, attrPos.ValueStart), offset(f.source, attrPos.ValueEnd)
+	value, _ := getAttribute(n, key)
+	if f.source[start:end] != value {
+		return fmt.Errorf("%s: %s: can not rename escaped attribute value %q", f.path, attrPos.ValueStart, f.source[start:end])
+	}
+	f.edits = append(f.edits, edit{start: start, end: end, text: text})
+	return nil
+}
+
+// apply returns the source with all recorded edits applied.
+func (f *sourceFile) apply() string {
+	sort.Slice(f.edits, func(i, j int) bool {
+		return f.edits[i].start > f.edits[j].start
+	})
+	source := f.source
+	for _, e := range f.edits {
+		source = source[:e.start] + e.text + source[e.end:]
+	}
+	return source
+}
+
+// offset converts a position of the parser to a byte offset in source.
+// Since the parser ignores a byte order mark and treats \r\n and \r as
+// line endings, so does offset.
+func offset(source string, pos parser.Position) int {
+	i := 0
+	if strings.HasPrefix(source, "\uFEFF") {
+		i = len("\uFEFF")
+	}
+	for line := 1; line < pos.Line; line++ {
+		next := strings.IndexAny(source[i:], "\r\n")
+		if next < 0 {
+			return len(source)
+		}
+		i += next + 1
+		if source[i-1] == '\r' && i < len(source) && source[i] == '\n' {
+			i++
+		}
+	}
+	return min(i+pos.Column-1, len(source))
+}
+
+// topLevelElements returns the elements of the given tag that are
+// direct children of root.
+func topLevelElements(root *html.Node, tag string) []*html.Node {
+	var result []*html.Node
+	for c := range root.ChildNodes() {
+		if c.Type == html.ElementNode && c.Data == tag {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// findElement returns the top-level element of the given tag whose
+// attribute key has the value val.
+func findElement(root *html.Node, tag string, key string, val string) *html.Node {
+	for _, n := range topLevelElements(root, tag) {
+		if v, _ := getAttribute(n, key); v == val {
+			return n
+		}
+	}
+	return nil
+}
+
+// findImport returns the top-level import of root that makes a
+// function available by the given name.
+func findImport(root *html.Node, name string) *html.Node {
+	for _, n := range topLevelElements(root, "import") {
+		if importedName(n) == name {
+			return n
+		}
+	}
+	return nil
+}
+
+// definesHelper reports whether function contains a nested function
+// with the given name that is visible in its whole body.
+func definesHelper(function *html.Node, name string) bool {
+	for c := range function.ChildNodes() {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		if c.Data == "function" {
+			if v, _ := getAttribute(c, "name"); v == name {
+				return true
+			}
+			continue
+		}
+		if definesHelper(c, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// rendersOf returns the render tags of root that refer to the
+// top-level function or import with the given name, skipping those
+// that refer to a nested function with the same name.
+func rendersOf(root *html.Node, name string) []*html.Node {
+	var result []*html.Node
+	var visit func(n *html.Node)
+	visit = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if n.Data == "function" && definesHelper(n, name) {
+				return
+			}
+			if v, _ := getAttribute(n, "function"); n.Data == "render" && v == name {
+				result = append(result, n)
+			}
+		}
+		for c := range n.ChildNodes() {
+			visit(c)
+		}
+	}
+	visit(root)
+	return result
+}
+
+// declares reports whether root has a top-level function, import or
+// export with the given name.
+func declares(root *html.Node, name string) bool {
+	if findElement(root, "function", "name", name) != nil || findImport(root, name) != nil {
+		return true
+	}
+	for _, n := range topLevelElements(root, "export") {
+		if importedName(n) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Rename renames the function oldName of the module moduleName to
+// newName. The definition, the imports and re-exports of the function in
+// other modules and every render call that refers to it, including those
+// of modules that import it with a wildcard import, are updated.
+//
+// Modules are read from fsys in the same way as Compiler.AddFS. The
+// result maps the path of every file that has changed to its new
+// source, and fsys is not modified.
+func Rename(fsys fs.FS, moduleName string, oldName string, newName string) (map[string]string, error) {
+	files, err := loadSourceFiles(fsys)
+	if err != nil {
+		return nil, err
+	}
+	file, ok := files[moduleName]
+	if !ok {
+		return nil, fmt.Errorf("no module with name %s", moduleName)
+	}
+	root := file.result.Root
+	definition := findElement(root, "function", "name", oldName)
+	if definition == nil {
+		return nil, fmt.Errorf("no function with name %s in module %s", oldName, moduleName)
+	}
+	if declares(root, newName) {
+		return nil, fmt.Errorf("module %s already has a function with name %s", moduleName, newName)
+	}
+	if err := file.replaceAttribute(definition, "name", newName); err != nil {
+		return nil, err
+	}
+	for _, render := range rendersOf(root, oldName) {
+		if err := file.replaceAttribute(render, "function", newName); err != nil {
+			return nil, err
+		}
+	}
+
+	// exporters holds the modules that other modules can import the
+	// function from by its name: the module that defines it and those
+	// that re-export it without an alias.
+	exporters := []string{moduleName}
+	// wildcards holds the modules whose renders were updated for a
+	// wildcard import.
+	wildcards := map[string]bool{}
+	for i := 0; i < len(exporters); i++ {
+		from := exporters[i]
+		for _, otherName := range slices.Sorted(maps.Keys(files)) {
+			if otherName == from {
+				continue
+			}
+			other := files[otherName]
+			root := other.result.Root
+			for n := range root.ChildNodes() {
+				if n.Type != html.ElementNode || (n.Data != "import" && n.Data != "export") {
+					continue
+				}
+				function, _ := getAttribute(n, "function")
+				if f, _ := getAttribute(n, "from"); function != oldName || f != from {
+					continue
+				}
+				if _, aliased := getAttribute(n, "as"); aliased {
+					// The module uses or exports the function by its
+					// alias.
+					if err := other.replaceAttribute(n, "function", newName); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				if declares(root, newName) {
+					return nil, fmt.Errorf("module %s %ss %s but already has a function with name %s", otherName, n.Data, oldName, newName)
+				}
+				if err := other.replaceAttribute(n, "function", newName); err != nil {
+					return nil, err
+				}
+				if n.Data == "export" {
+					if !slices.Contains(exporters, otherName) {
+						exporters = append(exporters, otherName)
+					}
+					continue
+				}
+				for _, render := range rendersOf(root, oldName) {
+					if err := other.replaceAttribute(render, "function", newName); err != nil {
+						return nil, err
+					}
+				}
+			}
+
+			// A wildcard import makes the function available by its
+			// name unless the module defines or explicitly imports a
+			// function with that name, as in expandWildcardImports.
+			if !importsAll(root, from) || wildcards[otherName] ||
+				findElement(root, "function", "name", oldName) != nil || findImport(root, oldName) != nil {
+				continue
+			}
+			if findElement(root, "function", "name", newName) != nil || findImport(root, newName) != nil {
+				return nil, fmt.Errorf("module %s imports %s from %s but already has a function with name %s", otherName, oldName, from, newName)
+			}
+			wildcards[otherName] = true
+			for _, render := range rendersOf(root, oldName) {
+				if err := other.replaceAttribute(render, "function", newName); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return changedSources(files), nil
+}
+
+// importsAll reports whether root has a wildcard import of the module
+// from, i.e. an import without a function.
+func importsAll(root *html.Node, from string) bool {
+	for _, n := range topLevelElements(root, "import") {
+		_, ok := getAttribute(n, "function")
+		if f, _ := getAttribute(n, "from"); !ok && f == from {
+			return true
+		}
+	}
+	return false
+}
+
+// RenameModule renames the module oldName to newName and updates the
+// imports and exports of every module that imports or re-exports
+// functions from it, and the tags that include or extend it.
+//
+// The result maps the path of every file that has changed to its new
+// source. It contains the renamed module under its new path; the file
+// at the old path is not part of the result and should be removed by
+// the caller.
+func RenameModule(fsys fs.FS, oldName string, newName string) (map[string]string, error) {
+	files, err := loadSourceFiles(fsys)
+	if err != nil {
+		return nil, err
+	}
+	file, ok := files[oldName]
+	if !ok {
+		return nil, fmt.Errorf("no module with name %s", oldName)
+	}
+	if _, exists := files[newName]; exists {
+		return nil, fmt.Errorf("module %s already exists", newName)
+	}
+	for _, other := range files {
+		for n := range other.result.Root.ChildNodes() {
+			if n.Type != html.ElementNode || (n.Data != "import" && n.Data != "export") {
+				continue
+			}
+			if from, _ := getAttribute(n, "from"); from == oldName {
+				if err := other.replaceAttribute(n, "from", newName); err != nil {
+					return nil, err
+				}
+			}
+		}
+		for _, tag := range []string{"include", "extends"} {
+			for _, n := range findElements(other.result.Root, tag) {
+				if module, _ := getAttribute(n, "module"); module == oldName {
+					if err := other.replaceAttribute(n, "module", newName); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+	result := changedSources(files)
+	delete(result, file.path)
+	result[newName+".hop"] = file.apply()
+	return result, nil
+}
+
+// changedSources returns the edited sources of the files that have
+// edits, keyed by path.
+func changedSources(files map[string]*sourceFile) map[string]string {
+	result := map[string]string{}
+	for _, f := range files {
+		if len(f.edits) > 0 {
+			result[f.path] = f.apply()
+		}
+	}
+	return result
+}
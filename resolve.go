@@ -0,0 +1,163 @@
+package hop
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/hoplang/hop-go/parser"
+	"golang.org/x/net/html"
+)
+
+// renderTarget is the function that a `render` tag resolves to.
+type renderTarget struct {
+	module   string
+	function string
+}
+
+// expandWildcardImports replaces every import without a function
+// attribute, which imports all public functions of a module:
+//
+// <import from="components"></import>
+//
+// with an import of each of those functions. Functions that the module
+// defines itself or imports explicitly take precedence, and a function
+// that two wildcard imports both provide is an error. Imports from
+// modules that do not exist are left for sorting to report.
+func (p *Program) expandWildcardImports() error {
+	for _, moduleName := range slices.Sorted(maps.Keys(p.modules)) {
+		mod := p.modules[moduleName]
+		explicit := map[string]bool{}
+		for name := range mod.functions {
+			explicit[name] = true
+		}
+		for _, imp := range topLevelElements(mod.root, "import") {
+			if _, ok := getAttribute(imp, "function"); ok {
+				explicit[importedName(imp)] = true
+			}
+		}
+		importedFrom := map[string]string{}
+		for _, imp := range topLevelElements(mod.root, "import") {
+			if _, ok := getAttribute(imp, "function"); ok {
+				continue
+			}
+			from, _ := getAttribute(imp, "from")
+			imported, exists := p.modules[from]
+			if !exists {
+				continue
+			}
+			public := slices.Collect(maps.Keys(imported.functions))
+			public = append(public, slices.Collect(maps.Keys(imported.exports))...)
+			slices.Sort(public)
+			for _, name := range public {
+				if imported.private[name] || explicit[name] {
+					continue
+				}
+				if other, ok := importedFrom[name]; ok {
+					if other == from {
+						continue
+					}
+					err := &parser.ParseError{
+						Pos:     mod.nodePositions[imp].Start,
+						Message: fmt.Sprintf("parse error: function '%s' is imported from both '%s' and '%s'", name, other, from),
+					}
+					return withModule(err, "parsing", moduleName, mod.path)
+				}
+				importedFrom[name] = from
+				expanded := &html.Node{
+					Type: html.ElementNode,
+					Data: "import",
+					Attr: []html.Attribute{{Key: "function", Val: name}, {Key: "from", Val: from}},
+				}
+				mod.root.InsertBefore(expanded, imp)
+				mod.nodePositions[expanded] = mod.nodePositions[imp]
+				if err := mod.addImport(expanded, from, name, mod.nodePositions); err != nil {
+					return withModule(err, "parsing", moduleName, mod.path)
+				}
+			}
+			mod.root.RemoveChild(imp)
+		}
+	}
+	return nil
+}
+
+// importedName returns the name that an import makes a function
+// available by, which is the value of its `as` attribute if it has one:
+//
+// <import function="button" from="ui" as="ui-button"></import>
+func importedName(imp *html.Node) string {
+	if as, ok := getAttribute(imp, "as"); ok {
+		return as
+	}
+	function, _ := getAttribute(imp, "function")
+	return function
+}
+
+// addImport records the import of a function by the import element imp.
+func (mod *module) addImport(imp *html.Node, from string, function string, positions map[*html.Node]parser.NodePosition) error {
+	errorf := func(format string, args ...any) error {
+		return &parser.ParseError{
+			Pos:     positions[imp].Start,
+			Message: "parse error: " + fmt.Sprintf(format, args...),
+		}
+	}
+	name := importedName(imp)
+	if name == "" {
+		return errorf("attribute 'as' of import can not be empty")
+	}
+	target := renderTarget{module: from, function: function}
+	if existing, ok := mod.importedAs[name]; ok && existing != target {
+		return errorf("function '%s' is imported from both '%s' and '%s'", name, existing.module, from)
+	}
+	if _, ok := mod.importedAs[name]; !ok {
+		mod.imports[from] = append(mod.imports[from], function)
+	}
+	mod.importedAs[name] = target
+	return nil
+}
+
+// resolveImports checks that every function imported by a module is
+// defined in the module it is imported from.
+func (p *Program) resolveImports(moduleName string) error {
+	mod := p.modules[moduleName]
+	for _, importModuleName := range slices.Sorted(maps.Keys(mod.imports)) {
+		importedModule := p.modules[importModuleName]
+		for _, functionName := range mod.imports[importModuleName] {
+			if _, ok := importedModule.functions[functionName]; !ok || importedModule.private[functionName] {
+				return fmt.Errorf("resolving module %s: function %s not found in module %s",
+					moduleName, functionName, importModuleName)
+			}
+		}
+	}
+	return nil
+}
+
+// resolveRenderTargets resolves the function called by every `render`
+// tag of a module to the module that defines it.
+//
+// Calls to functions that are neither imported nor defined in the module
+// are left unresolved and reported by the type checker.
+func (p *Program) resolveRenderTargets(moduleName string) {
+	mod := p.modules[moduleName]
+	var visit func(n *html.Node)
+	visit = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "render" {
+			if functionName, ok := getAttribute(n, "function"); ok {
+				if target, ok := mod.importedAs[functionName]; ok {
+					mod.renderTargets[n] = target
+				} else if _, ok := mod.functions[functionName]; ok {
+					mod.renderTargets[n] = renderTarget{module: moduleName, function: functionName}
+				}
+			}
+		}
+		for c := range n.ChildNodes() {
+			visit(c)
+		}
+	}
+	for _, function := range mod.functions {
+		visit(function)
+	}
+	if mod.markup != nil {
+		visit(mod.markup)
+	}
+}
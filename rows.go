@@ -0,0 +1,253 @@
+package hop
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+
+	"golang.org/x/net/html"
+)
+
+// Rows adapts the result of a database query for `for` tags, which
+// iterate over it lazily. Each row is an object whose fields are the
+// columns of the query:
+//
+// <for each="report.orders" as="order">
+// <tr><td inner-text="order.id"></td></tr>
+// </for>
+//
+// The output of each row is written before the next row is scanned, so
+// together with StreamHTTP a large result is never held in memory. Rows
+// can only be iterated once and are closed at the end of the loop. A
+// loop over rows can not be sorted.
+type Rows struct {
+	rows    *sql.Rows
+	columns []string
+	done    bool
+}
+
+// SQLRows returns a Rows for the result of a query.
+func SQLRows(rows *sql.Rows) (*Rows, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	return &Rows{rows: rows, columns: columns}, nil
+}
+
+// next scans the next row. It returns false at the end of the rows.
+func (r *Rows) next() (map[string]any, bool, error) {
+	if !r.rows.Next() {
+		return nil, false, r.rows.Err()
+	}
+	values := make([]any, len(r.columns))
+	pointers := make([]any, len(values))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if err := r.rows.Scan(pointers...); err != nil {
+		return nil, false, err
+	}
+	row := make(map[string]any, len(r.columns))
+	for i, column := range r.columns {
+		row[column] = sqlValue(values[i])
+	}
+	return row, true, nil
+}
+
+// sqlValue converts a value scanned from a database to the value that
+// templates see.
+func sqlValue(v any) any {
+	switch u := v.(type) {
+	case []byte:
+		return string(u)
+	case int64:
+		return int(u)
+	case float32:
+		return float64(u)
+	}
+	return v
+}
+
+// forRowsOptions are the attributes of a `for` tag over rows. The limit
+// is -1 if there is none.
+type forRowsOptions struct {
+	as, indexAs, metaAs string
+	filter, sortBy      string
+	offset, limit       int
+}
+
+// rowStream is a loop over rows that is rendered while it is evaluated.
+type rowStream struct {
+	rows   *Rows
+	render func(w io.Writer) error
+}
+
+// rowStreams holds the loops over rows of a render, keyed by the
+// placeholders that they replace.
+type rowStreams struct {
+	loops map[*html.Node]*rowStream
+	// ancestors holds the elements that contain a placeholder, which
+	// renderNode writes child by child. A placeholder is attached to its
+	// parent only after it is registered, so its ancestors are marked
+	// when the render first asks for them, see contain.
+	ancestors map[*html.Node]bool
+	unmarked  []*html.Node
+}
+
+// add registers the loop that replaces placeholder.
+func (s *rowStreams) add(placeholder *html.Node, stream *rowStream) {
+	s.loops[placeholder] = stream
+	s.unmarked = append(s.unmarked, placeholder)
+}
+
+// contain reports whether n contains the placeholder of a loop.
+func (s *rowStreams) contain(n *html.Node) bool {
+	for _, placeholder := range s.unmarked {
+		for a := placeholder.Parent; a != nil && !s.ancestors[a]; a = a.Parent {
+			s.ancestors[a] = true
+		}
+	}
+	s.unmarked = s.unmarked[:0]
+	return s.ancestors[n]
+}
+
+// closeStreams closes the rows of the loops of a render, including
+// those whose placeholder was not rendered because the render failed.
+func (p *Program) closeStreams() {
+	for _, stream := range p.streams.loops {
+		stream.rows.rows.Close()
+	}
+}
+
+// evaluateForRows evaluates a `for` tag over rows. It returns a
+// placeholder that renderNode replaces by the output of the loop, which
+// is evaluated while it is written. The rows are scanned one at a time,
+// and the filter, offset and limit of the loop are applied to them as
+// they are scanned.
+func (p *Program) evaluateForRows(currentModule string, n *html.Node, s map[string]any, rows *Rows, opts forRowsOptions) ([]*html.Node, error) {
+	as, indexAs, metaAs := opts.as, opts.indexAs, opts.metaAs
+	if opts.sortBy != "" {
+		return nil, errors.New("can not sort rows, sort them in the query")
+	}
+	if rows.done {
+		return nil, errors.New("rows can only be iterated once")
+	}
+	rows.done = true
+	// The scope is cloned since an enclosing loop changes its
+	// variables before the placeholder is rendered.
+	s = maps.Clone(s)
+	// The filter sees only the row bound to as, as in other loops.
+	filterScope := maps.Clone(s)
+	placeholder := &html.Node{Type: html.ElementNode, Data: n.Data}
+	p.streams.add(placeholder, &rowStream{rows: rows, render: func(w io.Writer) (err error) {
+		defer func() {
+			if closeErr := rows.rows.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		// skip counts the rows that the offset skips, and left the rows
+		// that the limit allows.
+		skip, left := opts.offset, opts.limit
+		next := func() (map[string]any, bool, error) {
+			for {
+				if left == 0 {
+					return nil, false, nil
+				}
+				row, ok, err := p.nextRow(rows, filterScope, as, opts.filter)
+				if !ok || err != nil {
+					return row, ok, err
+				}
+				if skip > 0 {
+					skip--
+					continue
+				}
+				left--
+				return row, true, nil
+			}
+		}
+		// The next row is scanned ahead, so that the metadata of the
+		// loop knows whether the current row is the last one.
+		row, ok, err := next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			nodes, err := p.evaluateForBody(currentModule, n, s, true)
+			if err != nil {
+				return err
+			}
+			return p.renderNodes(w, nodes)
+		}
+		for i := 0; ok; i++ {
+			if indexAs != "" {
+				s[indexAs] = float64(i)
+			}
+			following, hasNext, err := next()
+			if err != nil {
+				return err
+			}
+			if metaAs != "" {
+				meta := loopMeta(i, i+2)
+				meta["last"] = !hasNext
+				s[metaAs] = meta
+			}
+			if as != "" {
+				s[as] = row
+			}
+			nodes, err := p.evaluateForBody(currentModule, n, s, false)
+			if err != nil {
+				return err
+			}
+			if err := p.renderNodes(w, nodes); err != nil {
+				return err
+			}
+			if p.boundary != nil {
+				if err := p.boundary(); err != nil {
+					return err
+				}
+			}
+			row, ok = following, hasNext
+		}
+		return nil
+	}})
+	return []*html.Node{placeholder}, nil
+}
+
+// nextRow returns the next row that the filter of a loop accepts.
+func (p *Program) nextRow(rows *Rows, s map[string]any, as, filter string) (map[string]any, bool, error) {
+	for {
+		row, ok, err := rows.next()
+		if !ok || err != nil || filter == "" {
+			return row, ok, err
+		}
+		s[as] = row
+		v, err := p.evaluatePath(filter, s)
+		if err != nil {
+			return nil, false, err
+		}
+		keep, isBool := v.(bool)
+		if !isBool {
+			return nil, false, fmt.Errorf("can not use '%v' of type %T as filter", v, v)
+		}
+		if keep {
+			return row, true, nil
+		}
+	}
+}
+
+// renderNodes renders the nodes of an iteration of a loop. The render
+// that contains the loop only sees its placeholder, so the nodes are
+// counted and their assets collected here, as they are written.
+func (p *Program) renderNodes(w io.Writer, nodes []*html.Node) error {
+	for _, n := range nodes {
+		if err := p.renderNode(w, n); err != nil {
+			return err
+		}
+		p.stats.countNodes(n)
+		p.assets.collect(n)
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package hop
+
+import (
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+
+	"github.com/hoplang/hop-go/hoptype"
+	"github.com/hoplang/hop-go/parser"
+	"github.com/hoplang/hop-go/typechecker"
+	"golang.org/x/net/html"
+)
+
+// sampleArrayLength is the number of elements of generated arrays.
+const sampleArrayLength = 3
+
+// sampleStrings are placeholder strings for fields with common names.
+var sampleStrings = map[string]string{
+	"name":        "Jane Doe",
+	"author":      "Jane Doe",
+	"email":       "jane@example.com",
+	"title":       "Lorem ipsum dolor",
+	"description": "Lorem ipsum dolor sit amet, consectetur adipiscing elit.",
+	"url":         "https://example.com/",
+	"href":        "https://example.com/",
+	"link":        "https://example.com/",
+	"src":         "https://example.com/image.png",
+	"image":       "https://example.com/image.png",
+	"date":        "2024-01-01",
+}
+
+// SampleData returns placeholder data that matches the inferred
+// parameter type of a function, so that it can be rendered without
+// real data.
+//
+// Strings are chosen by the name of the field they are stored in and
+// arrays have a few elements. An element can provide the value used for
+// its bindings with an example attribute for inner-text and with
+// example-<name> attributes for attr-<name>:
+//
+//	<h1 inner-text="post.title" example="Hello world"></h1>
+//	<a attr-href="post.url" example-href="/posts/1"></a>
+//
+// Examples are also taken from the functions that are rendered with
+// parts of the parameter. The example attributes are not rendered.
+func (p *Program) SampleData(moduleName string, functionName string) (any, error) {
+	t, err := p.FunctionType(moduleName, functionName)
+	if err != nil {
+		return nil, err
+	}
+	examples := map[string]string{}
+	p.collectExamples(moduleName, functionName, "", examples)
+	return sampleValue(t, "", "", examples)
+}
+
+// collectExamples adds the examples of a function to examples, keyed by
+// their path in the parameter with prefix prepended. Array elements are
+// written as [] in paths.
+func (p *Program) collectExamples(moduleName string, functionName string, prefix string, examples map[string]string) {
+	mod := p.modules[moduleName]
+	function := mod.functions[functionName]
+	initial := map[string]string{}
+	if paramsAs, ok := getAttribute(function, "params-as"); ok {
+		initial[paramsAs] = prefix
+	}
+	for name, variable := range typechecker.NamedParams(function) {
+		initial[variable] = joinPath(prefix, name)
+	}
+	if len(initial) == 0 {
+		return
+	}
+	// resolve returns the path in the parameter of a path of the
+	// template, or false if it does not start in the parameter.
+	resolve := func(path string, scope map[string]string) (string, bool) {
+		parts, err := parser.ParsePath(path)
+		if err != nil || len(parts) == 0 {
+			return "", false
+		}
+		result, ok := scope[parts[0].Value]
+		if !ok {
+			return "", false
+		}
+		for _, part := range parts[1:] {
+			if part.IsArrayRef {
+				result += "[]"
+			} else {
+				result = joinPath(result, part.Value)
+			}
+		}
+		return result, true
+	}
+	var visit func(n *html.Node, scope map[string]string)
+	visit = func(n *html.Node, scope map[string]string) {
+		if n.Type != html.ElementNode {
+			return
+		}
+		switch n.Data {
+		case "for":
+			each, _ := getAttribute(n, "each")
+			as, hasAs := getAttribute(n, "as")
+			if hasAs {
+				path, ok := resolve(each, scope)
+				scope = maps.Clone(scope)
+				delete(scope, as)
+				if ok {
+					scope[as] = path + "[]"
+				}
+			}
+		case "render":
+			target, ok := mod.renderTargets[n]
+			params, hasParams := getAttribute(n, "params")
+			if path, resolved := resolve(params, scope); ok && hasParams && resolved {
+				p.collectExamples(target.module, target.function, path, examples)
+			}
+			if as, ok := getAttribute(n, "children-as"); ok {
+				scope = maps.Clone(scope)
+				delete(scope, as)
+			}
+		default:
+			for _, attr := range n.Attr {
+				key, ok := exampleBinding(n, attr.Key)
+				if !ok {
+					continue
+				}
+				binding, _ := getAttribute(n, key)
+				parsed, err := parser.ParseBinding(binding)
+				if err != nil || parsed.Alternatives[0].IsLiteral {
+					continue
+				}
+				// The example is for the first alternative of a
+				// binding with fallbacks.
+				if path, ok := resolve(parsed.Alternatives[0].Path, scope); ok {
+					if _, exists := examples[path]; !exists {
+						examples[path] = attr.Val
+					}
+				}
+			}
+		}
+		for c := range n.ChildNodes() {
+			visit(c, scope)
+		}
+	}
+	for c := range function.ChildNodes() {
+		visit(c, initial)
+	}
+}
+
+// exampleBinding returns the binding that an example attribute of n
+// provides a value for, e.g. attr-href for example-href. It returns
+// false if key is not an example attribute of a binding of n.
+func exampleBinding(n *html.Node, key string) (string, bool) {
+	var binding string
+	switch {
+	case key == "example":
+		binding = "inner-text"
+	case strings.HasPrefix(key, "example-"):
+		binding = "attr-" + strings.TrimPrefix(key, "example-")
+	default:
+		return "", false
+	}
+	_, ok := getAttribute(n, binding)
+	return binding, ok
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// sampleValue returns a placeholder value of type t for the value at
+// path, which is stored in a field with the given name.
+func sampleValue(t *hoptype.Type, path string, name string, examples map[string]string) (any, error) {
+	if example, ok := examples[path]; ok {
+		return exampleValue(t, example)
+	}
+	switch t.Kind {
+	case hoptype.Void:
+		return nil, nil
+	case hoptype.Any, hoptype.String:
+		if s, ok := sampleStrings[strings.ToLower(name)]; ok {
+			return s, nil
+		}
+		return "Lorem ipsum", nil
+	case hoptype.Number:
+		return float64(sampleArrayLength), nil
+	case hoptype.Boolean:
+		return true, nil
+	case hoptype.HTML:
+		return HTML("<p>Lorem ipsum</p>"), nil
+	case hoptype.URL:
+		return URL("https://example.com/"), nil
+	case hoptype.JS:
+		return JS(""), nil
+	case hoptype.Literal:
+		return t.Value, nil
+	case hoptype.Array:
+		result := make([]any, sampleArrayLength)
+		for i := range result {
+			v, err := sampleValue(t.Elem, path+"[]", name, examples)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = v
+		}
+		return result, nil
+	case hoptype.Map:
+		result := make(map[string]any, sampleArrayLength)
+		for i := range sampleArrayLength {
+			v, err := sampleValue(t.Elem, path+"[]", name, examples)
+			if err != nil {
+				return nil, err
+			}
+			result[fmt.Sprintf("key%d", i+1)] = v
+		}
+		return result, nil
+	case hoptype.Object:
+		result := make(map[string]any, len(t.Fields))
+		for field, fieldType := range t.Fields {
+			v, err := sampleValue(fieldType, joinPath(path, field), field, examples)
+			if err != nil {
+				return nil, err
+			}
+			result[field] = v
+		}
+		return result, nil
+	case hoptype.Union:
+		return sampleValue(t.Members[0], path, name, examples)
+	}
+	return nil, fmt.Errorf("can not generate sample data for type %s", t)
+}
+
+// exampleValue converts the value of an example attribute to type t.
+func exampleValue(t *hoptype.Type, example string) (any, error) {
+	if t.Kind == hoptype.Union {
+		for _, member := range t.Members {
+			if v, err := exampleValue(member, example); err == nil {
+				return v, nil
+			}
+		}
+		return nil, fmt.Errorf("example %q is not of type %s", example, t)
+	}
+	switch t.Kind {
+	case hoptype.Any, hoptype.String:
+		return example, nil
+	case hoptype.Number:
+		f, err := strconv.ParseFloat(example, 64)
+		if err != nil {
+			return nil, fmt.Errorf("example %q is not a number", example)
+		}
+		return f, nil
+	case hoptype.Boolean:
+		b, err := strconv.ParseBool(example)
+		if err != nil {
+			return nil, fmt.Errorf("example %q is not a boolean", example)
+		}
+		return b, nil
+	case hoptype.HTML:
+		return HTML(example), nil
+	case hoptype.URL:
+		return URL(example), nil
+	case hoptype.JS:
+		return JS(example), nil
+	case hoptype.Literal:
+		if example == t.Value {
+			return example, nil
+		}
+	}
+	return nil, fmt.Errorf("example %q is not of type %s", example, t)
+}
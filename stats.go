@@ -0,0 +1,202 @@
+package hop
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// ExecStats holds statistics about renders.
+type ExecStats struct {
+	// Nodes is the number of HTML nodes written.
+	Nodes int
+	// Bytes is the number of bytes written.
+	Bytes int64
+	// Lookups is the number of paths evaluated against the data.
+	Lookups int
+	// Functions holds the statistics of each rendered function, keyed
+	// by module/function.
+	Functions map[string]*FunctionStats
+}
+
+// FunctionStats holds statistics about the renders of a function.
+type FunctionStats struct {
+	// Calls is the number of times the function was rendered.
+	Calls int
+	// Duration is the total time spent rendering the function,
+	// including the functions it renders.
+	Duration time.Duration
+}
+
+// ExecuteFunctionWithStats is like ExecuteFunction but also records
+// statistics about the render in stats. The statistics are added to
+// those already in stats, so a single ExecStats can be used to
+// aggregate several renders.
+func (p *Program) ExecuteFunctionWithStats(w io.Writer, moduleName string, functionName string, data any, stats *ExecStats) error {
+	if stats == nil {
+		return p.ExecuteFunction(w, moduleName, functionName, data)
+	}
+	withStats := *p
+	withStats.stats = stats
+	return withStats.ExecuteFunction(&countingWriter{w: w, n: &stats.Bytes}, moduleName, functionName, data)
+}
+
+func (s *ExecStats) addFunction(moduleName string, functionName string, d time.Duration) {
+	if s == nil {
+		return
+	}
+	if s.Functions == nil {
+		s.Functions = map[string]*FunctionStats{}
+	}
+	key := moduleName + "/" + functionName
+	f, ok := s.Functions[key]
+	if !ok {
+		f = &FunctionStats{}
+		s.Functions[key] = f
+	}
+	f.Calls++
+	f.Duration += d
+}
+
+// countNodes adds the number of nodes in the tree rooted at n.
+func (s *ExecStats) countNodes(n *html.Node) {
+	if s == nil {
+		return
+	}
+	s.Nodes++
+	for c := range n.ChildNodes() {
+		s.countNodes(c)
+	}
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n *int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	*c.n += int64(n)
+	return n, err
+}
+
+// TemplateStats holds compile-time statistics about the template of a
+// function, to track how complex templates grow.
+type TemplateStats struct {
+	// Nodes is the number of elements, text nodes and comments in the
+	// body of the function.
+	Nodes int
+	// Bindings is the number of inner-text, inner-html, attr- and
+	// class- bindings.
+	Bindings int
+	// Loops is the number of `for` and `table-for` tags.
+	Loops int
+	// Renders is the number of `render` tags, the fan-out of the
+	// function.
+	Renders int
+	// MaxDepth is the deepest nesting of elements in the body, where
+	// the top-level elements have depth 1.
+	MaxDepth int
+	// StaticBytes is the number of bytes of the markup of the body that
+	// are in subtrees without bindings or control elements, which render
+	// the same for all data, and TotalBytes is the number of bytes of
+	// all of the markup.
+	StaticBytes int
+	TotalBytes  int
+}
+
+// StaticRatio returns the fraction of the markup of the function that
+// is static, or 0 for an empty function.
+func (s TemplateStats) StaticRatio() float64 {
+	if s.TotalBytes == 0 {
+		return 0
+	}
+	return float64(s.StaticBytes) / float64(s.TotalBytes)
+}
+
+// controlElements are the elements that hop evaluates instead of
+// rendering as they are.
+var controlElements = map[string]bool{
+	"render": true, "fragment": true, "children": true, "slot": true, "fill": true,
+	"for": true, "empty": true, "if": true, "match": true, "case": true, "default": true,
+	"range": true, "img-set": true, "icon": true, "field": true, "options": true,
+	"choices": true, "table-for": true, "column": true, "raw-html": true,
+	"include": true, "money": true, "measure": true, "variant": true, "t": true,
+}
+
+// Stats returns statistics about the templates of the functions of the
+// program, keyed by module/function.
+func (p *Program) Stats() map[string]TemplateStats {
+	result := map[string]TemplateStats{}
+	for moduleName, mod := range p.modules {
+		for functionName, function := range mod.functions {
+			result[moduleName+"/"+functionName] = functionStats(function)
+		}
+	}
+	return result
+}
+
+// functionStats returns the statistics of the template of a function.
+func functionStats(function *html.Node) TemplateStats {
+	var stats TemplateStats
+	for c := range function.ChildNodes() {
+		stats.add(c, 1)
+		stats.TotalBytes += renderedLength(c)
+	}
+	return stats
+}
+
+// add adds the statistics of the tree rooted at n, which is nested at
+// the given depth. It reports whether the tree is static.
+func (s *TemplateStats) add(n *html.Node, depth int) bool {
+	s.Nodes++
+	static := true
+	if n.Type == html.ElementNode {
+		s.MaxDepth = max(s.MaxDepth, depth)
+		if n.Data == "for" || n.Data == "table-for" {
+			s.Loops++
+		}
+		if n.Data == "render" {
+			s.Renders++
+		}
+		if _, ok := getAttribute(n, "from"); controlElements[n.Data] || (n.Data == "time" && ok) {
+			static = false
+		}
+		for _, attr := range n.Attr {
+			if attr.Key == "inner-text" || attr.Key == "inner-html" || strings.HasPrefix(attr.Key, "attr-") || strings.HasPrefix(attr.Key, "class-") || strings.HasPrefix(attr.Key, "data-bind-") {
+				s.Bindings++
+				static = false
+			}
+		}
+	}
+	var staticChildren []*html.Node
+	for c := range n.ChildNodes() {
+		if s.add(c, depth+1) {
+			staticChildren = append(staticChildren, c)
+		} else {
+			static = false
+		}
+	}
+	if !static {
+		// The static subtrees below n are counted on their own.
+		for _, c := range staticChildren {
+			s.StaticBytes += renderedLength(c)
+		}
+		return false
+	}
+	if n.Parent != nil && n.Parent.Type == html.ElementNode && n.Parent.Data == "function" {
+		s.StaticBytes += renderedLength(n)
+	}
+	return true
+}
+
+// renderedLength returns the length of the markup of n.
+func renderedLength(n *html.Node) int {
+	var buf bytes.Buffer
+	html.Render(&buf, n)
+	return buf.Len()
+}
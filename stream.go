@@ -0,0 +1,167 @@
+package hop
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+)
+
+// StreamOptions configures StreamHTTP.
+type StreamOptions struct {
+	// MinChunk is the number of bytes that must have been written
+	// since the last flush before a chunk boundary flushes again. Each
+	// flush of a gzip stream ends a compression block, so flushing
+	// less often compresses better. It defaults to 4096.
+	MinChunk int
+}
+
+// chunkElements are the elements whose children are chunk boundaries
+// of streamed output. They are the sectioning elements of a page,
+// whose children are usually large enough to be worth a flush.
+var chunkElements = map[string]bool{
+	"html":    true,
+	"head":    true,
+	"body":    true,
+	"main":    true,
+	"header":  true,
+	"footer":  true,
+	"nav":     true,
+	"aside":   true,
+	"section": true,
+	"article": true,
+}
+
+var gzipWriters = sync.Pool{
+	New: func() any {
+		w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return w
+	},
+}
+
+// StreamHTTP renders a function as the response to r, compressing it
+// with gzip if the client accepts it. The output is flushed to the
+// client while it is written, at boundaries between the top-level
+// nodes of the function and between the children of sectioning
+// elements such as body, main and section, so that the browser can
+// start on the head of a page while the rest is still being written.
+// The gzip writers are pooled across responses.
+//
+// The Content-Type header defaults to text/html. An error is returned
+// if rendering fails, by which time part of the response may already
+// have been sent.
+func (p *Program) StreamHTTP(w http.ResponseWriter, r *http.Request, moduleName string, functionName string, data any, opts StreamOptions) error {
+	minChunk := opts.MinChunk
+	if minChunk <= 0 {
+		minChunk = 4096
+	}
+	header := w.Header()
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", "text/html; charset=utf-8")
+	}
+	header.Add("Vary", "Accept-Encoding")
+
+	rc := http.NewResponseController(w)
+	var out io.Writer = w
+	flush := rc.Flush
+	if acceptsGzip(r) {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		gz := gzipWriters.Get().(*gzip.Writer)
+		gz.Reset(w)
+		defer func() {
+			gz.Close()
+			gz.Reset(nil)
+			gzipWriters.Put(gz)
+		}()
+		out = gz
+		flush = func() error {
+			if err := gz.Flush(); err != nil {
+				return err
+			}
+			return rc.Flush()
+		}
+	}
+
+	var pending int64
+	streaming := *p
+	streaming.boundary = func() error {
+		if pending < int64(minChunk) {
+			return nil
+		}
+		pending = 0
+		if err := flush(); err != nil && err != http.ErrNotSupported {
+			return err
+		}
+		return nil
+	}
+	return streaming.ExecuteFunction(&countingWriter{w: out, n: &pending}, moduleName, functionName, data)
+}
+
+// acceptsGzip reports whether the Accept-Encoding header of r allows
+// gzip.
+func acceptsGzip(r *http.Request) bool {
+	for _, coding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(coding, ";")
+		if strings.TrimSpace(name) != "gzip" {
+			continue
+		}
+		q, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !ok {
+			return true
+		}
+		weight, err := strconv.ParseFloat(q, 64)
+		return err == nil && weight > 0
+	}
+	return false
+}
+
+// renderNode renders n like html.Render, calling the boundary of a
+// streamed render between the children of chunk elements. The
+// placeholders of loops over rows are replaced by the output of the
+// loops, which is written while they are evaluated.
+func (p *Program) renderNode(w io.Writer, n *html.Node) error {
+	if stream, ok := p.streams.loops[n]; ok {
+		// The placeholder is counted as a node by the render that
+		// contains it, but only the nodes of the loop are written.
+		if p.stats != nil {
+			p.stats.Nodes--
+		}
+		return stream.render(w)
+	}
+	chunked := p.boundary != nil && chunkElements[n.Data]
+	if n.Type != html.ElementNode || n.FirstChild == nil || !chunked && !p.streams.contain(n) {
+		return html.Render(w, n)
+	}
+	var start bytes.Buffer
+	if err := html.Render(&start, &html.Node{
+		Type:      n.Type,
+		Data:      n.Data,
+		DataAtom:  n.DataAtom,
+		Namespace: n.Namespace,
+		Attr:      n.Attr,
+	}); err != nil {
+		return err
+	}
+	end := "</" + n.Data + ">"
+	if _, err := io.WriteString(w, strings.TrimSuffix(start.String(), end)); err != nil {
+		return err
+	}
+	for c := range n.ChildNodes() {
+		if err := p.renderNode(w, c); err != nil {
+			return err
+		}
+		if chunked {
+			if err := p.boundary(); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := io.WriteString(w, end)
+	return err
+}
@@ -0,0 +1,92 @@
+package hop
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// Swapper holds the program of a running server and replaces it
+// atomically, e.g. when templates are reloaded. Renders started with
+// ExecuteFunction or Acquire finish on the program they started with,
+// and Store waits for them before returning.
+type Swapper struct {
+	current atomic.Pointer[generation]
+}
+
+// generation is a stored program together with the number of renders
+// that are using it.
+type generation struct {
+	program *Program
+
+	mu       sync.Mutex
+	inFlight int
+	retired  bool
+	drained  chan struct{}
+}
+
+// NewSwapper returns a Swapper holding p.
+func NewSwapper(p *Program) *Swapper {
+	s := &Swapper{}
+	s.current.Store(newGeneration(p))
+	return s
+}
+
+func newGeneration(p *Program) *generation {
+	return &generation{program: p, drained: make(chan struct{})}
+}
+
+// Load returns the current program.
+func (s *Swapper) Load() *Program {
+	return s.current.Load().program
+}
+
+// Store replaces the current program by p and waits until the renders
+// that use the previous program have finished.
+func (s *Swapper) Store(p *Program) {
+	old := s.current.Swap(newGeneration(p))
+	old.mu.Lock()
+	old.retired = true
+	if old.inFlight == 0 {
+		close(old.drained)
+	}
+	old.mu.Unlock()
+	<-old.drained
+}
+
+// Acquire returns the current program and a function that must be
+// called once the caller is done using it. Store does not return while
+// the program is acquired.
+func (s *Swapper) Acquire() (*Program, func()) {
+	for {
+		g := s.current.Load()
+		g.mu.Lock()
+		if g.retired {
+			// Store replaced the program after it was loaded.
+			g.mu.Unlock()
+			continue
+		}
+		g.inFlight++
+		g.mu.Unlock()
+		var once sync.Once
+		return g.program, func() {
+			once.Do(g.release)
+		}
+	}
+}
+
+func (g *generation) release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.inFlight--
+	if g.retired && g.inFlight == 0 {
+		close(g.drained)
+	}
+}
+
+// ExecuteFunction executes a function of the current program.
+func (s *Swapper) ExecuteFunction(w io.Writer, moduleName string, functionName string, data any) error {
+	p, release := s.Acquire()
+	defer release()
+	return p.ExecuteFunction(w, moduleName, functionName, data)
+}
@@ -0,0 +1,105 @@
+package hop
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hoplang/hop-go/hoptype"
+)
+
+// Table is a columnar data source, such as an Arrow record batch or the
+// result of a database query, that a `for` tag can iterate over
+// without materializing a map for each row:
+//
+// <for each="report.sales" as="sale">
+// <td inner-text="sale.region"></td>
+// </for>
+//
+// Each row is an object whose fields are the columns. Value returns the
+// values as templates see them: strings, float64 or int numbers,
+// booleans or nil.
+type Table interface {
+	Columns() []Column
+	Len() int
+	Value(row int, column int) any
+}
+
+// Column describes a column of a Table. The kind is the type of its
+// values, or hoptype.Any if it is not known.
+type Column struct {
+	Name string
+	Kind hoptype.Kind
+}
+
+// TableType returns the type of a table as an array of objects, for
+// comparison with the types returned by Program.FunctionType.
+func TableType(t Table) *hoptype.Type {
+	row := &hoptype.Type{Kind: hoptype.Object, Fields: map[string]*hoptype.Type{}}
+	for _, column := range t.Columns() {
+		row.Fields[column.Name] = &hoptype.Type{Kind: column.Kind}
+	}
+	return &hoptype.Type{Kind: hoptype.Array, Elem: row}
+}
+
+// Columns is a Table that holds the values of each column in a slice.
+type Columns struct {
+	Schema []Column
+	// Values holds the values of each column of the schema, in the same
+	// order. All columns have the same length.
+	Values [][]any
+}
+
+func (c *Columns) Columns() []Column {
+	return c.Schema
+}
+
+func (c *Columns) Len() int {
+	if len(c.Values) == 0 {
+		return 0
+	}
+	return len(c.Values[0])
+}
+
+func (c *Columns) Value(row int, column int) any {
+	return c.Values[column][row]
+}
+
+// tableRow is a row of a table that a `for` tag iterates over. The
+// columns map the names of the columns to their index and are shared
+// by the rows of a loop.
+type tableRow struct {
+	table   Table
+	row     int
+	columns map[string]int
+}
+
+// field returns the value of the column with the given name.
+func (r tableRow) field(name string) (any, error) {
+	column, ok := r.columns[name]
+	if !ok {
+		return nil, fmt.Errorf("column not found: %s", name)
+	}
+	return r.table.Value(r.row, column), nil
+}
+
+// MarshalJSON encodes the row as an object, e.g. for data-bind-
+// bindings.
+func (r tableRow) MarshalJSON() ([]byte, error) {
+	object := map[string]any{}
+	for name, column := range r.columns {
+		object[name] = r.table.Value(r.row, column)
+	}
+	return json.Marshal(object)
+}
+
+// tableRows returns a function that returns the rows of a table. The
+// rows share the index of the columns by name.
+func tableRows(table Table) func(i int) any {
+	columns := map[string]int{}
+	for i, column := range table.Columns() {
+		columns[column.Name] = i
+	}
+	return func(i int) any {
+		return tableRow{table: table, row: i, columns: columns}
+	}
+}
@@ -0,0 +1,106 @@
+package hop
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// evaluateTableFor evaluates a `table-for` tag, which renders a table
+// with a header row and a row for each element of an array:
+//
+// <table-for each="users" as="user" class="users">
+// <column label="Name"><a attr-href="user.url" inner-text="user.name"></a></column>
+// <column label="Email" inner-text="user.email"></column>
+// </table-for>
+//
+// renders
+//
+// <table class="users">
+// <thead><tr><th>Name</th><th>Email</th></tr></thead>
+// <tbody><tr><td><a href="...">...</a></td><td>...</td></tr>...</tbody>
+// </table>
+//
+// The label of a column is its header and the other attributes of a
+// column are rendered on each of its cells.
+func (p *Program) evaluateTableFor(currentModule string, n *html.Node, s map[string]any) ([]*html.Node, error) {
+	var each, as, indexAs string
+	table := &html.Node{Type: html.ElementNode, Data: "table", DataAtom: atom.Table}
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "each":
+			each = attr.Val
+		case "as":
+			as = attr.Val
+		case "index-as":
+			indexAs = attr.Val
+		default:
+			table.Attr = append(table.Attr, attr)
+		}
+	}
+	v, err := p.evaluatePath(each, s)
+	if err != nil {
+		return nil, err
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("can not iterate over '%s' of type %s %v", stringify(v), typeof(v), reflect.TypeOf(v))
+	}
+
+	var columns []*html.Node
+	headRow := &html.Node{Type: html.ElementNode, Data: "tr", DataAtom: atom.Tr}
+	for c := range n.ChildNodes() {
+		if c.Type != html.ElementNode || c.Data != "column" {
+			continue
+		}
+		columns = append(columns, c)
+		label, _ := getAttribute(c, "label")
+		th := &html.Node{Type: html.ElementNode, Data: "th", DataAtom: atom.Th}
+		th.AppendChild(&html.Node{Type: html.TextNode, Data: label})
+		headRow.AppendChild(th)
+	}
+	head := &html.Node{Type: html.ElementNode, Data: "thead", DataAtom: atom.Thead}
+	head.AppendChild(headRow)
+	table.AppendChild(head)
+
+	body := &html.Node{Type: html.ElementNode, Data: "tbody", DataAtom: atom.Tbody}
+	s = maps.Clone(s)
+	for i := 0; i < rv.Len(); i++ {
+		if as != "" {
+			s[as] = rv.Index(i).Interface()
+		}
+		if indexAs != "" {
+			s[indexAs] = float64(i)
+		}
+		row := &html.Node{Type: html.ElementNode, Data: "tr", DataAtom: atom.Tr}
+		for _, column := range columns {
+			nodes, err := p.evaluateNative(currentModule, column, s)
+			if err != nil {
+				return nil, err
+			}
+			cell := nodes[0]
+			cell.Data = "td"
+			cell.DataAtom = atom.Td
+			cell.Attr = removeAttribute(cell.Attr, "label")
+			row.AppendChild(cell)
+		}
+		body.AppendChild(row)
+	}
+	table.AppendChild(body)
+	return []*html.Node{table}, nil
+}
+
+// removeAttribute returns attrs without the attribute with the given
+// key.
+func removeAttribute(attrs []html.Attribute, key string) []html.Attribute {
+	var result []html.Attribute
+	for _, attr := range attrs {
+		if attr.Key != key {
+			result = append(result, attr)
+		}
+	}
+	return result
+}
@@ -0,0 +1,71 @@
+package hop
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hoplang/hop-go/parser"
+	"golang.org/x/net/html"
+)
+
+// SetTarget sets the rendering context that the program is compiled
+// for, such as "web" or "email". A `target` element is replaced by its
+// children when the target is one of those listed in its only
+// attribute, and removed otherwise:
+//
+//	<target only="email|amp">
+//		<img attr-src="logo.absoluteURL">
+//	</target>
+//
+// Without a target every `target` element is removed.
+func (c *Compiler) SetTarget(target string) {
+	c.target = target
+}
+
+// resolveTargets strips the `target` elements below n that do not
+// include target.
+func resolveTargets(n *html.Node, positions map[*html.Node]parser.NodePosition, target string) error {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if err := resolveTargets(c, positions, target); err != nil {
+			return err
+		}
+		if c.Type == html.ElementNode && c.Data == "target" {
+			targets, err := parseTargets(c)
+			if err != nil {
+				return &parser.ParseError{
+					Pos:     positions[c].Start,
+					Message: "parse error: " + err.Error(),
+				}
+			}
+			if slices.Contains(targets, target) {
+				unwrap(c)
+			} else {
+				n.RemoveChild(c)
+			}
+		}
+		c = next
+	}
+	return nil
+}
+
+// parseTargets returns the targets listed in the only attribute of a
+// `target` element.
+func parseTargets(n *html.Node) ([]string, error) {
+	var targets []string
+	for _, attr := range n.Attr {
+		if attr.Key != "only" {
+			return nil, fmt.Errorf("unrecognized attribute '%s' in target", attr.Key)
+		}
+		for _, t := range strings.Split(attr.Val, "|") {
+			if t = strings.TrimSpace(t); t != "" {
+				targets = append(targets, t)
+			}
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("target is missing attribute 'only'")
+	}
+	return targets, nil
+}
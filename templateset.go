@@ -0,0 +1,114 @@
+package hop
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// TemplateSet adapts a Program to the API of a set of templates of
+// html/template, so that code written against Lookup, ExecuteTemplate
+// and Execute can render hop functions.
+//
+// A template is named by the module and the name of a function,
+// separated by a slash, e.g. "pages/blog/post". A function of the
+// default module may be named without its module.
+type TemplateSet struct {
+	program       *Program
+	defaultModule string
+}
+
+// NewTemplateSet returns a TemplateSet for the exported functions of p.
+// The functions of defaultModule can be looked up by their name alone.
+func NewTemplateSet(p *Program, defaultModule string) *TemplateSet {
+	return &TemplateSet{program: p, defaultModule: defaultModule}
+}
+
+// Template is a function of a program, see TemplateSet.
+type Template struct {
+	program  *Program
+	name     string
+	module   string
+	function string
+}
+
+// Lookup returns the template with the given name, or nil if there is
+// no such template, like html/template's Lookup.
+func (t *TemplateSet) Lookup(name string) *Template {
+	moduleName, functionName := t.defaultModule, name
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		moduleName, functionName = name[:i], name[i+1:]
+	}
+	module, exists := t.program.modules[moduleName]
+	if !exists {
+		return nil
+	}
+	if _, exists := module.functions[functionName]; !exists || module.private[functionName] {
+		return nil
+	}
+	return &Template{
+		program:  t.program,
+		name:     name,
+		module:   moduleName,
+		function: functionName,
+	}
+}
+
+// ExecuteTemplate renders the template with the given name to w.
+func (t *TemplateSet) ExecuteTemplate(w io.Writer, name string, data any) error {
+	tmpl := t.Lookup(name)
+	if tmpl == nil {
+		return fmt.Errorf("no template with name %s", name)
+	}
+	return tmpl.Execute(w, data)
+}
+
+// Templates returns the templates of the set, sorted by name. The
+// functions of the default module are named without their module.
+func (t *TemplateSet) Templates() []*Template {
+	var result []*Template
+	for moduleName, functions := range t.program.GetModules() {
+		for _, functionName := range functions {
+			name := moduleName + "/" + functionName
+			if moduleName == t.defaultModule {
+				name = functionName
+			}
+			result = append(result, &Template{
+				program:  t.program,
+				name:     name,
+				module:   moduleName,
+				function: functionName,
+			})
+		}
+	}
+	slices.SortFunc(result, func(a, b *Template) int {
+		return strings.Compare(a.name, b.name)
+	})
+	return result
+}
+
+// DefinedTemplates returns a string listing the templates of the set,
+// prefixed by "; defined templates are: ", or the empty string if there
+// are none, like html/template's DefinedTemplates.
+func (t *TemplateSet) DefinedTemplates() string {
+	templates := t.Templates()
+	if len(templates) == 0 {
+		return ""
+	}
+	names := make([]string, len(templates))
+	for i, tmpl := range templates {
+		names[i] = fmt.Sprintf("%q", tmpl.name)
+	}
+	return "; defined templates are: " + strings.Join(names, ", ")
+}
+
+// Name returns the name of the template.
+func (t *Template) Name() string {
+	return t.name
+}
+
+// Execute renders the template to w.
+func (t *Template) Execute(w io.Writer, data any) error {
+	return t.program.ExecuteFunction(w, t.module, t.function, data)
+}
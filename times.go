@@ -0,0 +1,87 @@
+package hop
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// standardTimeFormats are the layouts of the formats of `time` tags
+// that every compiler starts with.
+var standardTimeFormats = map[string]string{
+	"date":     "2006-01-02",
+	"short":    "Jan 2, 2006",
+	"long":     "January 2, 2006",
+	"time":     "15:04",
+	"datetime": "January 2, 2006 15:04",
+}
+
+var validTimeFormatNameRegex = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// SetTimeFormat sets the layout of a format that `time` tags can use,
+// as accepted by time.Time.Format. The standard formats are date,
+// short, long, time and datetime, which use English month names and
+// can be replaced to localize them:
+//
+//	c.SetTimeFormat("long", "2 January 2006")
+//
+// It panics if the name is not a lowercase identifier.
+func (c *Compiler) SetTimeFormat(name string, layout string) {
+	if !validTimeFormatNameRegex.MatchString(name) {
+		panic(fmt.Sprintf("hop: invalid time format name %q", name))
+	}
+	c.timeFormats[name] = layout
+}
+
+// evaluateTime evaluates a `time` tag with a from attribute, which
+// renders a time both for people and for machines:
+//
+// <time from="event.start" format="long"></time>
+//
+// renders
+//
+// <time datetime="2024-05-01T10:00:00Z">May 1, 2024</time>
+//
+// The value is a time.Time or a string in RFC 3339 format, as in JSON
+// data, and is formatted in its own time zone. The format defaults to
+// long, and the other attributes of the tag are copied.
+func (p *Program) evaluateTime(currentModule string, n *html.Node, s map[string]any) ([]*html.Node, error) {
+	from, _ := getAttribute(n, "from")
+	v, err := p.evaluatePath(from, s)
+	if err != nil {
+		return nil, err
+	}
+	var t time.Time
+	switch u := v.(type) {
+	case time.Time:
+		t = u
+	case string:
+		t, err = time.Parse(time.RFC3339Nano, u)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse %s as an RFC 3339 time", stringify(u))
+		}
+	default:
+		return nil, fmt.Errorf("can not use '%s' of type %s as a time", stringify(v), typeof(v))
+	}
+	format, ok := getAttribute(n, "format")
+	if !ok {
+		format = "long"
+	}
+
+	nodes, err := p.evaluateNative(currentModule, n, s)
+	if err != nil {
+		return nil, err
+	}
+	result := nodes[0]
+	var attrs []html.Attribute
+	for _, attr := range result.Attr {
+		if attr.Key != "from" && attr.Key != "format" {
+			attrs = append(attrs, attr)
+		}
+	}
+	result.Attr = append(attrs, html.Attribute{Key: "datetime", Val: t.Format(time.RFC3339)})
+	result.AppendChild(&html.Node{Type: html.TextNode, Data: t.Format(p.timeFormats[format])})
+	return nodes, nil
+}
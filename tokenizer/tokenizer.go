@@ -83,10 +83,23 @@ type Tokenizer struct {
 	specialTagNames map[string]bool
 }
 
-// NewTokenizer creates a new tokenizer with the given input
+// Normalize removes a leading UTF-8 byte order mark from input and
+// converts \r\n and \r line endings to \n, so that positions and text
+// do not depend on the editor a template was written with.
+func Normalize(input string) string {
+	input = strings.TrimPrefix(input, "\uFEFF")
+	if !strings.Contains(input, "\r") {
+		return input
+	}
+	input = strings.ReplaceAll(input, "\r\n", "\n")
+	return strings.ReplaceAll(input, "\r", "\n")
+}
+
+// NewTokenizer creates a new tokenizer with the given input. The input
+// is normalized with Normalize.
 func NewTokenizer(input string) *Tokenizer {
 	return &Tokenizer{
-		input:           input,
+		input:           Normalize(input),
 		state:           TEXT,
 		position:        Position{Line: 1, Column: 1},
 		currentPosition: 0,
@@ -139,3 +139,24 @@ func TestTokenizerExamples(t *testing.T) {
 		t.Fatalf("Failed to walk test directory: %v", err)
 	}
 }
+
+func TestNormalizeLineEndings(t *testing.T) {
+	tokens := NewTokenizer("\uFEFF<p>\r\n</p>\r<b></b>").Tokenize()
+	actualTokens := make([]string, len(tokens))
+	for i, token := range tokens {
+		actualTokens[i] = formatToken(token)
+	}
+	expectedTokens := []string{
+		"StartTag(p) 1:1-1:4",
+		"Text 1:4-2:1",
+		"EndTag(p) 2:1-2:5",
+		"Text 2:5-3:1",
+		"StartTag(b) 3:1-3:4",
+		"EndTag(b) 3:4-3:8",
+	}
+	if !reflect.DeepEqual(actualTokens, expectedTokens) {
+		t.Errorf("Token mismatch:\nExpected:\n%s\n\nActual:\n%s",
+			strings.Join(expectedTokens, "\n"),
+			strings.Join(actualTokens, "\n"))
+	}
+}
@@ -0,0 +1,96 @@
+package hop
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hoplang/hop-go/typechecker"
+	"golang.org/x/net/html"
+)
+
+// HTML is a string of markup that is known to be safe. inner-html and
+// raw-html insert it without escaping, while inner-text escapes it like
+// any other text.
+//
+// The caller is responsible for the contents of the value. Never
+// convert strings that contain user input to HTML.
+type HTML string
+
+// URL is a URL that is known to be safe. It can be bound to URL
+// attributes such as attr-href and is not checked by the URL policy.
+type URL string
+
+// JS is JavaScript code that is known to be safe. It can be bound to
+// event handler attributes such as attr-onclick.
+type JS string
+
+// attributeValue converts v to the value of the attribute with the
+// given name. The returned bool reports whether v is a trusted value
+// for the attribute.
+func attributeValue(name string, v any) (string, bool, error) {
+	switch u := v.(type) {
+	case float64:
+		return fmt.Sprintf("%g", u), false, nil
+	case int:
+		return fmt.Sprintf("%d", u), false, nil
+	case string:
+		return u, false, nil
+	case URL:
+		if !typechecker.IsURLAttribute(name) {
+			return "", false, fmt.Errorf("can not use trusted url as attribute %s", name)
+		}
+		return string(u), true, nil
+	case JS:
+		if !strings.HasPrefix(name, "on") {
+			return "", false, fmt.Errorf("can not use trusted js as attribute %s", name)
+		}
+		return string(u), true, nil
+	case HTML:
+		return "", false, fmt.Errorf("can not use trusted html as attribute %s", name)
+	}
+	return "", false, fmt.Errorf("can not use '%s' of type %s as an attribute", stringify(v), typeof(v))
+}
+
+// evaluateInnerHTML evaluates an inner-html binding, which inserts HTML
+// as the children of an element:
+//
+// <div inner-html="post.body"></div>
+func (p *Program) evaluateInnerHTML(s map[string]any, binding string) (*html.Node, error) {
+	v, err := p.evaluateBinding(binding, s)
+	if err != nil {
+		return nil, err
+	}
+	u, ok := v.(HTML)
+	if !ok {
+		return nil, fmt.Errorf("can not use '%s' of type %s as inner html, convert it to hop.HTML", stringify(v), typeof(v))
+	}
+	return &html.Node{Type: html.RawNode, Data: string(u)}, nil
+}
+
+// evaluateRawHTML evaluates a `raw-html` tag, which inserts markup
+// without escaping:
+//
+// <raw-html value="post.body"></raw-html>
+//
+// The value must be HTML, or a string if the program was compiled with
+// Compiler.SetRawHTMLStrings.
+func (p *Program) evaluateRawHTML(n *html.Node, s map[string]any) ([]*html.Node, error) {
+	path, _ := getAttribute(n, "value")
+	v, err := p.evaluatePath(path, s)
+	if err != nil {
+		return nil, err
+	}
+	var raw string
+	switch u := v.(type) {
+	case HTML:
+		raw = string(u)
+	case string:
+		if !p.rawHTMLStrings {
+			return nil, fmt.Errorf("can not insert a string as raw html, convert it to hop.HTML or allow strings with SetRawHTMLStrings")
+		}
+		raw = u
+	default:
+		return nil, fmt.Errorf("can not use '%s' of type %s as raw html", stringify(v), typeof(v))
+	}
+	return []*html.Node{{Type: html.RawNode, Data: raw}}, nil
+}
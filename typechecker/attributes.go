@@ -0,0 +1,141 @@
+package typechecker
+
+import (
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// knownAttributes lists the attributes of the hop control elements
+// that are checked in strict mode.
+var knownAttributes = map[string][]string{
+	"function": {"name", "params-as"},
+	"render":   {"function", "params", "params-literal", "children-as"},
+}
+
+// knownPrefixes are the prefixes of the attributes of the hop control
+// elements that declare and pass named parameters.
+var knownPrefixes = map[string]string{
+	"function": "param-",
+	"render":   "with-",
+}
+
+// checkAttributeNames reports attributes of n that look like
+// misspelled hop attributes. Unknown attributes on control elements
+// are rejected, and on other elements attributes that are close to
+// inner-text or to the attr- prefix are rejected since they would
+// otherwise be rendered literally.
+func (tc *typeChecker) checkAttributeNames(n *html.Node) error {
+	if known, ok := knownAttributes[n.Data]; ok {
+		for _, attr := range n.Attr {
+			if slices.Contains(known, attr.Key) {
+				continue
+			}
+			// A misspelling such as param-as takes precedence over
+			// the parameter that it would declare.
+			if suggestion := closest(attr.Key, known); suggestion != "" {
+				return tc.newErrorForAttr(n, attr.Key, "unrecognized attribute '%s' in %s, did you mean '%s'?", attr.Key, n.Data, suggestion)
+			}
+			if strings.HasPrefix(attr.Key, knownPrefixes[n.Data]) {
+				continue
+			}
+			return tc.newErrorForAttr(n, attr.Key, "unrecognized attribute '%s' in %s", attr.Key, n.Data)
+		}
+		return nil
+	}
+	for _, attr := range n.Attr {
+		if attr.Key == "inner-text" || attr.Key == "inner-html" || strings.HasPrefix(attr.Key, "attr-") || strings.HasPrefix(attr.Key, "class-") || strings.HasPrefix(attr.Key, "data-bind-") {
+			continue
+		}
+		if suggestion := closest(attr.Key, []string{"inner-text", "inner-html"}); suggestion != "" {
+			return tc.newErrorForAttr(n, attr.Key, "unrecognized attribute '%s' in %s, did you mean '%s'?", attr.Key, n.Data, suggestion)
+		}
+		if name, ok := cutAttrPrefix(attr.Key); ok {
+			return tc.newErrorForAttr(n, attr.Key, "unrecognized attribute '%s' in %s, did you mean 'attr-%s'?", attr.Key, n.Data, name)
+		}
+	}
+	return nil
+}
+
+// cutAttrPrefix returns the rest of a key such as "atr-class" or
+// "attr_class" that starts with a misspelling of the attr- prefix.
+func cutAttrPrefix(key string) (string, bool) {
+	i := strings.IndexAny(key, "-_:")
+	if i < 3 || i == len(key)-1 || key[:i+1] == "attr-" || levenshtein(key[:i], "attr") > 1 {
+		return "", false
+	}
+	return key[i+1:], true
+}
+
+// closest returns the candidate that is within a small edit distance
+// of s, or the empty string if there is none.
+func closest(s string, candidates []string) string {
+	best, bestDistance := "", 3
+	for _, c := range candidates {
+		if d := levenshtein(s, c); d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+	return best
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// mergedAttributes are the attributes that may be set both statically
+// and with an attr- binding. The values are joined with a space.
+var mergedAttributes = map[string]bool{
+	"class": true,
+}
+
+// checkAttributeCollisions reports attr- and data-bind- bindings that
+// generate an attribute with a reserved name, or an attribute that is
+// also set statically or by another binding and can not be merged.
+func (tc *typeChecker) checkAttributeCollisions(n *html.Node) error {
+	static := map[string]bool{}
+	bound := map[string]string{}
+	for _, attr := range n.Attr {
+		if attr.Key != "inner-text" && attr.Key != "inner-html" && !strings.HasPrefix(attr.Key, "attr-") && !strings.HasPrefix(attr.Key, "class-") && !strings.HasPrefix(attr.Key, "data-bind-") {
+			static[attr.Key] = true
+		}
+	}
+	for _, attr := range n.Attr {
+		name, ok := strings.CutPrefix(attr.Key, "attr-")
+		if data, isData := strings.CutPrefix(attr.Key, "data-bind-"); isData {
+			name, ok = "data-"+data, true
+		}
+		if !ok {
+			continue
+		}
+		if name == "" || name == "data-" || name == "inner-text" || name == "inner-html" || strings.HasPrefix(name, "attr-") || strings.HasPrefix(name, "class-") || strings.HasPrefix(name, "data-bind-") {
+			return tc.newErrorForAttr(n, attr.Key, "%s generates an attribute with the reserved name '%s'", attr.Key, name)
+		}
+		if static[name] && !mergedAttributes[name] {
+			return tc.newErrorForAttr(n, attr.Key, "attribute '%s' is set both statically and by %s", name, attr.Key)
+		}
+		if other, ok := bound[name]; ok && !mergedAttributes[name] {
+			return tc.newErrorForAttr(n, attr.Key, "attribute '%s' is set both by %s and by %s", name, other, attr.Key)
+		}
+		bound[name] = attr.Key
+	}
+	return nil
+}
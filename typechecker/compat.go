@@ -0,0 +1,100 @@
+package typechecker
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// resolve follows the links of bound type variables until it reaches
+// a concrete type or an unbound type variable.
+func resolve(t TypeExpr) TypeExpr {
+	for {
+		tv, ok := t.(*TypeVar)
+		if !ok || tv.Link == nil {
+			return t
+		}
+		t = *tv.Link
+	}
+}
+
+// CheckCompatible checks that every value accepted by a function
+// with parameter type old is also accepted by a function with
+// parameter type new.
+//
+// A nil error means that replacing old with new is not a breaking change.
+func CheckCompatible(old, new TypeExpr) error {
+	return checkCompatible(Normalize(old), Normalize(new))
+}
+
+func checkCompatible(old, new TypeExpr) error {
+	// An unconstrained parameter accepts anything.
+	if new == anyType {
+		return nil
+	}
+	if old == anyType {
+		return fmt.Errorf("accepted any value but now requires %s", new)
+	}
+
+	if old, ok := old.(*UnionType); ok {
+		for _, t := range old.Types {
+			if err := checkCompatible(t, new); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if new, ok := new.(*UnionType); ok {
+		for _, t := range new.Types {
+			if checkCompatible(old, t) == nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("accepted %s but now requires %s", old, new)
+	}
+
+	switch new := new.(type) {
+	case PrimitiveType:
+		if old, ok := old.(PrimitiveType); ok && old == new {
+			return nil
+		}
+		// A string literal is still accepted by a string parameter.
+		if _, ok := old.(LiteralType); ok && new == "string" {
+			return nil
+		}
+	case LiteralType:
+		if old, ok := old.(LiteralType); ok && old == new {
+			return nil
+		}
+	case *ArrayType:
+		if old, ok := old.(*ArrayType); ok {
+			if err := checkCompatible(old.ElementType, new.ElementType); err != nil {
+				return fmt.Errorf("array element: %w", err)
+			}
+			return nil
+		}
+	case *MapType:
+		if old, ok := old.(*MapType); ok {
+			if err := checkCompatible(old.ValueType, new.ValueType); err != nil {
+				return fmt.Errorf("map value: %w", err)
+			}
+			return nil
+		}
+	case *ObjectType:
+		if old, ok := old.(*ObjectType); ok {
+			for _, name := range slices.Sorted(maps.Keys(new.Fields)) {
+				newField := new.Fields[name]
+				oldField, exists := old.Fields[name]
+				if !exists {
+					return fmt.Errorf("requires new field '%s'", name)
+				}
+				if err := checkCompatible(oldField, newField); err != nil {
+					return fmt.Errorf("field %s: %w", name, err)
+				}
+			}
+			return nil
+		}
+	}
+
+	return fmt.Errorf("accepted %s but now requires %s", old, new)
+}
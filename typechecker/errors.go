@@ -10,6 +10,11 @@ import (
 
 // TypeError represents a type mismatch in template usage
 type TypeError struct {
+	// Module is the module that the error occurred in and File is the
+	// file it was read from, if any. They are set by the compiler since
+	// the type checker only sees a single module.
+	Module  string
+	File    string
 	Start   parser.Position
 	End     parser.Position
 	Context string
@@ -17,11 +22,17 @@ type TypeError struct {
 }
 
 func (e *TypeError) Error() string {
+	var prefix string
+	if e.File != "" {
+		prefix = e.File + ": "
+	} else if e.Module != "" {
+		prefix = e.Module + ": "
+	}
 	if len(e.Path) > 0 {
-		return fmt.Sprintf("%s-%s: type error in %s: %s",
-			e.Start, e.End, strings.Join(e.Path, "."), e.Context)
+		return fmt.Sprintf("%s%s-%s: type error in %s: %s",
+			prefix, e.Start, e.End, strings.Join(e.Path, "."), e.Context)
 	}
-	return fmt.Sprintf("%s-%s: type error: %s", e.Start, e.End, e.Context)
+	return fmt.Sprintf("%s%s-%s: type error: %s", prefix, e.Start, e.End, e.Context)
 }
 
 // Helper to create type errors with position information
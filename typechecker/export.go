@@ -0,0 +1,55 @@
+package typechecker
+
+import (
+	"github.com/hoplang/hop-go/hoptype"
+)
+
+// Export converts an inferred type into its public representation.
+//
+// The type is normalized first, so type variables that are still
+// unbound become hoptype.Any. The returned type shares no
+// state with t.
+func Export(t TypeExpr) *hoptype.Type {
+	return export(Normalize(t))
+}
+
+func export(t TypeExpr) *hoptype.Type {
+	switch t := t.(type) {
+	case PrimitiveType:
+		switch t {
+		case "void":
+			return &hoptype.Type{Kind: hoptype.Void}
+		case "string":
+			return &hoptype.Type{Kind: hoptype.String}
+		case "number":
+			return &hoptype.Type{Kind: hoptype.Number}
+		case "boolean":
+			return &hoptype.Type{Kind: hoptype.Boolean}
+		case HTMLType:
+			return &hoptype.Type{Kind: hoptype.HTML}
+		case URLType:
+			return &hoptype.Type{Kind: hoptype.URL}
+		case JSType:
+			return &hoptype.Type{Kind: hoptype.JS}
+		}
+	case LiteralType:
+		return &hoptype.Type{Kind: hoptype.Literal, Value: string(t)}
+	case *ArrayType:
+		return &hoptype.Type{Kind: hoptype.Array, Elem: export(t.ElementType)}
+	case *MapType:
+		return &hoptype.Type{Kind: hoptype.Map, Elem: export(t.ValueType)}
+	case *ObjectType:
+		fields := make(map[string]*hoptype.Type, len(t.Fields))
+		for name, field := range t.Fields {
+			fields[name] = export(field)
+		}
+		return &hoptype.Type{Kind: hoptype.Object, Fields: fields}
+	case *UnionType:
+		members := make([]*hoptype.Type, len(t.Types))
+		for i, member := range t.Types {
+			members[i] = export(member)
+		}
+		return &hoptype.Type{Kind: hoptype.Union, Members: members}
+	}
+	return &hoptype.Type{Kind: hoptype.Any}
+}
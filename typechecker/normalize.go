@@ -0,0 +1,85 @@
+package typechecker
+
+import (
+	"slices"
+	"strings"
+)
+
+// anyType is the type that unbound type variables are replaced
+// with when a type is normalized.
+const anyType = PrimitiveType("any")
+
+// Normalize returns a copy of t in canonical form.
+//
+// All type variable links are resolved, unbound type variables
+// become "any" and constrained type variables become unions.
+// Nested unions are flattened, duplicate union members are removed
+// and the members are sorted. Since ObjectType prints its fields in
+// sorted order, two normalized types are equal if and only if their
+// string forms are equal.
+func Normalize(t TypeExpr) TypeExpr {
+	switch t := resolve(t).(type) {
+	case *TypeVar:
+		switch len(t.Allowed) {
+		case 0:
+			return anyType
+		case 1:
+			return t.Allowed[0]
+		}
+		members := slices.Clone(t.Allowed)
+		sortMembers(members)
+		return &UnionType{Types: members}
+	case PrimitiveType, LiteralType:
+		return t
+	case *ArrayType:
+		return &ArrayType{ElementType: Normalize(t.ElementType)}
+	case *MapType:
+		return &MapType{ValueType: Normalize(t.ValueType)}
+	case *ObjectType:
+		fields := make(map[string]TypeExpr, len(t.Fields))
+		for name, field := range t.Fields {
+			fields[name] = Normalize(field)
+		}
+		return &ObjectType{Fields: fields}
+	case *UnionType:
+		var members []TypeExpr
+		seen := map[string]bool{}
+		var add func(t TypeExpr)
+		add = func(t TypeExpr) {
+			if u, ok := t.(*UnionType); ok {
+				for _, member := range u.Types {
+					add(member)
+				}
+				return
+			}
+			key := t.String()
+			if !seen[key] {
+				seen[key] = true
+				members = append(members, t)
+			}
+		}
+		for _, member := range t.Types {
+			add(Normalize(member))
+		}
+		if len(members) == 1 {
+			return members[0]
+		}
+		sortMembers(members)
+		return &UnionType{Types: members}
+	default:
+		return t
+	}
+}
+
+// sortMembers sorts union members by their string form, so that the
+// order in which the members were written does not matter.
+func sortMembers(members []TypeExpr) {
+	slices.SortFunc(members, func(a, b TypeExpr) int {
+		return strings.Compare(a.String(), b.String())
+	})
+}
+
+// Equal reports whether two types are equal after normalization.
+func Equal(t1, t2 TypeExpr) bool {
+	return Normalize(t1).String() == Normalize(t2).String()
+}
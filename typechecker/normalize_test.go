@@ -0,0 +1,44 @@
+package typechecker
+
+import (
+	"testing"
+)
+
+func TestEqualIgnoresUnionOrder(t *testing.T) {
+	tc := &typeChecker{}
+	tests := []struct {
+		name   string
+		t1, t2 TypeExpr
+	}{
+		{
+			name: "unions",
+			t1:   &UnionType{Types: []TypeExpr{PrimitiveType("string"), PrimitiveType("number")}},
+			t2:   &UnionType{Types: []TypeExpr{PrimitiveType("number"), PrimitiveType("string")}},
+		},
+		{
+			name: "nested unions",
+			t1: &UnionType{Types: []TypeExpr{
+				PrimitiveType("boolean"),
+				&UnionType{Types: []TypeExpr{PrimitiveType("string"), PrimitiveType("number")}},
+			}},
+			t2: &UnionType{Types: []TypeExpr{PrimitiveType("number"), PrimitiveType("boolean"), PrimitiveType("string")}},
+		},
+		{
+			name: "constrained vars",
+			t1:   tc.newConstrainedVar("string", "number"),
+			t2:   tc.newConstrainedVar("number", "string"),
+		},
+		{
+			name: "literals",
+			t1:   tc.newLiteralVar("published", "draft"),
+			t2:   &UnionType{Types: []TypeExpr{LiteralType("draft"), LiteralType("published")}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !Equal(tt.t1, tt.t2) {
+				t.Errorf("Expected %s and %s to be equal", Normalize(tt.t1), Normalize(tt.t2))
+			}
+		})
+	}
+}
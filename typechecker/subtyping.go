@@ -0,0 +1,117 @@
+package typechecker
+
+import (
+	"sort"
+
+	"golang.org/x/net/html"
+)
+
+// Options configures the type checker.
+type Options struct {
+	// StrictParams rejects render calls that pass an object with
+	// fields that the called function does not use.
+	StrictParams bool
+	// StrictAttributes rejects unknown attributes on control elements
+	// and attributes that look like misspelled hop attributes, such as
+	// innertext or atr-class.
+	StrictAttributes bool
+	// BuildVars are the compile-time constants that bindings can
+	// reference with a $ prefix, e.g. inner-text="$revision".
+	BuildVars map[string]string
+	// Filters are the filters that bindings can pipe their value
+	// through, e.g. inner-text="title | uppercase".
+	Filters map[string]*FilterType
+	// RawHTMLStrings allows raw-html to insert plain strings. Otherwise
+	// its value must be trusted html.
+	RawHTMLStrings bool
+	// TimeFormats are the layouts of the formats that `time` tags can
+	// use, by name.
+	TimeFormats map[string]string
+	// URLBindings, if not nil, is filled with the types of the attr-
+	// bindings of URL attributes. The types are final once Typecheck
+	// returns.
+	URLBindings map[Binding]TypeExpr
+}
+
+// Binding is an attribute of an element whose value is bound to data.
+type Binding struct {
+	Node *html.Node
+	Attr string
+}
+
+// paramsCheck is a render call whose argument is checked against the
+// parameter type of the called function once all functions of the
+// module have been type checked.
+type paramsCheck struct {
+	node         *html.Node
+	functionName string
+	argument     TypeExpr
+	parameter    TypeExpr
+}
+
+// instantiate returns a copy of t where every unbound type variable
+// has been replaced by a fresh type variable.
+//
+// Render calls unify their argument with an instance of the parameter
+// type of the called function, so that fields used by the caller are
+// never added to the signature of the called function.
+func (tc *typeChecker) instantiate(t TypeExpr, vars map[*TypeVar]*TypeVar) TypeExpr {
+	switch t := resolve(t).(type) {
+	case *TypeVar:
+		if v, ok := vars[t]; ok {
+			return v
+		}
+		v := tc.newVar()
+		v.Allowed = t.Allowed
+		vars[t] = v
+		return v
+	case *ArrayType:
+		return &ArrayType{ElementType: tc.instantiate(t.ElementType, vars)}
+	case *MapType:
+		return &MapType{ValueType: tc.instantiate(t.ValueType, vars)}
+	case *ObjectType:
+		fields := make(map[string]TypeExpr, len(t.Fields))
+		for name, field := range t.Fields {
+			fields[name] = tc.instantiate(field, vars)
+		}
+		return &ObjectType{Fields: fields}
+	case *UnionType:
+		types := make([]TypeExpr, len(t.Types))
+		for i, member := range t.Types {
+			types[i] = tc.instantiate(member, vars)
+		}
+		return &UnionType{Types: types}
+	default:
+		return t
+	}
+}
+
+// extraFields returns the paths of the object fields of argument
+// that are not present in parameter.
+func extraFields(argument, parameter TypeExpr, prefix string) []string {
+	var result []string
+	switch argument := resolve(argument).(type) {
+	case *ArrayType:
+		if parameter, ok := resolve(parameter).(*ArrayType); ok {
+			result = extraFields(argument.ElementType, parameter.ElementType, prefix)
+		}
+	case *MapType:
+		if parameter, ok := resolve(parameter).(*MapType); ok {
+			result = extraFields(argument.ValueType, parameter.ValueType, prefix)
+		}
+	case *ObjectType:
+		parameter, ok := resolve(parameter).(*ObjectType)
+		if !ok {
+			break
+		}
+		for name, field := range argument.Fields {
+			if parameterField, exists := parameter.Fields[name]; exists {
+				result = append(result, extraFields(field, parameterField, prefix+name+".")...)
+			} else {
+				result = append(result, prefix+name)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
@@ -1,8 +1,13 @@
 package typechecker
 
 import (
+	"cmp"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"maps"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/hoplang/hop-go/internal/toposort"
@@ -11,16 +16,25 @@ import (
 )
 
 type typeChecker struct {
-	nextVar        int
-	functionParams map[string]TypeExpr
-	nodePositions  map[*html.Node]parser.NodePosition
+	nextVar       int
+	functionTypes map[string]*FunctionType
+	currentSlot   TypeExpr
+	currentSlots  map[string]bool
+	// bareChildren is whether the current function has a <children>
+	// tag without params.
+	bareChildren  bool
+	nodePositions map[*html.Node]parser.NodePosition
+	options       Options
+	paramsChecks  []paramsCheck
+	matchChecks   []matchCheck
 }
 
-func newTypeChecker(positions map[*html.Node]parser.NodePosition) *typeChecker {
+func newTypeChecker(positions map[*html.Node]parser.NodePosition, options Options) *typeChecker {
 	return &typeChecker{
-		nextVar:        0,
-		functionParams: make(map[string]TypeExpr),
-		nodePositions:  positions,
+		nextVar:       0,
+		functionTypes: make(map[string]*FunctionType),
+		nodePositions: positions,
+		options:       options,
 	}
 }
 
@@ -29,6 +43,82 @@ func (tc *typeChecker) newVar() *TypeVar {
 	return &TypeVar{Name: fmt.Sprintf("t%d", tc.nextVar)}
 }
 
+// newConstrainedVar creates a type variable that may only be bound
+// to one of the given primitive types.
+func (tc *typeChecker) newConstrainedVar(allowed ...PrimitiveType) *TypeVar {
+	tv := tc.newVar()
+	for _, t := range allowed {
+		tv.Allowed = append(tv.Allowed, t)
+	}
+	return tv
+}
+
+// newLiteralVar creates a type variable that may only be one of the
+// given strings, i.e. a union of string literal types.
+func (tc *typeChecker) newLiteralVar(values ...string) *TypeVar {
+	tv := tc.newVar()
+	for _, value := range values {
+		tv.Allowed = append(tv.Allowed, LiteralType(value))
+	}
+	return tv
+}
+
+// allows reports whether a variable that allows the given types can be
+// bound to t. A string literal type is allowed wherever string is.
+func allows(allowed []TypeExpr, t TypeExpr) bool {
+	_, isLiteral := t.(LiteralType)
+	for _, a := range allowed {
+		if a == t || isLiteral && a == PrimitiveType("string") {
+			return true
+		}
+	}
+	return false
+}
+
+// intersectAllowed returns the types allowed by both a and b, where an
+// empty list allows every type. The intersection of string and string
+// literal types is the literal types.
+func intersectAllowed(a, b []TypeExpr) []TypeExpr {
+	if len(a) == 0 {
+		return b
+	}
+	if len(b) == 0 {
+		return a
+	}
+	var result []TypeExpr
+	for _, t := range a {
+		if allows(b, t) {
+			result = append(result, t)
+		}
+	}
+	for _, t := range b {
+		if allows(a, t) && !slices.Contains(result, t) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// literalDomain returns the string literal types that a value of type t
+// can have, or nil if t is not a union of string literals.
+func literalDomain(t TypeExpr) []LiteralType {
+	switch t := resolve(t).(type) {
+	case LiteralType:
+		return []LiteralType{t}
+	case *TypeVar:
+		var domain []LiteralType
+		for _, allowed := range t.Allowed {
+			literal, ok := allowed.(LiteralType)
+			if !ok {
+				return nil
+			}
+			domain = append(domain, literal)
+		}
+		return domain
+	}
+	return nil
+}
+
 // unify attempts to unify two types
 func (tc *typeChecker) unify(t1, t2 TypeExpr) error {
 	if t1 == t2 {
@@ -43,7 +133,30 @@ func (tc *typeChecker) unify(t1, t2 TypeExpr) error {
 	}
 
 	// Handle type variables
+	//
+	// A constrained type variable acts as a union of primitive types.
+	// Binding it narrows the union, so that a value that is used both
+	// as a string and as a number must be a string and a number at once.
 	if tv1, ok := t1.(*TypeVar); ok {
+		if tv2, ok := t2.(*TypeVar); ok {
+			allowed := intersectAllowed(tv1.Allowed, tv2.Allowed)
+			if len(allowed) == 0 && (len(tv1.Allowed) > 0 || len(tv2.Allowed) > 0) {
+				return fmt.Errorf("cannot unify %v with %v", Normalize(t1), Normalize(t2))
+			}
+			tv2.Allowed = allowed
+			tv1.Link = &t2
+			return nil
+		}
+		if len(tv1.Allowed) > 0 {
+			// A union of string literals that is used as a string keeps
+			// its literal types, since they are all strings.
+			if t2 == PrimitiveType("string") && literalDomain(tv1) != nil {
+				return nil
+			}
+			if !allows(tv1.Allowed, t2) {
+				return fmt.Errorf("cannot unify %v with %v", Normalize(t1), Normalize(t2))
+			}
+		}
 		tv1.Link = &t2
 		return nil
 	}
@@ -57,14 +170,26 @@ func (tc *typeChecker) unify(t1, t2 TypeExpr) error {
 		if t2, ok := t2.(PrimitiveType); ok && t1 == t2 {
 			return nil
 		}
+		if _, ok := t2.(LiteralType); ok && t1 == "string" {
+			return nil
+		}
+	case LiteralType:
+		if t1 == t2 || t2 == PrimitiveType("string") {
+			return nil
+		}
 	case *ArrayType:
 		if t2, ok := t2.(*ArrayType); ok {
 			return tc.unify(t1.ElementType, t2.ElementType)
 		}
+	case *MapType:
+		if t2, ok := t2.(*MapType); ok {
+			return tc.unify(t1.ValueType, t2.ValueType)
+		}
 	case *ObjectType:
 		if t2, ok := t2.(*ObjectType); ok {
 			mergedFields := maps.Clone(t1.Fields)
-			for name, typ2 := range t2.Fields {
+			for _, name := range slices.Sorted(maps.Keys(t2.Fields)) {
+				typ2 := t2.Fields[name]
 				if typ1, exists := mergedFields[name]; exists {
 					if err := tc.unify(typ1, typ2); err != nil {
 						return fmt.Errorf("field %s: %w", name, err)
@@ -76,37 +201,69 @@ func (tc *typeChecker) unify(t1, t2 TypeExpr) error {
 			t1.Fields = mergedFields
 			return nil
 		}
-	case *UnionType:
-		if t2, ok := t2.(*UnionType); ok {
-			for _, type1 := range t1.Types {
-				for _, type2 := range t2.Types {
-					if err := tc.unify(type1, type2); err == nil {
-						return nil
-					}
-				}
-			}
-		} else {
-			for _, type1 := range t1.Types {
-				if err := tc.unify(type1, t2); err == nil {
-					return nil
-				}
-			}
-		}
 	}
 
-	return fmt.Errorf("cannot unify %v with %v", t1, t2)
+	return fmt.Errorf("cannot unify %v with %v", Normalize(t1), Normalize(t2))
+}
+
+// functionRef identifies a function in the dependency graph of a
+// module. Functions defined in the module itself have an empty module.
+type functionRef struct {
+	module string
+	name   string
+}
+
+func (r functionRef) String() string {
+	if r.module == "" {
+		return r.name
+	}
+	return r.module + "/" + r.name
+}
+
+// dependencyEdge is an edge in the dependency graph of a module.
+type dependencyEdge struct {
+	from functionRef
+	to   functionRef
 }
 
-func constructDependencyGraph(root *html.Node) map[string]map[string]bool {
-	deps := map[string]map[string]bool{}
-	var findRenders func(n *html.Node, source string)
-	findRenders = func(n *html.Node, source string) {
+// constructDependencyGraph returns the dependency graph of the functions
+// of a module, along with the first render node that created each edge.
+func constructDependencyGraph(root *html.Node) (map[functionRef]map[functionRef]bool, map[dependencyEdge]*html.Node, error) {
+	deps := map[functionRef]map[functionRef]bool{}
+	edges := map[dependencyEdge]*html.Node{}
+	imported := map[string]functionRef{}
+	for c := range root.ChildNodes() {
+		if c.Type == html.ElementNode && c.Data == "import" {
+			from, _ := getAttribute(c, "from")
+			name, _ := getAttribute(c, "function")
+			ref := functionRef{module: from, name: name}
+			// The function is rendered by its alias if it has one.
+			if as, ok := getAttribute(c, "as"); ok {
+				name = as
+			}
+			imported[name] = ref
+			deps[ref] = map[functionRef]bool{}
+		}
+	}
+	refFor := func(name string) functionRef {
+		if ref, ok := imported[name]; ok {
+			return ref
+		}
+		return functionRef{name: name}
+	}
+	var findRenders func(n *html.Node, source functionRef)
+	findRenders = func(n *html.Node, source functionRef) {
 		if n.Type == html.ElementNode {
 			switch n.Data {
 			case "render":
 				for _, attr := range n.Attr {
 					if attr.Key == "function" {
-						deps[source][attr.Val] = true
+						target := refFor(attr.Val)
+						deps[source][target] = true
+						edge := dependencyEdge{from: source, to: target}
+						if _, exists := edges[edge]; !exists {
+							edges[edge] = n
+						}
 					}
 				}
 			}
@@ -116,33 +273,25 @@ func constructDependencyGraph(root *html.Node) map[string]map[string]bool {
 		}
 	}
 	for c := range root.ChildNodes() {
-		if c.Type == html.ElementNode && c.Data == "import" {
-			var name string
-			for _, attr := range c.Attr {
-				if attr.Key == "function" {
-					name = attr.Val
-				}
-			}
-			deps[name] = map[string]bool{}
-		}
 		if c.Type == html.ElementNode && c.Data == "function" {
-			var name string
-			for _, attr := range c.Attr {
-				if attr.Key == "name" {
-					name = attr.Val
-				}
+			name, _ := getAttribute(c, "name")
+			if ref, ok := imported[name]; ok {
+				return nil, nil, fmt.Errorf("function '%s' is both defined in this module and imported from module '%s'", name, ref.module)
 			}
-			deps[name] = map[string]bool{}
+			source := functionRef{name: name}
+			deps[source] = map[functionRef]bool{}
 			for cc := range c.ChildNodes() {
-				findRenders(cc, name)
+				findRenders(cc, source)
 			}
 		}
 	}
-	return deps
+	return deps, edges, nil
 }
 
 // Typecheck infers the types of all functions of a module.
-func Typecheck(root *html.Node, positions map[*html.Node]parser.NodePosition, importedFunctions map[string]TypeExpr) (map[string]TypeExpr, error) {
+func Typecheck(root *html.Node, positions map[*html.Node]parser.NodePosition, importedFunctions map[string]*FunctionType, options Options) (map[string]*FunctionType, error) {
+	tc := newTypeChecker(positions, options)
+
 	// Collect functions
 	functions := map[string]*html.Node{}
 	for c := range root.ChildNodes() {
@@ -154,44 +303,93 @@ func Typecheck(root *html.Node, positions map[*html.Node]parser.NodePosition, im
 				}
 			}
 			if name == "" {
-				return nil, fmt.Errorf("function is missing attribute 'name'")
+				return nil, tc.newError(c, "function is missing attribute 'name'")
 			}
 			functions[name] = c
 		}
 	}
 
-	dependencyGraph := constructDependencyGraph(root)
+	dependencyGraph, edges, err := constructDependencyGraph(root)
+	if err != nil {
+		return nil, fmt.Errorf("type error: %w", err)
+	}
 
 	sortedFunctions, err := toposort.TopologicalSort(dependencyGraph, "function")
 	if err != nil {
+		var undefined *toposort.UndefinedError[functionRef]
+		if errors.As(err, &undefined) {
+			if n, ok := edges[dependencyEdge{from: undefined.Node, to: undefined.Dependency}]; ok {
+				return nil, tc.newErrorForAttr(n, "function", "%s", err)
+			}
+		}
 		return nil, fmt.Errorf("type error: %w", err)
 	}
 
 	// Type check functions
-	tc := newTypeChecker(positions)
 
-	// Add imported functions to the function params
-	for name, typeExpr := range importedFunctions {
-		tc.functionParams[name] = typeExpr
+	// Add imported functions to the function types. Their signatures
+	// must already be known since render calls are checked against them.
+	for name, functionType := range importedFunctions {
+		if functionType == nil || functionType.Params == nil {
+			return nil, fmt.Errorf("type error: signature of imported function '%s' is not resolved", name)
+		}
+		tc.functionTypes[name] = functionType
 	}
 
-	for _, name := range sortedFunctions {
+	for _, ref := range sortedFunctions {
+		if ref.module != "" {
+			continue
+		}
+		name := ref.name
 		function, ok := functions[name]
 		if !ok {
 			continue
 		}
 		s := map[string]TypeExpr{}
-		if paramsAs, found := getAttribute(function, "params-as"); found {
-			tc.functionParams[name] = tc.newVar()
-			s[paramsAs] = tc.functionParams[name]
-		} else {
-			tc.functionParams[name] = PrimitiveType("void")
+		functionType := &FunctionType{Position: positions[function].Start}
+		paramsAs, hasParamsAs := getAttribute(function, "params-as")
+		namedParams := NamedParams(function)
+		switch {
+		case hasParamsAs && namedParams != nil:
+			return nil, tc.newErrorForAttr(function, "params-as", "params-as can not be combined with param- attributes")
+		case hasParamsAs:
+			functionType.Params = tc.newVar()
+			s[paramsAs] = functionType.Params
+		case namedParams != nil:
+			fields := map[string]TypeExpr{}
+			for name, variable := range namedParams {
+				if name == "" {
+					return nil, tc.newError(function, "empty parameter name in param-")
+				}
+				fields[name] = tc.newVar()
+				s[variable] = fields[name]
+			}
+			functionType.Params = &ObjectType{Fields: fields}
+		default:
+			functionType.Params = PrimitiveType("void")
 		}
+		tc.functionTypes[name] = functionType
+		tc.currentSlot = nil
+		tc.currentSlots = nil
+		tc.bareChildren = false
 		if err := tc.typecheckNode(function, s); err != nil {
 			return nil, err
 		}
+		functionType.Slot = tc.currentSlot
+		functionType.Slots = tc.currentSlots
+	}
+	for _, check := range tc.paramsChecks {
+		if extra := extraFields(check.argument, check.parameter, ""); len(extra) > 0 {
+			return nil, tc.newErrorForAttr(check.node, "params", "function '%s' does not use fields %s",
+				check.functionName, strings.Join(extra, ", "))
+		}
+	}
+	for _, check := range tc.matchChecks {
+		if err := tc.checkMatchCases(check); err != nil {
+			return nil, err
+		}
 	}
-	return tc.functionParams, nil
+	return tc.functionTypes, nil
 }
 
 func (tc *typeChecker) typecheckNode(n *html.Node, s map[string]TypeExpr) error {
@@ -203,8 +401,51 @@ func (tc *typeChecker) typecheckNode(n *html.Node, s map[string]TypeExpr) error
 			return tc.typecheckFor(n, s)
 		case "if":
 			return tc.typecheckIf(n, s)
+		case "match":
+			return tc.typecheckMatch(n, s)
+		case "case":
+			return tc.newError(n, "case can only be used directly inside match or variant")
+		case "default":
+			return tc.newError(n, "default can only be used directly inside match")
+		case "empty":
+			return tc.newError(n, "empty can only be used directly inside for")
+		case "range":
+			return tc.typecheckRange(n, s)
+		case "img-set":
+			return tc.typecheckImgSet(n, s)
+		case "field":
+			return tc.typecheckField(n, s)
+		case "options", "choices":
+			return tc.typecheckOptions(n, s)
+		case "table-for":
+			return tc.typecheckTableFor(n, s)
+		case "raw-html":
+			return tc.typecheckRawHTML(n, s)
+		case "money", "measure":
+			return tc.typecheckQuantity(n, s)
+		case "variant":
+			return tc.typecheckVariant(n, s)
+		case "t":
+			return tc.typecheckTranslation(n)
+		case "include":
+			// Includes are resolved before typechecking and the
+			// included markup is typechecked with its module.
+			return nil
+		case "time":
+			if _, ok := getAttribute(n, "from"); ok {
+				return tc.typecheckTime(n, s)
+			}
+			return tc.typecheckNative(n, s)
+		case "column":
+			return tc.newError(n, "column can only be used directly inside table-for")
 		case "render":
 			return tc.typecheckRender(n, s)
+		case "children":
+			return tc.typecheckChildren(n, s)
+		case "slot":
+			return tc.typecheckSlot(n, s)
+		case "fill":
+			return tc.newError(n, "fill can only be used directly inside render")
 		default:
 			return tc.typecheckNative(n, s)
 		}
@@ -212,7 +453,64 @@ func (tc *typeChecker) typecheckNode(n *html.Node, s map[string]TypeExpr) error
 	return nil
 }
 
+// typecheckBinding typechecks the value of an inner-text or attr-
+// binding. Unlike other paths, bindings may reference build variables,
+// which are strings, may have fallback alternatives, which must all
+// have the same type, and may pipe their value through filters.
+func (tc *typeChecker) typecheckBinding(binding string, scope map[string]TypeExpr) (TypeExpr, error) {
+	if name, ok := strings.CutPrefix(binding, "$"); ok {
+		if _, exists := tc.options.BuildVars[name]; !exists {
+			return nil, fmt.Errorf("undefined build variable '%s'", name)
+		}
+		return PrimitiveType("string"), nil
+	}
+	parsed, err := parser.ParseBinding(binding)
+	if err != nil {
+		return nil, err
+	}
+	var result TypeExpr = tc.newVar()
+	for _, alternative := range parsed.Alternatives {
+		var t TypeExpr = PrimitiveType("string")
+		if !alternative.IsLiteral {
+			t, err = tc.typecheckLookup(alternative.Path, scope)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if err := tc.unify(result, t); err != nil {
+			return nil, fmt.Errorf("fallback '%s' has a different type: %s", alternative.Path+alternative.Literal, err)
+		}
+	}
+	for _, filter := range parsed.Filters {
+		filterType, exists := tc.options.Filters[filter.Name]
+		if !exists {
+			return nil, fmt.Errorf("unknown filter '%s'", filter.Name)
+		}
+		if len(filter.Args) != len(filterType.Params) {
+			return nil, fmt.Errorf("filter %s expects %d arguments but got %d", filter.Name, len(filterType.Params), len(filter.Args))
+		}
+		vars := map[*TypeVar]*TypeVar{}
+		if err := tc.unify(tc.instantiate(filterType.Input, vars), result); err != nil {
+			return nil, fmt.Errorf("invalid input of filter %s: %s", filter.Name, err)
+		}
+		for i, arg := range filter.Args {
+			var t TypeExpr = PrimitiveType("string")
+			if _, ok := arg.(float64); ok {
+				t = PrimitiveType("number")
+			}
+			if err := tc.unify(tc.instantiate(filterType.Params[i], vars), t); err != nil {
+				return nil, fmt.Errorf("invalid argument %d of filter %s: %s", i+1, filter.Name, err)
+			}
+		}
+		result = tc.instantiate(filterType.Result, vars)
+	}
+	return result, nil
+}
+
 func (tc *typeChecker) typecheckLookup(path string, scope map[string]TypeExpr) (TypeExpr, error) {
+	if strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("build variable '%s' can only be used in inner-text and attr- bindings", path[1:])
+	}
 	parts, err := parser.ParsePath(path)
 	if err != nil {
 		return nil, fmt.Errorf("invalid path: %w", err)
@@ -250,24 +548,76 @@ func (tc *typeChecker) typecheckLookup(path string, scope map[string]TypeExpr) (
 	return currentType, nil
 }
 
+// bindingTypes returns the types that can be bound with the given
+// attribute. Besides strings and numbers, attribute bindings accept the
+// trusted type that is safe in their context, e.g. url for href, and
+// boolean attributes such as disabled also accept booleans. The text of
+// inner-text is always escaped, so it does not accept html, which is
+// inserted with inner-html or raw-html instead.
+func bindingTypes(key string) []PrimitiveType {
+	name, ok := strings.CutPrefix(key, "attr-")
+	switch {
+	case !ok:
+		return []PrimitiveType{"string", "number"}
+	case urlAttributes[name]:
+		return []PrimitiveType{"string", "number", URLType}
+	case strings.HasPrefix(name, "on"):
+		return []PrimitiveType{"string", "number", JSType}
+	case booleanAttributes[name]:
+		return []PrimitiveType{"string", "number", "boolean"}
+	}
+	return []PrimitiveType{"string", "number"}
+}
+
+// innerHTMLHint returns a hint for an inner-text binding of a value
+// that can only be html, which inner-text would escape.
+func innerHTMLHint(key string, t TypeExpr) string {
+	if key == "inner-text" && resolve(t) == HTMLType {
+		return ", use inner-html or raw-html to insert trusted html"
+	}
+	return ""
+}
+
 func (tc *typeChecker) typecheckNative(n *html.Node, s map[string]TypeExpr) error {
+	if tc.options.StrictAttributes {
+		if err := tc.checkAttributeNames(n); err != nil {
+			return err
+		}
+	}
+	if err := tc.checkAttributeCollisions(n); err != nil {
+		return err
+	}
 	for _, attr := range n.Attr {
-		if attr.Key == "inner-text" || strings.HasPrefix(attr.Key, "attr-") {
-			exprType, err := tc.typecheckLookup(attr.Val, s)
+		switch {
+		case attr.Key == "inner-text" || strings.HasPrefix(attr.Key, "attr-"):
+			exprType, err := tc.typecheckBinding(attr.Val, s)
 			if err != nil {
 				return tc.newErrorForAttr(n, attr.Key, "%s", err)
 			}
 
-			stringOrNumber := &UnionType{
-				Types: []TypeExpr{
-					PrimitiveType("string"),
-					PrimitiveType("number"),
-				},
+			if err := tc.unify(exprType, tc.newConstrainedVar(bindingTypes(attr.Key)...)); err != nil {
+				return tc.newErrorForAttr(n, attr.Key, "invalid type for %s binding: %s%s", attr.Key, err, innerHTMLHint(attr.Key, exprType))
 			}
-
-			if err := tc.unify(exprType, stringOrNumber); err != nil {
+			if name, ok := strings.CutPrefix(attr.Key, "attr-"); ok && IsURLAttribute(name) && tc.options.URLBindings != nil {
+				tc.options.URLBindings[Binding{Node: n, Attr: attr.Key}] = exprType
+			}
+		case attr.Key == "inner-html":
+			if err := tc.typecheckInnerHTML(n, attr.Val, s); err != nil {
+				return err
+			}
+		case strings.HasPrefix(attr.Key, "class-"):
+			condType, err := tc.typecheckLookup(attr.Val, s)
+			if err != nil {
+				return tc.newErrorForAttr(n, attr.Key, "%s", err)
+			}
+			if err := tc.unify(condType, PrimitiveType("boolean")); err != nil {
 				return tc.newErrorForAttr(n, attr.Key, "invalid type for %s binding: %s", attr.Key, err)
 			}
+		case strings.HasPrefix(attr.Key, "data-bind-"):
+			// Any value can be serialized as JSON.
+			if _, err := tc.typecheckLookup(attr.Val, s); err != nil {
+				return tc.newErrorForAttr(n, attr.Key, "%s", err)
+			}
 		}
 	}
 	for c := range n.ChildNodes() {
@@ -282,18 +632,12 @@ func (tc *typeChecker) typecheckFragment(n *html.Node, s map[string]TypeExpr) er
 	for _, attr := range n.Attr {
 		switch attr.Key {
 		case "inner-text":
-			exprType, err := tc.typecheckLookup(attr.Val, s)
+			exprType, err := tc.typecheckBinding(attr.Val, s)
 			if err != nil {
 				return err
 			}
-			stringOrNumber := &UnionType{
-				Types: []TypeExpr{
-					PrimitiveType("string"),
-					PrimitiveType("number"),
-				},
-			}
-			if err := tc.unify(exprType, stringOrNumber); err != nil {
-				return tc.newError(n, "invalid type for inner-text: %s", err)
+			if err := tc.unify(exprType, tc.newConstrainedVar(bindingTypes(attr.Key)...)); err != nil {
+				return tc.newError(n, "invalid type for inner-text: %s%s", err, innerHTMLHint(attr.Key, exprType))
 			}
 		default:
 			return tc.newError(n, "unrecognized attribute '%s' in %s", attr.Key, n.Data)
@@ -308,13 +652,33 @@ func (tc *typeChecker) typecheckFragment(n *html.Node, s map[string]TypeExpr) er
 }
 
 func (tc *typeChecker) typecheckFor(n *html.Node, s map[string]TypeExpr) error {
-	var each, as string
+	var each, as, indexAs, metaAs, keyAs, valueAs string
+	var sortBy, order, filter string
+	var limit, offset string
 	for _, attr := range n.Attr {
 		switch attr.Key {
 		case "each":
 			each = attr.Val
+		case "limit":
+			limit = attr.Val
+		case "offset":
+			offset = attr.Val
+		case "sort-by":
+			sortBy = attr.Val
+		case "order":
+			order = attr.Val
+		case "filter":
+			filter = attr.Val
 		case "as":
 			as = attr.Val
+		case "index-as":
+			indexAs = attr.Val
+		case "meta-as":
+			metaAs = attr.Val
+		case "key-as":
+			keyAs = attr.Val
+		case "value-as":
+			valueAs = attr.Val
 		default:
 			return tc.newError(n, "unrecognized attribute '%s' in %s", attr.Key, n.Data)
 		}
@@ -328,6 +692,14 @@ func (tc *typeChecker) typecheckFor(n *html.Node, s map[string]TypeExpr) error {
 	if err != nil {
 		return tc.newErrorForAttr(n, "each", "%s", err)
 	}
+	outer := s
+
+	if keyAs != "" || valueAs != "" {
+		if sortBy != "" || order != "" || filter != "" || limit != "" || offset != "" {
+			return tc.newError(n, "sort-by, order, filter, limit and offset can not be combined with key-as and value-as")
+		}
+		return tc.typecheckForEntries(n, s, iterType, keyAs, valueAs, as != "" || indexAs != "" || metaAs != "")
+	}
 
 	elemType := tc.newVar()
 
@@ -335,42 +707,220 @@ func (tc *typeChecker) typecheckFor(n *html.Node, s map[string]TypeExpr) error {
 		return tc.newErrorForAttr(n, "each", "cannot iterate over non-array value: %s", err)
 	}
 
-	if as != "" {
+	if indexAs != "" && indexAs == as {
+		return tc.newErrorForAttr(n, "index-as", "index-as and as can not have the same name '%s'", as)
+	}
+	if err := tc.typecheckForWindow(n, s, limit, offset); err != nil {
+		return err
+	}
+	if metaAs != "" && (metaAs == as || metaAs == indexAs) {
+		return tc.newErrorForAttr(n, "meta-as", "meta-as can not have the same name '%s' as another loop variable", metaAs)
+	}
+
+	if as != "" || indexAs != "" || metaAs != "" {
 		s = maps.Clone(s)
+	}
+	if as != "" {
 		s[as] = elemType
 	}
+	// The modifiers are evaluated before the loop, when only as is
+	// bound, so they are checked before the index and the metadata are
+	// added to the scope.
+	if err := tc.typecheckForModifiers(n, s, as, sortBy, order, filter); err != nil {
+		return err
+	}
+	if indexAs != "" {
+		s[indexAs] = PrimitiveType("number")
+	}
+	var meta *ObjectType
+	if metaAs != "" {
+		meta = loopMetaType()
+		s[metaAs] = meta
+	}
+	if err := tc.typecheckForBody(n, outer, s); err != nil {
+		return err
+	}
+	if meta != nil {
+		// The metadata is a fixed object, while the lookups in the body
+		// add the fields that they access to it.
+		for _, name := range slices.Sorted(maps.Keys(meta.Fields)) {
+			if _, ok := loopMetaType().Fields[name]; !ok {
+				return tc.newErrorForAttr(n, "meta-as", "%s has no field '%s', the fields of loop metadata are first, last, index, odd and even", metaAs, name)
+			}
+		}
+	}
+	return nil
+}
+
+// typecheckForModifiers checks the attributes of a `for` tag that sort
+// and filter the elements before the loop, which are evaluated with
+// only the element bound to as:
+//
+// <for each="posts" as="post" filter="post.published" sort-by="post.date" order="desc">
+func (tc *typeChecker) typecheckForModifiers(n *html.Node, s map[string]TypeExpr, as, sortBy, order, filter string) error {
+	if (sortBy != "" || filter != "") && as == "" {
+		return tc.newError(n, "sort-by and filter require as")
+	}
+	if filter != "" {
+		filterType, err := tc.typecheckLookup(filter, s)
+		if err != nil {
+			return tc.newErrorForAttr(n, "filter", "%s", err)
+		}
+		if err := tc.unify(filterType, PrimitiveType("boolean")); err != nil {
+			return tc.newErrorForAttr(n, "filter", "filter must be boolean: %s", err)
+		}
+	}
+	if sortBy != "" {
+		keyType, err := tc.typecheckLookup(sortBy, s)
+		if err != nil {
+			return tc.newErrorForAttr(n, "sort-by", "%s", err)
+		}
+		if err := tc.unify(keyType, tc.newConstrainedVar("string", "number")); err != nil {
+			return tc.newErrorForAttr(n, "sort-by", "sort key must be a string or number: %s", err)
+		}
+	}
+	switch {
+	case order != "" && sortBy == "":
+		return tc.newErrorForAttr(n, "order", "order requires sort-by")
+	case order != "" && order != "asc" && order != "desc":
+		return tc.newErrorForAttr(n, "order", "order must be asc or desc, not '%s'", order)
+	}
+	return nil
+}
+
+// typecheckForWindow checks the limit and offset of a `for` tag, which
+// are integer literals or paths that are evaluated outside of the loop:
+//
+// <for each="posts" as="post" offset="page.start" limit="10">
+func (tc *typeChecker) typecheckForWindow(n *html.Node, s map[string]TypeExpr, limit, offset string) error {
+	for _, attr := range []struct{ key, value string }{{"limit", limit}, {"offset", offset}} {
+		if attr.value == "" {
+			continue
+		}
+		if i, err := strconv.Atoi(attr.value); err == nil {
+			if i < 0 {
+				return tc.newErrorForAttr(n, attr.key, "%s of for can not be negative", attr.key)
+			}
+			continue
+		}
+		boundType, err := tc.typecheckLookup(attr.value, s)
+		if err != nil {
+			return tc.newErrorForAttr(n, attr.key, "%s", err)
+		}
+		if err := tc.unify(boundType, PrimitiveType("number")); err != nil {
+			return tc.newErrorForAttr(n, attr.key, "%s of for must be a number: %s", attr.key, err)
+		}
+	}
+	return nil
+}
+
+// loopMetaType is the type of the metadata of a `for` tag with a
+// meta-as attribute.
+func loopMetaType() *ObjectType {
+	return &ObjectType{Fields: map[string]TypeExpr{
+		"first": PrimitiveType("boolean"),
+		"last":  PrimitiveType("boolean"),
+		"index": PrimitiveType("number"),
+		"odd":   PrimitiveType("boolean"),
+		"even":  PrimitiveType("boolean"),
+	}}
+}
+
+// typecheckForBody typechecks the children of a `for` tag in the scope
+// of the loop, except for an `empty` child which is rendered when there
+// is nothing to iterate over and is typechecked in the outer scope:
+//
+// <for each="items" as="item">
+// ...
+// <empty>...</empty>
+// </for>
+func (tc *typeChecker) typecheckForBody(n *html.Node, outer, inner map[string]TypeExpr) error {
+	var hasEmpty bool
 	for c := range n.ChildNodes() {
-		if err := tc.typecheckNode(c, s); err != nil {
+		if c.Type == html.ElementNode && c.Data == "empty" {
+			if hasEmpty {
+				return tc.newError(c, "for can only have one empty")
+			}
+			hasEmpty = true
+			if len(c.Attr) > 0 {
+				return tc.newError(c, "unrecognized attribute '%s' in %s", c.Attr[0].Key, c.Data)
+			}
+			for cc := range c.ChildNodes() {
+				if err := tc.typecheckNode(cc, outer); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := tc.typecheckNode(c, inner); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (tc *typeChecker) typecheckIf(n *html.Node, s map[string]TypeExpr) error {
-	var cond string
+// typecheckForEntries typechecks a `for` tag that iterates over the
+// entries of an object:
+//
+// <for each="user.settings" key-as="k" value-as="v">
+func (tc *typeChecker) typecheckForEntries(n *html.Node, s map[string]TypeExpr, iterType TypeExpr, keyAs, valueAs string, hasAs bool) error {
+	if hasAs {
+		return tc.newError(n, "key-as and value-as can not be combined with as, index-as or meta-as")
+	}
+	if keyAs != "" && keyAs == valueAs {
+		return tc.newErrorForAttr(n, "value-as", "key-as and value-as can not have the same name '%s'", keyAs)
+	}
+
+	valueType := tc.newVar()
+	if err := tc.unify(iterType, &MapType{ValueType: valueType}); err != nil {
+		return tc.newErrorForAttr(n, "each", "cannot iterate over the entries of non-map value: %s", err)
+	}
+
+	inner := maps.Clone(s)
+	if keyAs != "" {
+		inner[keyAs] = PrimitiveType("string")
+	}
+	if valueAs != "" {
+		inner[valueAs] = valueType
+	}
+	return tc.typecheckForBody(n, s, inner)
+}
+
+func (tc *typeChecker) typecheckRange(n *html.Node, s map[string]TypeExpr) error {
+	var as string
+	bounds := map[string]string{}
 	for _, attr := range n.Attr {
 		switch attr.Key {
-		case "true":
-			cond = attr.Val
+		case "from", "to":
+			bounds[attr.Key] = attr.Val
+		case "as":
+			as = attr.Val
 		default:
 			return tc.newError(n, "unrecognized attribute '%s' in %s", attr.Key, n.Data)
 		}
 	}
 
-	if cond == "" {
-		return tc.newErrorForAttr(n, "true", "empty condition in if")
-	}
-
-	condType, err := tc.typecheckLookup(cond, s)
-	if err != nil {
-		return tc.newErrorForAttr(n, "true", "%s", err)
+	for _, key := range []string{"from", "to"} {
+		bound, ok := bounds[key]
+		if !ok || bound == "" {
+			return tc.newError(n, "range is missing attribute '%s'", key)
+		}
+		if _, err := strconv.Atoi(bound); err == nil {
+			continue
+		}
+		boundType, err := tc.typecheckLookup(bound, s)
+		if err != nil {
+			return tc.newErrorForAttr(n, key, "%s", err)
+		}
+		if err := tc.unify(boundType, PrimitiveType("number")); err != nil {
+			return tc.newErrorForAttr(n, key, "bound of range must be a number: %s", err)
+		}
 	}
 
-	if err := tc.unify(condType, PrimitiveType("boolean")); err != nil {
-		return tc.newErrorForAttr(n, "true", "condition must be boolean: %s", err)
+	if as != "" {
+		s = maps.Clone(s)
+		s[as] = PrimitiveType("number")
 	}
-
 	for c := range n.ChildNodes() {
 		if err := tc.typecheckNode(c, s); err != nil {
 			return err
@@ -379,31 +929,923 @@ func (tc *typeChecker) typecheckIf(n *html.Node, s map[string]TypeExpr) error {
 	return nil
 }
 
-func (tc *typeChecker) typecheckRender(n *html.Node, s map[string]TypeExpr) error {
-	functionName, ok := getAttribute(n, "function")
-	if !ok {
-		return tc.newError(n, "render is missing attribute 'function'")
-	}
+// imageType is the type of the value of an `img-set` tag.
+func imageType() *ObjectType {
+	return &ObjectType{Fields: map[string]TypeExpr{
+		"src":    PrimitiveType("string"),
+		"widths": &ArrayType{ElementType: PrimitiveType("number")},
+		"alt":    PrimitiveType("string"),
+	}}
+}
 
-	params, found := getAttribute(n, "params")
-	if found {
-		paramsType, err := tc.typecheckLookup(params, s)
-		if err != nil {
-			return tc.newErrorForAttr(n, "params", "%s", err)
+func (tc *typeChecker) typecheckImgSet(n *html.Node, s map[string]TypeExpr) error {
+	var from string
+	for _, attr := range n.Attr {
+		switch {
+		case attr.Key == "from":
+			from = attr.Val
+		case attr.Key == "inner-text" || strings.HasPrefix(attr.Key, "attr-"):
+			return tc.newErrorForAttr(n, attr.Key, "bindings are not allowed in img-set")
+		case attr.Key == "src" || attr.Key == "srcset" || attr.Key == "alt":
+			return tc.newErrorForAttr(n, attr.Key, "attribute '%s' of img-set is set from the image", attr.Key)
 		}
+	}
 
-		if err := tc.unify(paramsType, tc.functionParams[functionName]); err != nil {
-			return tc.newError(n, "invalid parameter type for function '%s': %s", functionName, err)
-		}
-	} else {
-		if tc.functionParams[functionName] != PrimitiveType("void") {
-			return tc.newError(n, "missing attribute params in render call for %s", functionName)
+	if from == "" {
+		return tc.newError(n, "img-set is missing attribute 'from'")
+	}
+	if n.FirstChild != nil {
+		return tc.newError(n, "img-set can not have children")
+	}
+
+	fromType, err := tc.typecheckLookup(from, s)
+	if err != nil {
+		return tc.newErrorForAttr(n, "from", "%s", err)
+	}
+	if err := tc.unify(fromType, imageType()); err != nil {
+		return tc.newErrorForAttr(n, "from", "invalid image: %s", err)
+	}
+	return nil
+}
+
+// formFieldType is the type of the value of a `field` tag.
+func formFieldType() *ObjectType {
+	return &ObjectType{Fields: map[string]TypeExpr{
+		"value": PrimitiveType("string"),
+		"error": PrimitiveType("string"),
+	}}
+}
+
+func (tc *typeChecker) typecheckField(n *html.Node, s map[string]TypeExpr) error {
+	var from string
+	var hasName bool
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "from":
+			from = attr.Val
+		case "name", "attr-name":
+			hasName = true
+		case "inner-text":
+			return tc.newErrorForAttr(n, attr.Key, "inner-text is not allowed in field")
+		case "value", "attr-value", "aria-invalid", "attr-aria-invalid", "aria-describedby", "attr-aria-describedby":
+			return tc.newErrorForAttr(n, attr.Key, "attribute '%s' of field is set from the form state", attr.Key)
 		}
 	}
+	if !hasName {
+		return tc.newError(n, "field is missing attribute 'name'")
+	}
+	if from == "" {
+		return tc.newError(n, "field is missing attribute 'from'")
+	}
+	if n.FirstChild != nil {
+		return tc.newError(n, "field can not have children")
+	}
+	fromType, err := tc.typecheckLookup(from, s)
+	if err != nil {
+		return tc.newErrorForAttr(n, "from", "%s", err)
+	}
+	if err := tc.unify(fromType, formFieldType()); err != nil {
+		return tc.newErrorForAttr(n, "from", "invalid form field: %s", err)
+	}
+	return tc.typecheckNative(n, s)
+}
 
-	for c := range n.ChildNodes() {
-		if err := tc.typecheckNode(c, s); err != nil {
-			return err
+// optionType is the type of the elements of the from attribute of an
+// `options` or `choices` tag.
+func optionType() *ObjectType {
+	return &ObjectType{Fields: map[string]TypeExpr{
+		"value": PrimitiveType("string"),
+		"label": PrimitiveType("string"),
+	}}
+}
+
+// typecheckOptions checks an `options` tag, which renders the options of
+// a select, or a `choices` tag, which renders a group of radio buttons or
+// checkboxes. The selected value is a string, except for checkboxes
+// where it is an array of strings.
+func (tc *typeChecker) typecheckOptions(n *html.Node, s map[string]TypeExpr) error {
+	var from, selected, name, kind string
+	var hasSelected bool
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "from":
+			from = attr.Val
+		case "selected":
+			selected, hasSelected = attr.Val, true
+		case "name":
+			if n.Data != "choices" {
+				return tc.newErrorForAttr(n, attr.Key, "unrecognized attribute '%s' in %s", attr.Key, n.Data)
+			}
+			name = attr.Val
+		case "type":
+			if n.Data != "choices" {
+				return tc.newErrorForAttr(n, attr.Key, "unrecognized attribute '%s' in %s", attr.Key, n.Data)
+			}
+			kind = attr.Val
+			if kind != "radio" && kind != "checkbox" {
+				return tc.newErrorForAttr(n, attr.Key, "type of choices must be radio or checkbox")
+			}
+		default:
+			return tc.newErrorForAttr(n, attr.Key, "unrecognized attribute '%s' in %s", attr.Key, n.Data)
+		}
+	}
+	if from == "" {
+		return tc.newError(n, "%s is missing attribute 'from'", n.Data)
+	}
+	if n.Data == "choices" && name == "" {
+		return tc.newError(n, "choices is missing attribute 'name'")
+	}
+	if n.Data == "choices" && kind == "" {
+		return tc.newError(n, "choices is missing attribute 'type'")
+	}
+	if n.FirstChild != nil {
+		return tc.newError(n, "%s can not have children", n.Data)
+	}
+	fromType, err := tc.typecheckLookup(from, s)
+	if err != nil {
+		return tc.newErrorForAttr(n, "from", "%s", err)
+	}
+	if err := tc.unify(fromType, &ArrayType{ElementType: optionType()}); err != nil {
+		return tc.newErrorForAttr(n, "from", "invalid options: %s", err)
+	}
+	if !hasSelected {
+		return nil
+	}
+	selectedType, err := tc.typecheckLookup(selected, s)
+	if err != nil {
+		return tc.newErrorForAttr(n, "selected", "%s", err)
+	}
+	var want TypeExpr = PrimitiveType("string")
+	if kind == "checkbox" {
+		want = &ArrayType{ElementType: PrimitiveType("string")}
+	}
+	if err := tc.unify(selectedType, want); err != nil {
+		return tc.newErrorForAttr(n, "selected", "invalid selected value: %s", err)
+	}
+	return nil
+}
+
+// typecheckTableFor checks a `table-for` tag, which renders a table with
+// a row for each element of an array. Its children are `column` tags
+// whose children are the cell of the column, typechecked in the scope
+// of the row. The other attributes of the tag are rendered on the table
+// and must be static.
+func (tc *typeChecker) typecheckTableFor(n *html.Node, s map[string]TypeExpr) error {
+	var each, as, indexAs string
+	for _, attr := range n.Attr {
+		switch {
+		case attr.Key == "each":
+			each = attr.Val
+		case attr.Key == "as":
+			as = attr.Val
+		case attr.Key == "index-as":
+			indexAs = attr.Val
+		case attr.Key == "inner-text" || strings.HasPrefix(attr.Key, "attr-"):
+			return tc.newErrorForAttr(n, attr.Key, "attribute '%s' of table-for must be static", attr.Key)
+		}
+	}
+	if each == "" {
+		return tc.newError(n, "table-for is missing attribute 'each'")
+	}
+	if indexAs != "" && indexAs == as {
+		return tc.newErrorForAttr(n, "index-as", "index-as and as can not have the same name '%s'", as)
+	}
+	iterType, err := tc.typecheckLookup(each, s)
+	if err != nil {
+		return tc.newErrorForAttr(n, "each", "%s", err)
+	}
+	elemType := tc.newVar()
+	if err := tc.unify(iterType, &ArrayType{ElementType: elemType}); err != nil {
+		return tc.newErrorForAttr(n, "each", "cannot iterate over non-array value: %s", err)
+	}
+	inner := maps.Clone(s)
+	if as != "" {
+		inner[as] = elemType
+	}
+	if indexAs != "" {
+		inner[indexAs] = PrimitiveType("number")
+	}
+
+	var columns int
+	for c := range n.ChildNodes() {
+		switch {
+		case c.Type == html.CommentNode:
+		case c.Type == html.TextNode && strings.TrimSpace(c.Data) == "":
+		case c.Type == html.ElementNode && c.Data == "column":
+			if _, ok := getAttribute(c, "label"); !ok {
+				return tc.newError(c, "column is missing attribute 'label'")
+			}
+			if err := tc.typecheckNative(c, inner); err != nil {
+				return err
+			}
+			columns++
+		default:
+			return tc.newError(n, "table-for can only contain column")
+		}
+	}
+	if columns == 0 {
+		return tc.newError(n, "table-for must have at least one column")
+	}
+	return nil
+}
+
+// typecheckInnerHTML checks an inner-html binding, which inserts its
+// value as the children of an element without escaping. Unlike
+// raw-html, it never accepts strings, so that markup is only inserted
+// from values that the application has explicitly converted to HTML.
+func (tc *typeChecker) typecheckInnerHTML(n *html.Node, binding string, s map[string]TypeExpr) error {
+	if _, ok := getAttribute(n, "inner-text"); ok {
+		return tc.newErrorForAttr(n, "inner-html", "inner-text and inner-html can not be used together")
+	}
+	if n.FirstChild != nil {
+		return tc.newErrorForAttr(n, "inner-html", "an element with inner-html can not have children")
+	}
+	exprType, err := tc.typecheckBinding(binding, s)
+	if err != nil {
+		return tc.newErrorForAttr(n, "inner-html", "%s", err)
+	}
+	if err := tc.unify(exprType, HTMLType); err != nil {
+		return tc.newErrorForAttr(n, "inner-html", "invalid type for inner-html binding: %s", err)
+	}
+	return nil
+}
+
+// typecheckTranslation typechecks a `t` tag, whose only child is the
+// text to translate.
+func (tc *typeChecker) typecheckTranslation(n *html.Node) error {
+	if len(n.Attr) > 0 {
+		return tc.newErrorForAttr(n, n.Attr[0].Key, "unrecognized attribute '%s' in t", n.Attr[0].Key)
+	}
+	for c := range n.ChildNodes() {
+		if c.Type != html.TextNode {
+			return tc.newError(n, "t can only contain text")
+		}
+	}
+	return nil
+}
+
+// typecheckRawHTML checks a `raw-html` tag, which inserts its value
+// without escaping. The value must be trusted html unless plain strings
+// were allowed with the RawHTMLStrings option.
+func (tc *typeChecker) typecheckRawHTML(n *html.Node, s map[string]TypeExpr) error {
+	var value string
+	for _, attr := range n.Attr {
+		if attr.Key != "value" {
+			return tc.newErrorForAttr(n, attr.Key, "unrecognized attribute '%s' in %s", attr.Key, n.Data)
+		}
+		value = attr.Val
+	}
+	if value == "" {
+		return tc.newError(n, "raw-html is missing attribute 'value'")
+	}
+	if n.FirstChild != nil {
+		return tc.newError(n, "raw-html can not have children")
+	}
+	valueType, err := tc.typecheckLookup(value, s)
+	if err != nil {
+		return tc.newErrorForAttr(n, "value", "%s", err)
+	}
+	var want TypeExpr = HTMLType
+	if tc.options.RawHTMLStrings {
+		want = tc.newConstrainedVar(HTMLType, PrimitiveType("string"))
+	}
+	if err := tc.unify(valueType, want); err != nil {
+		return tc.newErrorForAttr(n, "value", "invalid value of raw-html: %s", err)
+	}
+	return nil
+}
+
+// quantityType returns the type of the value of a `money` or `measure`
+// tag.
+func quantityType(tag string) *ObjectType {
+	if tag == "money" {
+		return &ObjectType{Fields: map[string]TypeExpr{
+			"amount":   PrimitiveType("number"),
+			"currency": PrimitiveType("string"),
+		}}
+	}
+	return &ObjectType{Fields: map[string]TypeExpr{
+		"value": PrimitiveType("number"),
+		"unit":  PrimitiveType("string"),
+	}}
+}
+
+// typecheckQuantity checks a `money` tag, which renders an amount of a
+// currency, or a `measure` tag, which renders a value with a unit.
+func (tc *typeChecker) typecheckQuantity(n *html.Node, s map[string]TypeExpr) error {
+	var from string
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "from":
+			from = attr.Val
+		case "digits":
+			if digits, err := strconv.Atoi(attr.Val); err != nil || digits < 0 || digits > 10 {
+				return tc.newErrorForAttr(n, attr.Key, "digits must be a number between 0 and 10")
+			}
+		case "inner-text", "value", "attr-value":
+			return tc.newErrorForAttr(n, attr.Key, "attribute '%s' of %s is set from the %s", attr.Key, n.Data, n.Data)
+		}
+	}
+	if from == "" {
+		return tc.newError(n, "%s is missing attribute 'from'", n.Data)
+	}
+	if n.FirstChild != nil {
+		return tc.newError(n, "%s can not have children", n.Data)
+	}
+	fromType, err := tc.typecheckLookup(from, s)
+	if err != nil {
+		return tc.newErrorForAttr(n, "from", "%s", err)
+	}
+	if err := tc.unify(fromType, quantityType(n.Data)); err != nil {
+		return tc.newErrorForAttr(n, "from", "invalid %s: %s", n.Data, err)
+	}
+	return tc.typecheckNative(n, s)
+}
+
+// typecheckTime checks a `time` tag with a from attribute, which renders
+// a time as text and as its datetime attribute. The time is a string in
+// RFC 3339 format.
+func (tc *typeChecker) typecheckTime(n *html.Node, s map[string]TypeExpr) error {
+	from, _ := getAttribute(n, "from")
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "format":
+			if _, ok := tc.options.TimeFormats[attr.Val]; !ok {
+				return tc.newErrorForAttr(n, attr.Key, "unknown time format '%s'", attr.Val)
+			}
+		case "inner-text", "datetime", "attr-datetime":
+			return tc.newErrorForAttr(n, attr.Key, "attribute '%s' of time is set from the time", attr.Key)
+		}
+	}
+	if n.FirstChild != nil {
+		return tc.newError(n, "time with attribute 'from' can not have children")
+	}
+	fromType, err := tc.typecheckLookup(from, s)
+	if err != nil {
+		return tc.newErrorForAttr(n, "from", "%s", err)
+	}
+	if err := tc.unify(fromType, PrimitiveType("string")); err != nil {
+		return tc.newErrorForAttr(n, "from", "invalid time: %s", err)
+	}
+	return tc.typecheckNative(n, s)
+}
+
+func (tc *typeChecker) typecheckIf(n *html.Node, s map[string]TypeExpr) error {
+	// key is the attribute holding the condition, either a path that
+	// must be true or not, or the name of a feature flag.
+	var key, cond string
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "true", "not", "feature":
+			if key != "" {
+				return tc.newErrorForAttr(n, attr.Key, "%s can not be combined with other conditions in if", attr.Key)
+			}
+			key, cond = attr.Key, attr.Val
+		default:
+			return tc.newError(n, "unrecognized attribute '%s' in %s", attr.Key, n.Data)
+		}
+	}
+
+	switch {
+	case key == "feature" && cond == "":
+		return tc.newErrorForAttr(n, key, "empty feature name in if")
+	case key == "feature":
+	case cond == "":
+		if key == "" {
+			key = "true"
+		}
+		return tc.newErrorForAttr(n, key, "empty condition in if")
+	default:
+		condType, err := tc.typecheckLookup(cond, s)
+		if err != nil {
+			return tc.newErrorForAttr(n, key, "%s", err)
+		}
+		if err := tc.unify(condType, PrimitiveType("boolean")); err != nil {
+			return tc.newErrorForAttr(n, key, "condition must be boolean: %s", err)
+		}
+	}
+
+	for c := range n.ChildNodes() {
+		if err := tc.typecheckNode(c, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// typecheckVariant checks a `variant` element, whose cases are the
+// named variants of an experiment.
+func (tc *typeChecker) typecheckVariant(n *html.Node, s map[string]TypeExpr) error {
+	var experiment string
+	for _, attr := range n.Attr {
+		if attr.Key != "experiment" {
+			return tc.newErrorForAttr(n, attr.Key, "unrecognized attribute '%s' in %s", attr.Key, n.Data)
+		}
+		experiment = attr.Val
+	}
+	if experiment == "" {
+		return tc.newError(n, "variant is missing attribute 'experiment'")
+	}
+	names := map[string]bool{}
+	for c := range n.ChildNodes() {
+		switch {
+		case c.Type == html.CommentNode:
+			continue
+		case c.Type == html.TextNode && strings.TrimSpace(c.Data) == "":
+			continue
+		case c.Type != html.ElementNode || c.Data != "case":
+			return tc.newError(c, "variant can only contain case")
+		}
+		name, found := "", false
+		for _, attr := range c.Attr {
+			if attr.Key != "name" {
+				return tc.newErrorForAttr(c, attr.Key, "unrecognized attribute '%s' in %s", attr.Key, c.Data)
+			}
+			name, found = attr.Val, true
+		}
+		if !found {
+			return tc.newError(c, "case is missing attribute 'name'")
+		}
+		if names[name] {
+			return tc.newErrorForAttr(c, "name", "duplicate variant '%s' in experiment '%s'", name, experiment)
+		}
+		names[name] = true
+		for cc := range c.ChildNodes() {
+			if err := tc.typecheckNode(cc, s); err != nil {
+				return err
+			}
+		}
+	}
+	if len(names) == 0 {
+		return tc.newError(n, "variant must have at least one case")
+	}
+	return nil
+}
+
+// typecheckMatch checks a `match` tag. The first match without a
+// default case on a value declares the values that it can have, so its
+// type is a union of the string literal types of the cases, e.g.
+// "draft" | "published". The cases of every match on the value are
+// checked against that union, see checkMatchCases:
+//
+// <match on="post.status">
+// <case value="draft">...</case>
+// <default>...</default>
+// </match>
+func (tc *typeChecker) typecheckMatch(n *html.Node, s map[string]TypeExpr) error {
+	var on string
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "on":
+			on = attr.Val
+		default:
+			return tc.newError(n, "unrecognized attribute '%s' in %s", attr.Key, n.Data)
+		}
+	}
+
+	if on == "" {
+		return tc.newError(n, "match is missing attribute 'on'")
+	}
+
+	onType, err := tc.typecheckLookup(on, s)
+	if err != nil {
+		return tc.newErrorForAttr(n, "on", "%s", err)
+	}
+
+	var cases []*html.Node
+	var values []string
+	var hasDefault bool
+	for c := range n.ChildNodes() {
+		switch {
+		case c.Type == html.CommentNode:
+			continue
+		case c.Type == html.TextNode:
+			if strings.TrimSpace(c.Data) != "" {
+				return tc.newError(c, "match can only contain case and default")
+			}
+			continue
+		case c.Type != html.ElementNode || (c.Data != "case" && c.Data != "default"):
+			return tc.newError(c, "match can only contain case and default")
+		}
+		if hasDefault {
+			return tc.newError(c, "default must be the last case in match")
+		}
+		if c.Data == "default" {
+			if len(c.Attr) > 0 {
+				return tc.newError(c, "unrecognized attribute '%s' in %s", c.Attr[0].Key, c.Data)
+			}
+			hasDefault = true
+		} else {
+			value, found := "", false
+			for _, attr := range c.Attr {
+				if attr.Key != "value" {
+					return tc.newError(c, "unrecognized attribute '%s' in %s", attr.Key, c.Data)
+				}
+				value, found = attr.Val, true
+			}
+			if !found {
+				return tc.newError(c, "case is missing attribute 'value'")
+			}
+			if slices.Contains(values, value) {
+				return tc.newErrorForAttr(c, "value", "duplicate case '%s' in match", value)
+			}
+			cases = append(cases, c)
+			values = append(values, value)
+		}
+	}
+
+	// The cases are checked against the values of on once the module
+	// has been checked, when they are known regardless of the order of
+	// the matches.
+	tc.matchChecks = append(tc.matchChecks, matchCheck{
+		node:       n,
+		on:         on,
+		onType:     onType,
+		cases:      cases,
+		values:     values,
+		hasDefault: hasDefault,
+	})
+	switch {
+	case literalDomain(onType) != nil:
+		// The values of on are already known, e.g. from another match
+		// on the same value, and the match is checked against them.
+	case hasDefault:
+		// A variable rather than string itself, so that a later match
+		// without a default can still narrow it to its cases.
+		if err := tc.unify(onType, tc.newConstrainedVar("string")); err != nil {
+			return tc.newErrorForAttr(n, "on", "value of match must be a string: %s", err)
+		}
+	default:
+		if err := tc.unify(onType, tc.newLiteralVar(values...)); err != nil {
+			return tc.newErrorForAttr(n, "on", "value of match must be a string: %s", err)
+		}
+	}
+
+	for c := range n.ChildNodes() {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		for cc := range c.ChildNodes() {
+			if err := tc.typecheckNode(cc, s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// matchCheck is a `match` tag whose cases are checked against the
+// values of on once all functions of the module have been type checked.
+type matchCheck struct {
+	node       *html.Node
+	on         string
+	onType     TypeExpr
+	cases      []*html.Node
+	values     []string
+	hasDefault bool
+}
+
+// checkMatchCases checks that a match on a union of string literals has
+// no case for a value that is not in the union, and that it covers all
+// values of the union unless it has a default.
+func (tc *typeChecker) checkMatchCases(check matchCheck) error {
+	domain := literalDomain(check.onType)
+	if domain == nil {
+		return nil
+	}
+	for i, value := range check.values {
+		if !slices.Contains(domain, LiteralType(value)) {
+			return tc.newErrorForAttr(check.cases[i], "value", "case '%s' is not a possible value of %s, which is %s", value, check.on, Normalize(check.onType))
+		}
+	}
+	if check.hasDefault {
+		return nil
+	}
+	var missing []string
+	for _, value := range domain {
+		if !slices.Contains(check.values, string(value)) {
+			missing = append(missing, value.String())
+		}
+	}
+	if len(missing) > 0 {
+		return tc.newErrorForAttr(check.node, "on", "match on %s is missing cases for %s, add them or a default", check.on, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func (tc *typeChecker) typecheckRender(n *html.Node, s map[string]TypeExpr) error {
+	if tc.options.StrictAttributes {
+		if err := tc.checkAttributeNames(n); err != nil {
+			return err
+		}
+	}
+	functionName, ok := getAttribute(n, "function")
+	if !ok {
+		return tc.newError(n, "render is missing attribute 'function'")
+	}
+	functionType := tc.functionTypes[functionName]
+
+	// Instantiate the signature of the called function so that its type
+	// variables are shared between the params and the children slot, but
+	// not between different render calls.
+	vars := map[*TypeVar]*TypeVar{}
+
+	isVoid := resolve(functionType.Params) == PrimitiveType("void")
+
+	paramsType, paramsKey, err := tc.typecheckRenderArguments(n, s)
+	if err != nil {
+		return err
+	}
+	found := paramsType != nil
+	if found && isVoid {
+		return tc.newErrorForAttr(n, paramsKey, "function '%s' does not take params", functionName)
+	}
+	if arguments, ok := paramsType.(*ObjectType); ok && strings.HasPrefix(paramsKey, "with-") {
+		// Unlike a params object, which may have fields that are not
+		// known here, with- attributes must pass every field.
+		if object, ok := resolve(functionType.Params).(*ObjectType); ok {
+			for _, name := range slices.Sorted(maps.Keys(object.Fields)) {
+				if _, exists := arguments.Fields[name]; !exists {
+					return tc.newError(n, "missing attribute with-%s in render call for %s", name, functionName)
+				}
+			}
+		}
+	}
+	if found {
+		if err := tc.unify(paramsType, tc.instantiate(functionType.Params, vars)); err != nil {
+			if functionType.Module != "" {
+				// Point to the definition of the function since it lives in
+				// another module and its positions are not known here.
+				return tc.newError(n, "invalid parameter type for function '%s' defined in module %s at %s: %s",
+					functionName, functionType.Module, functionType.Position, err)
+			}
+			return tc.newError(n, "invalid parameter type for function '%s': %s", functionName, err)
+		}
+		if tc.options.StrictParams {
+			tc.paramsChecks = append(tc.paramsChecks, paramsCheck{
+				node:         n,
+				functionName: functionName,
+				argument:     paramsType,
+				parameter:    functionType.Params,
+			})
+		}
+	} else if !isVoid {
+		return tc.newError(n, "missing attribute params in render call for %s", functionName)
+	}
+
+	if err := tc.typecheckFills(n, functionName, functionType, s); err != nil {
+		return err
+	}
+
+	if childrenAs, found := getAttribute(n, "children-as"); found {
+		if functionType.Slot == nil {
+			return tc.newErrorForAttr(n, "children-as", "function '%s' does not pass a value to its children", functionName)
+		}
+		s = maps.Clone(s)
+		s[childrenAs] = tc.instantiate(functionType.Slot, vars)
+	}
+
+	for c := range n.ChildNodes() {
+		if isFill(c) {
+			continue
+		}
+		if err := tc.typecheckNode(c, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// typecheckRenderArguments returns the type of the value that a render
+// call passes to the called function, either with the params attribute,
+// as JSON with the params-literal attribute or as an object with a
+// field for each with- attribute, together with the key of the first
+// attribute. The type is nil if the call passes no value.
+func (tc *typeChecker) typecheckRenderArguments(n *html.Node, s map[string]TypeExpr) (TypeExpr, string, error) {
+	var paramsKey string
+	for _, attr := range n.Attr {
+		if attr.Key != "params" && attr.Key != "params-literal" && !strings.HasPrefix(attr.Key, "with-") {
+			continue
+		}
+		if paramsKey != "" && (!strings.HasPrefix(paramsKey, "with-") || !strings.HasPrefix(attr.Key, "with-")) {
+			return nil, "", tc.newErrorForAttr(n, attr.Key, "%s can not be combined with %s", attr.Key, paramsKey)
+		}
+		if paramsKey == "" {
+			paramsKey = attr.Key
+		}
+	}
+	switch paramsKey {
+	case "params":
+		params, _ := getAttribute(n, "params")
+		paramsType, err := tc.typecheckLookup(params, s)
+		if err != nil {
+			return nil, "", tc.newErrorForAttr(n, "params", "%s", err)
+		}
+		return paramsType, "params", nil
+	case "params-literal":
+		literal, _ := getAttribute(n, "params-literal")
+		var v any
+		if err := json.Unmarshal([]byte(literal), &v); err != nil {
+			return nil, "", tc.newErrorForAttr(n, "params-literal", "invalid JSON in params-literal: %s", err)
+		}
+		t, err := tc.typeOfValue(v)
+		if err != nil {
+			return nil, "", tc.newErrorForAttr(n, "params-literal", "invalid params-literal: %s", err)
+		}
+		return t, "params-literal", nil
+	}
+	var object *ObjectType
+	var firstKey string
+	for _, attr := range n.Attr {
+		name, ok := strings.CutPrefix(attr.Key, "with-")
+		if !ok {
+			continue
+		}
+		if name == "" {
+			return nil, "", tc.newErrorForAttr(n, attr.Key, "empty parameter name in %s", attr.Key)
+		}
+		var fieldType TypeExpr
+		if literal, ok := parser.ParseLiteral(attr.Val); ok {
+			fieldType, _ = tc.typeOfValue(literal)
+		} else {
+			var err error
+			fieldType, err = tc.typecheckLookup(attr.Val, s)
+			if err != nil {
+				return nil, "", tc.newErrorForAttr(n, attr.Key, "%s", err)
+			}
+		}
+		if object == nil {
+			object = &ObjectType{Fields: map[string]TypeExpr{}}
+			firstKey = attr.Key
+		}
+		object.Fields[name] = fieldType
+	}
+	if object == nil {
+		return nil, "", nil
+	}
+	return object, firstKey, nil
+}
+
+// typeOfValue returns the type of a literal value as decoded from JSON.
+func (tc *typeChecker) typeOfValue(v any) (TypeExpr, error) {
+	switch v := v.(type) {
+	case string:
+		return PrimitiveType("string"), nil
+	case float64:
+		return PrimitiveType("number"), nil
+	case bool:
+		return PrimitiveType("boolean"), nil
+	case []any:
+		elem := tc.newVar()
+		for i, e := range v {
+			t, err := tc.typeOfValue(e)
+			if err != nil {
+				return nil, err
+			}
+			if err := tc.unify(elem, t); err != nil {
+				return nil, fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		return &ArrayType{ElementType: elem}, nil
+	case map[string]any:
+		fields := make(map[string]TypeExpr, len(v))
+		for _, name := range slices.Sorted(maps.Keys(v)) {
+			t, err := tc.typeOfValue(v[name])
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", name, err)
+			}
+			fields[name] = t
+		}
+		return &ObjectType{Fields: fields}, nil
+	}
+	return tc.newVar(), nil
+}
+
+// NamedParams returns the parameters that a function declares with
+// param- attributes, mapping the name of each parameter to the variable
+// that it is bound to:
+//
+//	<function name="card" param-title param-author="by">
+//
+// binds the parameter title to the variable title and the parameter
+// author to the variable by.
+func NamedParams(function *html.Node) map[string]string {
+	var params map[string]string
+	for _, attr := range function.Attr {
+		name, ok := strings.CutPrefix(attr.Key, "param-")
+		if !ok {
+			continue
+		}
+		if params == nil {
+			params = map[string]string{}
+		}
+		params[name] = cmp.Or(attr.Val, name)
+	}
+	return params
+}
+
+// isFill reports whether n is a `fill` tag.
+func isFill(n *html.Node) bool {
+	return n.Type == html.ElementNode && n.Data == "fill"
+}
+
+// typecheckFills checks the `fill` tags of a render call against the
+// named slots of the called function. Every slot without fallback
+// content must be filled.
+func (tc *typeChecker) typecheckFills(n *html.Node, functionName string, functionType *FunctionType, s map[string]TypeExpr) error {
+	filled := map[string]bool{}
+	for c := range n.ChildNodes() {
+		if !isFill(c) {
+			continue
+		}
+		name, found := getAttribute(c, "slot")
+		if !found {
+			return tc.newError(c, "fill is missing attribute 'slot'")
+		}
+		for _, attr := range c.Attr {
+			if attr.Key != "slot" {
+				return tc.newErrorForAttr(c, attr.Key, "unrecognized attribute '%s' in fill", attr.Key)
+			}
+		}
+		if _, exists := functionType.Slots[name]; !exists {
+			return tc.newErrorForAttr(c, "slot", "function '%s' has no slot '%s'", functionName, name)
+		}
+		if filled[name] {
+			return tc.newErrorForAttr(c, "slot", "duplicate fill for slot '%s'", name)
+		}
+		filled[name] = true
+		for cc := range c.ChildNodes() {
+			if err := tc.typecheckNode(cc, s); err != nil {
+				return err
+			}
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(functionType.Slots)) {
+		if functionType.Slots[name] && !filled[name] {
+			return tc.newError(n, "missing fill for required slot '%s' of function '%s'", name, functionName)
+		}
+	}
+	return nil
+}
+
+// typecheckSlot checks a `slot` tag. The children of the tag are the
+// fallback content that is rendered when the caller does not fill the
+// slot, and a slot without fallback content is required.
+func (tc *typeChecker) typecheckSlot(n *html.Node, s map[string]TypeExpr) error {
+	name, found := getAttribute(n, "name")
+	if !found || name == "" {
+		return tc.newError(n, "slot is missing attribute 'name'")
+	}
+	for _, attr := range n.Attr {
+		if attr.Key != "name" {
+			return tc.newErrorForAttr(n, attr.Key, "unrecognized attribute '%s' in slot", attr.Key)
+		}
+	}
+	hasFallback := false
+	for c := range n.ChildNodes() {
+		if c.Type != html.TextNode || strings.TrimSpace(c.Data) != "" {
+			hasFallback = true
+		}
+		if err := tc.typecheckNode(c, s); err != nil {
+			return err
+		}
+	}
+	if tc.currentSlots == nil {
+		tc.currentSlots = map[string]bool{}
+	}
+	tc.currentSlots[name] = tc.currentSlots[name] || !hasFallback
+	return nil
+}
+
+// typecheckChildren checks a <children> tag. A value passed with the
+// params attribute determines the slot type of the current function.
+// Either all or none of the <children> tags of a function pass a value,
+// since the children of a render call bind it with children-as.
+func (tc *typeChecker) typecheckChildren(n *html.Node, s map[string]TypeExpr) error {
+	_, hasParams := getAttribute(n, "params")
+	switch {
+	case hasParams && tc.bareChildren:
+		return tc.newErrorForAttr(n, "params", "children passes params but another children of the function does not")
+	case !hasParams && tc.currentSlot != nil:
+		return tc.newError(n, "children is missing params that another children of the function passes")
+	case !hasParams:
+		tc.bareChildren = true
+	}
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "params":
+			valueType, err := tc.typecheckLookup(attr.Val, s)
+			if err != nil {
+				return tc.newErrorForAttr(n, "params", "%s", err)
+			}
+			if tc.currentSlot == nil {
+				tc.currentSlot = tc.newVar()
+			}
+			if err := tc.unify(valueType, tc.currentSlot); err != nil {
+				return tc.newErrorForAttr(n, "params", "invalid type for children params: %s", err)
+			}
+		default:
+			return tc.newError(n, "unrecognized attribute '%s' in %s", attr.Key, n.Data)
 		}
 	}
 	return nil
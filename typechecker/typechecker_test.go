@@ -0,0 +1,167 @@
+package typechecker
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestUnifyConstrainedVars(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps func(tc *typeChecker) []TypeExpr
+		ok    bool
+	}{
+		{
+			name: "string or number with string",
+			steps: func(tc *typeChecker) []TypeExpr {
+				return []TypeExpr{tc.newConstrainedVar("string", "number"), PrimitiveType("string")}
+			},
+			ok: true,
+		},
+		{
+			name: "string or number with boolean",
+			steps: func(tc *typeChecker) []TypeExpr {
+				return []TypeExpr{tc.newConstrainedVar("string", "number"), PrimitiveType("boolean")}
+			},
+			ok: false,
+		},
+		{
+			name: "string or number narrowed to number then used as string",
+			steps: func(tc *typeChecker) []TypeExpr {
+				v := tc.newVar()
+				return []TypeExpr{v, tc.newConstrainedVar("string", "number"), v, PrimitiveType("number"), v, PrimitiveType("string")}
+			},
+			ok: false,
+		},
+		{
+			name: "string or number then string or boolean",
+			steps: func(tc *typeChecker) []TypeExpr {
+				v := tc.newVar()
+				return []TypeExpr{
+					v, tc.newConstrainedVar("string", "number"),
+					v, tc.newConstrainedVar("string", "boolean"),
+					v, PrimitiveType("string"),
+				}
+			},
+			ok: true,
+		},
+		{
+			name: "string or number then boolean through variable",
+			steps: func(tc *typeChecker) []TypeExpr {
+				v := tc.newVar()
+				w := tc.newVar()
+				return []TypeExpr{v, tc.newConstrainedVar("string", "number"), w, PrimitiveType("boolean"), v, w}
+			},
+			ok: false,
+		},
+		{
+			name: "literals with string",
+			steps: func(tc *typeChecker) []TypeExpr {
+				v := tc.newVar()
+				return []TypeExpr{v, tc.newLiteralVar("draft", "published"), v, PrimitiveType("string"), v, LiteralType("draft")}
+			},
+			ok: true,
+		},
+		{
+			name: "literals used as text",
+			steps: func(tc *typeChecker) []TypeExpr {
+				v := tc.newVar()
+				return []TypeExpr{v, tc.newConstrainedVar("string", "number"), v, tc.newLiteralVar("draft", "published")}
+			},
+			ok: true,
+		},
+		{
+			name: "literals with number",
+			steps: func(tc *typeChecker) []TypeExpr {
+				return []TypeExpr{tc.newLiteralVar("draft", "published"), PrimitiveType("number")}
+			},
+			ok: false,
+		},
+		{
+			name: "literals narrowed by other literals",
+			steps: func(tc *typeChecker) []TypeExpr {
+				v := tc.newVar()
+				return []TypeExpr{v, tc.newLiteralVar("draft", "published"), v, tc.newLiteralVar("published", "archived"), v, LiteralType("draft")}
+			},
+			ok: false,
+		},
+		{
+			name: "string or number with array",
+			steps: func(tc *typeChecker) []TypeExpr {
+				return []TypeExpr{tc.newConstrainedVar("string", "number"), &ArrayType{ElementType: tc.newVar()}}
+			},
+			ok: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTypeChecker(nil, Options{})
+			steps := tt.steps(tc)
+			var err error
+			for i := 0; i < len(steps) && err == nil; i += 2 {
+				err = tc.unify(steps[i], steps[i+1])
+			}
+			if tt.ok && err != nil {
+				t.Errorf("Expected unification to succeed but got %s", err)
+			}
+			if !tt.ok && err == nil {
+				t.Errorf("Expected unification to fail")
+			}
+		})
+	}
+}
+
+func TestCheckMatchCases(t *testing.T) {
+	tests := []struct {
+		name       string
+		values     []string
+		hasDefault bool
+		err        string
+	}{
+		{
+			name:   "all cases",
+			values: []string{"draft", "published"},
+		},
+		{
+			name:   "missing case",
+			values: []string{"draft"},
+			err:    `match on post.status is missing cases for "published", add them or a default`,
+		},
+		{
+			name:       "missing case with default",
+			values:     []string{"draft"},
+			hasDefault: true,
+		},
+		{
+			name:       "case that is not possible",
+			values:     []string{"draft", "archived"},
+			hasDefault: true,
+			err:        `case 'archived' is not a possible value of post.status, which is "draft" | "published"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTypeChecker(nil, Options{})
+			check := matchCheck{
+				node:       &html.Node{Type: html.ElementNode, Data: "match"},
+				on:         "post.status",
+				onType:     tc.newLiteralVar("draft", "published"),
+				values:     tt.values,
+				hasDefault: tt.hasDefault,
+			}
+			for range tt.values {
+				check.cases = append(check.cases, &html.Node{Type: html.ElementNode, Data: "case"})
+			}
+			err := tc.checkMatchCases(check)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("Expected no error but got %s", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("Expected error %s but got %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -2,7 +2,11 @@ package typechecker
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
+
+	"github.com/hoplang/hop-go/parser"
 )
 
 // TypeExpr represents a type expression in our system
@@ -14,12 +18,23 @@ type TypeExpr interface {
 type TypeVar struct {
 	Name string
 	Link *TypeExpr // For unification
+	// Allowed restricts the types that the variable may be bound
+	// to, which are primitive types or string literal types. An empty
+	// list means that the variable is unconstrained.
+	Allowed []TypeExpr
 }
 
 func (tv *TypeVar) String() string {
 	if tv.Link != nil {
 		return (*tv.Link).String()
 	}
+	if len(tv.Allowed) > 0 {
+		types := make([]string, len(tv.Allowed))
+		for i, t := range tv.Allowed {
+			types[i] = t.String()
+		}
+		return strings.Join(types, " | ")
+	}
 	return "?" + tv.Name
 }
 
@@ -30,6 +45,75 @@ func (pt PrimitiveType) String() string {
 	return string(pt)
 }
 
+// LiteralType is the type of a single string value, such as the value
+// of a case of a `match` tag. It is a subtype of string, and a variable
+// that allows several literal types is a union of the values that it
+// can have, e.g. "draft" | "published".
+type LiteralType string
+
+func (lt LiteralType) String() string {
+	return strconv.Quote(string(lt))
+}
+
+// Trusted types mark strings that the caller has already made safe for
+// a context. They are distinct from string, so a template that renders
+// a value as trusted html can not be passed an ordinary string.
+const (
+	HTMLType PrimitiveType = "html"
+	URLType  PrimitiveType = "url"
+	JSType   PrimitiveType = "js"
+)
+
+// urlAttributes are the attributes whose values are URLs.
+var urlAttributes = map[string]bool{
+	"href":       true,
+	"src":        true,
+	"action":     true,
+	"formaction": true,
+}
+
+// IsURLAttribute reports whether the value of the attribute with the
+// given name is a URL.
+func IsURLAttribute(name string) bool {
+	return urlAttributes[name]
+}
+
+// booleanAttributes are the HTML boolean attributes, which are present
+// or absent rather than having a value.
+var booleanAttributes = map[string]bool{
+	"allowfullscreen": true,
+	"async":           true,
+	"autofocus":       true,
+	"autoplay":        true,
+	"checked":         true,
+	"controls":        true,
+	"default":         true,
+	"defer":           true,
+	"disabled":        true,
+	"formnovalidate":  true,
+	"hidden":          true,
+	"inert":           true,
+	"ismap":           true,
+	"loop":            true,
+	"multiple":        true,
+	"muted":           true,
+	"nomodule":        true,
+	"novalidate":      true,
+	"open":            true,
+	"playsinline":     true,
+	"readonly":        true,
+	"required":        true,
+	"reversed":        true,
+	"selected":        true,
+}
+
+// IsBooleanAttribute reports whether the attribute with the given name
+// is an HTML boolean attribute, which a boolean binding includes or
+// omits.
+func IsBooleanAttribute(name string) bool {
+	return booleanAttributes[name]
+}
+
 // ArrayType represents an array of some type
 type ArrayType struct {
 	ElementType TypeExpr
@@ -45,13 +129,28 @@ type ObjectType struct {
 }
 
 func (ot *ObjectType) String() string {
-	fields := make([]string, 0, len(ot.Fields))
-	for name, typ := range ot.Fields {
-		fields = append(fields, fmt.Sprintf("%s: %s", name, typ))
+	names := make([]string, 0, len(ot.Fields))
+	for name := range ot.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fields := make([]string, len(names))
+	for i, name := range names {
+		fields[i] = fmt.Sprintf("%s: %s", name, ot.Fields[name])
 	}
 	return fmt.Sprintf("{%s}", strings.Join(fields, ", "))
 }
 
+// MapType represents an object with arbitrary keys whose values all
+// have the same type, such as an object iterated with key-as.
+type MapType struct {
+	ValueType TypeExpr
+}
+
+func (mt *MapType) String() string {
+	return fmt.Sprintf("map[string]%s", mt.ValueType)
+}
+
 // UnionType represents a type that could be one of several types
 type UnionType struct {
 	Types []TypeExpr
@@ -64,3 +163,47 @@ func (ut *UnionType) String() string {
 	}
 	return strings.Join(types, " | ")
 }
+
+// FunctionType represents the signature of a function
+// FilterType is the signature of a filter. Input is the type of the
+// value that is piped into the filter and Params are the types of its
+// arguments. Type variables that are shared between the types are
+// instantiated for each use of the filter.
+type FilterType struct {
+	Input  TypeExpr
+	Params []TypeExpr
+	Result TypeExpr
+}
+
+type FunctionType struct {
+	Params TypeExpr
+	// Slot is the type of the value that the function passes to its
+	// children using <children params="...">, or nil if it passes none.
+	Slot TypeExpr
+	// Slots are the named slots of the function, declared with
+	// <slot name="...">, mapped to whether they are required.
+	Slots map[string]bool
+	// Module and Position identify where the function is defined.
+	// Module is set by the compiler once the module has been checked.
+	Module   string
+	Position parser.Position
+}
+
+func (ft *FunctionType) String() string {
+	s := fmt.Sprintf("(%s)", ft.Params)
+	if ft.Slot != nil {
+		s += fmt.Sprintf(" children(%s)", ft.Slot)
+	}
+	if len(ft.Slots) > 0 {
+		var slots []string
+		for name, required := range ft.Slots {
+			if !required {
+				name += "?"
+			}
+			slots = append(slots, name)
+		}
+		sort.Strings(slots)
+		s += fmt.Sprintf(" slots(%s)", strings.Join(slots, ", "))
+	}
+	return s
+}
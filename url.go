@@ -0,0 +1,62 @@
+package hop
+
+import (
+	"fmt"
+	"strings"
+)
+
+// URLPolicy decides whether a URL may be used as the value of a bound
+// URL attribute such as `attr-href`. It is called at render time with the
+// name of the attribute, e.g. "href", and returns an error to reject the
+// value.
+type URLPolicy func(attribute string, url string) error
+
+// safeDataImages are the media types of data URLs that DefaultURLPolicy
+// allows in src attributes.
+var safeDataImages = map[string]bool{
+	"image/png":  true,
+	"image/gif":  true,
+	"image/jpeg": true,
+	"image/webp": true,
+	"image/avif": true,
+}
+
+// DefaultURLPolicy rejects javascript: and vbscript: URLs, and data: URLs
+// except for raster images used as src.
+func DefaultURLPolicy(attribute string, url string) error {
+	scheme, rest, found := strings.Cut(url, ":")
+	if !found {
+		return nil
+	}
+	// Browsers ignore leading whitespace and control characters as well
+	// as tabs and newlines inside the scheme.
+	scheme = strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, scheme)
+	if strings.ContainsAny(scheme, "/?#") {
+		// The colon is not part of a scheme, e.g. "/a:b".
+		return nil
+	}
+	switch strings.ToLower(scheme) {
+	case "javascript", "vbscript":
+		return fmt.Errorf("%s: URLs are not allowed in %s", strings.ToLower(scheme), attribute)
+	case "data":
+		mediaType, _, _ := strings.Cut(rest, ",")
+		mediaType, _, _ = strings.Cut(mediaType, ";")
+		if attribute == "src" && safeDataImages[strings.ToLower(strings.TrimSpace(mediaType))] {
+			return nil
+		}
+		return fmt.Errorf("data: URLs are not allowed in %s", attribute)
+	}
+	return nil
+}
+
+// SetURLPolicy sets the policy that compiled programs use to check values
+// bound to URL attributes. It defaults to DefaultURLPolicy. A nil policy
+// allows every URL.
+func (c *Compiler) SetURLPolicy(policy URLPolicy) {
+	c.urlPolicy = policy
+}
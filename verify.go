@@ -0,0 +1,80 @@
+package hop
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"golang.org/x/net/html"
+)
+
+// Problem is a broken invariant of a program found by Verify.
+type Problem struct {
+	Module   string
+	Function string
+	Message  string
+}
+
+func (p Problem) String() string {
+	if p.Function == "" {
+		return fmt.Sprintf("%s: %s", p.Module, p.Message)
+	}
+	return fmt.Sprintf("%s/%s: %s", p.Module, p.Function, p.Message)
+}
+
+// Verify re-checks the invariants that compilation establishes: every
+// function has a type, every import refers to a public function of an
+// existing module, and every render tag resolves to a typed function.
+// It is meant for smoke tests of deployments, and a program returned
+// by Compile has no problems. The problems are sorted by module and
+// function name.
+func (p *Program) Verify() []Problem {
+	var problems []Problem
+	for _, moduleName := range slices.Sorted(maps.Keys(p.modules)) {
+		mod := p.modules[moduleName]
+		report := func(functionName string, format string, args ...any) {
+			problems = append(problems, Problem{
+				Module:   moduleName,
+				Function: functionName,
+				Message:  fmt.Sprintf(format, args...),
+			})
+		}
+		for _, importModuleName := range slices.Sorted(maps.Keys(mod.imports)) {
+			importedModule, exists := p.modules[importModuleName]
+			for _, functionName := range mod.imports[importModuleName] {
+				switch {
+				case !exists:
+					report("", "import of %s from missing module %s", functionName, importModuleName)
+				case importedModule.functions[functionName] == nil || importedModule.private[functionName]:
+					report("", "import of missing function %s from module %s", functionName, importModuleName)
+				}
+			}
+		}
+		for _, functionName := range slices.Sorted(maps.Keys(mod.functions)) {
+			if t := mod.functionTypes[functionName]; t == nil || t.Params == nil || t.Module != moduleName {
+				report(functionName, "function has no type")
+			}
+			var visit func(n *html.Node)
+			visit = func(n *html.Node) {
+				if n.Type == html.ElementNode && n.Data == "render" {
+					target, ok := mod.renderTargets[n]
+					if !ok {
+						name, _ := getAttribute(n, "function")
+						report(functionName, "render of %s is not resolved", name)
+					} else if targetModule, exists := p.modules[target.module]; !exists {
+						report(functionName, "render of %s resolves to missing module %s",
+							target.function, target.module)
+					} else if targetModule.functionTypes[target.function] == nil {
+						report(functionName, "render of %s resolves to missing function %s/%s",
+							target.function, target.module, target.function)
+					}
+				}
+				for c := range n.ChildNodes() {
+					visit(c)
+				}
+			}
+			visit(mod.functions[functionName])
+		}
+	}
+	return problems
+}
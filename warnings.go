@@ -0,0 +1,80 @@
+package hop
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hoplang/hop-go/parser"
+	"github.com/hoplang/hop-go/typechecker"
+	"golang.org/x/net/html"
+)
+
+// Warning is a diagnostic that does not prevent a program from compiling.
+type Warning struct {
+	Module  string
+	File    string
+	Pos     parser.Position
+	Message string
+}
+
+func (w Warning) String() string {
+	location := w.Module
+	if w.File != "" {
+		location = w.File
+	}
+	return fmt.Sprintf("%s: %s: warning: %s", location, w.Pos, w.Message)
+}
+
+// Warnings returns the warnings reported while compiling the program,
+// ordered by module and position.
+func (p *Program) Warnings() []Warning {
+	return p.warnings
+}
+
+// collectWarnings reports the warnings for a module.
+func (p *Program) collectWarnings(moduleName string) {
+	mod := p.modules[moduleName]
+	warn := func(n *html.Node, attrName string, format string, args ...any) {
+		pos := mod.nodePositions[n].Start
+		if attrPos, ok := mod.nodePositions[n].Attributes[attrName]; ok {
+			pos = attrPos.NameStart
+		}
+		p.warnings = append(p.warnings, Warning{
+			Module:  moduleName,
+			File:    mod.path,
+			Pos:     pos,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+	var visit func(n *html.Node)
+	visit = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			for _, attr := range n.Attr {
+				name, ok := strings.CutPrefix(attr.Key, "attr-")
+				if !ok || !typechecker.IsURLAttribute(name) {
+					continue
+				}
+				if t, ok := mod.urlBindings[typechecker.Binding{Node: n, Attr: attr.Key}]; ok && mayBeString(t) {
+					warn(n, attr.Key, "URL attribute %s is bound to untyped data '%s' and will be checked by the URL policy at render time", name, attr.Val)
+				}
+			}
+		}
+		for c := range n.ChildNodes() {
+			visit(c)
+		}
+	}
+	visit(mod.root)
+}
+
+// mayBeString reports whether a value of type t can be a plain string,
+// as opposed to a number or a value that is typed as a URL.
+func mayBeString(t typechecker.TypeExpr) bool {
+	switch t := typechecker.Normalize(t).(type) {
+	case typechecker.PrimitiveType:
+		return t == typechecker.PrimitiveType("string") || t == typechecker.PrimitiveType("any")
+	case *typechecker.UnionType:
+		return slices.ContainsFunc(t.Types, mayBeString)
+	}
+	return false
+}